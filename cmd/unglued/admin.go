@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/auth"
+	"unglued/internal/backup"
+	"unglued/internal/httpx"
+	"unglued/internal/model"
+	"unglued/internal/store"
+	"unglued/internal/util"
+)
+
+var errAdminUsage = errors.New("usage: unglued admin list|inspect|delete|purge-expired [flags] [id...]")
+
+/*
+unglued admin …: Aufräumen per Skript über /api/admin. Mit -backup läuft dieselbe
+Admin-API im Prozess auf einer Backup-Datei; delete und purge-expired schreiben sie zurück.
+*/
+func runAdmin(args []string) error {
+	if len(args) == 0 {
+		return errAdminUsage
+	}
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet("admin "+cmd, flag.ExitOnError)
+	var c adminClient
+	c.flags(fs)
+	file := fs.String("backup", "", "work offline on this backup file (.tar, .tar.zst, .tar.gz) instead of a server")
+	asJSON := fs.Bool("json", false, "list: print JSON lines instead of a table")
+	q := fs.String("q", "", "list: only pastes whose ID, title, author or owner contains this")
+	sortBy := fs.String("sort", store.SortCreated, "list: created, expires or size")
+	ids, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	var save func() error
+	if *file != "" {
+		if save, err = offlineAdmin(&c, *file); err != nil {
+			return err
+		}
+	}
+
+	switch cmd {
+	case "list":
+		return adminList(&c, *q, *sortBy, *asJSON)
+	case "inspect":
+		return forEachID(ids, func(id string) error { return adminInspect(&c, id) })
+	case "delete":
+		err = forEachID(ids, func(id string) error {
+			resp, err := c.do(http.MethodDelete, "/api/admin/pastes/"+url.PathEscape(id), nil)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			fmt.Println("deleted", id)
+			return nil
+		})
+	case "purge-expired":
+		var out struct {
+			Purged int `json:"purged"`
+		}
+		if err = c.getJSON(http.MethodPost, "/api/admin/purge-expired", &out); err == nil {
+			fmt.Println("purged", out.Purged)
+		}
+	default:
+		return errAdminUsage
+	}
+	if save != nil && err == nil {
+		err = save()
+	}
+	return err
+}
+
+// forEachID versucht alle IDs und sammelt die Fehler.
+func forEachID(ids []string, fn func(string) error) error {
+	if len(ids) == 0 {
+		return errAdminUsage
+	}
+	var errs []error
+	for _, id := range ids {
+		errs = append(errs, fn(id))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *adminClient) getJSON(method, path string, v any) error {
+	resp, err := c.do(method, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+type adminItem struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Lang        string `json:"lang"`
+	Versions    int    `json:"versions"`
+	Size        int    `json:"size"`
+	CreatedAt   string `json:"created_at"`
+	ExpiresAt   string `json:"expires_at"`
+	Public      bool   `json:"public"`
+	Private     bool   `json:"private"`
+	Flagged     bool   `json:"flagged"`
+	Quarantined bool   `json:"quarantined"`
+}
+
+func (it adminItem) flags() string {
+	var f []string
+	for _, x := range []struct {
+		on   bool
+		name string
+	}{{it.Public, "public"}, {it.Private, "private"}, {it.Flagged, "flagged"}, {it.Quarantined, "quarantined"}} {
+		if x.on {
+			f = append(f, x.name)
+		}
+	}
+	return strings.Join(f, ",")
+}
+
+// adminList holt alle Seiten von /api/admin/pastes.
+func adminList(c *adminClient, q, sortBy string, asJSON bool) error {
+	var tw *tabwriter.Writer
+	if !asJSON {
+		tw = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tCREATED\tEXPIRES\tSIZE\tVERS\tLANG\tFLAGS\tTITLE")
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for page, seen := 1, 0; ; page++ {
+		var resp struct {
+			Total int               `json:"total"`
+			Items []json.RawMessage `json:"items"`
+		}
+		v := url.Values{"page": {strconv.Itoa(page)}, "per_page": {"100"}, "sort": {sortBy}, "q": {q}}
+		if err := c.getJSON(http.MethodGet, "/api/admin/pastes?"+v.Encode(), &resp); err != nil {
+			return err
+		}
+		for _, raw := range resp.Items {
+			if asJSON {
+				_ = enc.Encode(raw)
+				continue
+			}
+			var it adminItem
+			if err := json.Unmarshal(raw, &it); err != nil {
+				return err
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n", it.ID, shortTime(it.CreatedAt), shortTime(it.ExpiresAt),
+				util.HumanBytes(uint64(it.Size)), it.Versions, it.Lang, it.flags(), it.Title)
+		}
+		seen += len(resp.Items)
+		if len(resp.Items) == 0 || seen >= resp.Total {
+			break
+		}
+	}
+	if tw != nil {
+		return tw.Flush()
+	}
+	return nil
+}
+
+func shortTime(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func adminInspect(c *adminClient, id string) error {
+	resp, err := c.do(http.MethodGet, "/api/admin/pastes/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, b, "", "  "); err != nil {
+		return err
+	}
+	_, err = out.WriteTo(os.Stdout)
+	return err
+}
+
+/*
+offlineAdmin lädt file in einen eigenen Store und leitet c an die Admin-API eines
+Servers im Prozess um. Abgelaufene Pastes bleiben sichtbar (inspect), bis purge-expired
+oder das Zurückschreiben sie entfernt. save schreibt den Stand atomar nach file.
+*/
+func offlineAdmin(c *adminClient, file string) (save func() error, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := decompressor(f, file)
+	if err != nil {
+		return nil, err
+	}
+	st := store.New(time.Hour)
+	st.SetGrace(100 * 365 * 24 * time.Hour)
+	st.SetDeleteGrace(100 * 365 * 24 * time.Hour)
+	if _, err := backup.Read(r, func(p model.Paste) error { st.Put(p); return nil }); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	token := util.NewID(32)
+	index, view, edit := httpx.LoadTemplates()
+	srv := httpx.NewServer(httpx.Config{Admin: auth.AdminConfig{Token: token}}, st, index, view, edit)
+	router := chi.NewRouter()
+	httpx.MountRoutes(router, srv)
+	c.hc = &http.Client{Transport: handlerTransport{router}}
+	c.token, c.password, c.otp = token, "", ""
+
+	return func() error {
+		tmp, err := os.CreateTemp(dirOf(file), ".unglued-backup-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if err := tmp.Chmod(0o600); err != nil {
+			return err
+		}
+		w, closeW, err := compressor(tmp, file)
+		if err != nil {
+			return err
+		}
+		if err := backup.Write(w, st.Snapshot()); err != nil {
+			return err
+		}
+		if err := closeW(); err != nil {
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		return os.Rename(tmp.Name(), file)
+	}, nil
+}
+
+// handlerTransport beantwortet Requests direkt mit h, ohne Netzwerk.
+type handlerTransport struct{ h http.Handler }
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.h.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
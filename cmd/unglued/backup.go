@@ -0,0 +1,203 @@
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+/*
+adminClient spricht mit einem laufenden Server über /api/admin – der Store lebt im
+Speicher dieses Prozesses, also laufen Backup und Restore über die API.
+*/
+type adminClient struct {
+	server, token, user, password, otp string
+
+	// gesetzt = statt server (offline auf einer Backup-Datei, siehe admin.go)
+	hc *http.Client
+}
+
+func (c *adminClient) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.server, "server", envOr("UNGLUED_SERVER", "http://localhost:8080"), "base URL of the running unglued (or unix:/path/to.sock)")
+	fs.StringVar(&c.token, "admin-token", os.Getenv("UNGLUED_ADMIN_TOKEN"), "admin bearer token")
+	fs.StringVar(&c.user, "admin-user", "admin", "admin basic-auth user")
+	fs.StringVar(&c.password, "admin-password", os.Getenv("UNGLUED_ADMIN_PASSWORD"), "admin basic-auth password")
+	fs.StringVar(&c.otp, "otp", "", "current TOTP or recovery code, if admin 2FA is enabled")
+}
+
+func (c *adminClient) do(method, path string, body io.Reader) (*http.Response, error) {
+	hc, base := httpFor(c.server)
+	if c.hc != nil {
+		hc, base = c.hc, "http://unglued"
+	}
+	req, err := http.NewRequest(method, base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.password != "":
+		req.SetBasicAuth(c.user, c.password)
+	}
+	if c.otp != "" {
+		req.Header.Set("X-Unglued-OTP", c.otp)
+	}
+	return checkResponse(hc.Do(req))
+}
+
+// httpFor: Client und Basis-URL für server (http(s)://… oder unix:/path).
+func httpFor(server string) (*http.Client, string) {
+	if sock, ok := strings.CutPrefix(server, "unix:"); ok {
+		return unixClient(sock), "http://unglued"
+	}
+	return http.DefaultClient, strings.TrimRight(server, "/")
+}
+
+// checkResponse macht aus Antworten ab 300 einen Fehler mit dem Anfang des Bodys.
+func checkResponse(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return nil, fmt.Errorf("%s %s: %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// unglued backup -out pastes.tar.zst
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	var c adminClient
+	c.flags(fs)
+	out := fs.String("out", "", "target file; .zst or .gz compresses, - writes to stdout")
+	_ = fs.Parse(args)
+	if *out == "" {
+		return fmt.Errorf("-out is required")
+	}
+	resp, err := c.do(http.MethodGet, "/api/admin/backup", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var f *os.File = os.Stdout
+	if *out != "-" {
+		// erst in eine Temp-Datei, damit ein abgebrochenes Backup kein altes überschreibt
+		if f, err = os.CreateTemp(dirOf(*out), ".unglued-backup-*"); err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		if err := f.Chmod(0o600); err != nil {
+			return err
+		}
+	}
+	w, closeW, err := compressor(f, *out)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := closeW(); err != nil {
+		return err
+	}
+	if *out != "-" {
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(f.Name(), *out); err != nil {
+			return err
+		}
+		log.Printf("backup: %d bytes (uncompressed) written to %s", n, *out)
+	}
+	return nil
+}
+
+// unglued restore -in pastes.tar.zst [-overwrite]
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	var c adminClient
+	c.flags(fs)
+	in := fs.String("in", "", "backup file (.tar, .tar.zst, .tar.gz), - reads stdin")
+	overwrite := fs.Bool("overwrite", false, "replace pastes whose ID already exists")
+	_ = fs.Parse(args)
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+	var f io.ReadCloser = os.Stdin
+	if *in != "-" {
+		var err error
+		if f, err = os.Open(*in); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+	r, err := decompressor(f, *in)
+	if err != nil {
+		return err
+	}
+	path := "/api/admin/restore"
+	if *overwrite {
+		path += "?overwrite=1"
+	}
+	resp, err := c.do(http.MethodPost, path, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	log.Printf("restore: %s", strings.TrimSpace(string(body)))
+	return nil
+}
+
+func compressor(w io.Writer, name string) (io.Writer, func() error, error) {
+	switch {
+	case strings.HasSuffix(name, ".zst"):
+		zw, err := zstd.NewWriter(w)
+		return zw, zw.Close, err
+	case strings.HasSuffix(name, ".gz"):
+		gw := gzip.NewWriter(w)
+		return gw, gw.Close, nil
+	}
+	return w, func() error { return nil }, nil
+}
+
+func decompressor(r io.Reader, name string) (io.Reader, error) {
+	switch {
+	case strings.HasSuffix(name, ".zst"):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case strings.HasSuffix(name, ".gz"):
+		return gzip.NewReader(r)
+	}
+	return r, nil
+}
+
+func dirOf(path string) string {
+	if i := strings.LastIndexByte(path, '/'); i >= 0 {
+		return path[:i+1]
+	}
+	return "."
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"unglued/internal/util"
+)
+
+/*
+Client-Unterbefehle paste/get/edit. Server und Token kommen aus Flags, Umgebung
+oder der Client-Konfiguration (in dieser Reihenfolge); Edit-Keys eigener Pastes
+merkt sich der Client neben der Konfiguration in keys.json.
+*/
+type apiClient struct {
+	server, token string
+}
+
+// Einstellungen, die client.conf setzen darf, und ihre Umgebungsvariablen.
+var clientSettings = map[string]string{
+	"server": "UNGLUED_SERVER",
+	"token":  "UNGLUED_TOKEN",
+	"author": "UNGLUED_AUTHOR",
+	"ttl":    "UNGLUED_TTL",
+}
+
+func (c *apiClient) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.server, "server", "http://localhost:8080", "base URL of the unglued instance (or unix:/path/to.sock)")
+	fs.StringVar(&c.token, "token", "", "sent as Authorization: Bearer, e.g. for an instance behind an auth proxy")
+}
+
+func clientDir() string {
+	if d := os.Getenv("UNGLUED_CLIENT_DIR"); d != "" {
+		return d
+	}
+	d, err := os.UserConfigDir()
+	if err != nil {
+		return ".unglued"
+	}
+	return filepath.Join(d, "unglued")
+}
+
+/*
+parseClientArgs parst Flags auch hinter Dateinamen (unglued paste x.go -lang go)
+und füllt nicht gesetzte Flags aus Umgebung und client.conf.
+*/
+func parseClientArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	path := filepath.Join(clientDir(), "client.conf")
+	vals, err := parseSettings(path, func(name string) bool { return clientSettings[name] != "" })
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for name, env := range clientSettings {
+		f := fs.Lookup(name)
+		if f == nil || set[name] {
+			continue
+		}
+		v, ok := os.LookupEnv(env)
+		if !ok {
+			v, ok = vals[name]
+		}
+		if !ok {
+			continue
+		}
+		if err := f.Value.Set(v); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return rest, nil
+}
+
+// parseInterspersed: wie fs.Parse, aber Flags dürfen auch zwischen den Argumenten stehen.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return rest, nil
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func (c *apiClient) do(method, path string, body any) (*http.Response, error) {
+	hc, base := httpFor(c.server)
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, base+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return checkResponse(hc.Do(req))
+}
+
+// savedPaste: was der Client zu eigenen Pastes behält, um sie später zu bearbeiten.
+type savedPaste struct {
+	Server string `json:"server"`
+	Key    string `json:"key"`
+	Lang   string `json:"lang,omitempty"`
+}
+
+func keysPath() string { return filepath.Join(clientDir(), "keys.json") }
+
+func loadKeys() (map[string]savedPaste, error) {
+	keys := map[string]savedPaste{}
+	b, err := os.ReadFile(keysPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return keys, json.Unmarshal(b, &keys)
+}
+
+func rememberKey(id string, sp savedPaste) error {
+	keys, err := loadKeys()
+	if err != nil {
+		return err
+	}
+	keys[id] = sp
+	if err := os.MkdirAll(clientDir(), 0o700); err != nil {
+		return err
+	}
+	return util.WriteJSONFile(keysPath(), keys, 0o600)
+}
+
+// pasteRef zerlegt eine ID oder eine /p/…-, /raw/…- bzw. Edit-URL in ID und Query.
+func pasteRef(arg string) (id string, q url.Values) {
+	u, err := url.Parse(arg)
+	if err != nil || !strings.Contains(arg, "/") {
+		return arg, url.Values{}
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, p := range parts {
+		if (p == "p" || p == "raw") && i+1 < len(parts) {
+			return parts[i+1], u.Query()
+		}
+	}
+	return parts[len(parts)-1], u.Query()
+}
+
+// extOf: Endung der Temp-Datei, damit der Editor passend hervorhebt.
+func extOf(lang string) string {
+	for _, ext := range []string{".go", ".js", ".ts", ".json", ".yaml", ".toml", ".py", ".sh", ".html", ".css", ".sql", ".md"} {
+		if util.LangExts[ext] == lang {
+			return ext
+		}
+	}
+	return ".txt"
+}
+
+// readInput liest file, "-" oder – ohne Argument – stdin, sofern dort etwas ankommt.
+func readInput(args []string) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("expected at most one file, got %d", len(args))
+	}
+	if len(args) == 1 && args[0] != "-" {
+		b, err := os.ReadFile(args[0])
+		return string(b), err
+	}
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 && len(args) == 0 {
+		return "", fmt.Errorf("nothing to paste: pass a file or pipe into stdin")
+	}
+	b, err := io.ReadAll(os.Stdin)
+	return string(b), err
+}
+
+// unglued paste [flags] [file]
+func runPaste(args []string) error {
+	fs := flag.NewFlagSet("paste", flag.ExitOnError)
+	var c apiClient
+	c.flags(fs)
+	var req struct {
+		Code     string   `json:"code"`
+		Title    string   `json:"title,omitempty"`
+		Tags     []string `json:"tags,omitempty"`
+		Lang     string   `json:"lang,omitempty"`
+		TTL      string   `json:"ttl,omitempty"`
+		Editable bool     `json:"editable"`
+		Public   bool     `json:"public,omitempty"`
+		Private  bool     `json:"private,omitempty"`
+		Author   string   `json:"author,omitempty"`
+	}
+	var tags string
+	fs.StringVar(&req.Lang, "lang", "", "code language (default: from the file extension, else plaintext)")
+	fs.StringVar(&req.TTL, "ttl", "", "expiry, e.g. 1h (default: the server's)")
+	fs.StringVar(&req.Title, "title", "", "title")
+	fs.StringVar(&tags, "tags", "", "comma-separated tags")
+	fs.StringVar(&req.Author, "author", "", "author name")
+	fs.BoolVar(&req.Editable, "editable", true, "create an edit link and remember it for unglued edit")
+	fs.BoolVar(&req.Public, "public", false, "list in the public archive")
+	fs.BoolVar(&req.Private, "private", false, "only viewable with a share link")
+	files, err := parseClientArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if req.Code, err = readInput(files); err != nil {
+		return err
+	}
+	if req.Lang == "" && len(files) == 1 {
+		req.Lang = util.LangForFile(files[0])
+	}
+	if len(files) == 1 && req.Title == "" && files[0] != "-" {
+		req.Title = filepath.Base(files[0])
+	}
+	if tags != "" {
+		req.Tags = strings.Split(tags, ",")
+	}
+
+	resp, err := c.do(http.MethodPost, "/api/paste", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		ID       string   `json:"id"`
+		URL      string   `json:"url"`
+		EditURL  string   `json:"edit_url"`
+		Redacted []string `json:"redacted"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return err
+	}
+	if len(out.Redacted) > 0 {
+		fmt.Fprintf(os.Stderr, "redacted: %s\n", strings.Join(out.Redacted, ", "))
+	}
+	if out.EditURL != "" {
+		_, q := pasteRef(out.EditURL)
+		if err := rememberKey(out.ID, savedPaste{Server: c.server, Key: q.Get("key"), Lang: req.Lang}); err != nil {
+			fmt.Fprintf(os.Stderr, "could not remember edit key: %v\n", err)
+		}
+		fmt.Fprintf(os.Stderr, "edit: %s\n", out.EditURL)
+	}
+	fmt.Println(out.URL)
+	return nil
+}
+
+// unglued get [-v N] <id|url>
+func runGet(args []string) error {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	var c apiClient
+	c.flags(fs)
+	ver := fs.Int("v", 0, "version to fetch (default: latest)")
+	refs, err := parseClientArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(refs) != 1 {
+		return fmt.Errorf("usage: unglued get [-v N] <id|url>")
+	}
+	body, err := c.raw(refs[0], *ver)
+	if err != nil {
+		return err
+	}
+	// der Server speichert ohne abschließenden Zeilenumbruch
+	if !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+	_, err = os.Stdout.WriteString(body)
+	return err
+}
+
+// raw holt den Inhalt; ein ?grant= aus einer Share-URL wird mitgeschickt.
+func (c *apiClient) raw(ref string, ver int) (string, error) {
+	id, q := pasteRef(ref)
+	rq := url.Values{}
+	if g := q.Get("grant"); g != "" {
+		rq.Set("grant", g)
+	}
+	if ver > 0 {
+		rq.Set("v", strconv.Itoa(ver))
+	}
+	path := "/raw/" + url.PathEscape(id)
+	if len(rq) > 0 {
+		path += "?" + rq.Encode()
+	}
+	resp, err := c.do(http.MethodGet, path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return string(b), err
+}
+
+// unglued edit [flags] <id|edit-url> [file]: ohne Datei öffnet sich $EDITOR.
+func runEdit(args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	var c apiClient
+	c.flags(fs)
+	key := fs.String("key", "", "edit key (default: from the edit URL or remembered by unglued paste)")
+	lang := fs.String("lang", "", "change the code language (default: keep)")
+	author := fs.String("author", "", "author of this version")
+	refs, err := parseClientArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(refs) < 1 || len(refs) > 2 {
+		return fmt.Errorf("usage: unglued edit [flags] <id|edit-url> [file|-]")
+	}
+	id, q := pasteRef(refs[0])
+	saved := savedPaste{}
+	if keys, err := loadKeys(); err == nil {
+		saved = keys[id]
+	}
+	if *key == "" {
+		*key = q.Get("key")
+	}
+	if *key == "" {
+		*key = saved.Key
+	}
+	if *key == "" {
+		return fmt.Errorf("no edit key for %s: pass -key or the edit URL", id)
+	}
+
+	var code string
+	if len(refs) == 2 {
+		if code, err = readInput(refs[1:]); err != nil {
+			return err
+		}
+	} else {
+		current, err := c.raw(id, 0)
+		if err != nil {
+			return err
+		}
+		l := *lang
+		if l == "" {
+			l = saved.Lang
+		}
+		if code, err = editInEditor(id, current, extOf(l)); err != nil {
+			return err
+		}
+		// der Server speichert ohne Leerraum an den Rändern
+		if strings.TrimSpace(code) == strings.TrimSpace(current) {
+			fmt.Fprintln(os.Stderr, "no changes")
+			return nil
+		}
+	}
+
+	body := map[string]string{"code": code, "lang": *lang, "author": *author}
+	resp, err := c.do(http.MethodPost, "/api/paste/"+url.PathEscape(id)+"/edit?key="+url.QueryEscape(*key), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return err
+	}
+	if *lang != "" && saved.Key != "" {
+		saved.Lang = *lang
+		_ = rememberKey(id, saved)
+	}
+	fmt.Println(out.URL)
+	return nil
+}
+
+// editInEditor schreibt text in eine Temp-Datei, öffnet $VISUAL/$EDITOR (sonst vi) und liest zurück.
+func editInEditor(id, text, ext string) (string, error) {
+	f, err := os.CreateTemp("", "unglued-"+id+"-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	editor := envOr("VISUAL", envOr("EDITOR", "vi"))
+	// $EDITOR darf Argumente enthalten ("code --wait")
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", f.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %v", editor, err)
+	}
+	b, err := os.ReadFile(f.Name())
+	return string(b), err
+}
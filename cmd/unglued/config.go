@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// reloadable: Flags, die SIGHUP bzw. POST /api/admin/reload zur Laufzeit übernimmt.
+var reloadable = map[string]bool{
+	"rate-limit":      true,
+	"rate-window":     true,
+	"allow-cidrs":     true,
+	"max-paste-bytes": true,
+	"max-ttl":         true,
+	"min-ttl":         true,
+	"memory-budget":   true,
+	"expired-grace":   true,
+	"delete-grace":    true,
+	"enable-archive":  true,
+	"enable-search":   true,
+	"enable-uploads":  true,
+	"enable-edit":     true,
+	"enable-private":  true,
+	"ui-lang":         true,
+	"template-dir":    true,
+	"robots-file":     true,
+	"bot-honeypot":    true,
+	"bot-min-fill":    true,
+	"bot-ua":          true,
+	"bot-log-only":    true,
+}
+
+/*
+readConfigFile liest "name = value"-Zeilen mit den Flag-Namen (ohne "-");
+Leerzeilen und Zeilen mit # am Anfang werden übersprungen.
+*/
+func readConfigFile(path string) (map[string]string, error) {
+	return parseSettings(path, func(name string) bool {
+		return flag.Lookup(name) != nil && name != "config"
+	})
+}
+
+// parseSettings: das Zeilenformat von readConfigFile, known entscheidet über gültige Namen.
+func parseSettings(path string, known func(string) bool) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	vals := map[string]string{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "-")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s:%d: expected name = value", path, n)
+		}
+		if !known(name) {
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", path, n, name)
+		}
+		vals[name] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return vals, sc.Err()
+}
+
+/*
+applyConfigFile setzt die Werte aus path auf die Flags; was auf der Kommandozeile
+stand, gewinnt. Mit only != nil (Reload) werden nur diese Flags übernommen – fehlen
+sie in der Datei, gilt wieder der Default –, Änderungen an anderen nur gemeldet.
+*/
+func applyConfigFile(path string, cmdline, only map[string]bool) error {
+	vals, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if only != nil {
+		for name := range only {
+			if _, ok := vals[name]; !ok && !cmdline[name] {
+				vals[name] = flag.Lookup(name).DefValue
+			}
+		}
+	}
+	for name, v := range vals {
+		if cmdline[name] {
+			continue
+		}
+		f := flag.Lookup(name)
+		if only != nil && !only[name] {
+			if f.Value.String() != v {
+				log.Printf("config: %s changed, takes effect after a restart", name)
+			}
+			continue
+		}
+		if err := f.Value.Set(v); err != nil {
+			return fmt.Errorf("%s: %s: %v", path, name, err)
+		}
+	}
+	return nil
+}
+
+// checkTTLBounds: -min-ttl darf nicht über -max-ttl liegen, sonst ließe sich nichts mehr anlegen.
+func checkTTLBounds(min, max time.Duration) error {
+	if min > 0 && max > 0 && min > max {
+		return fmt.Errorf("%s is longer than -max-ttl %s", min, max)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"unglued/internal/keysource"
+)
+
+// keyWatch hält einen aus einer keysource geholten Wert aktuell.
+type keyWatch struct {
+	src keysource.Source
+	cur keysource.Value
+}
+
+// fetchKey holt den Wert für flagName aus uri; ein Fehler beim Start ist fatal.
+func fetchKey(flagName, uri string, o keysource.Options) (string, *keyWatch) {
+	src, err := keysource.Parse(uri, o)
+	if err != nil {
+		log.Fatalf("-%s: %v", flagName, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	v, err := src.Fetch(ctx)
+	if err != nil {
+		log.Fatalf("-%s: %s: %v", flagName, src, err)
+	}
+	if v.Secret == "" {
+		log.Fatalf("-%s: %s is empty", flagName, src)
+	}
+	return v.Secret, &keyWatch{src: src, cur: v}
+}
+
+// watch holt den Wert im Hintergrund vor Ablauf seines Leases (oder alle refresh) neu.
+func (k *keyWatch) watch(refresh time.Duration, apply func(string) error) {
+	if k != nil {
+		go keysource.Watch(context.Background(), k.src, k.cur, refresh, apply)
+	}
+}
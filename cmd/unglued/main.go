@@ -2,48 +2,814 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"unglued/internal/abuse"
+	"unglued/internal/atrest"
+	"unglued/internal/audit"
+	"unglued/internal/auth"
+	"unglued/internal/awsv4"
+	"unglued/internal/captcha"
+	"unglued/internal/clamav"
+	"unglued/internal/blob"
+	"unglued/internal/coldstore"
+	"unglued/internal/discord"
+	"unglued/internal/errreport"
+	"unglued/internal/gitstore"
 	"unglued/internal/httpx"
+	"unglued/internal/keysource"
+	"unglued/internal/matrix"
+	"unglued/internal/mattermost"
+	"unglued/internal/model"
+	"unglued/internal/moderation"
+	"unglued/internal/playground"
+	"unglued/internal/redisstore"
+	"unglued/internal/render"
+	"unglued/internal/s3"
+	"unglued/internal/slack"
+	"unglued/internal/smtpd"
+	"unglued/internal/statsd"
 	"unglued/internal/store"
+	"unglued/internal/util"
+	"unglued/internal/version"
+	"unglued/internal/viewlog"
+	"unglued/internal/webhook"
 )
 
 func main() {
+	// Unterbefehle sprechen mit einem laufenden Server, statt selbst einen zu starten
+	if len(os.Args) > 1 {
+		run := map[string]func([]string) error{
+			"backup": runBackup, "restore": runRestore,
+			"paste": runPaste, "get": runGet, "edit": runEdit,
+			"admin": runAdmin, "replicas": runReplicas,
+		}[os.Args[1]]
+		if run != nil {
+			if err := run(os.Args[2:]); err != nil {
+				log.Fatalf("%s: %v", os.Args[1], err)
+			}
+			return
+		}
+	}
 	var listenAddr string
 	var publicBase string
-	flag.StringVar(&listenAddr, "listen", ":8080", "HTTP listen address")
+	flag.StringVar(&listenAddr, "listen", ":8080", "HTTP listen address, or unix:/path/to.sock for a Unix domain socket")
+	var socketMode, socketGroup string
+	flag.StringVar(&socketMode, "socket-mode", "0660", "file mode of the Unix socket (with -listen unix:...)")
+	flag.StringVar(&socketGroup, "socket-group", "", "group owning the Unix socket, e.g. the one nginx/caddy runs as")
+	var readHeaderTimeout, readTimeout, writeTimeout, idleTimeout time.Duration
+	var maxHeaderBytes int
+	var h2c bool
+	flag.DurationVar(&readHeaderTimeout, "read-header-timeout", 10*time.Second, "how long a client may take to send the request headers (slowloris protection)")
+	flag.DurationVar(&readTimeout, "read-timeout", time.Minute, "how long a client may take to send the whole request, body included (0 = no limit)")
+	flag.DurationVar(&writeTimeout, "write-timeout", 2*time.Minute, "how long writing a response may take (0 = no limit)")
+	flag.DurationVar(&idleTimeout, "idle-timeout", 2*time.Minute, "how long an idle keep-alive connection stays open")
+	flag.IntVar(&maxHeaderBytes, "max-header-bytes", 64<<10, "maximum size of the request headers")
+	flag.BoolVar(&h2c, "h2c", false, "also serve HTTP/2 without TLS (h2c), for reverse proxies that speak HTTP/2 to the backend")
 	flag.StringVar(&publicBase, "public", "", "public base URL (e.g. https://paste.example.com)")
+	var frameAncestors string
+	flag.StringVar(&frameAncestors, "frame-ancestors", "'none'", "CSP frame-ancestors sources allowed to embed pages (e.g. \"'self' https://wiki.example.com\")")
+	var idemTTL time.Duration
+	flag.DurationVar(&idemTTL, "idempotency-ttl", 24*time.Hour, "how long an Idempotency-Key replays the same create response (0 disables)")
+	var tokenSecrets string
+	var tokenTTL time.Duration
+	flag.StringVar(&tokenSecrets, "edit-token-secret", os.Getenv("UNGLUED_EDIT_TOKEN_SECRET"), "comma-separated HMAC secrets for edit links; the first signs, the rest still verify (rotation). Empty = random per process")
+	flag.DurationVar(&tokenTTL, "edit-token-ttl", 30*24*time.Hour, "how long a signed edit link stays valid")
+	var adminCfg auth.AdminConfig
+	flag.StringVar(&adminCfg.Token, "admin-token", os.Getenv("UNGLUED_ADMIN_TOKEN"), "bearer token for /admin and /api/admin")
+	flag.StringVar(&adminCfg.User, "admin-user", "admin", "basic-auth user for the admin area")
+	flag.StringVar(&adminCfg.Password, "admin-password", os.Getenv("UNGLUED_ADMIN_PASSWORD"), "basic-auth password for the admin area (empty disables basic auth)")
+	var admin2FAFile string
+	flag.StringVar(&admin2FAFile, "admin-2fa-file", "", "JSON file holding the admin TOTP secret and recovery codes (enables two-factor enrollment)")
+	var oidcCfg auth.OIDCConfig
+	var requireLogin bool
+	flag.StringVar(&oidcCfg.Issuer, "oidc-issuer", os.Getenv("UNGLUED_OIDC_ISSUER"), "OpenID Connect issuer URL (enables user login)")
+	flag.StringVar(&oidcCfg.ClientID, "oidc-client-id", os.Getenv("UNGLUED_OIDC_CLIENT_ID"), "OIDC client id")
+	flag.StringVar(&oidcCfg.ClientSecret, "oidc-client-secret", os.Getenv("UNGLUED_OIDC_CLIENT_SECRET"), "OIDC client secret (empty for public clients)")
+	flag.StringVar(&oidcCfg.RedirectURL, "oidc-redirect", "", "login redirect URL, shared by OIDC, GitHub and OAuth2 (default: <public>/auth/callback)")
+	var githubURL, githubID, githubSecret string
+	flag.StringVar(&githubID, "github-client-id", os.Getenv("UNGLUED_GITHUB_CLIENT_ID"), "GitHub OAuth app client id (enables login with GitHub)")
+	flag.StringVar(&githubSecret, "github-client-secret", os.Getenv("UNGLUED_GITHUB_CLIENT_SECRET"), "GitHub OAuth app client secret")
+	flag.StringVar(&githubURL, "github-url", envOr("UNGLUED_GITHUB_URL", "https://github.com"), "GitHub URL; set it for GitHub Enterprise Server")
+	var oauth2Cfg auth.OAuth2Config
+	flag.StringVar(&oauth2Cfg.Label, "oauth2-name", envOr("UNGLUED_OAUTH2_NAME", "OAuth2"), "name of the generic OAuth2 provider on the login page")
+	flag.StringVar(&oauth2Cfg.AuthURL, "oauth2-auth-url", os.Getenv("UNGLUED_OAUTH2_AUTH_URL"), "authorization endpoint of a generic OAuth2 provider (enables login with it)")
+	flag.StringVar(&oauth2Cfg.TokenURL, "oauth2-token-url", os.Getenv("UNGLUED_OAUTH2_TOKEN_URL"), "token endpoint of the OAuth2 provider")
+	flag.StringVar(&oauth2Cfg.UserURL, "oauth2-userinfo-url", os.Getenv("UNGLUED_OAUTH2_USERINFO_URL"), "endpoint returning the user's profile as JSON")
+	flag.StringVar(&oauth2Cfg.Scopes, "oauth2-scopes", os.Getenv("UNGLUED_OAUTH2_SCOPES"), "space-separated scopes to request")
+	flag.StringVar(&oauth2Cfg.ClientID, "oauth2-client-id", os.Getenv("UNGLUED_OAUTH2_CLIENT_ID"), "OAuth2 client id")
+	flag.StringVar(&oauth2Cfg.ClientSecret, "oauth2-client-secret", os.Getenv("UNGLUED_OAUTH2_CLIENT_SECRET"), "OAuth2 client secret")
+	flag.BoolVar(&requireLogin, "require-login", false, "only logged-in users may create pastes (needs a login provider or -api-tokens)")
+	var tokenFile string
+	flag.StringVar(&tokenFile, "api-tokens", "", "JSON file with API token accounts (owner, token_sha256, quotas, default/max TTL); reloaded on SIGHUP")
+	var hookCfg webhook.Config
+	flag.StringVar(&hookCfg.URL, "webhook-url", os.Getenv("UNGLUED_WEBHOOK_URL"), "POST signed JSON events on create/edit to this URL")
+	flag.StringVar(&hookCfg.Secret, "webhook-secret", os.Getenv("UNGLUED_WEBHOOK_SECRET"), "HMAC-SHA256 secret for X-Unglued-Signature")
+	flag.StringVar(&hookCfg.TargetsFile, "webhook-targets", os.Getenv("UNGLUED_WEBHOOK_TARGETS"), "JSON file with more webhook targets and their payload templates (reloaded on SIGHUP)")
+	var slackCfg slack.Config
+	flag.StringVar(&slackCfg.SigningSecret, "slack-signing-secret", os.Getenv("UNGLUED_SLACK_SIGNING_SECRET"), "Slack app signing secret; enables /paste and link previews at POST /api/integrations/slack")
+	flag.StringVar(&slackCfg.BotToken, "slack-bot-token", os.Getenv("UNGLUED_SLACK_BOT_TOKEN"), "Slack bot token (xoxb-…, scope links:write) for unfurling unglued links")
+	var discordCfg discord.Config
+	flag.StringVar(&discordCfg.PublicKey, "discord-public-key", os.Getenv("UNGLUED_DISCORD_PUBLIC_KEY"), "Discord application public key (hex); enables the interactions endpoint at POST /api/integrations/discord")
+	flag.StringVar(&discordCfg.BotToken, "discord-bot-token", os.Getenv("UNGLUED_DISCORD_BOT_TOKEN"), "Discord bot token (Manage Messages) to replace converted messages with the paste link")
+	var mmCfg mattermost.Config
+	flag.StringVar(&mmCfg.Tokens, "mattermost-token", os.Getenv("UNGLUED_MATTERMOST_TOKEN"), "comma-separated Mattermost outgoing webhook/slash command tokens; enables POST /api/integrations/mattermost")
+	flag.StringVar(&mmCfg.URL, "mattermost-url", os.Getenv("UNGLUED_MATTERMOST_URL"), "Mattermost server URL, needed to replace converted posts")
+	flag.StringVar(&mmCfg.BotToken, "mattermost-bot-token", os.Getenv("UNGLUED_MATTERMOST_BOT_TOKEN"), "Mattermost bot/access token to replace converted posts with the paste link")
+	flag.IntVar(&mmCfg.MinLines, "mattermost-min-lines", 15, "outgoing webhook: convert posts with at least this many lines")
+	var playgroundURL string
+	flag.StringVar(&playgroundURL, "go-playground", envOr("UNGLUED_GO_PLAYGROUND", "https://play.golang.org"), "Go Playground for \"Run on Playground\" on Go pastes; empty = off (air-gapped instances)")
+	var matrixCfg matrix.Config
+	flag.StringVar(&matrixCfg.Homeserver, "matrix-homeserver", os.Getenv("UNGLUED_MATRIX_HOMESERVER"), "Matrix homeserver URL for the bot (needs -matrix-token and -public)")
+	flag.StringVar(&matrixCfg.Token, "matrix-token", os.Getenv("UNGLUED_MATRIX_TOKEN"), "access token of the Matrix bot account")
+	flag.StringVar(&matrixCfg.Rooms, "matrix-rooms", os.Getenv("UNGLUED_MATRIX_ROOMS"), "comma-separated room IDs or aliases the bot joins")
+	flag.IntVar(&matrixCfg.MinLines, "matrix-min-lines", 15, "convert code blocks with at least this many lines")
+	flag.StringVar(&matrixCfg.StateFile, "matrix-state-file", "", "JSON file to persist watched pastes (empty = in memory only)")
+	var smtpCfg smtpd.Config
+	var relay smtpd.Relay
+	flag.StringVar(&smtpCfg.Addr, "smtp-listen", "", "accept mail on this address (e.g. :2525) and turn bodies and text attachments into pastes (needs -public)")
+	flag.StringVar(&smtpCfg.Domain, "smtp-domain", "", "only accept recipients @this domain (empty = any)")
+	flag.StringVar(&smtpCfg.Allow, "smtp-allow", os.Getenv("UNGLUED_SMTP_ALLOW"), "comma-separated IPs/CIDRs, sender addresses or @domains allowed to send (required with -smtp-listen)")
+	flag.StringVar(&relay.Addr, "smtp-relay", os.Getenv("UNGLUED_SMTP_RELAY"), "host:port of a mail server for replies with the paste links (empty = no replies)")
+	flag.StringVar(&relay.From, "smtp-from", os.Getenv("UNGLUED_SMTP_FROM"), "sender address of replies")
+	flag.StringVar(&relay.User, "smtp-relay-user", os.Getenv("UNGLUED_SMTP_RELAY_USER"), "user for -smtp-relay (PLAIN auth)")
+	flag.StringVar(&relay.Password, "smtp-relay-password", os.Getenv("UNGLUED_SMTP_RELAY_PASSWORD"), "password for -smtp-relay")
+	var ciToken string
+	flag.StringVar(&ciToken, "ci-token", os.Getenv("UNGLUED_CI_TOKEN"), "shared secret for CI log ingestion at POST /api/ingest/ci (empty = off)")
+	var metricsToken string
+	flag.StringVar(&metricsToken, "metrics-token", os.Getenv("UNGLUED_METRICS_TOKEN"), "bearer token for Prometheus metrics at GET /metrics (empty = off)")
+	var errCfg errreport.Config
+	flag.StringVar(&errCfg.DSN, "sentry-dsn", os.Getenv("SENTRY_DSN"), "report panics and 5xx responses to this Sentry project")
+	flag.StringVar(&errCfg.Environment, "sentry-environment", os.Getenv("SENTRY_ENVIRONMENT"), "environment name sent with error reports, e.g. production")
+	flag.StringVar(&errCfg.WebhookURL, "error-webhook", os.Getenv("UNGLUED_ERROR_WEBHOOK"), "POST panics and 5xx responses as JSON to this URL")
+	var metricsSink, statsdTags string
+	var statsdCfg statsd.Config
+	flag.StringVar(&metricsSink, "metrics-sink", os.Getenv("UNGLUED_METRICS_SINK"), "also push metrics to a collector: statsd or dogstatsd (empty = only /metrics)")
+	flag.StringVar(&statsdCfg.Addr, "statsd-addr", envOr("UNGLUED_STATSD_ADDR", "127.0.0.1:8125"), "UDP address of the StatsD server or Datadog agent")
+	flag.StringVar(&statsdCfg.Prefix, "statsd-prefix", os.Getenv("UNGLUED_STATSD_PREFIX"), "prefix for every StatsD metric name")
+	flag.StringVar(&statsdTags, "statsd-tags", os.Getenv("UNGLUED_STATSD_TAGS"), "comma-separated tags added to every metric, e.g. env:prod (dogstatsd only)")
+	flag.BoolVar(&hookCfg.IncludeContent, "webhook-content", false, "include paste content in webhook events")
+	var modCfg moderation.Config
+	flag.StringVar(&modCfg.URL, "moderation-url", os.Getenv("UNGLUED_MODERATION_URL"), "external scanner that gets new content and answers allow/flag/block before it is stored")
+	flag.StringVar(&modCfg.Secret, "moderation-secret", os.Getenv("UNGLUED_MODERATION_SECRET"), "HMAC-SHA256 secret for X-Unglued-Signature on moderation requests")
+	flag.DurationVar(&modCfg.Timeout, "moderation-timeout", 3*time.Second, "how long to wait for the moderation hook")
+	flag.BoolVar(&modCfg.FailClosed, "moderation-fail-closed", false, "reject pastes when the moderation hook fails or times out (default: fail open)")
+	var encKeys, encKeyFile string
+	flag.StringVar(&encKeys, "encryption-key", os.Getenv("UNGLUED_ENCRYPTION_KEY"), "comma-separated 32-byte AES keys (base64/hex) to encrypt pastes at rest; the first encrypts, the rest still decrypt (rotation)")
+	flag.StringVar(&encKeyFile, "encryption-key-file", "", "read -encryption-key from this file (e.g. mounted from a KMS/secret store)")
+	var encKeySource, tokenSecretSource string
+	var keyOpts keysource.Options
+	var keyRefresh time.Duration
+	flag.StringVar(&encKeySource, "encryption-key-source", os.Getenv("UNGLUED_ENCRYPTION_KEY_SOURCE"), "fetch -encryption-key from file:PATH, age:PATH, vault:PATH#FIELD or awskms:PATH; re-fetched before a lease expires")
+	flag.StringVar(&tokenSecretSource, "edit-token-secret-source", os.Getenv("UNGLUED_EDIT_TOKEN_SECRET_SOURCE"), "fetch -edit-token-secret from a key source (see -encryption-key-source)")
+	flag.StringVar(&keyOpts.AgeIdentity, "age-identity", os.Getenv("UNGLUED_AGE_IDENTITY"), "age identity file for age: key sources (passphrase-encrypted files use UNGLUED_AGE_PASSPHRASE)")
+	flag.DurationVar(&keyRefresh, "key-refresh", 0, "also re-fetch key sources at this interval, e.g. to pick up rotated keys (0 = only when a lease expires)")
+	var gitStore, gitStorePush string
+	flag.StringVar(&gitStore, "git-store", os.Getenv("UNGLUED_GIT_STORE"), "keep pastes in this bare git repository (one commit per change); loaded on startup")
+	flag.StringVar(&gitStorePush, "git-store-push", os.Getenv("UNGLUED_GIT_STORE_PUSH"), "git remote URL to mirror -git-store to (force-pushed at most once a minute)")
+	var redisURL, redisPrefix string
+	flag.StringVar(&redisURL, "redis-url", os.Getenv("UNGLUED_REDIS_URL"), "share pastes with other instances through this Redis (redis://[:password@]host:port/db, rediss:// or unix:///path); loaded on startup")
+	flag.StringVar(&redisPrefix, "redis-prefix", envOr("UNGLUED_REDIS_PREFIX", "unglued:"), "prefix for all Redis keys and the change channel")
+	var s3Cfg s3.Config
+	var s3Prefix string
+	flag.StringVar(&s3Cfg.Bucket, "archive-s3-bucket", os.Getenv("UNGLUED_ARCHIVE_S3_BUCKET"), "archive expired pastes to this S3 bucket instead of deleting them")
+	flag.StringVar(&s3Cfg.Endpoint, "archive-s3-endpoint", os.Getenv("UNGLUED_ARCHIVE_S3_ENDPOINT"), "S3 endpoint (default: AWS for -archive-s3-region; MinIO etc. work too)")
+	flag.StringVar(&s3Cfg.Region, "archive-s3-region", os.Getenv("UNGLUED_ARCHIVE_S3_REGION"), "S3 region (default AWS_REGION, then us-east-1)")
+	flag.StringVar(&s3Prefix, "archive-s3-prefix", envOr("UNGLUED_ARCHIVE_S3_PREFIX", "expired/"), "key prefix for archived pastes")
+	var archiveStore string
+	flag.StringVar(&archiveStore, "archive-store", os.Getenv("UNGLUED_ARCHIVE_STORE"), "archive expired pastes to this blob store: s3://bucket/prefix, gs://bucket/prefix, azure://account/container/prefix or file:///dir")
+	var auditPath string
+	var auditOn bool
+	flag.BoolVar(&auditOn, "audit", false, "keep an audit log of creates, edits, private views and admin actions")
+	flag.StringVar(&auditPath, "audit-log", "", "append the audit log as JSON lines to this file (implies -audit; default: last 10000 entries in memory)")
+	var viewLog, countryHeader string
+	var viewLogKeep int
+	flag.StringVar(&viewLog, "view-log", envOr("UNGLUED_VIEW_LOG", "off"), "show paste owners when their paste was opened at /p/{id}/access: off, time (hour only), agent (+ browser family), country (+ -country-header)")
+	flag.IntVar(&viewLogKeep, "view-log-keep", 100, "views kept per paste for -view-log")
+	flag.StringVar(&countryHeader, "country-header", os.Getenv("UNGLUED_COUNTRY_HEADER"), "request header with the client's country code set by your proxy or CDN, e.g. CF-IPCountry (for -view-log country)")
+	var cookieSecure, cookieSameSite string
+	flag.StringVar(&cookieSecure, "cookie-secure", httpx.CookieSecureAuto, "Secure flag on cookies: auto (when served via HTTPS), always, never")
+	flag.StringVar(&cookieSameSite, "cookie-samesite", "lax", "SameSite for edit-key, author and session cookies: lax, strict, none")
+	var captchaProvider, captchaSiteKey, captchaSecret string
+	flag.StringVar(&captchaProvider, "captcha", "", "CAPTCHA on the HTML create form for anonymous visitors: hcaptcha or turnstile")
+	flag.StringVar(&captchaSiteKey, "captcha-site-key", os.Getenv("UNGLUED_CAPTCHA_SITE_KEY"), "CAPTCHA site key")
+	flag.StringVar(&captchaSecret, "captcha-secret", os.Getenv("UNGLUED_CAPTCHA_SECRET"), "CAPTCHA secret for server-side verification")
+	var bots httpx.BotTraps
+	flag.BoolVar(&bots.Honeypot, "bot-honeypot", true, "reject form posts that fill the hidden honeypot field")
+	flag.DurationVar(&bots.MinFill, "bot-min-fill", 0, "reject form posts submitted faster than this after loading the form (e.g. 3s; 0 disables)")
+	flag.BoolVar(&bots.UserAgent, "bot-ua", false, "reject form posts with empty or scripted user agents (curl, python-requests, ...)")
+	flag.BoolVar(&bots.LogOnly, "bot-log-only", false, "only log bot-trap hits instead of rejecting them")
+	var clamAddr string
+	var clamTimeout time.Duration
+	var clamFailClosed bool
+	flag.StringVar(&clamAddr, "clamav", os.Getenv("UNGLUED_CLAMAV"), "clamd address for malware scans (tcp://host:3310 or unix:///run/clamav/clamd.ctl)")
+	flag.DurationVar(&clamTimeout, "clamav-timeout", 10*time.Second, "timeout per clamd scan")
+	flag.BoolVar(&clamFailClosed, "clamav-fail-closed", false, "quarantine pastes when clamd is unreachable (default: let them through)")
+	var memBudget int64
+	flag.Int64Var(&memBudget, "memory-budget", 0, "bytes of paste content to keep in memory before evicting the pastes closest to expiry (0 = half of GOMEMLIMIT if set, -1 = unlimited)")
+	var expiredGrace time.Duration
+	flag.DurationVar(&expiredGrace, "expired-grace", time.Hour, "how long expired pastes answer 410 Gone and can be undeleted by their key holders (0 = remove at once)")
+	var deleteGrace time.Duration
+	flag.DurationVar(&deleteGrace, "delete-grace", 24*time.Hour, "how long deleted pastes can be restored by their key holders and admins before they are purged (0 = delete at once)")
+	features := httpx.AllFeatures()
+	flag.BoolVar(&features.Archive, "enable-archive", true, "public archive (/archive, GET /api/pastes) and listing pastes as public")
+	flag.BoolVar(&features.Search, "enable-search", true, "full-text search (/api/search and in the archive)")
+	flag.BoolVar(&features.Uploads, "enable-uploads", true, "file uploads via multipart POST /")
+	flag.BoolVar(&features.Edit, "enable-edit", true, "editable pastes and edit routes")
+	flag.BoolVar(&features.Private, "enable-private", true, "private pastes with share links")
+	avatars := flag.String("avatars", os.Getenv("UNGLUED_AVATARS"), "show avatars for anonymous authors who give an email: gravatar or libravatar (empty = off)")
+	allowIndexing := flag.Bool("allow-indexing", false, "let public pastes opt into search engine indexing and serve /sitemap.xml (default: noindex everywhere)")
+	var uiLang string
+	flag.StringVar(&uiLang, "ui-lang", "de", "UI language when neither ?lang=, cookie nor Accept-Language pick one (de, en)")
+	var maxPasteBytes int64
+	flag.Int64Var(&maxPasteBytes, "max-paste-bytes", 1<<20, "max size of a paste (and of each edited version) in bytes; larger requests get 413 (0 = unlimited)")
+	var abuseCfg abuse.Config
+	var trustedProxies, allowCIDRs, banFile, blocklistFile string
+	flag.IntVar(&abuseCfg.Limit, "rate-limit", 30, "pastes per IP and -rate-window (0 disables)")
+	flag.DurationVar(&abuseCfg.Window, "rate-window", 10*time.Minute, "sliding window for -rate-limit")
+	flag.StringVar(&allowCIDRs, "allow-cidrs", "", "comma-separated IPs/CIDRs exempt from rate limit and bans (e.g. CI runners)")
+	flag.StringVar(&banFile, "ban-file", "", "JSON file to persist the ban list (empty = in memory only)")
+	flag.StringVar(&blocklistFile, "blocklist-file", "", "JSON file to persist the spam blocklist (empty = in memory only)")
+	flag.StringVar(&trustedProxies, "trusted-proxies", "", "comma-separated proxy IPs/CIDRs whose X-Forwarded-For is trusted")
+	var maxTTL time.Duration
+	flag.DurationVar(&maxTTL, "max-ttl", 0, "longest expiry a new paste may ask for (0 = no cap)")
+	var minTTL time.Duration
+	flag.DurationVar(&minTTL, "min-ttl", 0, "shortest expiry a new paste may ask for (0 = no lower bound)")
+	var templateDir string
+	flag.StringVar(&templateDir, "template-dir", "", "directory with *.html templates overriding the embedded ones (reloadable)")
+	var robotsFile string
+	flag.StringVar(&robotsFile, "robots-file", "", "serve this file as /robots.txt instead of the generated one (reloadable)")
+	var dev bool
+	flag.BoolVar(&dev, "dev", false, "UI development: re-read templates (from -template-dir or internal/httpx/templates) and internal/httpx/static on every request, disable HTTP caching")
+	var tlsOpts tlsOptions
+	var acmeHTTP string
+	flag.StringVar(&tlsOpts.CertFile, "tls-cert", "", "serve HTTPS with this PEM certificate (chain); re-read on SIGHUP")
+	flag.StringVar(&tlsOpts.KeyFile, "tls-key", "", "PEM private key for -tls-cert")
+	flag.BoolVar(&tlsOpts.ACME, "acme", false, "get certificates from Let's Encrypt automatically (needs -acme-domains and port 80 for HTTP-01)")
+	flag.StringVar(&tlsOpts.ACMEDomains, "acme-domains", "", "comma-separated host names to request certificates for")
+	flag.StringVar(&tlsOpts.ACMEEmail, "acme-email", "", "contact address for the ACME account (expiry notices)")
+	flag.StringVar(&tlsOpts.ACMECache, "acme-cache", "acme-cache", "directory for ACME account key and certificates")
+	flag.StringVar(&tlsOpts.ACMEDir, "acme-directory", "", "ACME directory URL (default: Let's Encrypt production)")
+	flag.StringVar(&acmeHTTP, "acme-http", ":80", "listener for HTTP-01 challenges and the redirect to HTTPS (with -acme)")
+	var accessLog, accessFormat, accessSkip string
+	flag.StringVar(&accessLog, "access-log", "", "write an HTTP access log: - for stdout or a file path (empty = off)")
+	var compressMin int
+	flag.IntVar(&compressMin, "compress-min-size", 1024, "compress HTML, JSON and text responses of at least this many bytes with brotli or gzip (0 disables)")
+	var prerenderThemes string
+	var prerenderBytes int64
+	flag.StringVar(&prerenderThemes, "prerender-themes", os.Getenv("UNGLUED_PRERENDER_THEMES"), "comma-separated themes (dark, light) to render highlighted HTML for when a version is saved (empty = render on every view)")
+	flag.Int64Var(&prerenderBytes, "prerender-cache-bytes", 64<<20, "memory budget for prerendered HTML; least recently viewed entries are dropped first")
+	var renderLimits render.Limits
+	flag.IntVar(&renderLimits.MaxBytes, "highlight-max-bytes", 512<<10, "show larger pastes as plain text instead of highlighting them (0 = no limit)")
+	flag.IntVar(&renderLimits.MaxLines, "highlight-max-lines", 20000, "show pastes with more lines as plain text (0 = no limit)")
+	flag.DurationVar(&renderLimits.Timeout, "highlight-timeout", time.Second, "time budget for tokenizing one paste; slower ones fall back to plain text (0 = no limit)")
+	var maxRenders, maxInFlight int
+	var renderWait time.Duration
+	flag.IntVar(&maxRenders, "max-renders", runtime.NumCPU(), "highlighting jobs running at the same time; views beyond that wait up to -render-wait, then get 503 (0 = no limit)")
+	flag.DurationVar(&renderWait, "render-wait", 2*time.Second, "how long a view waits for a free render slot")
+	flag.IntVar(&maxInFlight, "max-in-flight", 0, "requests handled at the same time; more are answered with 503 and Retry-After (0 = no limit)")
+	flag.StringVar(&accessFormat, "access-log-format", httpx.AccessLogCombined, "access log format: common, combined or json")
+	flag.StringVar(&accessSkip, "access-log-skip", "/healthz,/readyz", "comma-separated path prefixes left out of the access log")
+	var debugListen string
+	flag.StringVar(&debugListen, "debug-listen", "", "separate internal listener for pprof, expvar and /debug/runtime behind admin auth (e.g. 127.0.0.1:6060)")
+	var configFile string
+	flag.StringVar(&configFile, "config", os.Getenv("UNGLUED_CONFIG"), "file with \"flag = value\" lines; rate limits, size/TTL caps, bot traps and templates are re-read on SIGHUP")
+	showVersion := flag.Bool("version", false, "print version and build info, then exit")
 	flag.Parse()
+	if *showVersion {
+		fmt.Println(version.Get())
+		return
+	}
+	log.Printf("%s", version.Get())
+	cmdline := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { cmdline[f.Name] = true })
+	if configFile != "" {
+		if err := applyConfigFile(configFile, cmdline, nil); err != nil {
+			log.Fatalf("-config: %v", err)
+		}
+	}
+	if oidcCfg.RedirectURL == "" && publicBase != "" {
+		oidcCfg.RedirectURL = strings.TrimRight(publicBase, "/") + "/auth/callback"
+	}
+	if oidcCfg.Enabled() && oidcCfg.RedirectURL == "" {
+		log.Fatal("-oidc-issuer needs -public or -oidc-redirect")
+	}
+	var oauth2 []auth.OAuth2Config
+	if githubID != "" {
+		oauth2 = append(oauth2, auth.GitHubConfig(githubURL, githubID, githubSecret, oidcCfg.RedirectURL))
+	}
+	if oauth2Cfg.Enabled() {
+		if oauth2Cfg.TokenURL == "" || oauth2Cfg.UserURL == "" {
+			log.Fatal("-oauth2-auth-url needs -oauth2-token-url and -oauth2-userinfo-url")
+		}
+		oauth2Cfg.RedirectURL = oidcCfg.RedirectURL
+		oauth2 = append(oauth2, oauth2Cfg)
+	}
+	if len(oauth2) > 0 && oidcCfg.RedirectURL == "" {
+		log.Fatal("-github-client-id and -oauth2-auth-url need -public or -oidc-redirect")
+	}
+
+	if err := checkTTLBounds(minTTL, maxTTL); err != nil {
+		log.Fatalf("-min-ttl: %v", err)
+	}
+	if _, ok := httpx.AvatarServices[*avatars]; *avatars != "" && !ok {
+		log.Fatalf("-avatars: unknown service %q (want gravatar or libravatar)", *avatars)
+	}
+
+	sameSite, ok := util.ParseSameSite(cookieSameSite)
+	if !ok {
+		log.Fatalf("-cookie-samesite: unknown value %q", cookieSameSite)
+	}
+	switch cookieSecure {
+	case httpx.CookieSecureAuto, httpx.CookieSecureAlways, httpx.CookieSecureNever:
+	default:
+		log.Fatalf("-cookie-secure: unknown value %q", cookieSecure)
+	}
+
+	captchaV, err := captcha.New(captchaProvider, captchaSiteKey, captchaSecret)
+	if err != nil {
+		log.Fatalf("-captcha: %v", err)
+	}
+
+	var tlsCfg *tls.Config
+	var http01 http.Handler
+	var certs *keyPair
+	if tlsOpts.enabled() {
+		if tlsCfg, http01, certs, err = setupTLS(tlsOpts); err != nil {
+			log.Fatalf("tls: %v", err)
+		}
+		if !cmdline["listen"] {
+			listenAddr = ":443"
+		}
+	}
+
+	proxies, err := abuse.ParsePrefixes(trustedProxies)
+	if err != nil {
+		log.Fatalf("-trusted-proxies: %v", err)
+	}
+	if abuseCfg.Allow, err = abuse.ParsePrefixes(allowCIDRs); err != nil {
+		log.Fatalf("-allow-cidrs: %v", err)
+	}
+	bans, err := abuse.LoadBans(banFile)
+	if err != nil {
+		log.Fatalf("-ban-file: %v", err)
+	}
+
+	blocklist, err := abuse.LoadBlocklist(blocklistFile)
+	if err != nil {
+		log.Fatalf("-blocklist-file: %v", err)
+	}
+
+	var twoFactor *auth.TwoFactor
+	if admin2FAFile != "" {
+		if twoFactor, err = auth.LoadTwoFactor(admin2FAFile); err != nil {
+			log.Fatalf("-admin-2fa-file: %v", err)
+		}
+	}
+
+	keyOpts.AgePassphrase = os.Getenv("UNGLUED_AGE_PASSPHRASE")
+	if encKeyFile != "" && encKeySource == "" {
+		encKeySource = "file:" + encKeyFile
+	}
+	var encKeyWatch, tokenSecretWatch *keyWatch
+	if encKeySource != "" {
+		encKeys, encKeyWatch = fetchKey("encryption-key-source", encKeySource, keyOpts)
+	}
+	if tokenSecretSource != "" {
+		tokenSecrets, tokenSecretWatch = fetchKey("edit-token-secret-source", tokenSecretSource, keyOpts)
+	}
 
 	st := store.New(30 * time.Second)
 	defer st.Close()
+	budget := func() int64 {
+		if memBudget == 0 {
+			return store.BudgetFromMemLimit(0.5)
+		}
+		return memBudget
+	}
+	st.SetBudget(budget())
+	st.SetGrace(expiredGrace)
+	st.SetDeleteGrace(deleteGrace)
+	if b := st.Budget(); b > 0 {
+		log.Printf("store: memory budget %d bytes", b)
+	}
+	if encKeys != "" {
+		kr, err := atrest.ParseKeys(encKeys)
+		if err != nil {
+			log.Fatalf("-encryption-key: %v", err)
+		}
+		st.Sealer = kr
+		// neue Keys ersetzen den Keyring an Ort und Stelle; Store und Archiv teilen ihn
+		encKeyWatch.watch(keyRefresh, func(v string) error {
+			next, err := atrest.ParseKeys(v)
+			if err != nil {
+				return err
+			}
+			return kr.Replace(next)
+		})
+	}
+	if s3Cfg.Bucket != "" {
+		s3Cfg.Credentials, _ = awsv4.FromEnv()
+		if s3Cfg.Region == "" {
+			s3Cfg.Region = awsv4.Region()
+		}
+	}
+	var archive blob.Store
+	switch {
+	case archiveStore != "" && s3Cfg.Bucket != "":
+		log.Fatal("-archive-store and -archive-s3-bucket are mutually exclusive")
+	case archiveStore != "":
+		if archive, err = blob.Open(archiveStore); err != nil {
+			log.Fatalf("-archive-store: %v", err)
+		}
+	case s3Cfg.Bucket != "":
+		s3Client, err := s3.New(s3Cfg)
+		if err != nil {
+			log.Fatalf("-archive-s3-bucket: %v", err)
+		}
+		archive = blob.WithPrefix(blob.NewS3(s3Client), s3Prefix)
+	}
+	var coldStore *coldstore.Archive
+	if archive != nil {
+		coldStore = coldstore.New(archive, "")
+		coldStore.Sealer = st.Sealer
+		st.Archiver = coldStore
+		log.Printf("store: archiving expired pastes to %s", archive)
+	}
+	if gitStore != "" {
+		// das Repository enthält Klartext; Verschlüsselung ginge damit ins Leere
+		if st.Sealer != nil {
+			log.Fatal("-git-store cannot be combined with -encryption-key")
+		}
+		repo, err := gitstore.Open(gitStore, gitStorePush)
+		if err != nil {
+			log.Fatalf("-git-store: %v", err)
+		}
+		n := 0
+		if err := repo.Load(func(p model.Paste) error {
+			st.Load(p)
+			n++
+			return nil
+		}); err != nil {
+			log.Fatalf("-git-store: %v", err)
+		}
+		log.Printf("store: loaded %d pastes from %s", n, gitStore)
+		st.Backend = repo
+		defer repo.Close()
+	}
+	if redisURL != "" {
+		if gitStore != "" {
+			log.Fatal("-redis-url and -git-store are mutually exclusive")
+		}
+		// Edit-Links, Sessions und Formular-Tokens müssen auf jeder Instanz gelten
+		if tokenSecrets == "" {
+			log.Fatal("-redis-url needs -edit-token-secret, the same on every instance")
+		}
+		shared, err := redisstore.Open(redisURL, redisPrefix)
+		if err != nil {
+			log.Fatalf("-redis-url: %v", err)
+		}
+		if st.Sealer != nil {
+			shared.Sealer = st.Sealer
+		}
+		n, err := shared.Start(st)
+		if err != nil {
+			log.Fatalf("-redis-url: %v", err)
+		}
+		log.Printf("store: loaded %d pastes from redis %s", n, shared)
+		st.Backend = shared
+		st.JanitorLease = shared.Lease
+		defer shared.Close()
+	}
+
+	reloadCfg := func() httpx.Reloadable {
+		return httpx.Reloadable{
+			MaxPasteBytes: maxPasteBytes,
+			MaxTTL:        maxTTL,
+			MinTTL:        minTTL,
+			Bots:          bots,
+			TemplateDir:   templateDir,
+			RobotsFile:    robotsFile,
+			Features:      features,
+			UILang:        uiLang,
+		}
+	}
 
 	// ⬇️ Templates laden und an den Server übergeben
 	indexTmpl, viewTmpl, editTmpl := httpx.LoadTemplates()
 
 	srv := httpx.NewServer(
-		httpx.Config{PublicBase: publicBase},
+		httpx.Config{
+			PublicBase:     publicBase,
+			IdempotencyTTL: idemTTL,
+			Admin:          adminCfg,
+			OIDC:           oidcCfg,
+			OAuth2:         oauth2,
+			RequireLogin:   requireLogin,
+			TrustedProxies: proxies,
+			CookieSecure:   cookieSecure,
+			CookieSameSite: sameSite,
+			Reloadable:     reloadCfg(),
+
+			ClamAVFailClosed: clamFailClosed,
+			DevDir:           devDir(dev),
+			CIToken:          ciToken,
+			MetricsToken:     metricsToken,
+			CountryHeader:    countryHeader,
+			AllowIndexing:    *allowIndexing,
+			Avatars:          *avatars,
+		},
 		st,
 		indexTmpl, viewTmpl, editTmpl,
 	)
+	if err := srv.Reconfigure(reloadCfg()); err != nil {
+		log.Fatalf("-template-dir: %v", err)
+	}
+	srv.Auth = auth.NewSigner(strings.Split(tokenSecrets, ","), tokenTTL)
+	tokenSecretWatch.watch(keyRefresh, func(v string) error { return srv.Auth.SetSecrets(strings.Split(v, ",")) })
+	if srv.Tokens, err = auth.LoadTokens(tokenFile); err != nil {
+		log.Fatalf("-api-tokens: %v", err)
+	}
+	if srv.Hooks, err = webhook.New(hookCfg); err != nil {
+		log.Fatalf("-webhook-targets: %v", err)
+	}
+	srv.Abuse = abuse.New(abuseCfg, bans)
+	srv.Blocklist = blocklist
+	srv.TwoFactor = twoFactor
+	srv.Captcha = captchaV
+	srv.Slack = slack.New(slackCfg)
+	if srv.Discord, err = discord.New(discordCfg); err != nil {
+		log.Fatalf("-discord-public-key: %v", err)
+	}
+	srv.Mattermost = mattermost.New(mmCfg)
+	srv.Playground = playground.New(playgroundURL)
+	srv.ColdStore = coldStore
+	var themes []string
+	for _, t := range strings.Split(prerenderThemes, ",") {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+		if !slices.Contains(httpx.Themes, t) {
+			log.Fatalf("-prerender-themes: unknown theme %q (want %s)", t, strings.Join(httpx.Themes, ", "))
+		}
+		themes = append(themes, t)
+	}
+	srv.Prerender = render.NewCache(themes, prerenderBytes)
+	if srv.Prerender != nil {
+		st.OnChange(srv.Prerender.Invalidate)
+	}
+	srv.RenderLimits = renderLimits
+	srv.Renders = httpx.NewLimiter(maxRenders, renderWait)
+	errCfg.Release = version.Get().Version
+	if srv.Errors, err = errreport.New(errCfg); err != nil {
+		log.Fatalf("-sentry-dsn: %v", err)
+	}
+	smtpSrv, err := smtpd.New(smtpCfg, srv.MailHandler(relay))
+	if err != nil {
+		log.Fatalf("-smtp-allow: %v", err)
+	}
+	if smtpSrv != nil {
+		if publicBase == "" {
+			log.Fatal("-smtp-listen needs -public")
+		}
+		go func() {
+			log.Printf("SMTP: %s\n", smtpCfg.Addr)
+			if err := smtpSrv.ListenAndServe(); err != nil {
+				log.Fatalf("-smtp-listen: %v", err)
+			}
+		}()
+	}
+	if srv.Matrix, err = matrix.New(matrixCfg, srv.MatrixCallbacks()); err != nil {
+		log.Fatalf("-matrix-state-file: %v", err)
+	}
+	if srv.Matrix != nil {
+		if publicBase == "" {
+			log.Fatal("-matrix-homeserver needs -public")
+		}
+		matrixCtx, stopMatrix := context.WithCancel(context.Background())
+		defer stopMatrix()
+		go func() {
+			if err := srv.Matrix.Run(matrixCtx); err != nil {
+				log.Printf("matrix: %v", err)
+			}
+		}()
+	}
+	switch metricsSink {
+	case "":
+		statsdCfg.Addr = ""
+	case "statsd", "dogstatsd":
+		statsdCfg.Dog = metricsSink == "dogstatsd"
+		if statsdTags != "" {
+			statsdCfg.Tags = strings.Split(statsdTags, ",")
+		}
+	default:
+		log.Fatalf("-metrics-sink: unknown sink %q (statsd, dogstatsd)", metricsSink)
+	}
+	sd, err := statsd.New(statsdCfg)
+	if err != nil {
+		log.Fatalf("-metrics-sink: %v", err)
+	}
+	if sd != nil {
+		metricsCtx, stopMetrics := context.WithCancel(context.Background())
+		defer stopMetrics()
+		go sd.Run(metricsCtx)
+		go srv.Metrics().Emit(metricsCtx, sd, 10*time.Second)
+	}
+	if srv.ClamAV, err = clamav.New(clamAddr, clamTimeout); err != nil {
+		log.Fatalf("-clamav: %v", err)
+	}
+	viewLevel, err := viewlog.ParseLevel(viewLog)
+	if err != nil {
+		log.Fatalf("-view-log: %v", err)
+	}
+	if viewLevel == viewlog.Country && countryHeader == "" {
+		log.Printf("-view-log country without -country-header: countries stay empty")
+	}
+	srv.Views = viewlog.New(viewLevel, viewLogKeep)
+	if auditOn || auditPath != "" {
+		al, err := audit.Open(auditPath)
+		if err != nil {
+			log.Fatalf("-audit-log: %v", err)
+		}
+		defer al.Close()
+		srv.Audit = al
+	}
+	srv.Moderator = moderation.New(modCfg)
+	defer srv.Hooks.Close()
+
+	// Reload tauscht nur Konfiguration aus; Store und laufende Requests bleiben
+	var reloadMu sync.Mutex
+	srv.Reload = func() error {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+		if configFile != "" {
+			if err := applyConfigFile(configFile, cmdline, reloadable); err != nil {
+				return err
+			}
+		}
+		if err := checkTTLBounds(minTTL, maxTTL); err != nil {
+			return fmt.Errorf("min-ttl: %w", err)
+		}
+		cfg := abuseCfg
+		var err error
+		if cfg.Allow, err = abuse.ParsePrefixes(allowCIDRs); err != nil {
+			return fmt.Errorf("allow-cidrs: %w", err)
+		}
+		if err := srv.Reconfigure(reloadCfg()); err != nil {
+			return fmt.Errorf("templates: %w", err)
+		}
+		if err := bans.Reload(); err != nil {
+			return fmt.Errorf("ban-file: %w", err)
+		}
+		if err := blocklist.Reload(); err != nil {
+			return fmt.Errorf("blocklist-file: %w", err)
+		}
+		if err := srv.Tokens.Reload(); err != nil {
+			return fmt.Errorf("api-tokens: %w", err)
+		}
+		if err := srv.Hooks.Reload(); err != nil {
+			return fmt.Errorf("webhook-targets: %w", err)
+		}
+		if certs != nil {
+			if err := certs.reload(); err != nil {
+				return fmt.Errorf("tls-cert: %w", err)
+			}
+		}
+		srv.Abuse.Reconfigure(cfg)
+		st.SetBudget(budget())
+		st.SetGrace(expiredGrace)
+		st.SetDeleteGrace(deleteGrace)
+		return nil
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := srv.Reload(); err != nil {
+				log.Printf("reload: %v", err)
+				continue
+			}
+			log.Printf("reload: configuration reloaded")
+		}
+	}()
 
 	r := chi.NewRouter()
+	if compressMin > 0 {
+		// außen, damit auch die Request-ID unter Fehlerseiten mit gepackt wird
+		r.Use(httpx.Compress(httpx.CompressConfig{MinSize: compressMin}))
+	}
+	r.Use(httpx.RequestID)
+	if accessLog != "" {
+		out := os.Stdout
+		if accessLog != "-" {
+			if out, err = os.OpenFile(accessLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640); err != nil {
+				log.Fatalf("-access-log: %v", err)
+			}
+			defer out.Close()
+		}
+		switch accessFormat {
+		case httpx.AccessLogCommon, httpx.AccessLogCombined, httpx.AccessLogJSON:
+		default:
+			log.Fatalf("-access-log-format: unknown format %q", accessFormat)
+		}
+		var skip []string
+		for _, p := range strings.Split(accessSkip, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				skip = append(skip, p)
+			}
+		}
+		r.Use(httpx.AccessLog(httpx.AccessLogConfig{Out: out, Format: accessFormat, Skip: skip, Proxies: proxies}))
+	}
+	// nach dem Access-Log, damit abgewiesene Anfragen dort auftauchen
+	r.Use(httpx.LimitInFlight(maxInFlight))
 	r.Use(httpx.NoIndex)
+	var thirdParty []string
+	if captchaV != nil {
+		thirdParty = captchaV.Origins
+	}
+	r.Use(httpx.SecurityHeaders(frameAncestors, srv.AvatarOrigins(), thirdParty...))
 	httpx.MountRoutes(r, srv)
 
-	log.Printf("HTTP: http://localhost%s\n", listenAddr)
-	httpSrv := &http.Server{Addr: listenAddr, Handler: r}
+	httpSrv := &http.Server{
+		Addr: listenAddr, Handler: r, TLSConfig: tlsCfg,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+	if h2c {
+		if tlsCfg != nil {
+			log.Fatal("-h2c is for plain HTTP listeners; with TLS HTTP/2 is negotiated anyway")
+		}
+		// Go ≥ 1.24 kann h2c selbst (Prior Knowledge, kein Upgrade-Header)
+		httpSrv.Protocols = new(http.Protocols)
+		httpSrv.Protocols.SetHTTP1(true)
+		httpSrv.Protocols.SetUnencryptedHTTP2(true)
+	}
+	var challengeSrv *http.Server
+	if http01 != nil {
+		challengeSrv = &http.Server{Addr: acmeHTTP, Handler: http01, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := challengeSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("-acme-http: %v", err)
+			}
+		}()
+	}
+
+	var debugSrv *http.Server
+	if debugListen != "" {
+		if !adminCfg.Enabled() {
+			log.Fatal("-debug-listen needs -admin-token or -admin-password")
+		}
+		debugSrv = &http.Server{Addr: debugListen, Handler: httpx.DebugHandler(srv), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			log.Printf("debug: http://%s/debug/pprof/\n", debugListen)
+			if err := debugSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("-debug-listen: %v", err)
+			}
+		}()
+	}
+
+	mode, err := strconv.ParseUint(socketMode, 8, 32)
+	if err != nil {
+		log.Fatalf("-socket-mode: %v", err)
+	}
+	ln, err := listen(listenAddr, os.FileMode(mode), socketGroup)
+	if err != nil {
+		log.Fatalf("-listen: %v", err)
+	}
+	where := "localhost" + listenAddr
+	if strings.HasPrefix(listenAddr, "unix:") {
+		where = listenAddr
+	}
 
 	go func() {
-		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsCfg != nil {
+			log.Printf("HTTPS: https://%s\n", where)
+			err = httpSrv.ServeTLS(ln, "", "")
+		} else {
+			log.Printf("HTTP: http://%s\n", where)
+			err = httpSrv.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatal(err)
 		}
 	}()
@@ -54,6 +820,25 @@ func main() {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	if challengeSrv != nil {
+		_ = challengeSrv.Shutdown(ctx)
+	}
+	if debugSrv != nil {
+		_ = debugSrv.Shutdown(ctx)
+	}
+	_ = smtpSrv.Close()
 	_ = httpSrv.Shutdown(ctx)
 }
 
+// devDir: Quellverzeichnis für -dev, relativ zum Repo-Root.
+func devDir(on bool) string {
+	if !on {
+		return ""
+	}
+	const dir = "internal/httpx"
+	if _, err := os.Stat(filepath.Join(dir, "templates")); err != nil {
+		log.Printf("-dev: %s/templates not found, start from the repository root or set -template-dir", dir)
+	}
+	log.Printf("dev mode: templates and static files are read from disk on every request")
+	return dir
+}
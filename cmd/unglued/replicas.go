@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"unglued/internal/util"
+)
+
+/*
+unglued replicas <url> <url> [url...]: prüft, ob mehrere Instanzen (mit
+-redis-url) wirklich dieselben Pastes sehen. Legt eine Paste auf der ersten an,
+liest sie auf allen, bearbeitet sie auf der letzten, rotiert den Edit-Key auf der
+zweiten und prüft, dass der alte Key danach auf keiner mehr gilt. Die URLs sind
+die einzelnen Instanzen, nicht der Load-Balancer davor.
+*/
+func runReplicas(args []string) error {
+	fs := flag.NewFlagSet("replicas", flag.ExitOnError)
+	token := fs.String("token", os.Getenv("UNGLUED_TOKEN"), "sent as Authorization: Bearer, e.g. with -require-login")
+	wait := fs.Duration("wait", 5*time.Second, "how long a change may take to show up on every instance")
+	ttl := fs.String("ttl", "10m", "lifetime of the test paste")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return fmt.Errorf("usage: unglued replicas [flags] <url> <url> [url...]")
+	}
+	cs := make([]apiClient, fs.NArg())
+	for i, u := range fs.Args() {
+		cs[i] = apiClient{server: u, token: *token}
+	}
+	first, second, last := cs[0], cs[1], cs[len(cs)-1]
+
+	code := "unglued replica check " + util.NewID(8)
+	var created struct {
+		ID      string `json:"id"`
+		EditURL string `json:"edit_url"`
+	}
+	if err := first.call(http.MethodPost, "/api/paste", map[string]any{"code": code, "ttl": *ttl, "editable": true}, &created); err != nil {
+		return err
+	}
+	_, q := pasteRef(created.EditURL)
+	id, key := created.ID, q.Get("key")
+	fmt.Printf("created %s on %s\n", id, first.server)
+	if err := converge(cs, id, code, *wait); err != nil {
+		return err
+	}
+
+	code += " (edited)"
+	if err := last.call(http.MethodPost, "/api/paste/"+url.PathEscape(id)+"/edit?key="+url.QueryEscape(key), map[string]any{"code": code}, nil); err != nil {
+		return err
+	}
+	fmt.Printf("edited on %s\n", last.server)
+	if err := converge(cs, id, code, *wait); err != nil {
+		return err
+	}
+
+	if err := second.call(http.MethodPost, "/api/paste/"+url.PathEscape(id)+"/rotate-key?key="+url.QueryEscape(key), nil, nil); err != nil {
+		return err
+	}
+	fmt.Printf("rotated the edit key on %s\n", second.server)
+	for _, c := range cs {
+		status, err := c.status(http.MethodPost, "/api/paste/"+url.PathEscape(id)+"/edit?key="+url.QueryEscape(key), map[string]any{"code": "stale key"})
+		if err != nil {
+			return err
+		}
+		if status != http.StatusForbidden {
+			return fmt.Errorf("%s: edit with the rotated-away key: %d, want 403", c.server, status)
+		}
+	}
+	fmt.Println("the old edit key is refused everywhere")
+	fmt.Printf("ok: %d instances share their pastes\n", len(cs))
+	return nil
+}
+
+// converge wartet, bis jede Instanz für id den Inhalt want liefert.
+func converge(cs []apiClient, id, want string, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+	for _, c := range cs {
+		for {
+			got, err := c.raw(id, 0)
+			if err == nil && got == want {
+				fmt.Printf("  %s: up to date\n", c.server)
+				break
+			}
+			if time.Now().After(deadline) {
+				if err != nil {
+					return fmt.Errorf("%s: %v", c.server, err)
+				}
+				return fmt.Errorf("%s: still serves %q after %s", c.server, got, wait)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// call: do mit JSON-Antwort in out (nil = verwerfen).
+func (c *apiClient) call(method, path string, body, out any) error {
+	resp, err := c.do(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// status schickt einen JSON-Request und liefert nur den Status – auch Fehler sind hier eine Antwort.
+func (c *apiClient) status(method, path string, body any) (int, error) {
+	hc, base := httpFor(c.server)
+	b, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(method, base+path, bytes.NewReader(b))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
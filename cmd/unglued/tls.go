@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+type tlsOptions struct {
+	CertFile, KeyFile string
+
+	ACME        bool
+	ACMEDomains string // kommagetrennt, Pflicht für -acme
+	ACMEEmail   string
+	ACMECache   string // Verzeichnis für Account-Key und Zertifikate
+	ACMEDir     string // leer = Let's Encrypt
+}
+
+func (o tlsOptions) enabled() bool { return o.ACME || o.CertFile != "" || o.KeyFile != "" }
+
+// keyPair hält das Zertifikat aus -tls-cert/-tls-key; reload tauscht es aus (SIGHUP).
+type keyPair struct {
+	certFile, keyFile string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func (k *keyPair) reload() error {
+	c, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	k.cert = &c
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *keyPair) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.cert, nil
+}
+
+/*
+setupTLS baut die tls.Config. Bei -acme kommt zusätzlich der Handler für den
+HTTP-01-Listener zurück (Challenges, alles andere wird auf HTTPS umgeleitet),
+bei Dateien ein keyPair zum Neuladen.
+*/
+func setupTLS(o tlsOptions) (cfg *tls.Config, http01 http.Handler, kp *keyPair, err error) {
+	if o.ACME {
+		if o.CertFile != "" || o.KeyFile != "" {
+			return nil, nil, nil, errors.New("-acme and -tls-cert/-tls-key are mutually exclusive")
+		}
+		var hosts []string
+		for _, h := range strings.Split(o.ACMEDomains, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				hosts = append(hosts, h)
+			}
+		}
+		if len(hosts) == 0 {
+			return nil, nil, nil, errors.New("-acme needs -acme-domains")
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(hosts...),
+			Cache:      autocert.DirCache(o.ACMECache),
+			Email:      o.ACMEEmail,
+		}
+		if o.ACMEDir != "" {
+			m.Client = &acme.Client{DirectoryURL: o.ACMEDir} // z.B. Staging
+		}
+		cfg = m.TLSConfig()
+		cfg.MinVersion = tls.VersionTLS12
+		return cfg, m.HTTPHandler(nil), nil, nil
+	}
+	if o.CertFile == "" || o.KeyFile == "" {
+		return nil, nil, nil, errors.New("-tls-cert and -tls-key must be given together")
+	}
+	kp = &keyPair{certFile: o.CertFile, keyFile: o.KeyFile}
+	if err := kp.reload(); err != nil {
+		return nil, nil, nil, err
+	}
+	cfg = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: kp.get,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	return cfg, nil, kp, nil
+}
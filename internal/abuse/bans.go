@@ -0,0 +1,135 @@
+package abuse
+
+import (
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"unglued/internal/util"
+)
+
+// Ban sperrt eine IP oder ein Netz; Until leer = dauerhaft.
+type Ban struct {
+	Prefix  netip.Prefix `json:"prefix"`
+	Reason  string       `json:"reason,omitempty"`
+	Created time.Time    `json:"created"`
+	Until   time.Time    `json:"until,omitzero"`
+}
+
+func (b Ban) active(now time.Time) bool {
+	return b.Until.IsZero() || now.Before(b.Until)
+}
+
+/*
+BanList hält die Sperren im Speicher und schreibt sie bei jeder Änderung als JSON
+nach path (atomar per Rename). path leer = nur im Speicher.
+*/
+type BanList struct {
+	mu   sync.RWMutex
+	path string
+	bans map[netip.Prefix]Ban
+}
+
+// LoadBans liest path, falls vorhanden; eine fehlende Datei ist kein Fehler.
+func LoadBans(path string) (*BanList, error) {
+	l := &BanList{path: path, bans: make(map[netip.Prefix]Ban)}
+	if path == "" {
+		return l, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Ban
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+	for _, ban := range list {
+		l.bans[ban.Prefix] = ban
+	}
+	return l, nil
+}
+
+// Reload liest path neu ein, z.B. nach Änderungen von Hand; ohne path passiert nichts.
+func (l *BanList) Reload() error {
+	if l.path == "" {
+		return nil
+	}
+	fresh, err := LoadBans(l.path)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.bans = fresh.bans
+	l.mu.Unlock()
+	return nil
+}
+
+// Match liefert die erste aktive Sperre, die ip abdeckt.
+func (l *BanList) Match(ip netip.Addr, now time.Time) (Ban, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, b := range l.bans {
+		if b.Prefix.Contains(ip) && b.active(now) {
+			return b, true
+		}
+	}
+	return Ban{}, false
+}
+
+// List: aktive Sperren, sortiert nach Prefix.
+func (l *BanList) List(now time.Time) []Ban {
+	l.mu.RLock()
+	out := make([]Ban, 0, len(l.bans))
+	for _, b := range l.bans {
+		if b.active(now) {
+			out = append(out, b)
+		}
+	}
+	l.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Prefix.String() < out[j].Prefix.String() })
+	return out
+}
+
+// Add legt eine Sperre an oder ersetzt die für denselben Prefix.
+func (l *BanList) Add(b Ban) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bans[b.Prefix] = b
+	return l.saveLocked()
+}
+
+// Remove hebt die Sperre für p auf; false, wenn es keine gab.
+func (l *BanList) Remove(p netip.Prefix) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.bans[p]; !ok {
+		return false, nil
+	}
+	delete(l.bans, p)
+	return true, l.saveLocked()
+}
+
+func (l *BanList) saveLocked() error {
+	if l.path == "" {
+		return nil
+	}
+	now := time.Now()
+	list := make([]Ban, 0, len(l.bans))
+	for p, b := range l.bans {
+		if !b.active(now) {
+			delete(l.bans, p) // abgelaufene gleich mit aufräumen
+			continue
+		}
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Prefix.String() < list[j].Prefix.String() })
+	return util.WriteJSONFile(l.path, list, 0o644)
+}
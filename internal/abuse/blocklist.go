@@ -0,0 +1,205 @@
+package abuse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"unglued/internal/util"
+)
+
+// Regel-Arten und Aktionen der Blocklist.
+const (
+	KindRegex  = "regex"  // Go-Regexp auf Titel + Inhalt
+	KindDomain = "domain" // Host in einer URL im Inhalt, inkl. Subdomains
+
+	ActionReject     = "reject"     // Anlegen/Bearbeiten ablehnen
+	ActionQuarantine = "quarantine" // speichern, aber nie listen
+)
+
+type Rule struct {
+	ID      string    `json:"id"`
+	Kind    string    `json:"kind"`
+	Pattern string    `json:"pattern"`
+	Action  string    `json:"action"`
+	Note    string    `json:"note,omitempty"`
+	Created time.Time `json:"created"`
+
+	re *regexp.Regexp
+}
+
+// compile prüft und normalisiert die Regel; leere Action = reject.
+func (r *Rule) compile() error {
+	switch r.Action {
+	case "":
+		r.Action = ActionReject
+	case ActionReject, ActionQuarantine:
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	switch r.Kind {
+	case KindRegex:
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+		r.re = re
+	case KindDomain:
+		r.Pattern = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(r.Pattern)), ".")
+		if r.Pattern == "" || strings.ContainsAny(r.Pattern, "/ ") {
+			return fmt.Errorf("invalid domain %q", r.Pattern)
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", r.Kind)
+	}
+	return nil
+}
+
+/*
+Blocklist: Spam-Regeln, zur Laufzeit über die Admin-API pflegbar und wie die
+Sperrliste als JSON unter path gesichert (leer = nur im Speicher).
+*/
+type Blocklist struct {
+	mu    sync.RWMutex
+	path  string
+	rules []Rule
+}
+
+func LoadBlocklist(path string) (*Blocklist, error) {
+	l := &Blocklist{path: path}
+	if path == "" {
+		return l, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rules[i].ID, err)
+		}
+	}
+	l.rules = rules
+	return l, nil
+}
+
+// Reload liest path neu ein; ungültige Regeln lassen den alten Stand stehen.
+func (l *Blocklist) Reload() error {
+	if l == nil || l.path == "" {
+		return nil
+	}
+	fresh, err := LoadBlocklist(l.path)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.rules = fresh.rules
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *Blocklist) List() []Rule {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]Rule(nil), l.rules...)
+}
+
+// Add prüft r, vergibt eine ID und speichert.
+func (l *Blocklist) Add(r Rule) (Rule, error) {
+	if err := r.compile(); err != nil {
+		return Rule{}, err
+	}
+	r.ID = util.NewID(8)
+	r.Created = time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules = append(l.rules, r)
+	return r, l.saveLocked()
+}
+
+func (l *Blocklist) Remove(id string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, r := range l.rules {
+		if r.ID == id {
+			l.rules = append(l.rules[:i], l.rules[i+1:]...)
+			return true, l.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+func (l *Blocklist) saveLocked() error {
+	if l.path == "" {
+		return nil
+	}
+	return util.WriteJSONFile(l.path, l.rules, 0o644)
+}
+
+var urlRe = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'()\[\]]+`)
+
+/*
+Match prüft text gegen alle Regeln. Ablehnende Regeln gewinnen gegen Quarantäne,
+damit die Reihenfolge in der Liste keine Rolle spielt.
+*/
+func (l *Blocklist) Match(text string) (Rule, bool) {
+	if l == nil {
+		return Rule{}, false
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if len(l.rules) == 0 {
+		return Rule{}, false
+	}
+	hosts := linkHosts(text)
+	var hits []Rule
+	for _, r := range l.rules {
+		if r.matches(text, hosts) {
+			hits = append(hits, r)
+		}
+	}
+	if len(hits) == 0 {
+		return Rule{}, false
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Action == ActionReject && hits[j].Action != ActionReject
+	})
+	return hits[0], true
+}
+
+func (r Rule) matches(text string, hosts []string) bool {
+	if r.Kind == KindRegex {
+		return r.re.MatchString(text)
+	}
+	for _, h := range hosts {
+		if h == r.Pattern || strings.HasSuffix(h, "."+r.Pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkHosts sammelt die (kleingeschriebenen) Hosts aller http(s)-Links.
+func linkHosts(text string) []string {
+	var out []string
+	for _, m := range urlRe.FindAllString(text, -1) {
+		if u, err := url.Parse(m); err == nil && u.Hostname() != "" {
+			out = append(out, strings.ToLower(strings.TrimSuffix(u.Hostname(), ".")))
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+/*
+Package abuse bündelt die Missbrauchsabwehr beim Anlegen: Rate-Limit pro IP,
+eine persistente Sperrliste und eine Allowlist (z.B. für CI-Runner).
+*/
+package abuse
+
+import (
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	Limit  int           // Anlagen pro Fenster und IP; 0 = kein Limit
+	Window time.Duration // Fensterlänge
+	Allow  []netip.Prefix
+}
+
+type Guard struct {
+	Bans *BanList
+
+	mu      sync.RWMutex
+	allow   []netip.Prefix
+	limiter *Limiter // nil = kein Limit
+}
+
+func New(cfg Config, bans *BanList) *Guard {
+	g := &Guard{Bans: bans}
+	g.Reconfigure(cfg)
+	return g
+}
+
+/*
+Reconfigure übernimmt Limit und Allowlist zur Laufzeit. Bei gleichem Fenster
+laufen die Zähler weiter, sonst beginnen sie von vorn.
+*/
+func (g *Guard) Reconfigure(cfg Config) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.allow = cfg.Allow
+	switch {
+	case cfg.Limit <= 0 || cfg.Window <= 0:
+		g.limiter = nil
+	case g.limiter != nil && g.limiter.window == cfg.Window:
+		g.limiter.setLimit(cfg.Limit)
+	default:
+		g.limiter = NewLimiter(cfg.Limit, cfg.Window)
+	}
+}
+
+// Verdict ist das Ergebnis von Check; ein leeres Verdict heißt „durchlassen“.
+type Verdict struct {
+	Banned     bool
+	Ban        Ban
+	Limited    bool
+	RetryAfter time.Duration
+}
+
+func (v Verdict) OK() bool { return !v.Banned && !v.Limited }
+
+/*
+Check prüft einen Anlage-Versuch von ip. Allowlist schlägt Sperre und Limit;
+ohne gültige IP (z.B. Unix-Socket) gibt es nichts zu prüfen.
+*/
+func (g *Guard) Check(ip netip.Addr) Verdict {
+	if !ip.IsValid() {
+		return Verdict{}
+	}
+	g.mu.RLock()
+	allow, limiter := g.allow, g.limiter
+	g.mu.RUnlock()
+	if contains(allow, ip) {
+		return Verdict{}
+	}
+	now := time.Now()
+	if b, ok := g.Bans.Match(ip, now); ok {
+		return Verdict{Banned: true, Ban: b}
+	}
+	if limiter != nil {
+		if ok, retry := limiter.Allow(ip.String(), now); !ok {
+			return Verdict{Limited: true, RetryAfter: retry}
+		}
+	}
+	return Verdict{}
+}
+
+// CheckSender zählt eine Anlage per Mail gegen das Limit des Absenders (zusätzlich zu dem der IP).
+func (g *Guard) CheckSender(addr string) Verdict {
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	if addr == "" {
+		return Verdict{}
+	}
+	g.mu.RLock()
+	limiter := g.limiter
+	g.mu.RUnlock()
+	if limiter != nil {
+		if ok, retry := limiter.Allow("mail:"+addr, time.Now()); !ok {
+			return Verdict{Limited: true, RetryAfter: retry}
+		}
+	}
+	return Verdict{}
+}
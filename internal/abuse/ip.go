@@ -0,0 +1,84 @@
+package abuse
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParsePrefixes: "10.0.0.0/8, 192.0.2.7" -> Prefixe; einzelne IPs werden zu /32 bzw. /128.
+func ParsePrefixes(s string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		p, err := ParsePrefix(part)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// ParsePrefix akzeptiert CIDR oder nackte IP.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", s)
+		}
+		return p.Masked(), nil
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP %q", s)
+	}
+	a = a.Unmap()
+	return netip.PrefixFrom(a, a.BitLen()), nil
+}
+
+func contains(ps []netip.Prefix, a netip.Addr) bool {
+	for _, p := range ps {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Proxies: Reverse-Proxies, deren X-Forwarded-For wir glauben. Ohne Eintrag zählt
+nur RemoteAddr – sonst könnte jeder Client sich per Header eine fremde IP geben.
+*/
+type Proxies []netip.Prefix
+
+// ClientIP liefert die IP des eigentlichen Clients. X-Forwarded-For wird von rechts
+// gelesen, vertrauenswürdige Hops werden übersprungen. Ein Peer ohne IP (Unix-Socket)
+// ist per Definition ein lokaler Proxy und zählt als vertrauenswürdig.
+func (t Proxies) ClientIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err == nil {
+		ip = ip.Unmap()
+		if !contains(t, ip) {
+			return ip
+		}
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		a, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		ip = a.Unmap()
+		if !contains(t, ip) {
+			break
+		}
+	}
+	return ip
+}
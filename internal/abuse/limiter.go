@@ -0,0 +1,87 @@
+package abuse
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+Limiter zählt pro Schlüssel (IP oder Mail-Absender) im gleitenden Fenster (zwei Buckets, der vorige wird anteilig
+gewichtet). Das ist genau genug und braucht pro Schlüssel nur zwei Zähler statt eines Logs.
+*/
+type Limiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string]*window
+	sweep  time.Time
+}
+
+type window struct {
+	start     time.Time
+	cur, prev int
+}
+
+func NewLimiter(limit int, per time.Duration) *Limiter {
+	return &Limiter{limit: limit, window: per, hits: make(map[string]*window)}
+}
+
+func (l *Limiter) setLimit(n int) {
+	l.mu.Lock()
+	l.limit = n
+	l.mu.Unlock()
+}
+
+// Allow zählt einen Versuch; bei false sagt retry, wann es wieder geht.
+func (l *Limiter) Allow(key string, now time.Time) (ok bool, retry time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.sweep) > l.window {
+		for k, w := range l.hits {
+			if now.Sub(w.start) >= 2*l.window {
+				delete(l.hits, k)
+			}
+		}
+		l.sweep = now
+	}
+
+	w := l.hits[key]
+	if w == nil {
+		w = &window{start: now.Truncate(l.window)}
+		l.hits[key] = w
+	}
+	w.roll(now, l.window)
+
+	elapsed := now.Sub(w.start)
+	weight := 1 - float64(elapsed)/float64(l.window)
+	if float64(w.prev)*weight+float64(w.cur) >= float64(l.limit) {
+		return false, w.retry(now, l.limit, l.window)
+	}
+	w.cur++
+	return true, 0
+}
+
+func (w *window) roll(now time.Time, size time.Duration) {
+	start := now.Truncate(size)
+	switch {
+	case start.Equal(w.start):
+	case start.Sub(w.start) == size:
+		w.prev, w.cur, w.start = w.cur, 0, start
+	default:
+		w.prev, w.cur, w.start = 0, 0, start
+	}
+}
+
+// retry schätzt, ab wann der gewichtete Zähler wieder unter dem Limit liegt.
+func (w *window) retry(now time.Time, limit int, size time.Duration) time.Duration {
+	if w.cur < limit {
+		// der vorige Bucket muss weit genug „auslaufen“
+		frac := 1 - float64(limit-w.cur)/float64(w.prev)
+		d := time.Duration(frac*float64(size)) - now.Sub(w.start)
+		return max(d, time.Second)
+	}
+	// erst im nächsten Fenster, wenn der aktuelle Bucket zum vorigen geworden ist
+	frac := 1 - float64(limit)/float64(w.cur)
+	d := w.start.Add(size).Sub(now) + time.Duration(frac*float64(size))
+	return max(d, time.Second)
+}
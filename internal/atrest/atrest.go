@@ -0,0 +1,152 @@
+/*
+Package atrest verschlüsselt Paste-Inhalte im Store mit AES-256-GCM.
+
+Format eines versiegelten Blobs:
+
+	0x01 | Key-ID (4 Byte) | Nonce (12 Byte) | Ciphertext+Tag
+
+Die Key-ID sind die ersten 4 Byte von SHA-256(Key). So findet Open auch nach einer
+Rotation den passenden alten Key und meldet „stale“, damit der Aufrufer neu versiegelt.
+*/
+package atrest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const version = 0x01
+
+var ErrNoKey = errors.New("atrest: no key for this blob")
+
+type key struct {
+	id   [4]byte
+	aead cipher.AEAD
+}
+
+// Keyring: der erste Key versiegelt, alle anderen öffnen nur noch (Rotation).
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []key
+}
+
+// ParseKeys: kommagetrennte 32-Byte-Keys, base64 oder hex kodiert.
+func ParseKeys(s string) (*Keyring, error) {
+	var raw [][]byte
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, err := decodeKey(part)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, k)
+	}
+	return NewKeyring(raw...)
+}
+
+func decodeKey(s string) ([]byte, error) {
+	if b, err := hex.DecodeString(s); err == nil && len(b) == 32 {
+		return b, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil && len(b) == 32 {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("atrest: key must be 32 bytes, base64 or hex encoded")
+}
+
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("atrest: no keys")
+	}
+	kr := &Keyring{}
+	for _, k := range keys {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(k)
+		kr.keys = append(kr.keys, key{id: [4]byte(sum[:4]), aead: aead})
+	}
+	return kr, nil
+}
+
+/*
+Replace übernimmt die Keys aus next im laufenden Betrieb (neu geladen aus Vault & Co.).
+Der bisher versiegelnde Key muss dabei sein – sonst wäre alles, was schon damit
+versiegelt ist, nicht mehr lesbar.
+*/
+func (kr *Keyring) Replace(next *Keyring) error {
+	next.mu.RLock()
+	keys := slices.Clone(next.keys)
+	next.mu.RUnlock()
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if !slices.ContainsFunc(keys, func(k key) bool { return k.id == kr.keys[0].id }) {
+		return errors.New("atrest: new keys do not include the current encryption key")
+	}
+	kr.keys = keys
+	return nil
+}
+
+// Seal versiegelt plain mit dem aktuellen Key.
+func (kr *Keyring) Seal(plain []byte) []byte {
+	kr.mu.RLock()
+	k := kr.keys[0]
+	kr.mu.RUnlock()
+	out := make([]byte, 5, 5+k.aead.NonceSize()+len(plain)+k.aead.Overhead())
+	out[0] = version
+	copy(out[1:5], k.id[:])
+	nonce := make([]byte, k.aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	out = append(out, nonce...)
+	return k.aead.Seal(out, nonce, plain, nil)
+}
+
+/*
+Open entschlüsselt b. stale = true heißt: mit einem älteren Key versiegelt oder noch
+Klartext aus der Zeit vor der Verschlüsselung – der Aufrufer sollte neu versiegeln.
+*/
+func (kr *Keyring) Open(b []byte) (plain []byte, stale bool, err error) {
+	if len(b) == 0 || b[0] != version {
+		return b, true, nil
+	}
+	if len(b) < 5 {
+		return nil, false, errors.New("atrest: short blob")
+	}
+	kr.mu.RLock()
+	keys := kr.keys
+	kr.mu.RUnlock()
+	for i, k := range keys {
+		if [4]byte(b[1:5]) != k.id {
+			continue
+		}
+		ns := k.aead.NonceSize()
+		if len(b) < 5+ns {
+			return nil, false, errors.New("atrest: short blob")
+		}
+		plain, err := k.aead.Open(nil, b[5:5+ns], b[5+ns:], nil)
+		if err != nil {
+			return nil, false, err
+		}
+		return plain, i > 0, nil
+	}
+	return nil, false, ErrNoKey
+}
@@ -0,0 +1,183 @@
+/*
+Package audit schreibt ein Append-only-Protokoll: wer (IP, Autor, Benutzer,
+Credential) hat wann was getan. Mit Pfad landet jede Zeile als JSON in der Datei
+(O_APPEND, nie umgeschrieben); ohne Pfad bleibt nur ein Ringpuffer im Speicher.
+*/
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Aktionen im Audit-Log.
+const (
+	ActionCreate      = "paste.create"
+	ActionEdit        = "paste.edit"
+	ActionViewPrivate = "paste.view_private"
+	ActionDelete      = "paste.delete"
+	ActionUndelete    = "paste.undelete" // in der Schonfrist wiederhergestellt
+	ActionExport      = "paste.export"
+	ActionShare       = "paste.share"      // Share-Grant ausgestellt/widerrufen
+	ActionRotateKey   = "paste.rotate_key" // Edit-Key ersetzt, alte Links ungültig
+	ActionEditKeys    = "paste.edit_keys"  // Mitbearbeiter-Key ausgestellt/widerrufen
+	ActionAdmin       = "admin"            // Detail sagt, was genau
+)
+
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	PasteID string    `json:"paste_id,omitempty"`
+	IP      string    `json:"ip,omitempty"`
+	Author  string    `json:"author,omitempty"`  // frei eingegebener oder verifizierter Name
+	UserID  string    `json:"user_id,omitempty"` // OIDC "iss|sub"
+	Via     string    `json:"via,omitempty"`     // edit-token, edit-cookie, session, admin-token, …
+	Token   string    `json:"token,omitempty"`   // Fingerprint des Credentials, nie das Credential selbst
+	Detail  string    `json:"detail,omitempty"`
+}
+
+// Fingerprint: erste 12 Hex-Zeichen von SHA-256 – reicht zum Korrelieren.
+func Fingerprint(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:6])
+}
+
+const memEntries = 10000
+
+type Log struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	mem  []Entry // Ringpuffer, nur ohne Datei
+	next int
+}
+
+// Open öffnet (oder erzeugt) path zum Anhängen; leerer Pfad = nur Speicher.
+func Open(path string) (*Log, error) {
+	l := &Log{path: path}
+	if path == "" {
+		return l, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l.f = f
+	return l, nil
+}
+
+func (l *Log) Close() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// Record hängt e an; ein nil-Log ignoriert alles.
+func (l *Log) Record(e Entry) {
+	if l == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		if len(l.mem) < memEntries {
+			l.mem = append(l.mem, e)
+		} else {
+			l.mem[l.next] = e
+			l.next = (l.next + 1) % memEntries
+		}
+		return
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		log.Printf("audit: write failed: %v", err)
+	}
+}
+
+// Filter für Query; leere Felder filtern nicht.
+type Filter struct {
+	Action  string
+	PasteID string
+	IP      string
+	UserID  string
+	Since   time.Time
+	Until   time.Time
+	Limit   int // 0 = 100
+}
+
+func (f Filter) match(e Entry) bool {
+	switch {
+	case f.Action != "" && e.Action != f.Action,
+		f.PasteID != "" && e.PasteID != f.PasteID,
+		f.IP != "" && e.IP != f.IP,
+		f.UserID != "" && e.UserID != f.UserID,
+		!f.Since.IsZero() && e.Time.Before(f.Since),
+		!f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Query liefert die neuesten passenden Einträge (neueste zuerst).
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+	var all []Entry
+	if l.f == nil {
+		l.mu.Lock()
+		all = append(append(all, l.mem[l.next:]...), l.mem[:l.next]...)
+		l.mu.Unlock()
+	} else {
+		var err error
+		if all, err = l.readFile(f); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]Entry, 0, f.Limit)
+	for i := len(all) - 1; i >= 0 && len(out) < f.Limit; i-- {
+		if f.match(all[i]) {
+			out = append(out, all[i])
+		}
+	}
+	return out, nil
+}
+
+// readFile liest die Datei von vorn und behält nur Treffer (schont Speicher bei großen Logs).
+func (l *Log) readFile(f Filter) ([]Entry, error) {
+	fh, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	var out []Entry
+	sc := bufio.NewScanner(fh)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) != nil || !f.match(e) {
+			continue
+		}
+		out = append(out, e)
+		if len(out) > 4*f.Limit {
+			out = append(out[:0], out[len(out)-f.Limit:]...)
+		}
+	}
+	return out, sc.Err()
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminConfig: statischer Bearer-Token und/oder Basic-Auth. Leer = Admin-Bereich aus.
+type AdminConfig struct {
+	Token    string
+	User     string
+	Password string
+}
+
+func (c AdminConfig) Enabled() bool {
+	return c.Token != "" || (c.User != "" && c.Password != "")
+}
+
+// Admin beschreibt, wie sich ein Request als Admin ausgewiesen hat.
+type Admin struct {
+	Method string // "token" | "basic"
+	Name   string
+}
+
+type ctxKey int
+
+const adminKey ctxKey = iota
+
+// AdminFrom liefert den Admin aus dem Request-Kontext (nach RequireAdmin).
+func AdminFrom(ctx context.Context) (Admin, bool) {
+	a, ok := ctx.Value(adminKey).(Admin)
+	return a, ok
+}
+
+// CheckAdmin prüft Authorization-Header gegen die Konfiguration.
+func (c AdminConfig) CheckAdmin(r *http.Request) (Admin, bool) {
+	if c.Token != "" {
+		if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+			if equal(strings.TrimPrefix(h, "Bearer "), c.Token) {
+				return Admin{Method: "token", Name: "token"}, true
+			}
+			return Admin{}, false
+		}
+	}
+	if c.User != "" && c.Password != "" {
+		if u, p, ok := r.BasicAuth(); ok && equal(u, c.User) && equal(p, c.Password) {
+			return Admin{Method: "basic", Name: u}, true
+		}
+	}
+	return Admin{}, false
+}
+
+/*
+RequireAdmin schützt /admin/* und /api/admin/*. Ist nichts konfiguriert, antwortet
+der Bereich mit 404, damit er auf öffentlichen Instanzen gar nicht erst auffällt.
+onDeny schreibt die Fehlerantwort (HTML vs. problem+json entscheidet der Aufrufer).
+*/
+func RequireAdmin(c AdminConfig, onDeny func(w http.ResponseWriter, r *http.Request, status int)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !c.Enabled() {
+				onDeny(w, r, http.StatusNotFound)
+				return
+			}
+			a, ok := c.CheckAdmin(r)
+			if !ok {
+				if c.User != "" {
+					w.Header().Set("WWW-Authenticate", `Basic realm="unglued admin", charset="UTF-8"`)
+				} else {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="unglued admin"`)
+				}
+				onDeny(w, r, http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminKey, a)))
+		})
+	}
+}
+
+// equal vergleicht in konstanter Zeit (auch bei unterschiedlicher Länge).
+func equal(a, b string) bool {
+	ha, hb := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Signer stellt HMAC-signierte, ablaufende Edit-Tokens aus.
+
+Format: <pasteID>.<exp (unix, base36)>.<sig>
+sig = HMAC-SHA256(key, "edit|id|exp|editKey"). Der gespeicherte Edit-Key fließt
+mit ein, taucht aber nie im Token auf; so entwertet ein neuer Edit-Key alle alten Links.
+
+Rotation: der erste Key signiert, alle weiteren werden nur noch zum Prüfen benutzt.
+*/
+type Signer struct {
+	mu   sync.RWMutex
+	keys [][]byte
+	ttl  time.Duration
+}
+
+// NewSigner: ohne Secrets wird ein Zufallskey erzeugt (Tokens überleben dann keinen Neustart).
+func NewSigner(secrets []string, ttl time.Duration) *Signer {
+	s := &Signer{ttl: ttl, keys: parseSecrets(secrets)}
+	if len(s.keys) == 0 {
+		k := make([]byte, 32)
+		_, _ = rand.Read(k)
+		s.keys = [][]byte{k}
+	}
+	return s
+}
+
+func parseSecrets(secrets []string) [][]byte {
+	var keys [][]byte
+	for _, sec := range secrets {
+		if sec = strings.TrimSpace(sec); sec != "" {
+			keys = append(keys, []byte(sec))
+		}
+	}
+	return keys
+}
+
+// SetSecrets tauscht die Secrets im laufenden Betrieb aus (z.B. nach einem Lease-Ablauf in Vault).
+func (s *Signer) SetSecrets(secrets []string) error {
+	keys := parseSecrets(secrets)
+	if len(keys) == 0 {
+		return errors.New("auth: no secrets")
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Signer) signing() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[0]
+}
+
+func (s *Signer) verifying() [][]byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys
+}
+
+func (s *Signer) TTL() time.Duration { return s.ttl }
+
+// EditToken stellt ein Token für paste id aus, gültig für TTL (höchstens bis notAfter, falls gesetzt).
+func (s *Signer) EditToken(id, editKey string, notAfter time.Time) string {
+	exp := time.Now().Add(s.ttl)
+	if !notAfter.IsZero() && notAfter.Before(exp) {
+		exp = notAfter
+	}
+	e := strconv.FormatInt(exp.Unix(), 36)
+	return id + "." + e + "." + mac(s.signing(), "edit", id, e, editKey)
+}
+
+// VerifyEditToken prüft Signatur, Paste-ID und Ablauf.
+func (s *Signer) VerifyEditToken(tok, id, editKey string) bool {
+	return s.VerifyEditTokenAt(tok, id, editKey, time.Now())
+}
+
+// VerifyEditTokenAt prüft, ob tok zum Zeitpunkt at gültig war (z.B. kurz vor Ablauf der Paste).
+func (s *Signer) VerifyEditTokenAt(tok, id, editKey string, at time.Time) bool {
+	if editKey == "" {
+		return false
+	}
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 || parts[0] != id {
+		return false
+	}
+	exp, err := strconv.ParseInt(parts[1], 36, 64)
+	if err != nil || at.Unix() > exp {
+		return false
+	}
+	for _, k := range s.verifying() {
+		if hmac.Equal([]byte(parts[2]), []byte(mac(k, "edit", id, parts[1], editKey))) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+KeyDigest bindet einen Wert an einen Key, ohne ihn preiszugeben: Seal signiert
+nur, der Inhalt ist für jeden lesbar. Ändert sich der Key, passt der Digest nicht mehr.
+*/
+func (s *Signer) KeyDigest(kind, id, key string) string {
+	return mac(s.signing(), "digest", kind, id, key)
+}
+
+// MatchKeyDigest prüft einen KeyDigest, auch gegen ältere Secrets.
+func (s *Signer) MatchKeyDigest(kind, id, key, digest string) bool {
+	if key == "" || digest == "" {
+		return false
+	}
+	for _, k := range s.verifying() {
+		if hmac.Equal([]byte(digest), []byte(mac(k, "digest", kind, id, key))) {
+			return true
+		}
+	}
+	return false
+}
+
+// mac signiert die mit "|" verbundenen Teile; der erste Teil benennt den Zweck.
+func mac(key []byte, parts ...string) string {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(strings.Join(parts, "|")))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+/*
+Seal verpackt v als signierten, ablaufenden Wert (z.B. für Session-Cookies):
+<base64url(json)>.<exp>.<sig>. kind trennt Verwendungszwecke, damit ein
+Session-Cookie nicht als etwas anderes durchgeht.
+*/
+func (s *Signer) Seal(kind string, v any, ttl time.Duration) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	e := strconv.FormatInt(time.Now().Add(ttl).Unix(), 36)
+	return payload + "." + e + "." + mac(s.signing(), "seal", kind, e, payload), nil
+}
+
+// Open prüft einen mit Seal erzeugten Wert und entpackt ihn nach v.
+func (s *Signer) Open(kind, tok string, v any) bool {
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	exp, err := strconv.ParseInt(parts[1], 36, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	ok := false
+	for _, k := range s.verifying() {
+		if hmac.Equal([]byte(parts[2]), []byte(mac(k, "seal", kind, parts[1], parts[0]))) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	return err == nil && json.Unmarshal(b, v) == nil
+}
@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/*
+Provider ist eine Login-Methode für Benutzer: OIDC, GitHub oder ein anderer
+OAuth2-Dienst. ID taucht in /login?provider= auf, Label im Formular.
+*/
+type Provider interface {
+	ID() string
+	Label() string
+	AuthURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (User, error)
+}
+
+func (o *OIDC) ID() string    { return "oidc" }
+func (o *OIDC) Label() string { return "SSO" }
+
+/*
+OAuth2Config: Login über einen OAuth2-Dienst ohne OIDC (Authorization Code + PKCE,
+danach Profil von UserURL). Welche Felder Kennung, Name, Avatar und Profil sind,
+wird an den üblichen Namen erkannt (GitHub, GitLab, Gitea, …).
+*/
+type OAuth2Config struct {
+	Name         string // ID des Providers, z.B. "github"
+	Label        string
+	AuthURL      string
+	TokenURL     string
+	UserURL      string
+	Scopes       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Issuer steht in der Identität (Issuer|Sub); leer = Origin von AuthURL
+	Issuer string
+	// Avatare nur von diesen Origins (landen so auch in der CSP); leer = Origin von UserURL
+	AvatarOrigins []string
+}
+
+func (c OAuth2Config) Enabled() bool { return c.ClientID != "" && c.AuthURL != "" }
+
+/*
+GitHubConfig: Login mit GitHub. base ist https://github.com oder die Adresse
+einer GitHub-Enterprise-Instanz.
+*/
+func GitHubConfig(base, clientID, clientSecret, redirect string) OAuth2Config {
+	base = strings.TrimRight(base, "/")
+	if base == "" {
+		base = "https://github.com"
+	}
+	c := OAuth2Config{
+		Name: "github", Label: "GitHub",
+		AuthURL:  base + "/login/oauth/authorize",
+		TokenURL: base + "/login/oauth/access_token",
+		UserURL:  base + "/api/v3/user",
+		Scopes:   "read:user",
+		ClientID: clientID, ClientSecret: clientSecret, RedirectURL: redirect,
+		Issuer:        base,
+		AvatarOrigins: []string{base},
+	}
+	if base == "https://github.com" {
+		c.UserURL = "https://api.github.com/user"
+		c.AvatarOrigins = []string{"https://avatars.githubusercontent.com"}
+	}
+	return c
+}
+
+type OAuth2 struct {
+	cfg    OAuth2Config
+	client *http.Client
+}
+
+func NewOAuth2(cfg OAuth2Config) *OAuth2 {
+	if cfg.Name == "" {
+		cfg.Name = "oauth2"
+	}
+	if cfg.Label == "" {
+		cfg.Label = "OAuth2"
+	}
+	if cfg.Issuer == "" {
+		cfg.Issuer = origin(cfg.AuthURL)
+	}
+	if len(cfg.AvatarOrigins) == 0 {
+		cfg.AvatarOrigins = []string{origin(cfg.UserURL)}
+	}
+	return &OAuth2{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (o *OAuth2) ID() string    { return o.cfg.Name }
+func (o *OAuth2) Label() string { return o.cfg.Label }
+
+// AvatarOrigins: für img-src in der CSP.
+func (o *OAuth2) AvatarOrigins() []string { return o.cfg.AvatarOrigins }
+
+// AuthURL: wie bei OIDC, nonce spielt ohne ID-Token keine Rolle.
+func (o *OAuth2) AuthURL(_ context.Context, state, _, verifier string) (string, error) {
+	sum := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.cfg.ClientID},
+		"redirect_uri":          {o.cfg.RedirectURL},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	if o.cfg.Scopes != "" {
+		q.Set("scope", o.cfg.Scopes)
+	}
+	sep := "?"
+	if strings.Contains(o.cfg.AuthURL, "?") {
+		sep = "&"
+	}
+	return o.cfg.AuthURL + sep + q.Encode(), nil
+}
+
+// Exchange tauscht den Code gegen ein Access-Token und liest damit das Profil.
+func (o *OAuth2) Exchange(ctx context.Context, code, verifier, _ string) (User, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"client_id":     {o.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	// GitHub erwartet das Secret im Body, nicht per Basic-Auth
+	if o.cfg.ClientSecret != "" {
+		form.Set("client_secret", o.cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return User{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	res, err := o.client.Do(req)
+	if err != nil {
+		return User{}, err
+	}
+	defer res.Body.Close()
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&tok); err != nil {
+		return User{}, fmt.Errorf("%s token response: %w", o.cfg.Name, err)
+	}
+	if res.StatusCode != http.StatusOK || tok.AccessToken == "" {
+		return User{}, fmt.Errorf("%s token endpoint: status %d %s %s", o.cfg.Name, res.StatusCode, tok.Error, tok.Description)
+	}
+	return o.profile(ctx, tok.AccessToken)
+}
+
+func (o *OAuth2) profile(ctx context.Context, token string) (User, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.cfg.UserURL, nil)
+	if err != nil {
+		return User{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "unglued") // ohne lehnt die GitHub-API ab
+	res, err := o.client.Do(req)
+	if err != nil {
+		return User{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return User{}, fmt.Errorf("%s user endpoint: status %d", o.cfg.Name, res.StatusCode)
+	}
+	dec := json.NewDecoder(io.LimitReader(res.Body, 1<<20))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return User{}, fmt.Errorf("%s user endpoint: %w", o.cfg.Name, err)
+	}
+	u := User{
+		Issuer:  o.cfg.Issuer,
+		Sub:     pick(m, "id", "sub"),
+		Login:   pick(m, "login", "username", "preferred_username"),
+		Name:    pick(m, "name", "full_name"),
+		Email:   pick(m, "email"),
+		Avatar:  pick(m, "avatar_url", "picture"),
+		Profile: pick(m, "html_url", "web_url", "profile"),
+	}
+	if u.Sub == "" {
+		return User{}, fmt.Errorf("%s user endpoint: no id in profile", o.cfg.Name)
+	}
+	if !o.avatarAllowed(u.Avatar) {
+		u.Avatar = ""
+	}
+	if !strings.HasPrefix(u.Profile, "https://") {
+		u.Profile = ""
+	}
+	return u, nil
+}
+
+func (o *OAuth2) avatarAllowed(a string) bool {
+	for _, orig := range o.cfg.AvatarOrigins {
+		if a != "" && origin(a) == orig {
+			return true
+		}
+	}
+	return false
+}
+
+// pick liefert das erste nicht leere Feld als String (Zahlen wie GitHubs id eingeschlossen).
+func pick(m map[string]any, keys ...string) string {
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case json.Number:
+			return v.String()
+		}
+	}
+	return ""
+}
+
+func origin(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+var errNoProvider = errors.New("no login provider")
+
+// Find liefert den Provider mit id; leere id = der erste.
+func Find(ps []Provider, id string) (Provider, error) {
+	for _, p := range ps {
+		if id == "" || p.ID() == id {
+			return p, nil
+		}
+	}
+	return nil, errNoProvider
+}
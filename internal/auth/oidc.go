@@ -0,0 +1,351 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCConfig: OpenID-Connect-Login (Authorization Code + PKCE). Leerer Issuer = aus.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // z.B. https://paste.example.com/auth/callback
+}
+
+func (c OIDCConfig) Enabled() bool { return c.Issuer != "" && c.ClientID != "" }
+
+// User ist eine per Login (OIDC, GitHub, OAuth2) verifizierte Identität.
+type User struct {
+	Issuer string `json:"iss"`
+	Sub    string `json:"sub"`
+	Name   string `json:"name,omitempty"`
+	Email  string `json:"email,omitempty"`
+	// nur bei OAuth2-Providern wie GitHub
+	Login   string `json:"login,omitempty"`
+	Avatar  string `json:"avatar,omitempty"`
+	Profile string `json:"profile,omitempty"`
+}
+
+// Display liefert den Anzeigenamen (Name > Login > E-Mail > Subject).
+func (u User) Display() string {
+	switch {
+	case u.Name != "":
+		return u.Name
+	case u.Login != "":
+		return u.Login
+	case u.Email != "":
+		return u.Email
+	}
+	return u.Sub
+}
+
+/*
+OIDC spricht mit einem Provider. Discovery-Dokument und JWKS werden lazy geholt
+und gecacht, damit ein kurz nicht erreichbarer IdP den Start nicht verhindert.
+*/
+type OIDC struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	disc   *discovery
+	keys   map[string]crypto.PublicKey
+	keysAt time.Time
+}
+
+type discovery struct {
+	Issuer        string `json:"issuer"`
+	AuthEndpoint  string `json:"authorization_endpoint"`
+	TokenEndpoint string `json:"token_endpoint"`
+	JWKSURI       string `json:"jwks_uri"`
+}
+
+func NewOIDC(cfg OIDCConfig) *OIDC {
+	return &OIDC{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (o *OIDC) discover(ctx context.Context) (*discovery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.disc != nil {
+		return o.disc, nil
+	}
+	var d discovery
+	u := strings.TrimRight(o.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := o.getJSON(ctx, u, &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if d.AuthEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: incomplete document")
+	}
+	o.disc = &d
+	return o.disc, nil
+}
+
+// AuthURL baut die Redirect-URL zum Provider (state/nonce/PKCE erzeugt der Aufrufer).
+func (o *OIDC) AuthURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	d, err := o.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.cfg.ClientID},
+		"redirect_uri":          {o.cfg.RedirectURL},
+		"scope":                 {"openid profile email"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(d.AuthEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange tauscht den Code gegen Tokens und liefert den verifizierten Benutzer.
+func (o *OIDC) Exchange(ctx context.Context, code, verifier, nonce string) (User, error) {
+	d, err := o.discover(ctx)
+	if err != nil {
+		return User{}, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"client_id":     {o.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return User{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(o.cfg.ClientSecret))
+	}
+	res, err := o.client.Do(req)
+	if err != nil {
+		return User{}, err
+	}
+	defer res.Body.Close()
+	var tok struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tok); err != nil {
+		return User{}, fmt.Errorf("oidc token response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK || tok.IDToken == "" {
+		return User{}, fmt.Errorf("oidc token endpoint: status %d %s", res.StatusCode, tok.Error)
+	}
+	return o.verifyIDToken(ctx, d, tok.IDToken, nonce)
+}
+
+type idClaims struct {
+	Iss   string          `json:"iss"`
+	Sub   string          `json:"sub"`
+	Aud   json.RawMessage `json:"aud"`
+	Exp   int64           `json:"exp"`
+	Nonce string          `json:"nonce"`
+	Name  string          `json:"name"`
+	Pref  string          `json:"preferred_username"`
+	Email string          `json:"email"`
+}
+
+func (o *OIDC) verifyIDToken(ctx context.Context, d *discovery, raw, nonce string) (User, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return User{}, errors.New("id_token: malformed")
+	}
+	var hdr struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return User{}, err
+	}
+	key, err := o.key(ctx, d, hdr.Kid)
+	if err != nil {
+		return User{}, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return User{}, errors.New("id_token: bad signature encoding")
+	}
+	if err := verifyJWS(hdr.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return User{}, err
+	}
+
+	var c idClaims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return User{}, err
+	}
+	if c.Iss != d.Issuer && c.Iss != strings.TrimRight(o.cfg.Issuer, "/") {
+		return User{}, errors.New("id_token: wrong issuer")
+	}
+	if !audContains(c.Aud, o.cfg.ClientID) {
+		return User{}, errors.New("id_token: wrong audience")
+	}
+	if time.Now().Unix() > c.Exp+60 {
+		return User{}, errors.New("id_token: expired")
+	}
+	if c.Nonce != nonce {
+		return User{}, errors.New("id_token: nonce mismatch")
+	}
+	name := c.Name
+	if name == "" {
+		name = c.Pref
+	}
+	return User{Issuer: c.Iss, Sub: c.Sub, Name: name, Email: c.Email}, nil
+}
+
+func verifyJWS(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	sum := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("id_token: key type mismatch")
+		}
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig); err != nil {
+			return errors.New("id_token: bad signature")
+		}
+	case "ES256":
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return errors.New("id_token: key type mismatch")
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(k, sum[:], r, s) {
+			return errors.New("id_token: bad signature")
+		}
+	default:
+		return fmt.Errorf("id_token: unsupported alg %q", alg)
+	}
+	return nil
+}
+
+// key holt den Schlüssel zu kid; unbekannte kids lösen (max. 1×/Minute) einen JWKS-Refresh aus.
+func (o *OIDC) key(ctx context.Context, d *discovery, kid string) (crypto.PublicKey, error) {
+	o.mu.Lock()
+	k, ok := o.keys[kid]
+	stale := time.Since(o.keysAt) > time.Minute
+	o.mu.Unlock()
+	if ok {
+		return k, nil
+	}
+	if !stale && o.keys != nil {
+		return nil, errors.New("id_token: unknown key id")
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := o.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jk := range set.Keys {
+		switch jk.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(jk.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(jk.E)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[jk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if jk.Crv != "P-256" {
+				continue
+			}
+			x, err1 := base64.RawURLEncoding.DecodeString(jk.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(jk.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[jk.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	o.mu.Lock()
+	o.keys, o.keysAt = keys, time.Now()
+	o.mu.Unlock()
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	// Provider mit nur einem Key lassen kid gern weg
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, nil
+		}
+	}
+	return nil, errors.New("id_token: unknown key id")
+}
+
+func (o *OIDC) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", u, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return errors.New("id_token: bad encoding")
+	}
+	return json.Unmarshal(b, v)
+}
+
+func audContains(raw json.RawMessage, clientID string) bool {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return one == clientID
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		for _, a := range many {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
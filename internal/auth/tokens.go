@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"unglued/internal/util"
+)
+
+// TokenIssuer steht in User.Issuer bei Requests mit API-Token.
+const TokenIssuer = "token"
+
+/*
+Account: ein Besitzer von API-Tokens. Alle seine Pastes liegen unter Owner; Quoten
+und Laufzeiten gelten pro Account, 0 = die Vorgaben der Instanz.
+*/
+type Account struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name,omitempty"`
+	// SHA-256 (hex) der Tokens, damit die Datei selbst keine Zugangsdaten enthält
+	TokenHashes []string `json:"token_sha256"`
+
+	MaxPastes  int    `json:"max_pastes,omitempty"`
+	MaxBytes   int64  `json:"max_bytes,omitempty"`
+	DefaultTTL string `json:"default_ttl,omitempty"`
+	MaxTTL     string `json:"max_ttl,omitempty"`
+
+	defaultTTL, maxTTL time.Duration
+}
+
+func (a Account) User() User { return User{Issuer: TokenIssuer, Sub: a.Owner, Name: a.Name} }
+
+// TTLs: Standard- und Höchstlaufzeit des Accounts; 0 = nicht gesetzt.
+func (a Account) TTLs() (def, max time.Duration) { return a.defaultTTL, a.maxTTL }
+
+/*
+Tokens: API-Tokens aus einer JSON-Datei (Liste von Accounts). Reload liest sie neu,
+etwa nach SIGHUP. Ein nil-*Tokens kennt keine Tokens.
+*/
+type Tokens struct {
+	path string
+
+	mu     sync.RWMutex
+	byHash map[string]*Account
+	owners map[string]*Account
+}
+
+func LoadTokens(path string) (*Tokens, error) {
+	if path == "" {
+		return nil, nil
+	}
+	t := &Tokens{path: path}
+	return t, t.Reload()
+}
+
+func (t *Tokens) Reload() error {
+	if t == nil {
+		return nil
+	}
+	b, err := os.ReadFile(t.path)
+	if err != nil {
+		return err
+	}
+	var list []*Account
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	byHash, owners := map[string]*Account{}, map[string]*Account{}
+	for _, a := range list {
+		if a.Owner == "" || strings.Contains(a.Owner, "|") {
+			return fmt.Errorf("invalid owner %q", a.Owner)
+		}
+		if owners[a.Owner] != nil {
+			return fmt.Errorf("duplicate owner %q", a.Owner)
+		}
+		for _, f := range []struct {
+			in  string
+			out *time.Duration
+		}{{a.DefaultTTL, &a.defaultTTL}, {a.MaxTTL, &a.maxTTL}} {
+			if f.in == "" {
+				continue
+			}
+			if *f.out, err = util.ParseTTL(f.in); err != nil || *f.out <= 0 {
+				return fmt.Errorf("%s: invalid duration %q", a.Owner, f.in)
+			}
+		}
+		for _, h := range a.TokenHashes {
+			h = strings.ToLower(h)
+			if raw, err := hex.DecodeString(h); err != nil || len(raw) != sha256.Size {
+				return fmt.Errorf("%s: token_sha256 must be 64 hex characters", a.Owner)
+			}
+			byHash[h] = a
+		}
+		owners[a.Owner] = a
+	}
+	t.mu.Lock()
+	t.byHash, t.owners = byHash, owners
+	t.mu.Unlock()
+	return nil
+}
+
+// Lookup findet den Account zu einem Token.
+func (t *Tokens) Lookup(token string) (Account, bool) {
+	if t == nil || token == "" {
+		return Account{}, false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	a, ok := t.byHash[HashToken(token)]
+	if !ok {
+		return Account{}, false
+	}
+	return *a, true
+}
+
+// Owner findet einen Account über seinen Namen.
+func (t *Tokens) Owner(owner string) (Account, bool) {
+	if t == nil {
+		return Account{}, false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	a, ok := t.owners[owner]
+	if !ok {
+		return Account{}, false
+	}
+	return *a, true
+}
+
+// HashToken: so steht ein Token in token_sha256.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
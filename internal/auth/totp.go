@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"unglued/internal/util"
+)
+
+// TOTP nach RFC 6238: SHA-1, 30 s, 6 Stellen – das, was jede Authenticator-App kann.
+const (
+	totpStep   = 30 * time.Second
+	totpDigits = 6
+	totpSkew   = 1 // ±1 Schritt Uhrenabweichung
+
+	recoveryCodes = 10
+	maxFailures   = 5
+	lockout       = 15 * time.Minute
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func totpCode(secret []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	m := hmac.New(sha1.New, secret)
+	m.Write(msg[:])
+	sum := m.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, v%1000000)
+}
+
+var (
+	ErrLocked     = errors.New("2fa: too many failed attempts, locked")
+	ErrBadCode    = errors.New("2fa: invalid code")
+	ErrNotEnabled = errors.New("2fa: not enrolled")
+)
+
+/*
+TwoFactor hält den TOTP-Zustand des Admin-Zugangs (ein Admin, ein Secret) samt
+Recovery-Codes (nur als SHA-256 gespeichert) und sperrt nach maxFailures
+Fehlversuchen für lockout. Mit path wird der Zustand als JSON (0600) gesichert.
+*/
+type TwoFactor struct {
+	mu   sync.Mutex
+	path string
+	st   twoFactorState
+
+	failures    int
+	lockedUntil time.Time
+}
+
+type twoFactorState struct {
+	Secret   string   `json:"secret"`             // base32
+	Enabled  bool     `json:"enabled"`            // erst nach bestätigtem Code
+	Recovery []string `json:"recovery,omitempty"` // hex(sha256(code)), verbrauchte fliegen raus
+	LastStep uint64   `json:"last_step"`          // gegen Replay desselben Codes
+}
+
+func LoadTwoFactor(path string) (*TwoFactor, error) {
+	t := &TwoFactor{path: path}
+	if path == "" {
+		return t, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &t.st); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *TwoFactor) Enabled() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.st.Enabled
+}
+
+// Status: aktiv, verbleibende Recovery-Codes, Sperre.
+func (t *TwoFactor) Status() (enabled bool, recoveryLeft int, lockedUntil time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.st.Enabled, len(t.st.Recovery), t.lockedUntil
+}
+
+/*
+Enroll erzeugt ein neues (noch inaktives) Secret und liefert es samt otpauth-URI.
+Ist 2FA schon aktiv, muss vorher Disable laufen.
+*/
+func (t *TwoFactor) Enroll(issuer, account string) (secret, uri string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.st.Enabled {
+		return "", "", errors.New("2fa: already enabled")
+	}
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	t.st = twoFactorState{Secret: b32.EncodeToString(raw)}
+	label := url.PathEscape(issuer + ":" + account)
+	uri = "otpauth://totp/" + label + "?" + url.Values{
+		"secret": {t.st.Secret}, "issuer": {issuer}, "algorithm": {"SHA1"},
+		"digits": {"6"}, "period": {"30"},
+	}.Encode()
+	return t.st.Secret, uri, t.saveLocked()
+}
+
+// Confirm aktiviert 2FA mit einem ersten gültigen Code und liefert die Recovery-Codes (einmalig).
+func (t *TwoFactor) Confirm(code string, now time.Time) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.st.Secret == "" {
+		return nil, ErrNotEnabled
+	}
+	if t.st.Enabled {
+		return nil, errors.New("2fa: already enabled")
+	}
+	if err := t.checkLocked(code, now, false); err != nil {
+		return nil, err
+	}
+	codes := make([]string, recoveryCodes)
+	t.st.Recovery = t.st.Recovery[:0]
+	for i := range codes {
+		raw := make([]byte, 6)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		codes[i] = strings.ToLower(b32.EncodeToString(raw)[:10])
+		t.st.Recovery = append(t.st.Recovery, hashCode(codes[i]))
+	}
+	t.st.Enabled = true
+	return codes, t.saveLocked()
+}
+
+// Verify prüft einen TOTP- oder Recovery-Code; Recovery-Codes sind danach verbraucht.
+func (t *TwoFactor) Verify(code string, now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.st.Enabled {
+		return ErrNotEnabled
+	}
+	return t.checkLocked(code, now, true)
+}
+
+// Disable schaltet 2FA nach einem gültigen Code ab.
+func (t *TwoFactor) Disable(code string, now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.st.Enabled {
+		return ErrNotEnabled
+	}
+	if err := t.checkLocked(code, now, true); err != nil {
+		return err
+	}
+	t.st = twoFactorState{}
+	return t.saveLocked()
+}
+
+func (t *TwoFactor) checkLocked(code string, now time.Time, allowRecovery bool) error {
+	if now.Before(t.lockedUntil) {
+		return ErrLocked
+	}
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+	if t.matchTOTP(code, now) || (allowRecovery && t.useRecovery(code)) {
+		t.failures = 0
+		return t.saveLocked()
+	}
+	t.failures++
+	if t.failures >= maxFailures {
+		t.failures = 0
+		t.lockedUntil = now.Add(lockout)
+		return ErrLocked
+	}
+	return ErrBadCode
+}
+
+func (t *TwoFactor) matchTOTP(code string, now time.Time) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	secret, err := b32.DecodeString(strings.ToUpper(t.st.Secret))
+	if err != nil {
+		return false
+	}
+	step := uint64(now.Unix()) / uint64(totpStep/time.Second)
+	for d := -totpSkew; d <= totpSkew; d++ {
+		c := step + uint64(d)
+		if c <= t.st.LastStep {
+			continue // schon benutzt (Replay)
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, c)), []byte(code)) == 1 {
+			t.st.LastStep = c
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TwoFactor) useRecovery(code string) bool {
+	h := hashCode(code)
+	for i, r := range t.st.Recovery {
+		if subtle.ConstantTimeCompare([]byte(r), []byte(h)) == 1 {
+			t.st.Recovery = append(t.st.Recovery[:i], t.st.Recovery[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *TwoFactor) saveLocked() error {
+	if t.path == "" {
+		return nil
+	}
+	return util.WriteJSONFile(t.path, t.st, 0o600)
+}
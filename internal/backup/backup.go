@@ -0,0 +1,100 @@
+/*
+Package backup schreibt und liest Snapshots des Stores als tar: manifest.json
+plus eine JSON-Datei pro Paste (alle Versionen, Edit-Key, Owner, Flags).
+Kompression (zstd/gzip) ist Sache des Aufrufers.
+*/
+package backup
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"unglued/internal/model"
+)
+
+// FormatVersion wird bei inkompatiblen Änderungen am Paste-Format hochgezählt.
+const FormatVersion = 1
+
+type Manifest struct {
+	Format  int       `json:"format"`
+	Created time.Time `json:"created"`
+	Pastes  int       `json:"pastes"`
+}
+
+// Write schreibt ps als tar nach w.
+func Write(w io.Writer, ps []model.Paste) error {
+	tw := tar.NewWriter(w)
+	now := time.Now().UTC()
+	m, _ := json.Marshal(Manifest{Format: FormatVersion, Created: now, Pastes: len(ps)})
+	if err := writeFile(tw, "manifest.json", m, now); err != nil {
+		return err
+	}
+	for _, p := range ps {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(tw, "pastes/"+p.ID+".json", b, p.UpdatedAt); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeFile(tw *tar.Writer, name string, b []byte, mod time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(b)), ModTime: mod, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := tw.Write(b)
+	return err
+}
+
+// Read liest ein Backup und ruft fn für jede Paste auf; das Manifest muss vorne stehen.
+func Read(r io.Reader, fn func(model.Paste) error) (Manifest, error) {
+	tr := tar.NewReader(r)
+	var m Manifest
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			if m.Format == 0 {
+				return m, errors.New("backup: no manifest.json")
+			}
+			return m, nil
+		}
+		if err != nil {
+			return m, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		switch {
+		case h.Name == "manifest.json":
+			if err := json.NewDecoder(tr).Decode(&m); err != nil {
+				return m, fmt.Errorf("backup: manifest: %w", err)
+			}
+			if m.Format != FormatVersion {
+				return m, fmt.Errorf("backup: unsupported format %d", m.Format)
+			}
+		case strings.HasPrefix(h.Name, "pastes/") && path.Ext(h.Name) == ".json":
+			if m.Format == 0 {
+				return m, errors.New("backup: manifest.json must come first")
+			}
+			var p model.Paste
+			if err := json.NewDecoder(tr).Decode(&p); err != nil {
+				return m, fmt.Errorf("backup: %s: %w", h.Name, err)
+			}
+			if p.ID == "" || len(p.Versions) == 0 {
+				return m, fmt.Errorf("backup: %s: incomplete paste", h.Name)
+			}
+			if err := fn(p); err != nil {
+				return m, err
+			}
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const azureVersion = "2021-08-06"
+
+/*
+Azure spricht die REST-API von Azure Blob Storage (Block-Blobs). Anmeldung per
+Shared Key (AZURE_STORAGE_KEY) oder SAS-Token (AZURE_STORAGE_SAS_TOKEN).
+AZURE_STORAGE_ENDPOINT ersetzt https://<account>.blob.core.windows.net, etwa
+für Azurite (http://127.0.0.1:10000/devstoreaccount1).
+*/
+type Azure struct {
+	account   string
+	container string
+	base      *url.URL
+	key       []byte
+	sas       url.Values
+	client    *http.Client
+}
+
+func NewAzure(account, container string) (*Azure, error) {
+	a := &Azure{account: account, container: container, client: &http.Client{Timeout: time.Minute}}
+	endpoint := os.Getenv("AZURE_STORAGE_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("azure: invalid endpoint %q", endpoint)
+	}
+	a.base = u
+	switch key, sas := os.Getenv("AZURE_STORAGE_KEY"), os.Getenv("AZURE_STORAGE_SAS_TOKEN"); {
+	case key != "":
+		if a.key, err = base64.StdEncoding.DecodeString(key); err != nil {
+			return nil, errors.New("azure: AZURE_STORAGE_KEY is not base64")
+		}
+	case sas != "":
+		if a.sas, err = url.ParseQuery(strings.TrimPrefix(sas, "?")); err != nil {
+			return nil, errors.New("azure: invalid AZURE_STORAGE_SAS_TOKEN")
+		}
+	default:
+		return nil, errors.New("azure: AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN is required")
+	}
+	return a, nil
+}
+
+func (a *Azure) Put(ctx context.Context, key string, body []byte, ct string) error {
+	res, err := a.do(ctx, http.MethodPut, key, nil, body, map[string]string{"Content-Type": ct, "x-ms-blob-type": "BlockBlob"})
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (a *Azure) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := a.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+func (a *Azure) Delete(ctx context.Context, key string) error {
+	res, err := a.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (a *Azure) List(ctx context.Context, prefix string) ([]Object, error) {
+	var out []Object
+	marker := ""
+	for {
+		q := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+		if marker != "" {
+			q.Set("marker", marker)
+		}
+		res, err := a.do(ctx, http.MethodGet, "", q, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Blobs []struct {
+				Name  string `xml:"Name"`
+				Props struct {
+					Length   int64  `xml:"Content-Length"`
+					Modified string `xml:"Last-Modified"`
+				} `xml:"Properties"`
+			} `xml:"Blobs>Blob"`
+			Next string `xml:"NextMarker"`
+		}
+		err = xml.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range page.Blobs {
+			mod, _ := time.Parse(time.RFC1123, b.Props.Modified)
+			out = append(out, Object{Key: b.Name, Size: b.Props.Length, LastModified: mod.UTC()})
+		}
+		if page.Next == "" {
+			return out, nil
+		}
+		marker = page.Next
+	}
+}
+
+func (a *Azure) String() string { return "azure://" + a.account + "/" + a.container }
+
+// do: Status ≥ 300 wird zum Fehler, 404 bei einem Blob zu ErrNotFound.
+func (a *Azure) do(ctx context.Context, method, key string, q url.Values, body []byte, h map[string]string) (*http.Response, error) {
+	u := *a.base
+	u.Path = u.Path + "/" + a.container
+	if key != "" {
+		u.Path += "/" + key
+	}
+	if q == nil {
+		q = url.Values{}
+	}
+	for k, v := range a.sas {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureVersion)
+	if a.key != nil {
+		req.Header.Set("Authorization", "SharedKey "+a.account+":"+a.sign(req, len(body)))
+	}
+	res, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound && key != "" {
+		return nil, ErrNotFound
+	}
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&e)
+	return nil, fmt.Errorf("azure: %s %s: %s %s %s", method, u.Path, res.Status, e.Code, strings.SplitN(e.Message, "\n", 2)[0])
+}
+
+// sign: Shared-Key-Signatur nach "Authorize with Shared Key" (Blob-Dienst ab 2009-09-19).
+func (a *Azure) sign(req *http.Request, n int) string {
+	length := ""
+	if n > 0 {
+		length = strconv.Itoa(n)
+	}
+	var ms []string
+	for k := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-ms-") {
+			ms = append(ms, lk+":"+strings.TrimSpace(req.Header.Get(k)))
+		}
+	}
+	slices.Sort(ms)
+	resource := "/" + a.account + req.URL.EscapedPath()
+	q := req.URL.Query()
+	names := make([]string, 0, len(q))
+	for k := range q {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	for _, k := range names {
+		vs := slices.Sorted(slices.Values(q[k]))
+		resource += "\n" + strings.ToLower(k) + ":" + strings.Join(vs, ",")
+	}
+	sts := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		length,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date: steht in x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(ms, "\n"),
+		resource,
+	}, "\n")
+	m := hmac.New(sha256.New, a.key)
+	m.Write([]byte(sts))
+	return base64.StdEncoding.EncodeToString(m.Sum(nil))
+}
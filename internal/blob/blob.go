@@ -0,0 +1,147 @@
+/*
+Package blob legt große Objekte (Archiv abgelaufener Pastes, später Anhänge)
+außerhalb des Stores ab, der die Paste-Metadaten hält. Backends: lokales
+Verzeichnis, S3, Google Cloud Storage und Azure Blob Storage; welches, bestimmt
+die URI in Open.
+*/
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"unglued/internal/awsv4"
+	"unglued/internal/s3"
+)
+
+var ErrNotFound = errors.New("blob: not found")
+
+// Object: ein Eintrag aus List; Key ohne den Präfix des Stores.
+type Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// Store ist ein flacher Schlüssel-Wert-Speicher; "/" in Keys ist erlaubt.
+type Store interface {
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	// Get liefert ErrNotFound, wenn es key nicht gibt.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete ist für fehlende Keys kein Fehler.
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// String: die URI ohne Zugangsdaten, für Logs.
+	String() string
+}
+
+/*
+Open öffnet einen Store aus einer URI; der Pfad hinter Bucket bzw. Container
+wird Präfix aller Keys:
+
+	file:///var/lib/unglued/blobs
+	s3://bucket/prefix?region=eu-central-1&endpoint=https://minio:9000
+	gs://bucket/prefix
+	azure://account/container/prefix
+
+Zugangsdaten kommen aus den üblichen Umgebungsvariablen der Anbieter
+(AWS_ACCESS_KEY_ID…, GOOGLE_APPLICATION_CREDENTIALS, AZURE_STORAGE_KEY…).
+*/
+func Open(uri string) (Store, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("blob: %w", err)
+	}
+	switch u.Scheme {
+	case "file":
+		dir := u.Path
+		if dir == "" {
+			dir = u.Opaque // file:relativ/pfad
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("blob: %s: missing directory", uri)
+		}
+		return NewDisk(dir)
+	case "s3":
+		cfg := s3.Config{Bucket: u.Host, Region: u.Query().Get("region"), Endpoint: u.Query().Get("endpoint")}
+		if cfg.Bucket == "" {
+			return nil, fmt.Errorf("blob: %s: missing bucket", uri)
+		}
+		cfg.Credentials, _ = awsv4.FromEnv()
+		if cfg.Region == "" {
+			cfg.Region = awsv4.Region()
+		}
+		c, err := s3.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return WithPrefix(NewS3(c), dirPrefix(u.Path)), nil
+	case "gs":
+		if u.Host == "" {
+			return nil, fmt.Errorf("blob: %s: missing bucket", uri)
+		}
+		g, err := NewGCS(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return WithPrefix(g, dirPrefix(u.Path)), nil
+	case "azure":
+		container, prefix, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+		if u.Host == "" || container == "" {
+			return nil, fmt.Errorf("blob: %s: want azure://account/container[/prefix]", uri)
+		}
+		a, err := NewAzure(u.Host, container)
+		if err != nil {
+			return nil, err
+		}
+		return WithPrefix(a, dirPrefix(prefix)), nil
+	}
+	return nil, fmt.Errorf("blob: %s: unknown scheme (file, s3, gs, azure)", uri)
+}
+
+// dirPrefix: der Pfad aus der URI als Präfix, immer mit "/" am Ende.
+func dirPrefix(path string) string {
+	if path = strings.Trim(path, "/"); path == "" {
+		return ""
+	}
+	return path + "/"
+}
+
+// WithPrefix stellt allen Keys prefix voran (so wie er ist); List liefert sie ohne.
+func WithPrefix(s Store, prefix string) Store {
+	if prefix == "" {
+		return s
+	}
+	return &prefixed{s, prefix}
+}
+
+type prefixed struct {
+	Store
+	prefix string
+}
+
+func (p *prefixed) Put(ctx context.Context, key string, body []byte, ct string) error {
+	return p.Store.Put(ctx, p.prefix+key, body, ct)
+}
+
+func (p *prefixed) Get(ctx context.Context, key string) ([]byte, error) {
+	return p.Store.Get(ctx, p.prefix+key)
+}
+
+func (p *prefixed) Delete(ctx context.Context, key string) error {
+	return p.Store.Delete(ctx, p.prefix+key)
+}
+
+func (p *prefixed) List(ctx context.Context, prefix string) ([]Object, error) {
+	objs, err := p.Store.List(ctx, p.prefix+prefix)
+	for i := range objs {
+		objs[i].Key = strings.TrimPrefix(objs[i].Key, p.prefix)
+	}
+	return objs, err
+}
+
+func (p *prefixed) String() string { return p.Store.String() + "/" + p.prefix }
@@ -0,0 +1,295 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+/*
+GCS spricht die JSON-API von Google Cloud Storage. Token: Service-Account aus
+GOOGLE_APPLICATION_CREDENTIALS, sonst der Metadaten-Server (GCE, GKE, Cloud
+Run). Mit STORAGE_EMULATOR_HOST (etwa fake-gcs-server) geht es ohne Anmeldung
+an den Emulator.
+*/
+type GCS struct {
+	bucket string
+	base   string
+	client *http.Client
+	token  tokenSource
+}
+
+type tokenSource interface {
+	token(ctx context.Context) (string, error)
+}
+
+func NewGCS(bucket string) (*GCS, error) {
+	g := &GCS{bucket: bucket, base: "https://storage.googleapis.com", client: &http.Client{Timeout: time.Minute}}
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		g.base = strings.TrimRight(host, "/")
+		return g, nil
+	}
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		sa, err := loadServiceAccount(path)
+		if err != nil {
+			return nil, err
+		}
+		g.token = &cachedToken{fetch: sa.fetch}
+		return g, nil
+	}
+	g.token = &cachedToken{fetch: metadataToken}
+	return g, nil
+}
+
+func (g *GCS) objectURL(key string) string {
+	return g.base + "/storage/v1/b/" + url.PathEscape(g.bucket) + "/o/" + url.PathEscape(key)
+}
+
+func (g *GCS) Put(ctx context.Context, key string, body []byte, ct string) error {
+	q := url.Values{"uploadType": {"media"}, "name": {key}}
+	res, err := g.do(ctx, http.MethodPost, g.base+"/upload/storage/v1/b/"+url.PathEscape(g.bucket)+"/o?"+q.Encode(), body, ct)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (g *GCS) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := g.do(ctx, http.MethodGet, g.objectURL(key)+"?alt=media", nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+func (g *GCS) Delete(ctx context.Context, key string) error {
+	res, err := g.do(ctx, http.MethodDelete, g.objectURL(key), nil, "")
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (g *GCS) List(ctx context.Context, prefix string) ([]Object, error) {
+	var out []Object
+	token := ""
+	for {
+		q := url.Values{"prefix": {prefix}, "fields": {"items(name,size,updated),nextPageToken"}}
+		if token != "" {
+			q.Set("pageToken", token)
+		}
+		res, err := g.do(ctx, http.MethodGet, g.base+"/storage/v1/b/"+url.PathEscape(g.bucket)+"/o?"+q.Encode(), nil, "")
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []struct {
+				Name    string    `json:"name"`
+				Size    string    `json:"size"` // int64 als String
+				Updated time.Time `json:"updated"`
+			} `json:"items"`
+			Next string `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range page.Items {
+			size, _ := strconv.ParseInt(it.Size, 10, 64)
+			out = append(out, Object{Key: it.Name, Size: size, LastModified: it.Updated})
+		}
+		if page.Next == "" {
+			return out, nil
+		}
+		token = page.Next
+	}
+}
+
+func (g *GCS) String() string { return "gs://" + g.bucket }
+
+// do: Status ≥ 300 wird zum Fehler, 404 zu ErrNotFound.
+func (g *GCS) do(ctx context.Context, method, target string, body []byte, ct string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+	if g.token != nil {
+		tok, err := g.token.token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("gcs: token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+	res, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&e)
+	return nil, fmt.Errorf("gcs: %s %s: %s %s", method, req.URL.Path, res.Status, e.Error.Message)
+}
+
+// cachedToken holt ein neues Token erst kurz vor Ablauf des alten.
+type cachedToken struct {
+	fetch func(ctx context.Context) (string, time.Duration, error)
+
+	mu      sync.Mutex
+	current string
+	until   time.Time
+}
+
+func (c *cachedToken) token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current != "" && time.Now().Before(c.until) {
+		return c.current, nil
+	}
+	tok, ttl, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.current, c.until = tok, time.Now().Add(ttl-time.Minute)
+	return tok, nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func readToken(res *http.Response) (string, time.Duration, error) {
+	defer res.Body.Close()
+	var t tokenResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<16)).Decode(&t); err != nil {
+		return "", 0, fmt.Errorf("%s: %w", res.Status, err)
+	}
+	if res.StatusCode != http.StatusOK || t.AccessToken == "" {
+		return "", 0, fmt.Errorf("%s: %s %s", res.Status, t.Error, t.Description)
+	}
+	return t.AccessToken, time.Duration(t.ExpiresIn) * time.Second, nil
+}
+
+// metadataToken: das Token des Dienstkontos der VM bzw. des Pods.
+func metadataToken(ctx context.Context) (string, time.Duration, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	res, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("metadata server (set GOOGLE_APPLICATION_CREDENTIALS outside Google Cloud): %w", err)
+	}
+	return readToken(res)
+}
+
+type serviceAccount struct {
+	Email    string `json:"client_email"`
+	Key      string `json:"private_key"`
+	TokenURI string `json:"token_uri"`
+	key      *rsa.PrivateKey
+}
+
+func loadServiceAccount(path string) (*serviceAccount, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("gcs: %w", err)
+	}
+	var sa serviceAccount
+	if err := json.Unmarshal(b, &sa); err != nil {
+		return nil, fmt.Errorf("gcs: %s: %w", path, err)
+	}
+	block, _ := pem.Decode([]byte(sa.Key))
+	if block == nil || sa.Email == "" {
+		return nil, fmt.Errorf("gcs: %s: not a service account key", path)
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("gcs: %s: %w", path, err)
+	}
+	var ok bool
+	if sa.key, ok = k.(*rsa.PrivateKey); !ok {
+		return nil, fmt.Errorf("gcs: %s: private key is not RSA", path)
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &sa, nil
+}
+
+// fetch tauscht ein selbst signiertes JWT (RS256) gegen ein Access-Token.
+func (sa *serviceAccount) fetch(ctx context.Context) (string, time.Duration, error) {
+	now := time.Now()
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]any{
+		"iss": sa.Email, "scope": gcsScope, "aud": sa.TokenURI,
+		"iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+	})
+	unsigned := header + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(nil, sa.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", 0, err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + enc.EncodeToString(sig)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sa.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	return readToken(res)
+}
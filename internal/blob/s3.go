@@ -0,0 +1,49 @@
+package blob
+
+import (
+	"context"
+	"errors"
+
+	"unglued/internal/s3"
+)
+
+// S3 hängt den vorhandenen S3-Client hinter Store.
+type S3 struct {
+	c *s3.Client
+}
+
+func NewS3(c *s3.Client) *S3 { return &S3{c: c} }
+
+func (s *S3) Put(ctx context.Context, key string, body []byte, ct string) error {
+	return s.c.Put(ctx, key, body, ct)
+}
+
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := s.c.Get(ctx, key)
+	if errors.Is(err, s3.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+// Delete: S3 meldet auch für fehlende Keys Erfolg.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := s.c.Delete(ctx, key); err != nil && !errors.Is(err, s3.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	objs, err := s.c.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Object, len(objs))
+	for i, o := range objs {
+		out[i] = Object{Key: o.Key, Size: o.Size, LastModified: o.LastModified}
+	}
+	return out, nil
+}
+
+func (s *S3) String() string { return "s3://" + s.c.Bucket() }
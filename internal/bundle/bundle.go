@@ -0,0 +1,309 @@
+/*
+Package bundle packt kleine .zip- und .tar.gz-Archive in eine Paste aus: alle
+Textdateien hintereinander, jede mit einer Kopfzeile "==> pfad/datei <==" wie
+bei head(1). So bleibt es eine Paste mit einer Version, Raw-URL und Suche
+funktionieren wie gehabt, und Index findet die Dateien für das Inhaltsverzeichnis.
+*/
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	headPrefix = "==> "
+	headSuffix = " <=="
+)
+
+var (
+	ErrNotArchive = errors.New("not a .zip or .tar.gz archive")
+	ErrTooMany    = errors.New("archive has too many files")
+	ErrTooLarge   = errors.New("archive unpacks to too much data")
+	ErrEmpty      = errors.New("archive contains no text files")
+)
+
+/*
+Limits gegen Archivbomben; 0 = Vorgabe. Beide zählen alles, was ausgepackt wird,
+auch Verzeichnisse und übersprungene Binärdateien.
+*/
+type Limits struct {
+	MaxFiles int   // Einträge im Archiv; Vorgabe 100
+	MaxBytes int64 // entpackt, alle Einträge zusammen; Vorgabe 1 MiB
+}
+
+// so viel wird angesehen, bevor eine Binärdatei übersprungen wird
+const sniffLen = 512
+
+type File struct {
+	Name string
+	Data string
+}
+
+// Result: die Textdateien in Archivreihenfolge und wie viele Binärdateien übersprungen wurden.
+type Result struct {
+	Files   []File
+	Skipped int
+}
+
+// IsArchive erkennt zip und gzip an den ersten Bytes.
+func IsArchive(b []byte) bool {
+	return bytes.HasPrefix(b, []byte("PK\x03\x04")) || bytes.HasPrefix(b, []byte{0x1f, 0x8b})
+}
+
+// Explode packt b aus. Pfade werden bereinigt, Verzeichnisse, Links und Binärdateien übergangen.
+func Explode(b []byte, lim Limits) (Result, error) {
+	if lim.MaxFiles <= 0 {
+		lim.MaxFiles = 100
+	}
+	if lim.MaxBytes <= 0 {
+		lim.MaxBytes = 1 << 20
+	}
+	u := &unpacker{lim: lim, left: lim.MaxBytes}
+	var err error
+	switch {
+	case bytes.HasPrefix(b, []byte("PK\x03\x04")):
+		err = u.zip(b)
+	case bytes.HasPrefix(b, []byte{0x1f, 0x8b}):
+		err = u.tarGz(b)
+	default:
+		err = ErrNotArchive
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	if len(u.res.Files) == 0 {
+		return Result{}, ErrEmpty
+	}
+	return u.res, nil
+}
+
+type unpacker struct {
+	lim     Limits
+	left    int64 // noch erlaubte Bytes
+	entries int
+	res     Result
+}
+
+func (u *unpacker) entry() error {
+	u.entries++
+	if u.entries > u.lim.MaxFiles {
+		return ErrTooMany
+	}
+	return nil
+}
+
+// read liest bis zu n Bytes aus r und rechnet sie gegen MaxBytes.
+func (u *unpacker) read(r io.Reader, n int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, min(n, u.left+1)))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > u.left {
+		return nil, ErrTooLarge
+	}
+	u.left -= int64(len(b))
+	return b, nil
+}
+
+func (u *unpacker) zip(b []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return fmt.Errorf("zip: %w", err)
+	}
+	if len(zr.File) > u.lim.MaxFiles {
+		return ErrTooMany
+	}
+	for _, f := range zr.File {
+		if err := u.entry(); err != nil {
+			return err
+		}
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("zip: %s: %w", f.Name, err)
+		}
+		err = u.add(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *unpacker) tarGz(b []byte) error {
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("gzip: %w", err)
+	}
+	defer gz.Close()
+	// Next entpackt übersprungene Reste mit; darum zählt der ganze Strom (plus Kopfblöcke)
+	tr := tar.NewReader(&capped{r: gz, n: u.lim.MaxBytes + int64(u.lim.MaxFiles+2)*2*512})
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if errors.Is(err, ErrTooLarge) {
+			return ErrTooLarge
+		}
+		if err != nil {
+			return fmt.Errorf("tar: %w", err)
+		}
+		if err := u.entry(); err != nil {
+			return err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := u.add(h.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (u *unpacker) add(name string, r io.Reader) error {
+	name = CleanName(name)
+	if name == "" || junk(name) {
+		return nil
+	}
+	// nie mehr lesen als noch erlaubt ist: die Größenangaben im Archiv können lügen
+	head, err := u.read(r, sniffLen)
+	if err != nil {
+		return wrap(name, err)
+	}
+	// Binärdateien am Anfang erkennen, ohne sie ganz zu entpacken
+	if !looksLikeText(head, len(head) < sniffLen) {
+		u.res.Skipped++
+		return nil
+	}
+	rest, err := u.read(r, math.MaxInt64)
+	if err != nil {
+		return wrap(name, err)
+	}
+	data := append(head, rest...)
+	if !looksLikeText(data, true) {
+		u.res.Skipped++
+		return nil
+	}
+	u.res.Files = append(u.res.Files, File{Name: name, Data: string(data)})
+	return nil
+}
+
+// capped liefert höchstens n Bytes, danach ErrTooLarge.
+type capped struct {
+	r io.Reader
+	n int64
+}
+
+func (c *capped) Read(p []byte) (int, error) {
+	if c.n <= 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > c.n {
+		p = p[:c.n]
+	}
+	n, err := c.r.Read(p)
+	c.n -= int64(n)
+	return n, err
+}
+
+func wrap(name string, err error) error {
+	if errors.Is(err, ErrTooLarge) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// looksLikeText: kein NUL und gültiges UTF-8; bei einem Anfang (whole = false) darf das letzte Zeichen abgeschnitten sein.
+func looksLikeText(b []byte, whole bool) bool {
+	if bytes.IndexByte(b, 0) >= 0 {
+		return false
+	}
+	if !whole {
+		for i := 0; i < utf8.UTFMax-1 && len(b) > 0 && !utf8.Valid(b); i++ {
+			b = b[:len(b)-1]
+		}
+	}
+	return utf8.Valid(b)
+}
+
+/*
+CleanName macht aus einem Archivpfad einen relativen Pfad ohne "..", führende
+Schrägstriche und Steuerzeichen; leer, wenn nichts Brauchbares übrig bleibt.
+*/
+func CleanName(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return ""
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+// junk: Beifang von macOS und Co., den niemand sehen will.
+func junk(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(name, "__MACOSX/") || base == ".DS_Store" || base == "Thumbs.db"
+}
+
+// Header: die Kopfzeile vor jeder Datei.
+func Header(name string) string { return headPrefix + name + headSuffix }
+
+// Join hängt die Dateien mit Kopfzeilen aneinander, durch eine Leerzeile getrennt.
+func Join(files []File) string {
+	var b strings.Builder
+	for i, f := range files {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(Header(f.Name))
+		b.WriteByte('\n')
+		b.WriteString(f.Data)
+		if !strings.HasSuffix(f.Data, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+type Entry struct {
+	Name string
+	Line int // Zeile der Kopfzeile, ab 1
+}
+
+// Index findet die Kopfzeilen; nil, wenn code nicht mit einer beginnt (dann ist es kein Bundle).
+func Index(code string) []Entry {
+	if !strings.HasPrefix(code, headPrefix) {
+		return nil
+	}
+	var out []Entry
+	for i, line := range strings.Split(code, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if name, ok := strings.CutPrefix(line, headPrefix); ok {
+			if name, ok := strings.CutSuffix(name, headSuffix); ok && name != "" {
+				out = append(out, Entry{Name: name, Line: i + 1})
+			}
+		}
+	}
+	if len(out) == 0 || out[0].Line != 1 {
+		return nil
+	}
+	return out
+}
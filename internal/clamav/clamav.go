@@ -0,0 +1,102 @@
+/*
+Package clamav spricht mit clamd über das INSTREAM-Protokoll: Inhalt in Chunks
+(4 Byte Länge, big endian) schicken, mit einem leeren Chunk abschließen, Antwort
+"stream: OK" bzw. "stream: <Signatur> FOUND" lesen.
+*/
+package clamav
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const chunkSize = 64 << 10
+
+type Client struct {
+	network, addr string
+	timeout       time.Duration
+}
+
+/*
+New: addr ist "tcp://host:3310", "unix:///run/clamav/clamd.ctl" oder schlicht
+"host:port". Ein nil-Client gilt als „kein Scanner konfiguriert“.
+*/
+func New(addr string, timeout time.Duration) (*Client, error) {
+	if addr == "" {
+		return nil, nil
+	}
+	c := &Client{network: "tcp", addr: addr, timeout: timeout}
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		c.network, c.addr = "unix", strings.TrimPrefix(addr, "unix://")
+	case strings.HasPrefix(addr, "tcp://"):
+		c.addr = strings.TrimPrefix(addr, "tcp://")
+	case strings.HasPrefix(addr, "/"):
+		c.network = "unix"
+	}
+	if c.addr == "" {
+		return nil, errors.New("clamav: empty address")
+	}
+	if c.timeout <= 0 {
+		c.timeout = 10 * time.Second
+	}
+	return c, nil
+}
+
+// Scan liefert die Signatur bei einem Fund, "" wenn sauber; err nur bei Scanner-Problemen.
+func (c *Client) Scan(ctx context.Context, data []byte) (signature string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.network, c.addr)
+	if err != nil {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+	var size [4]byte
+	for len(data) > 0 {
+		n := min(len(data), chunkSize)
+		binary.BigEndian.PutUint32(size[:], uint32(n))
+		if _, err := conn.Write(size[:]); err != nil {
+			return "", fmt.Errorf("clamav: %w", err)
+		}
+		if _, err := conn.Write(data[:n]); err != nil {
+			return "", fmt.Errorf("clamav: %w", err)
+		}
+		data = data[n:]
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := conn.Write(size[:]); err != nil {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+	return parseReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+func parseReply(reply string) (string, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return "", nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return strings.TrimSuffix(reply, " FOUND"), nil
+	}
+	return "", fmt.Errorf("clamav: %s", reply)
+}
@@ -0,0 +1,142 @@
+/*
+Package coldstore archiviert abgelaufene Pastes in einem Blob-Store (S3, GCS,
+Azure oder Verzeichnis) statt sie zu löschen: eine gzip-komprimierte JSON-Datei pro Paste (wie im Backup, mit allen
+Versionen und dem Edit-Key), mit Sealer zusätzlich verschlüsselt. Wie lange sie
+dort liegen, regeln etwa die Lifecycle-Regeln des Buckets.
+*/
+package coldstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"unglued/internal/blob"
+	"unglued/internal/model"
+	"unglued/internal/store"
+)
+
+const (
+	plainExt  = ".json.gz"
+	sealedExt = ".json.gz.sealed"
+)
+
+type Archive struct {
+	client blob.Store
+	prefix string
+	// optional: verschlüsselt die Objekte (derselbe Keyring wie im Store)
+	Sealer store.Sealer
+}
+
+// New liefert nil, wenn client nil ist.
+func New(client blob.Store, prefix string) *Archive {
+	if client == nil {
+		return nil
+	}
+	return &Archive{client: client, prefix: prefix}
+}
+
+// Entry: eine archivierte Paste in der Liste.
+type Entry struct {
+	ID         string    `json:"id"`
+	Size       int64     `json:"size"`
+	ArchivedAt time.Time `json:"archived_at"`
+	Sealed     bool      `json:"sealed"`
+}
+
+// Archive erfüllt store.Archiver.
+func (a *Archive) Archive(p model.Paste) error {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	body, key, ct := buf.Bytes(), a.prefix+p.ID+plainExt, "application/gzip"
+	if a.Sealer != nil {
+		body, key, ct = a.Sealer.Seal(body), a.prefix+p.ID+sealedExt, "application/octet-stream"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return a.client.Put(ctx, key, body, ct)
+}
+
+// Get lädt eine archivierte Paste; blob.ErrNotFound, wenn es sie nicht (mehr) gibt.
+func (a *Archive) Get(ctx context.Context, id string) (model.Paste, error) {
+	var p model.Paste
+	for _, ext := range []string{sealedExt, plainExt} {
+		body, err := a.client.Get(ctx, a.prefix+id+ext)
+		if errors.Is(err, blob.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return p, err
+		}
+		if ext == sealedExt {
+			if a.Sealer == nil {
+				return p, errors.New("archived paste is encrypted, but no encryption key is configured")
+			}
+			if body, _, err = a.Sealer.Open(body); err != nil {
+				return p, err
+			}
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return p, err
+		}
+		raw, err := io.ReadAll(zr)
+		if err != nil {
+			return p, err
+		}
+		return p, json.Unmarshal(raw, &p)
+	}
+	return p, blob.ErrNotFound
+}
+
+// Delete entfernt eine archivierte Paste (beide Varianten).
+func (a *Archive) Delete(ctx context.Context, id string) error {
+	for _, ext := range []string{sealedExt, plainExt} {
+		if err := a.client.Delete(ctx, a.prefix+id+ext); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List: alle archivierten Pastes, neueste zuerst.
+func (a *Archive) List(ctx context.Context) ([]Entry, error) {
+	objs, err := a.client.List(ctx, a.prefix)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Entry, 0, len(objs))
+	for _, o := range objs {
+		name := strings.TrimPrefix(o.Key, a.prefix)
+		e := Entry{Size: o.Size, ArchivedAt: o.LastModified}
+		switch {
+		case strings.HasSuffix(name, sealedExt):
+			e.ID, e.Sealed = strings.TrimSuffix(name, sealedExt), true
+		case strings.HasSuffix(name, plainExt):
+			e.ID = strings.TrimSuffix(name, plainExt)
+		default:
+			continue
+		}
+		if !strings.Contains(e.ID, "/") {
+			out = append(out, e)
+		}
+	}
+	slices.SortFunc(out, func(a, b Entry) int { return b.ArchivedAt.Compare(a.ArchivedAt) })
+	return out, nil
+}
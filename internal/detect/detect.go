@@ -0,0 +1,165 @@
+// Package detect rät die Sprache einer Paste, wenn keine angegeben wurde.
+package detect
+
+import (
+	"encoding/json"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"unglued/internal/lint"
+	"unglued/internal/util"
+)
+
+// Woran die Sprache erkannt wurde.
+const (
+	ByShebang   = "shebang"
+	BySyntax    = "syntax"    // parst fehlerfrei als JSON/YAML/TOML
+	ByHeuristic = "heuristic" // typische Schlüsselwörter und Konstrukte
+	ByNone      = "none"      // nichts passte: plaintext
+)
+
+// sample: nur so viel wird für die Heuristiken angesehen.
+const sample = 32 << 10
+
+// MinConfidence: schwächere Treffer zählen nicht, dann bleibt es plaintext.
+const MinConfidence = 0.5
+
+type Result struct {
+	Lang       string  `json:"lang"`
+	Confidence float64 `json:"confidence"` // 0..1
+	By         string  `json:"by"`
+}
+
+/*
+Guess liefert die wahrscheinlichste Sprache aus langs (siehe httpx.Langs) für code.
+Ein Shebang entscheidet sofort; sonst gewinnt die Regel mit der höchsten Zuversicht.
+*/
+func Guess(code string, langs []string) Result {
+	code = strings.TrimSpace(code)
+	if l := shebang(code); l != "" && slices.Contains(langs, l) {
+		return Result{Lang: l, Confidence: 1, By: ByShebang}
+	}
+	best := Result{Lang: "plaintext", Confidence: MinConfidence, By: ByNone}
+	for _, c := range candidates(code) {
+		if c.Confidence > best.Confidence && slices.Contains(langs, c.Lang) {
+			best = c
+		}
+	}
+	if best.By == ByNone {
+		best.Confidence = 0
+	}
+	best.Confidence = math.Round(best.Confidence*100) / 100
+	return best
+}
+
+// shebang: "#!/usr/bin/env python3" → python; leer ohne Shebang oder bei Unbekanntem.
+func shebang(code string) string {
+	line, _, _ := strings.Cut(code, "\n")
+	rest, ok := strings.CutPrefix(line, "#!")
+	if !ok {
+		return ""
+	}
+	f := strings.Fields(rest)
+	if len(f) == 0 {
+		return ""
+	}
+	prog := path.Base(f[0])
+	if prog == "env" {
+		prog = ""
+		for _, a := range f[1:] {
+			if !strings.HasPrefix(a, "-") && !strings.Contains(a, "=") {
+				prog = path.Base(a)
+				break
+			}
+		}
+	}
+	// python3.12 → python, node18 → node
+	prog = strings.TrimRight(prog, "0123456789.")
+	switch prog {
+	case "bash", "sh", "dash", "ksh", "zsh", "ash":
+		return "bash"
+	case "python", "pypy":
+		return "python"
+	case "node", "nodejs", "deno", "bun":
+		return "javascript"
+	case "ts-node", "tsx":
+		return "typescript"
+	}
+	return util.CanonicalLang(prog)
+}
+
+// signals: je Treffer steigt die Zuversicht um step, beginnend bei base, höchstens max.
+type signals struct {
+	lang            string
+	base, step, max float64
+	res             []*regexp.Regexp
+}
+
+var heuristics = []signals{
+	{"go", 0.5, 0.2, 0.9, res(`(?m)^package \w+\s*$`, `(?m)^func (\(\w+ \*?\w+\) )?\w+\(`, `(?m)^import (\(|")`, `:= `)},
+	{"python", 0.4, 0.15, 0.85, res(`(?m)^\s*def \w+\(.*\)( -> .+)?:\s*$`, `(?m)^(from [\w.]+ )?import \w+`, `(?m)^\s*class \w+(\(.*\))?:\s*$`, `if __name__ == .__main__.:`, `(?m)^\s*(elif|except|with) .*:\s*$`)},
+	{"javascript", 0.3, 0.15, 0.75, res(`(?m)^\s*(const|let|var) \w+ = `, `\bfunction\s*\w*\s*\(`, `\) => `, `console\.log\(`, `require\(['"]`, `(?m)^(import .* from ['"]|export (default |const |function ))`)},
+	// nur was es in JavaScript nicht gibt; die JS-Treffer kommen in candidates dazu
+	{"typescript", 0.4, 0.2, 0.8, res(`(?m)^\s*(export )?(interface|type) \w+ (\{|=)`, `\w\??: (string|number|boolean|any|void|unknown)\b`, `(?m)^\s*(public|private|protected|readonly) \w+`)},
+	{"bash", 0.3, 0.15, 0.75, res(`(?m)^\s*(fi|done|esac)\s*$`, `(?m)^\s*(if|while|for) .*; (then|do)\s*$`, `(?m)^\s*(echo|export|source|set -[euxo]+)\b`, `\$\{?\w+\}?`, `(?m)^\s*\w+\(\)\s*\{`)},
+	{"sql", 0.6, 0.1, 0.85, res(`(?is)^(select\s.+?\sfrom\s|insert\s+into\s|create\s+(table|index|view|unique index)\s|update\s+\S+\s+set\s|delete\s+from\s|alter\s+table\s|with\s+\w+\s+as\s*\()`, `(?i)\b(where|join|group by|order by|values)\b`)},
+	{"html", 0.5, 0.2, 0.9, res(`(?i)^(<!doctype html|<html)`, `(?i)</(div|p|span|body|head|a|table|ul)>`, `(?i)<(meta|link|script)\b`)},
+	{"css", 0.4, 0.2, 0.8, res(`(?m)^[\w.#:\-\[\]="*>+~, ]+\s*\{\s*$`, `(?m)^\s*[\w-]+\s*:\s*[^;{}]+;\s*$`, `(?m)^\s*@(media|import|font-face)\b`)},
+	{"markdown", 0.3, 0.15, 0.7, res("(?m)^#{1,6} \\S", "(?m)^```", `(?m)^\s*([-*]|\d+\.) \S`, `\[[^\]]+\]\([^)]+\)`, `\*\*\S.*\S\*\*`)},
+}
+
+func res(exprs ...string) []*regexp.Regexp {
+	out := make([]*regexp.Regexp, len(exprs))
+	for i, e := range exprs {
+		out[i] = regexp.MustCompile(e)
+	}
+	return out
+}
+
+var (
+	tomlTable = regexp.MustCompile(`(?m)^\[\[?[\w."-]+\]\]?\s*$`)
+	tomlKey   = regexp.MustCompile(`(?m)^[\w"-][\w."-]*\s*=\s*\S`)
+	yamlKey   = regexp.MustCompile(`(?m)^\s*(- )?[\w"'.-]+:(\s|$)`)
+)
+
+// candidates: alle Sprachen, für die etwas spricht, mit ihrer Zuversicht.
+func candidates(code string) []Result {
+	var out []Result
+	whole := len(code) <= sample
+	head := code[:min(len(code), sample)]
+
+	if (strings.HasPrefix(code, "{") || strings.HasPrefix(code, "[")) && json.Valid([]byte(code)) {
+		out = append(out, Result{Lang: "json", Confidence: 0.95, By: BySyntax})
+	}
+	// "a = 1" parst auch als TOML: erst Tabellen oder mehrere Schlüssel zählen
+	if n := len(tomlKey.FindAllStringIndex(head, 3)); tomlTable.MatchString(head) || n >= 3 {
+		if whole && lint.Check(code, "toml") == nil {
+			out = append(out, Result{Lang: "toml", Confidence: 0.85, By: BySyntax})
+		}
+	}
+	if n := len(yamlKey.FindAllStringIndex(head, 3)); n >= 2 || strings.HasPrefix(code, "---\n") {
+		if whole && lint.Check(code, "yaml") == nil && !strings.ContainsAny(head, ";{") {
+			out = append(out, Result{Lang: "yaml", Confidence: 0.6 + 0.1*float64(n), By: BySyntax})
+		}
+	}
+	for _, h := range heuristics {
+		hits := 0
+		for _, re := range h.res {
+			if re.MatchString(head) {
+				hits++
+			}
+		}
+		if hits == 0 {
+			continue
+		}
+		c := min(h.base+h.step*float64(hits-1), h.max)
+		if h.lang == "typescript" && len(out) > 0 && out[len(out)-1].Lang == "javascript" {
+			c = min(max(c, out[len(out)-1].Confidence)+0.1, h.max)
+		}
+		out = append(out, Result{Lang: h.lang, Confidence: c, By: ByHeuristic})
+	}
+	return out
+}
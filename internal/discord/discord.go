@@ -0,0 +1,167 @@
+/*
+Package discord prüft signierte Interactions (Slash- und Nachrichten-Befehle) von
+Discord und löscht auf Wunsch die ursprüngliche Nachricht über die Bot-API.
+
+Ohne Gateway-Verbindung sieht ein HTTP-Endpunkt keine normalen Nachrichten; das
+Umwandeln läuft deshalb über den Kontextmenü-Befehl an einer Nachricht.
+*/
+package discord
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Interaction-Typen und Antwort-Typen aus der Discord-API.
+const (
+	TypePing    = 1
+	TypeCommand = 2
+
+	CommandChatInput = 1
+	CommandMessage   = 3
+
+	ResponsePong    = 1
+	ResponseMessage = 4
+
+	FlagEphemeral = 1 << 6
+)
+
+type Config struct {
+	PublicKey string // hex, aus den App-Einstellungen
+	BotToken  string // optional: ersetzt umgewandelte Nachrichten (Recht "Manage Messages")
+}
+
+// Client ist nil, wenn kein Public Key gesetzt ist.
+type Client struct {
+	key    ed25519.PublicKey
+	token  string
+	client *http.Client
+	api    string
+}
+
+func New(cfg Config) (*Client, error) {
+	if cfg.PublicKey == "" {
+		return nil, nil
+	}
+	k, err := hex.DecodeString(cfg.PublicKey)
+	if err != nil || len(k) != ed25519.PublicKeySize {
+		return nil, errors.New("public key must be 64 hex characters")
+	}
+	return &Client{key: k, token: cfg.BotToken, client: &http.Client{Timeout: 5 * time.Second}, api: "https://discord.com/api/v10"}, nil
+}
+
+// Verify prüft X-Signature-Ed25519 über X-Signature-Timestamp und Body.
+func (c *Client) Verify(h http.Header, body []byte) bool {
+	sig, err := hex.DecodeString(h.Get("X-Signature-Ed25519"))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	msg := append([]byte(h.Get("X-Signature-Timestamp")), body...)
+	return ed25519.Verify(c.key, msg, sig)
+}
+
+type User struct {
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name,omitempty"`
+}
+
+func (u *User) Name() string {
+	if u == nil {
+		return ""
+	}
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+type Message struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	Author  *User  `json:"author"`
+}
+
+// Interaction: die Felder, die für /paste und "In Paste umwandeln" gebraucht werden.
+type Interaction struct {
+	Type      int    `json:"type"`
+	ChannelID string `json:"channel_id"`
+	Member    *struct {
+		User *User `json:"user"`
+	} `json:"member,omitempty"`
+	User *User `json:"user,omitempty"`
+	Data struct {
+		Name     string `json:"name"`
+		Type     int    `json:"type"`
+		TargetID string `json:"target_id"`
+		Options  []struct {
+			Name  string `json:"name"`
+			Value any    `json:"value"`
+		} `json:"options"`
+		Resolved struct {
+			Messages map[string]Message `json:"messages"`
+		} `json:"resolved"`
+	} `json:"data"`
+}
+
+// Caller: wer den Befehl ausgelöst hat (in Servern über member, in DMs über user).
+func (in Interaction) Caller() string {
+	if in.Member != nil {
+		return in.Member.User.Name()
+	}
+	return in.User.Name()
+}
+
+// Option liefert den Wert einer String-Option des Slash-Befehls.
+func (in Interaction) Option(name string) string {
+	for _, o := range in.Data.Options {
+		if s, ok := o.Value.(string); o.Name == name && ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Response: Antwort auf eine Interaction; Mentions sind immer aus.
+type Response struct {
+	Type int           `json:"type"`
+	Data *ResponseData `json:"data,omitempty"`
+}
+
+type ResponseData struct {
+	Content         string         `json:"content"`
+	Flags           int            `json:"flags,omitempty"`
+	AllowedMentions map[string]any `json:"allowed_mentions"`
+}
+
+func Reply(content string, ephemeral bool) Response {
+	d := &ResponseData{Content: content, AllowedMentions: map[string]any{"parse": []string{}}}
+	if ephemeral {
+		d.Flags = FlagEphemeral
+	}
+	return Response{Type: ResponseMessage, Data: d}
+}
+
+func (c *Client) CanDelete() bool { return c != nil && c.token != "" }
+
+// DeleteMessage entfernt eine Nachricht (der Bot braucht "Manage Messages" im Kanal).
+func (c *Client) DeleteMessage(ctx context.Context, channel, id string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.api+"/channels/"+channel+"/messages/"+id, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+c.token)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("delete message: status %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+/*
+Package errreport meldet Panics und 5xx-Antworten an Sentry (per DSN) oder als
+JSON an einen beliebigen Webhook. Gemeldet werden nur Metadaten des Requests
+(Request-ID, Methode, Route, Paste-ID, Status) und die geloggte Ursache, nie
+der Inhalt einer Paste. Verschickt wird im Hintergrund; ist die Warteschlange
+voll, fallen Meldungen weg, statt Requests aufzuhalten.
+*/
+package errreport
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const queueSize = 100
+
+type Config struct {
+	DSN         string // Sentry-DSN, z.B. https://<key>@o1.ingest.sentry.io/<project>
+	WebhookURL  string // bekommt jedes Event als JSON per POST
+	Environment string
+	Release     string
+}
+
+// Event: eine Meldung. Stack nur bei Panics.
+type Event struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"` // "fatal" bei Panics, sonst "error"
+	Message   string    `json:"message"`
+	Causes    []string  `json:"causes,omitempty"` // was der Request vorher geloggt hat
+	Stack     string    `json:"stack,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	Method    string    `json:"method"`
+	Route     string    `json:"route"`
+	PasteID   string    `json:"paste_id,omitempty"`
+	Status    int       `json:"status"`
+	Release   string    `json:"release,omitempty"`
+	Env       string    `json:"environment,omitempty"`
+}
+
+// Reporter ist nil, wenn weder DSN noch Webhook gesetzt ist; Capture ist nil-sicher.
+type Reporter struct {
+	cfg    Config
+	sentry *sentryDSN
+	client *http.Client
+	queue  chan Event
+}
+
+type sentryDSN struct {
+	key      string
+	storeURL string
+}
+
+func New(cfg Config) (*Reporter, error) {
+	if cfg.DSN == "" && cfg.WebhookURL == "" {
+		return nil, nil
+	}
+	r := &Reporter{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}, queue: make(chan Event, queueSize)}
+	if cfg.DSN != "" {
+		d, err := parseDSN(cfg.DSN)
+		if err != nil {
+			return nil, err
+		}
+		r.sentry = d
+	}
+	if cfg.WebhookURL != "" && !strings.HasPrefix(cfg.WebhookURL, "https://") && !strings.HasPrefix(cfg.WebhookURL, "http://") {
+		return nil, errors.New("errreport: webhook url must be http(s)")
+	}
+	go r.run()
+	return r, nil
+}
+
+// parseDSN: https://<public key>@<host>[/<path>]/<project id>
+func parseDSN(dsn string) (*sentryDSN, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, fmt.Errorf("errreport: invalid sentry dsn")
+	}
+	// Sentry hinter einem Pfad: die Projekt-ID ist immer das letzte Segment
+	i := strings.LastIndex(u.Path, "/")
+	if i < 0 {
+		return nil, fmt.Errorf("errreport: sentry dsn without project id")
+	}
+	path, project := strings.Trim(u.Path[:i], "/"), u.Path[i+1:]
+	if project == "" {
+		return nil, fmt.Errorf("errreport: sentry dsn without project id")
+	}
+	base := u.Scheme + "://" + u.Host + "/"
+	if path != "" {
+		base += path + "/"
+	}
+	return &sentryDSN{key: u.User.Username(), storeURL: base + "api/" + project + "/store/"}, nil
+}
+
+// Capture reiht ev ein; ID, Zeit, Release und Environment setzt es selbst.
+func (r *Reporter) Capture(ev Event) {
+	if r == nil {
+		return
+	}
+	ev.ID = newEventID()
+	ev.Time = time.Now().UTC()
+	ev.Release, ev.Env = r.cfg.Release, r.cfg.Environment
+	if ev.Level == "" {
+		ev.Level = "error"
+	}
+	select {
+	case r.queue <- ev:
+	default:
+		log.Printf("errreport: queue full, dropping event for %s %s", ev.Method, ev.Route)
+	}
+}
+
+func (r *Reporter) run() {
+	for ev := range r.queue {
+		if r.sentry != nil {
+			if err := r.post(r.sentry.storeURL, sentryEvent(ev), r.sentryAuth()); err != nil {
+				log.Printf("errreport: sentry: %v", err)
+			}
+		}
+		if r.cfg.WebhookURL != "" {
+			if err := r.post(r.cfg.WebhookURL, ev, nil); err != nil {
+				log.Printf("errreport: webhook: %v", err)
+			}
+		}
+	}
+}
+
+func (r *Reporter) sentryAuth() http.Header {
+	h := http.Header{}
+	h.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_client=unglued/1.0, sentry_key="+r.sentry.key)
+	return h
+}
+
+func (r *Reporter) post(target string, v any, h http.Header) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, vs := range h {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("%s", res.Status)
+	}
+	return nil
+}
+
+// sentryEvent: das Event im Format der Store-API von Sentry.
+func sentryEvent(ev Event) map[string]any {
+	tags := map[string]string{"method": ev.Method, "route": ev.Route, "status": fmt.Sprint(ev.Status)}
+	if ev.RequestID != "" {
+		tags["request_id"] = ev.RequestID
+	}
+	if ev.PasteID != "" {
+		tags["paste_id"] = ev.PasteID
+	}
+	out := map[string]any{
+		"event_id":    ev.ID,
+		"timestamp":   ev.Time.Format(time.RFC3339),
+		"level":       ev.Level,
+		"platform":    "go",
+		"logger":      "unglued",
+		"message":     map[string]string{"formatted": ev.Message},
+		"tags":        tags,
+		"fingerprint": []string{ev.Method, ev.Route, ev.Level},
+		"transaction": ev.Method + " " + ev.Route,
+	}
+	if ev.Release != "" {
+		out["release"] = ev.Release
+	}
+	if ev.Env != "" {
+		out["environment"] = ev.Env
+	}
+	extra := map[string]any{}
+	if len(ev.Causes) > 0 {
+		extra["causes"] = ev.Causes
+	}
+	if ev.Stack != "" {
+		extra["stack"] = ev.Stack
+	}
+	if len(extra) > 0 {
+		out["extra"] = extra
+	}
+	return out
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
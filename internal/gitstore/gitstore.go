@@ -0,0 +1,555 @@
+/*
+Package gitstore hält den Store in einem Bare-Git-Repository: pro Paste liegen
+pastes/<id>/content (aktuelle Version) und pastes/<id>/meta.json unter main, jede
+Änderung ist ein Commit. Die Versionen einer Paste ergeben sich beim Laden aus der
+Historie (Trailer "Paste:" und "Version:" in den Commit-Nachrichten).
+
+Es wird das git-Binary benutzt (Plumbing auf einem eigenen Index), kein Worktree.
+*/
+package gitstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"unglued/internal/model"
+	"unglued/internal/util"
+)
+
+const branch = "refs/heads/main"
+
+// versionMeta: eine Version ohne Inhalt; der steht im Commit mit passendem Trailer.
+type versionMeta struct {
+	Lang     string    `json:"lang"`
+	Author   string    `json:"author,omitempty"`
+	AuthorID string    `json:"author_id,omitempty"`
+	At       time.Time `json:"at"`
+
+	AuthorAvatar string `json:"author_avatar,omitempty"`
+	AuthorURL    string `json:"author_url,omitempty"`
+
+	Lint      *model.Problem `json:"lint,omitempty"`
+	Formatted bool           `json:"formatted,omitempty"`
+	KeyName   string         `json:"key_name,omitempty"`
+	Message   string         `json:"message,omitempty"`
+	Files     []model.File   `json:"files,omitempty"`
+}
+
+// meta: alles außer dem Inhalt; Versions überdeckt das Feld der Paste.
+type meta struct {
+	model.Paste
+	Versions []versionMeta `json:"Versions"`
+}
+
+type op struct {
+	id   string
+	save *model.Paste // nil = löschen
+}
+
+// so viele Änderungen warten höchstens einzeln; darüber wird je Paste zusammengefasst
+const maxPending = 4096
+
+// keysFile liegt im Git-Verzeichnis, aber in keinem Commit: Edit-Keys gehören nicht in die Historie.
+const keysFile = "unglued-keys.json"
+
+type secrets struct {
+	EditKey  string          `json:"edit_key,omitempty"`
+	EditKeys []model.EditKey `json:"edit_keys,omitempty"`
+}
+
+type Repo struct {
+	dir   string
+	push  string
+	wake  chan struct{}
+	flush chan chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+
+	mu       sync.Mutex
+	pending  []op
+	closed   bool
+	versions map[string]int // zuletzt committete Versionsanzahl je Paste
+	dirty    bool           // Commits seit dem letzten Push
+	keys     map[string]secrets
+}
+
+/*
+Open legt das Repository bei Bedarf an (git init --bare). push ist ein optionales
+Remote, auf das einmal pro Minute gespiegelt wird.
+*/
+func Open(dir, push string) (*Repo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("git binary not found")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); errors.Is(err, os.ErrNotExist) {
+		if out, err := exec.Command("git", "init", "--bare", "-q", "--initial-branch=main", dir).CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("git init: %v: %s", err, out)
+		}
+	}
+	g := &Repo{dir: dir, push: push, wake: make(chan struct{}, 1), flush: make(chan chan struct{}), stop: make(chan struct{}), done: make(chan struct{}), versions: map[string]int{}, keys: map[string]secrets{}}
+	if b, err := os.ReadFile(filepath.Join(dir, keysFile)); err == nil {
+		if err := json.Unmarshal(b, &g.keys); err != nil {
+			return nil, fmt.Errorf("%s: %w", keysFile, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	// der Index spiegelt immer main; nach einem Absturz lieber neu aufbauen
+	if g.head() != "" {
+		if _, err := g.git(nil, nil, "read-tree", branch); err != nil {
+			return nil, err
+		}
+	}
+	go g.run()
+	return g, nil
+}
+
+// git führt einen Befehl im Repository mit eigenem Index aus und liefert stdout.
+func (g *Repo) git(stdin []byte, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"--git-dir", g.dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_INDEX_FILE="+filepath.Join(g.dir, "unglued.index"))
+	cmd.Env = append(cmd.Env, env...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (g *Repo) head() string {
+	h, err := g.git(nil, nil, "rev-parse", "--verify", "-q", branch)
+	if err != nil {
+		return ""
+	}
+	return h
+}
+
+// Saved und Removed erfüllen store.Backend; geschrieben wird im Hintergrund, in Reihenfolge.
+func (g *Repo) Saved(p model.Paste) { g.enqueue(op{id: p.ID, save: &p}) }
+func (g *Repo) Removed(id string)   { g.enqueue(op{id: id}) }
+
+/*
+enqueue blockiert nie (der Store hält dabei seine Sperre). Ein neuer Stand ersetzt
+den noch wartenden derselben Paste, wenn dabei keine Version verloren geht; ist die
+Schlange voll, auch dann – im Baum landet trotzdem der letzte Stand, nur der
+Zwischenstand fehlt in der Historie. Nach Close wird nichts mehr angenommen.
+*/
+func (g *Repo) enqueue(o op) {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		log.Printf("gitstore: closed, dropping change to %s", o.id)
+		return
+	}
+	merged := false
+	for i := len(g.pending) - 1; i >= 0; i-- {
+		prev := g.pending[i]
+		if prev.id != o.id {
+			continue
+		}
+		if o.save == nil || len(g.pending) >= maxPending || (prev.save != nil && len(prev.save.Versions) == len(o.save.Versions)) {
+			g.pending[i] = o
+			merged = true
+		}
+		break
+	}
+	if !merged {
+		g.pending = append(g.pending, o)
+	}
+	g.mu.Unlock()
+	select {
+	case g.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Flush wartet, bis alle bisher gemeldeten Änderungen committet sind; nach Close sofort zurück.
+func (g *Repo) Flush() {
+	done := make(chan struct{})
+	select {
+	case g.flush <- done:
+		<-done
+	case <-g.done:
+	}
+}
+
+// Close schreibt ausstehende Änderungen und spiegelt ein letztes Mal.
+func (g *Repo) Close() {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return
+	}
+	g.closed = true
+	g.mu.Unlock()
+	close(g.stop)
+	<-g.done
+}
+
+func (g *Repo) run() {
+	defer close(g.done)
+	tick := time.NewTicker(time.Minute)
+	defer tick.Stop()
+	for {
+		select {
+		case <-g.wake:
+			g.drain()
+		case done := <-g.flush:
+			g.drain()
+			close(done)
+		case <-tick.C:
+			g.mirror()
+		case <-g.stop:
+			g.drain()
+			g.mirror()
+			return
+		}
+	}
+}
+
+// drain committet alles, was wartet, und schreibt danach die Edit-Keys.
+func (g *Repo) drain() {
+	for {
+		g.mu.Lock()
+		batch := g.pending
+		g.pending = nil
+		g.mu.Unlock()
+		if len(batch) == 0 {
+			return
+		}
+		keysChanged := false
+		for _, o := range batch {
+			var err error
+			if o.save != nil {
+				err = g.commitSave(*o.save)
+			} else {
+				err = g.commitRemove(o.id)
+			}
+			if err != nil {
+				log.Printf("gitstore: %v", err)
+			}
+			keysChanged = g.rememberKeys(o) || keysChanged
+		}
+		if keysChanged {
+			g.mu.Lock()
+			keys := maps.Clone(g.keys)
+			g.mu.Unlock()
+			if err := util.WriteJSONFile(filepath.Join(g.dir, keysFile), keys, 0o600); err != nil {
+				log.Printf("gitstore: %v", err)
+			}
+		}
+	}
+}
+
+// rememberKeys hält die Edit-Keys von o in g.keys nach; true, wenn sich etwas geändert hat.
+func (g *Repo) rememberKeys(o op) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	old, had := g.keys[o.id]
+	if o.save == nil {
+		delete(g.keys, o.id)
+		return had
+	}
+	k := secrets{EditKey: o.save.EditKey, EditKeys: o.save.EditKeys}
+	if had && old.EditKey == k.EditKey && editKeysEqual(old.EditKeys, k.EditKeys) {
+		return false
+	}
+	if k.EditKey == "" && len(k.EditKeys) == 0 {
+		delete(g.keys, o.id)
+		return had
+	}
+	g.keys[o.id] = k
+	return true
+}
+
+func editKeysEqual(a, b []model.EditKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Key != b[i].Key || !a[i].CreatedAt.Equal(b[i].CreatedAt) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Repo) mirror() {
+	g.mu.Lock()
+	dirty := g.dirty
+	g.dirty = false
+	g.mu.Unlock()
+	if g.push == "" || !dirty {
+		return
+	}
+	if _, err := g.git(nil, nil, "push", "-q", "--force", g.push, branch+":"+branch); err != nil {
+		log.Printf("gitstore: %v", err)
+		g.mu.Lock()
+		g.dirty = true
+		g.mu.Unlock()
+	}
+}
+
+func (g *Repo) blob(b []byte) (string, error) {
+	return g.git(b, nil, "hash-object", "-w", "--stdin")
+}
+
+func (g *Repo) commitSave(p model.Paste) error {
+	m := meta{Paste: p}
+	m.Code = ""
+	// Edit-Keys stehen nur in keysFile, nie im Commit (und damit nie auf dem Mirror)
+	m.EditKey, m.EditKeys = "", nil
+	m.Versions = make([]versionMeta, len(p.Versions))
+	for i, v := range p.Versions {
+		m.Versions[i] = versionMeta{Lang: v.Lang, Author: v.Author, AuthorID: v.AuthorID, At: v.At, AuthorAvatar: v.AuthorAvatar, AuthorURL: v.AuthorURL, Lint: v.Lint, Formatted: v.Formatted, KeyName: v.KeyName, Message: v.Message, Files: v.Files}
+	}
+	mj, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	content, err := g.blob([]byte(p.Code))
+	if err != nil {
+		return err
+	}
+	mb, err := g.blob(mj)
+	if err != nil {
+		return err
+	}
+	if _, err := g.git(nil, nil, "update-index", "--add",
+		"--cacheinfo", "100644,"+content+",pastes/"+p.ID+"/content",
+		"--cacheinfo", "100644,"+mb+",pastes/"+p.ID+"/meta.json"); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	prev, known := g.versions[p.ID]
+	g.versions[p.ID] = len(p.Versions)
+	g.mu.Unlock()
+	n := len(p.Versions)
+	subject := "update " + p.ID
+	switch {
+	case !known:
+		subject = "create " + p.ID
+	case n != prev:
+		subject = "edit " + p.ID + " (version " + strconv.Itoa(n) + ")"
+		if m := p.Versions[n-1].Message; m != "" {
+			subject += ": " + m
+		}
+	}
+	author, at := "unglued", p.UpdatedAt
+	if n > 0 {
+		if v := p.Versions[n-1]; v.Author != "" {
+			author = v.Author
+		}
+	}
+	return g.commit(subject+"\n\nPaste: "+p.ID+"\nVersion: "+strconv.Itoa(n)+"\n", author, at)
+}
+
+func (g *Repo) commitRemove(id string) error {
+	// --force-remove braucht einen Arbeitsbaum; Modus 0 in --index-info entfernt ohne
+	zero := "0 0000000000000000000000000000000000000000\t"
+	info := zero + "pastes/" + id + "/content\n" + zero + "pastes/" + id + "/meta.json\n"
+	if _, err := g.git([]byte(info), nil, "update-index", "--index-info"); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	delete(g.versions, id)
+	g.mu.Unlock()
+	return g.commit("delete "+id+"\n\nPaste: "+id+"\n", "unglued", time.Now())
+}
+
+// commit schreibt den Index als Commit auf main; ohne Änderung am Baum passiert nichts.
+func (g *Repo) commit(msg, author string, at time.Time) error {
+	tree, err := g.git(nil, nil, "write-tree")
+	if err != nil {
+		return err
+	}
+	head := g.head()
+	args := []string{"commit-tree", tree}
+	if head != "" {
+		if cur, _ := g.git(nil, nil, "rev-parse", head+"^{tree}"); cur == tree {
+			return nil
+		}
+		args = append(args, "-p", head)
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	date := at.UTC().Format(time.RFC3339)
+	env := []string{
+		"GIT_AUTHOR_NAME=" + author, "GIT_AUTHOR_EMAIL=unglued@localhost", "GIT_AUTHOR_DATE=" + date,
+		"GIT_COMMITTER_NAME=unglued", "GIT_COMMITTER_EMAIL=unglued@localhost",
+	}
+	c, err := g.git([]byte(msg), env, args...)
+	if err != nil {
+		return err
+	}
+	if _, err := g.git(nil, nil, "update-ref", branch, c); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	g.dirty = true
+	g.mu.Unlock()
+	return nil
+}
+
+/*
+Load liest alle Pastes unter main und baut ihre Versionen aus der Historie wieder
+auf: für Version N zählt der letzte Commit mit "Paste: <id>" und "Version: N".
+*/
+func (g *Repo) Load(fn func(model.Paste) error) error {
+	if g.head() == "" {
+		return nil
+	}
+	ls, err := g.git(nil, nil, "ls-tree", "-d", "--name-only", branch, "pastes/")
+	if err != nil {
+		return err
+	}
+	// Commit je (Paste, Version); --reverse, damit spätere gleiche Versionen gewinnen
+	logOut, err := g.git(nil, nil, "log", "--reverse", "--format=%H%x00%B%x1e", branch)
+	if err != nil {
+		return err
+	}
+	commits := map[string]string{}
+	for _, entry := range strings.Split(logOut, "\x1e") {
+		hash, body, ok := strings.Cut(strings.TrimSpace(entry), "\x00")
+		if !ok {
+			continue
+		}
+		var id, ver string
+		for _, line := range strings.Split(body, "\n") {
+			if v, ok := strings.CutPrefix(line, "Paste: "); ok {
+				id = v
+			} else if v, ok := strings.CutPrefix(line, "Version: "); ok {
+				ver = v
+			}
+		}
+		if id != "" && ver != "" {
+			commits[id+"/"+ver] = hash
+		}
+	}
+	cat, err := newCatFile(g)
+	if err != nil {
+		return err
+	}
+	defer cat.close()
+	for _, dir := range strings.Split(ls, "\n") {
+		id, ok := strings.CutPrefix(dir, "pastes/")
+		if !ok {
+			continue
+		}
+		p, err := g.loadPaste(cat, id, commits)
+		if err != nil {
+			log.Printf("gitstore: %s: %v", id, err)
+			continue
+		}
+		g.versions[id] = len(p.Versions)
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Repo) loadPaste(cat *catFile, id string, commits map[string]string) (model.Paste, error) {
+	raw, err := cat.get(branch + ":pastes/" + id + "/meta.json")
+	if err != nil {
+		return model.Paste{}, err
+	}
+	var m meta
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return model.Paste{}, err
+	}
+	code, err := cat.get(branch + ":pastes/" + id + "/content")
+	if err != nil {
+		return model.Paste{}, err
+	}
+	p := m.Paste
+	p.Code = string(code)
+	// ältere Commits enthalten die Keys noch selbst; keysFile hat Vorrang
+	g.mu.Lock()
+	k, ok := g.keys[id]
+	g.mu.Unlock()
+	if ok {
+		p.EditKey, p.EditKeys = k.EditKey, k.EditKeys
+	}
+	p.Versions = make([]model.Version, len(m.Versions))
+	for i, v := range m.Versions {
+		body := code
+		if c, ok := commits[id+"/"+strconv.Itoa(i+1)]; ok && i < len(m.Versions)-1 {
+			if body, err = cat.get(c + ":pastes/" + id + "/content"); err != nil {
+				return model.Paste{}, err
+			}
+		}
+		p.Versions[i] = model.Version{ZCode: util.GzipEncode(string(body)), Lang: v.Lang, Author: v.Author, AuthorID: v.AuthorID, At: v.At, AuthorAvatar: v.AuthorAvatar, AuthorURL: v.AuthorURL, Lint: v.Lint, Formatted: v.Formatted, KeyName: v.KeyName, Message: v.Message, Files: v.Files}
+	}
+	return p, nil
+}
+
+// catFile liest viele Objekte über einen einzigen "git cat-file --batch".
+type catFile struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+func newCatFile(g *Repo) (*catFile, error) {
+	cmd := exec.Command("git", "--git-dir", g.dir, "cat-file", "--batch")
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &catFile{cmd: cmd, in: in, out: bufio.NewReader(out)}, nil
+}
+
+func (c *catFile) get(rev string) ([]byte, error) {
+	if _, err := io.WriteString(c.in, rev+"\n"); err != nil {
+		return nil, err
+	}
+	header, err := c.out.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	f := strings.Fields(header)
+	if len(f) != 3 {
+		return nil, fmt.Errorf("%s: %s", rev, strings.TrimSpace(header))
+	}
+	n, err := strconv.Atoi(f[2])
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n+1) // plus abschließendes \n
+	if _, err := io.ReadFull(c.out, b); err != nil {
+		return nil, err
+	}
+	return b[:n], nil
+}
+
+func (c *catFile) close() {
+	c.in.Close()
+	_ = c.cmd.Wait()
+}
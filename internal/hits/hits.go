@@ -0,0 +1,84 @@
+/*
+Package hits zählt Aufrufe je Paste und schätzt, wie viele verschiedene
+Besucher es waren (HyperLogLog mit 256 Registern, etwa ±7 %). Besucher werden
+nur als Hash mit einem Seed pro Prozess gespeichert, nicht wiedererkennbar
+und nach einem Neustart nicht mehr vergleichbar. Alles liegt im Speicher.
+*/
+package hits
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"sync"
+)
+
+const registers = 1 << 8
+
+type counter struct {
+	views int64
+	reg   [registers]uint8
+}
+
+type Counter struct {
+	seed maphash.Seed
+
+	mu   sync.Mutex
+	byID map[string]*counter
+}
+
+func New() *Counter {
+	return &Counter{seed: maphash.MakeSeed(), byID: map[string]*counter{}}
+}
+
+// Add zählt einen Aufruf von id; visitor ist irgendetwas Besucher-Typisches, z.B. IP und User-Agent.
+func (c *Counter) Add(id, visitor string) {
+	h := maphash.String(c.seed, visitor)
+	i, rank := h>>56, uint8(bits.LeadingZeros64(h<<8|1<<7)+1)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.byID[id]
+	if e == nil {
+		e = &counter{}
+		c.byID[id] = e
+	}
+	e.views++
+	if rank > e.reg[i] {
+		e.reg[i] = rank
+	}
+}
+
+// Get: Aufrufe und geschätzte verschiedene Besucher von id.
+func (c *Counter) Get(id string) (views int64, unique int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.byID[id]
+	if e == nil {
+		return 0, 0
+	}
+	return e.views, min(estimate(&e.reg), e.views)
+}
+
+// Forget wirft die Zähler von id weg (Paste endgültig entfernt).
+func (c *Counter) Forget(id string) {
+	c.mu.Lock()
+	delete(c.byID, id)
+	c.mu.Unlock()
+}
+
+func estimate(reg *[registers]uint8) int64 {
+	const m = float64(registers)
+	sum, zeros := 0.0, 0
+	for _, r := range reg {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// wenige Besucher: lineares Zählen ist hier genauer
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(e))
+}
@@ -0,0 +1,45 @@
+package httpx
+
+import (
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+// guardCreate hängt Sperrliste und Rate-Limit vor einen Anlage-Handler.
+func (s *Server) guardCreate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.checkQuota(w, r) {
+			return
+		}
+		if s.Abuse == nil {
+			next(w, r)
+			return
+		}
+		v := s.Abuse.Check(s.clientIP(r))
+		switch {
+		case v.Banned:
+			if isAPIPath(r.URL.Path) {
+				writeProblem(w, r, http.StatusForbidden, codeBanned, "your address is banned from creating pastes")
+				return
+			}
+			httpError(w, r, "Deine Adresse ist für neue Pastes gesperrt.", http.StatusForbidden)
+		case v.Limited:
+			secs := int((v.RetryAfter + time.Second - 1) / time.Second)
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+			if isAPIPath(r.URL.Path) {
+				writeProblem(w, r, http.StatusTooManyRequests, codeRateLimited, "too many pastes, retry in "+strconv.Itoa(secs)+"s")
+				return
+			}
+			http.Error(w, tr(r, "Zu viele Pastes – bitte in %ds erneut versuchen.", secs), http.StatusTooManyRequests)
+		default:
+			next(w, r)
+		}
+	}
+}
+
+// clientIP berücksichtigt X-Forwarded-For nur von konfigurierten Proxies.
+func (s *Server) clientIP(r *http.Request) netip.Addr {
+	return s.Config.TrustedProxies.ClientIP(r)
+}
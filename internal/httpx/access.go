@@ -0,0 +1,123 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/model"
+	"unglued/internal/viewlog"
+)
+
+// noteView hält einen Aufruf im Zugriffsprotokoll fest; die eigenen Aufrufe des Besitzers zählen nicht.
+func (s *Server) noteView(r *http.Request, p model.Paste) {
+	if s.Views == nil || r.Method == http.MethodHead || s.ownsPaste(r, p) {
+		return
+	}
+	country := ""
+	if h := s.Config.CountryHeader; h != "" {
+		country = r.Header.Get(h)
+	}
+	s.Views.Add(p.ID, time.Now(), r.UserAgent(), country)
+}
+
+// forgetViews: Paste ist endgültig weg, ihr Protokoll auch.
+func (s *Server) forgetViews(id string) {
+	if s.Views != nil {
+		s.Views.Forget(id)
+	}
+}
+
+// accessURL: Link aufs Zugriffsprotokoll für den Besitzer; leer, wenn es keins gibt.
+func (s *Server) accessURL(r *http.Request, p model.Paste) string {
+	if s.Views == nil || !s.ownsPaste(r, p) {
+		return ""
+	}
+	if k := r.URL.Query().Get("key"); k != "" {
+		return "/p/" + p.ID + "/access?key=" + k
+	}
+	return "/p/" + p.ID + "/access"
+}
+
+type accessResp struct {
+	ID     string          `json:"id"`
+	Level  string          `json:"level"`
+	Total  int             `json:"total"` // seit dem Start, auch was nicht mehr in events steht
+	Events []viewlog.Event `json:"events"`
+}
+
+func (s *Server) accessLog(p model.Paste) accessResp {
+	evs, total := s.Views.Events(p.ID)
+	return accessResp{ID: p.ID, Level: s.Views.Level().String(), Total: total, Events: evs}
+}
+
+/*
+GET /p/{id}/access: wer die Paste wann geöffnet hat, für den Besitzer (Edit-Key
+oder Login). Text, mit Accept: application/json oder ?format=json als JSON.
+*/
+func (s *Server) handleAccess(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.GetMeta(id)
+	if !ok || s.Views == nil {
+		s.notFound(w, r, id, false)
+		return
+	}
+	if !s.ownsPaste(r, p) {
+		httpError(w, r, "Forbidden (nur für den Besitzer)", http.StatusForbidden)
+		return
+	}
+	log := s.accessLog(p)
+	w.Header().Set("Cache-Control", "private, no-store")
+	if strings.Contains(r.Header.Get("Accept"), "application/json") || r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(log)
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", tr(r, "Aufrufe von %s: %d (Protokoll: %s)", p.ID, log.Total, log.Level))
+	if len(log.Events) < log.Total {
+		fmt.Fprintf(&b, "# %s\n", tr(r, "die letzten %d:", len(log.Events)))
+	}
+	for _, e := range log.Events {
+		b.WriteString(e.At.Format("2006-01-02 15:04 UTC"))
+		if e.Agent != "" {
+			b.WriteString("  " + e.Agent)
+		}
+		if e.Country != "" {
+			b.WriteString("  " + e.Country)
+		}
+		b.WriteByte('\n')
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Add("Vary", "Accept, Accept-Language, Cookie")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+// GET /api/paste/{id}/access?key=…: dasselbe als JSON mit Problem-Antworten.
+func (s *Server) handleAPIAccess(w http.ResponseWriter, r *http.Request) {
+	if s.Views == nil {
+		writeProblem(w, r, http.StatusNotFound, codeFeatureDisabled, "the view log is disabled on this instance")
+		return
+	}
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.GetMeta(id)
+	if !ok {
+		s.apiNotFound(w, r, id)
+		return
+	}
+	if !s.ownsPaste(r, p) {
+		if r.URL.Query().Get("key") == "" {
+			writeProblem(w, r, http.StatusUnauthorized, codeMissingKey, "missing ?key")
+		} else {
+			writeProblem(w, r, http.StatusForbidden, codeInvalidKey, "invalid or expired edit token")
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-store")
+	_ = json.NewEncoder(w).Encode(s.accessLog(p))
+}
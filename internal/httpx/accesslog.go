@@ -0,0 +1,144 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"unglued/internal/abuse"
+)
+
+// Formate für AccessLogConfig.Format
+const (
+	AccessLogCommon   = "common"
+	AccessLogCombined = "combined"
+	AccessLogJSON     = "json"
+)
+
+type AccessLogConfig struct {
+	Out     io.Writer
+	Format  string        // common, combined (Default) oder json
+	Skip    []string      // Pfad-Präfixe ohne Log, z.B. Health-Checks
+	Proxies abuse.Proxies // Client-IP wie beim Rate-Limit
+}
+
+// Query-Parameter mit Geheimnissen (Edit-Keys, Freigaben, OAuth) nie ins Log
+var secretParams = []string{"key", "grant", "confirm", "code", "state", "token"}
+
+/*
+AccessLog schreibt pro Request eine Zeile: Methode, Pfad, Status, Bytes, Dauer und
+Client-IP. common/combined entsprechen dem Apache-Format plus Dauer in Sekunden
+und Request-ID als letzte Felder.
+*/
+func AccessLog(cfg AccessLogConfig) func(http.Handler) http.Handler {
+	var mu sync.Mutex
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, p := range cfg.Skip {
+				if strings.HasPrefix(r.URL.Path, p) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			line := formatAccess(cfg, r, sw, start, time.Since(start))
+			mu.Lock()
+			_, _ = io.WriteString(cfg.Out, line)
+			mu.Unlock()
+		})
+	}
+}
+
+func formatAccess(cfg AccessLogConfig, r *http.Request, sw *statusWriter, start time.Time, d time.Duration) string {
+	ip := "-"
+	if a := cfg.Proxies.ClientIP(r); a.IsValid() {
+		ip = a.String()
+	}
+	target := redactQuery(r.URL)
+	status := sw.code()
+	if cfg.Format == AccessLogJSON {
+		b, _ := json.Marshal(map[string]any{
+			"time":        start.UTC().Format(time.RFC3339Nano),
+			"ip":          ip,
+			"method":      r.Method,
+			"path":        target,
+			"proto":       r.Proto,
+			"status":      status,
+			"bytes":       sw.bytes,
+			"duration_ms": float64(d.Microseconds()) / 1000,
+			"referer":     r.Referer(),
+			"user_agent":  r.UserAgent(),
+			"request_id":  RequestIDFrom(r.Context()),
+		})
+		return string(b) + "\n"
+	}
+	line := fmt.Sprintf("%s - - [%s] %q %d %d", ip, start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method+" "+target+" "+r.Proto, status, sw.bytes)
+	if cfg.Format != AccessLogCommon {
+		line += fmt.Sprintf(" %q %q", dashIfEmpty(r.Referer()), dashIfEmpty(r.UserAgent()))
+	}
+	line += fmt.Sprintf(" %.3f", d.Seconds())
+	if id := RequestIDFrom(r.Context()); id != "" {
+		line += " " + id
+	}
+	return line + "\n"
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func redactQuery(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.EscapedPath()
+	}
+	q := u.Query()
+	for _, k := range secretParams {
+		if q.Has(k) {
+			q.Set(k, "REDACTED")
+		}
+	}
+	return u.EscapedPath() + "?" + q.Encode()
+}
+
+// statusWriter merkt sich Status und Bytes; Flush & Co. gehen per Unwrap durch.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *statusWriter) code() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
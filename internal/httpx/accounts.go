@@ -0,0 +1,92 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"unglued/internal/auth"
+	"unglued/internal/model"
+)
+
+// account: der Account zum Bearer-Token des Requests, falls es ein API-Token ist.
+func (s *Server) account(r *http.Request) (auth.Account, bool) {
+	tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || s.Tokens == nil {
+		return auth.Account{}, false
+	}
+	return s.Tokens.Lookup(tok)
+}
+
+/*
+spoofedAuthor: der Request ist angemeldet (API-Token, Session), nennt aber einen
+anderen author. Statt ihn still zu ersetzen, lehnt die API ab – so merkt ein
+Client, dass seine Angabe nicht in der Historie landet.
+*/
+func spoofedAuthor(w http.ResponseWriter, r *http.Request, by authorship, author string) bool {
+	if by.ID == "" || author == "" || author == by.Name {
+		return false
+	}
+	writeProblem(w, r, http.StatusForbidden, codeAuthorMismatch, "author is set from your credentials ("+by.Name+"); omit the field")
+	return true
+}
+
+// accountTTLs: Standard- und Höchstlaufzeit, wenn authorID zu einem Token-Account gehört.
+func (s *Server) accountTTLs(authorID string) (def, max time.Duration) {
+	owner, ok := strings.CutPrefix(authorID, auth.TokenIssuer+"|")
+	if !ok {
+		return 0, 0
+	}
+	a, ok := s.Tokens.Owner(owner)
+	if !ok {
+		return 0, 0
+	}
+	return a.TTLs()
+}
+
+// accountUsage: Anzahl und Größe der aktiven Pastes eines Accounts.
+type accountUsage struct {
+	Owner     string `json:"owner"`
+	Pastes    int    `json:"pastes"`
+	Bytes     int64  `json:"bytes"`
+	MaxPastes int    `json:"max_pastes,omitempty"`
+	MaxBytes  int64  `json:"max_bytes,omitempty"`
+}
+
+func (s *Server) usage(a auth.Account) accountUsage {
+	u := accountUsage{Owner: a.Owner, MaxPastes: a.MaxPastes, MaxBytes: a.MaxBytes}
+	uid := userID(a.User())
+	for _, p := range s.Store.Find(func(p *model.Paste) bool { return p.Owner == uid }) {
+		u.Pastes++
+		u.Bytes += int64(len(p.Code))
+	}
+	return u
+}
+
+/*
+checkQuota: Token-Accounts mit max_pastes/max_bytes dürfen erst wieder anlegen, wenn sie
+darunter liegen. Die Grenze ist weich: die Paste, die sie überschreitet, geht noch durch.
+*/
+func (s *Server) checkQuota(w http.ResponseWriter, r *http.Request) bool {
+	a, ok := s.account(r)
+	if !ok || (a.MaxPastes == 0 && a.MaxBytes == 0) {
+		return true
+	}
+	u := s.usage(a)
+	var detail string
+	switch {
+	case a.MaxPastes > 0 && u.Pastes >= a.MaxPastes:
+		detail = "quota exceeded: " + strconv.Itoa(u.Pastes) + " of " + strconv.Itoa(a.MaxPastes) + " pastes in use"
+	case a.MaxBytes > 0 && u.Bytes >= a.MaxBytes:
+		detail = "quota exceeded: " + strconv.FormatInt(u.Bytes, 10) + " of " + strconv.FormatInt(a.MaxBytes, 10) + " bytes in use"
+	default:
+		return true
+	}
+	if isAPIPath(r.URL.Path) {
+		writeProblem(w, r, http.StatusForbidden, codeQuotaExceeded, detail)
+	} else {
+		http.Error(w, detail, http.StatusForbidden)
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/abuse"
+	"unglued/internal/audit"
+	"unglued/internal/auth"
+)
+
+// mountAdmin hängt /admin und /api/admin hinter die Admin-Authentifizierung.
+func (s *Server) mountAdmin(r chi.Router) {
+	guard := auth.RequireAdmin(s.Config.Admin, denyAdmin)
+
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(guard)
+		// Enrollment/Verify nur mit erstem Faktor, alles andere zusätzlich mit TOTP
+		r.Route("/2fa", s.mount2FA)
+		r.Group(func(r chi.Router) {
+			r.Use(s.require2FA)
+			r.Get("/whoami", s.handleAdminWhoami)
+			r.Get("/bans", s.handleAdminBans)
+			r.Post("/bans", s.limitSmall(s.handleAdminBanAdd))
+			r.Delete("/bans", s.limitSmall(s.handleAdminBanRemove))
+			r.Get("/blocklist", s.handleAdminBlocklist)
+			r.Post("/blocklist", s.limitSmall(s.handleAdminBlocklistAdd))
+			r.Delete("/blocklist/{id}", s.limitSmall(s.handleAdminBlocklistRemove))
+			r.Get("/audit", s.handleAdminAudit)
+			r.Get("/quarantine", s.handleAdminQuarantine)
+			r.Post("/quarantine/{id}/release", s.limitSmall(s.handleAdminRelease))
+			r.Post("/reload", s.limitSmall(s.handleAdminReload))
+			r.Get("/stats", s.handleAdminStats)
+			r.Get("/pastes", s.handleAdminPastes)
+			r.Get("/pastes/{id}", s.handleAdminInspect)
+			r.Delete("/pastes/{id}", s.limitSmall(s.handleAdminDelete))
+			r.Post("/pastes/{id}/expiry", s.limitSmall(s.handleAdminExpiry))
+			r.Post("/pastes/{id}/undelete", s.limitSmall(s.handleAdminUndelete))
+			r.Get("/trash", s.handleAdminTrash)
+			r.Post("/trash/{id}/restore", s.limitSmall(s.handleAdminUndelete))
+			r.Delete("/trash/{id}", s.limitSmall(s.handleAdminTrashPurge))
+			r.Post("/purge-expired", s.limitSmall(s.handleAdminPurgeExpired))
+			r.Get("/render-cache", s.handleAdminRenderCache)
+			r.Post("/render-cache/flush", s.limitSmall(s.handleAdminRenderCacheFlush))
+			r.Get("/backup", s.handleAdminBackup)
+			// Backups sind beliebig groß und werden gestreamt gelesen
+			r.Post("/restore", s.handleAdminRestore)
+			r.Get("/archived", s.handleAdminArchived)
+			r.Post("/archived/{id}/restore", s.limitSmall(s.handleAdminArchivedRestore))
+			r.Delete("/archived/{id}", s.limitSmall(s.handleAdminArchivedDelete))
+		})
+	})
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(guard, s.require2FA)
+		r.Get("/", s.handleAdminDashboard)
+		r.Get("/stats", s.handleAdminStatsPage)
+		r.Post("/pastes/{id}/delete", s.limitSmall(s.handleAdminDeleteForm))
+		r.Post("/pastes/{id}/expiry", s.limitSmall(s.handleAdminExpiryForm))
+		r.Post("/trash/{id}/restore", s.limitSmall(s.handleAdminTrashRestoreForm))
+		r.Post("/trash/{id}/purge", s.limitSmall(s.handleAdminTrashPurgeForm))
+	})
+}
+
+func denyAdmin(w http.ResponseWriter, r *http.Request, status int) {
+	if isAPIPath(r.URL.Path) {
+		code := codeUnauthorized
+		if status == http.StatusNotFound {
+			code = codeNotFound
+		}
+		writeProblem(w, r, status, code, "admin authentication required")
+		return
+	}
+	if status == http.StatusNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	httpError(w, r, "Admin-Login erforderlich", status)
+}
+
+func (s *Server) handleAdminWhoami(w http.ResponseWriter, r *http.Request) {
+	a, _ := auth.AdminFrom(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"admin":  true,
+		"method": a.Method,
+		"name":   a.Name,
+	})
+}
+
+type banJSON struct {
+	IP      string     `json:"ip"` // IP oder CIDR
+	Reason  string     `json:"reason,omitempty"`
+	Created time.Time  `json:"created"`
+	Until   *time.Time `json:"until,omitempty"`
+}
+
+func toBanJSON(b abuse.Ban) banJSON {
+	out := banJSON{IP: b.Prefix.String(), Reason: b.Reason, Created: b.Created}
+	if !b.Until.IsZero() {
+		out.Until = &b.Until
+	}
+	return out
+}
+
+func (s *Server) abuseEnabled(w http.ResponseWriter, r *http.Request) bool {
+	if s.Abuse == nil {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "abuse control is disabled")
+		return false
+	}
+	return true
+}
+
+// GET /api/admin/bans
+func (s *Server) handleAdminBans(w http.ResponseWriter, r *http.Request) {
+	if !s.abuseEnabled(w, r) {
+		return
+	}
+	out := []banJSON{}
+	for _, b := range s.Abuse.Bans.List(time.Now()) {
+		out = append(out, toBanJSON(b))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"bans": out})
+}
+
+// POST /api/admin/bans {"ip":"203.0.113.0/24","reason":"spam","ttl":"72h"}; ttl leer = dauerhaft
+func (s *Server) handleAdminBanAdd(w http.ResponseWriter, r *http.Request) {
+	if !s.abuseEnabled(w, r) {
+		return
+	}
+	var req struct {
+		IP     string `json:"ip"`
+		Reason string `json:"reason"`
+		TTL    string `json:"ttl"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, "invalid JSON body")
+		return
+	}
+	p, err := abuse.ParsePrefix(req.IP)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	b := abuse.Ban{Prefix: p, Reason: req.Reason, Created: time.Now()}
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidTTL, "invalid ttl")
+			return
+		}
+		b.Until = b.Created.Add(d)
+	}
+	if err := s.Abuse.Bans.Add(b); err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, "could not persist ban list")
+		return
+	}
+	s.record(r, audit.ActionAdmin, "", "", "ban.add "+p.String())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(toBanJSON(b))
+}
+
+// DELETE /api/admin/bans?ip=203.0.113.0/24
+func (s *Server) handleAdminBanRemove(w http.ResponseWriter, r *http.Request) {
+	if !s.abuseEnabled(w, r) {
+		return
+	}
+	p, err := abuse.ParsePrefix(r.URL.Query().Get("ip"))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	ok, err := s.Abuse.Bans.Remove(p)
+	switch {
+	case err != nil:
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, "could not persist ban list")
+	case !ok:
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "no ban for "+p.String())
+	default:
+		s.record(r, audit.ActionAdmin, "", "", "ban.remove "+p.String())
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) blocklistEnabled(w http.ResponseWriter, r *http.Request) bool {
+	if s.Blocklist == nil {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "blocklist is disabled")
+		return false
+	}
+	return true
+}
+
+// GET /api/admin/blocklist
+func (s *Server) handleAdminBlocklist(w http.ResponseWriter, r *http.Request) {
+	if !s.blocklistEnabled(w, r) {
+		return
+	}
+	rules := s.Blocklist.List()
+	if rules == nil {
+		rules = []abuse.Rule{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"rules": rules})
+}
+
+// POST /api/admin/blocklist {"kind":"domain","pattern":"spam.example","action":"quarantine"}
+func (s *Server) handleAdminBlocklistAdd(w http.ResponseWriter, r *http.Request) {
+	if !s.blocklistEnabled(w, r) {
+		return
+	}
+	var req abuse.Rule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, "invalid JSON body")
+		return
+	}
+	rule, err := s.Blocklist.Add(abuse.Rule{Kind: req.Kind, Pattern: req.Pattern, Action: req.Action, Note: req.Note})
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	s.record(r, audit.ActionAdmin, "", "", "blocklist.add "+rule.ID+" "+rule.Kind+" "+rule.Pattern)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+// DELETE /api/admin/blocklist/{id}
+func (s *Server) handleAdminBlocklistRemove(w http.ResponseWriter, r *http.Request) {
+	if !s.blocklistEnabled(w, r) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	ok, err := s.Blocklist.Remove(id)
+	switch {
+	case err != nil:
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, "could not persist blocklist")
+	case !ok:
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "no rule "+id)
+	default:
+		s.record(r, audit.ActionAdmin, "", "", "blocklist.remove "+id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,48 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+
+	"unglued/internal/model"
+	"unglued/internal/render"
+	"unglued/internal/util"
+)
+
+// wantsANSI: /p/{id}.ansi oder Accept: text/x-ansi (curl -H …).
+func wantsANSI(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, ".ansi") || strings.Contains(r.Header.Get("Accept"), "text/x-ansi")
+}
+
+/*
+serveANSI liefert Version v eingefärbt fürs Terminal. Nicht gecacht: der
+Render-Cache hält nur HTML, und Terminal-Abrufe sind selten. Belegt aber
+einen Render-Platz wie jedes andere Highlighting.
+*/
+func (s *Server) serveANSI(w http.ResponseWriter, r *http.Request, p model.Paste, v int, theme string, pinned bool) {
+	if !s.Renders.Acquire(r.Context()) {
+		writeBusy(w, r)
+		return
+	}
+	defer s.Renders.Release()
+	_, ver, ok := s.Store.GetVersion(p.ID, v)
+	if !ok {
+		s.notFound(w, r, p.ID, false)
+		return
+	}
+	code, err := util.GzipDecode(ver.ZCode)
+	if err == nil {
+		code, _, err = render.ANSI(code, ver.Lang, theme, s.RenderLimits)
+	}
+	if err != nil {
+		logf(r, "ansi %s: %v", p.ID, err)
+		httpError(w, r, "Darstellung fehlgeschlagen", http.StatusInternalServerError)
+		return
+	}
+	if !strings.HasSuffix(code, "\n") {
+		code += "\n"
+	}
+	s.setCacheHeaders(w, p, pinned)
+	w.Header().Add("Vary", "Accept")
+	serveBody(w, r, "text/x-ansi; charset=utf-8", ver.At, []byte(code))
+}
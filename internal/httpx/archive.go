@@ -0,0 +1,195 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"unglued/internal/model"
+	"unglued/internal/store"
+	"unglued/internal/util"
+)
+
+const (
+	archivePerPage    = 20
+	archiveMaxPerPage = 100
+)
+
+type apiListItem struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	RawURL    string   `json:"raw_url"`
+	Title     string   `json:"title,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Lang      string   `json:"lang"`
+	Author    string   `json:"author,omitempty"`
+	Avatar    string   `json:"author_avatar,omitempty"`
+	Versions  int      `json:"versions"`
+	Size      int      `json:"size"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+	ExpiresAt string   `json:"expires_at"`
+
+	VersionSizes []int64 `json:"version_sizes,omitempty"`
+	Snippet      string  `json:"snippet,omitempty"`
+	ReplyTo      string  `json:"reply_to,omitempty"`
+}
+
+type apiListResp struct {
+	Page    int           `json:"page"`
+	PerPage int           `json:"per_page"`
+	Total   int           `json:"total"`
+	Sort    string        `json:"sort"`
+	Items   []apiListItem `json:"items"`
+}
+
+// archivePage liest ?sort=, ?page= (1-basiert) und ?per_page= und holt die Seite aus dem Store.
+func (s *Server) archivePage(r *http.Request) (apiListResp, []model.Paste) {
+	q := r.URL.Query()
+	sortBy := q.Get("sort")
+	if sortBy != store.SortExpires {
+		sortBy = store.SortCreated
+	}
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	per, _ := strconv.Atoi(q.Get("per_page"))
+	if per < 1 {
+		per = archivePerPage
+	}
+	if per > archiveMaxPerPage {
+		per = archiveMaxPerPage
+	}
+	items, total := s.Store.ListPublic(sortBy, (page-1)*per, per)
+	return apiListResp{Page: page, PerPage: per, Total: total, Sort: sortBy}, items
+}
+
+func (s *Server) listItem(r *http.Request, p model.Paste) apiListItem {
+	return apiListItem{
+		ID:        p.ID,
+		URL:       s.makeURL(r, "/p/"+p.ID),
+		RawURL:    s.makeURL(r, "/raw/"+p.ID),
+		Title:     p.Title,
+		Tags:      p.Tags,
+		Lang:      p.Lang,
+		Author:    p.Author,
+		Avatar:    authorAvatar(p),
+		Versions:  len(p.Versions),
+		Size:      pasteSize(p),
+		CreatedAt: p.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: p.UpdatedAt.Format(time.RFC3339),
+		ExpiresAt: p.ExpiresAt.Format(time.RFC3339),
+
+		VersionSizes: versionSizes(p),
+		ReplyTo:      p.ReplyTo,
+	}
+}
+
+// authorAvatar: Bild des Erstellers (erste Version), per Login oder -avatars.
+func authorAvatar(p model.Paste) string {
+	if len(p.Versions) == 0 {
+		return ""
+	}
+	return p.Versions[0].AuthorAvatar
+}
+
+// pasteSize: Bytes der aktuellen Fassung; bei GetMeta-Sichten (Code leer) aus der letzten Version.
+func pasteSize(p model.Paste) int {
+	if p.Code != "" || len(p.Versions) == 0 {
+		return len(p.Code)
+	}
+	return int(versionSizes(p)[len(p.Versions)-1])
+}
+
+/*
+versionSizes: entpackte Größe je Version. Der Store setzt Size beim Ablegen;
+frisch gebaute Pastes (Antwort aufs Anlegen) haben sie noch nicht, dann
+kommt sie aus dem gzip-Trailer.
+*/
+func versionSizes(p model.Paste) []int64 {
+	if len(p.Versions) == 0 {
+		return nil
+	}
+	out := make([]int64, len(p.Versions))
+	for i, v := range p.Versions {
+		out[i] = v.Size
+		if v.Size == 0 && len(v.ZCode) > 0 {
+			out[i] = max(util.GzipSize(v.ZCode), 0)
+		}
+	}
+	return out
+}
+
+func (s *Server) handleAPIList(w http.ResponseWriter, r *http.Request) {
+	resp, items := s.archivePage(r)
+	resp.Items = make([]apiListItem, 0, len(items))
+	for _, p := range items {
+		resp.Items = append(resp.Items, s.listItem(r, p))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if !s.conf().Features.Search {
+		q = ""
+	}
+	var resp apiListResp
+	var items []model.Paste
+	if q != "" {
+		items = s.searchPastes(r, q, searchMaxResults)
+		resp = apiListResp{Page: 1, PerPage: len(items), Total: len(items), Sort: store.SortCreated}
+	} else {
+		resp, items = s.archivePage(r)
+	}
+	rows := make([]map[string]any, 0, len(items))
+	for _, p := range items {
+		rows = append(rows, map[string]any{
+			"ID":        p.ID,
+			"Title":     p.Title,
+			"Tags":      p.Tags,
+			"Lang":      p.Lang,
+			"Author":    orDash(p.Author),
+			"Avatar":    authorAvatar(p),
+			"Versions":  len(p.Versions),
+			"Size":      len(p.Code),
+			"CreatedAt": p.CreatedAt.Format("2006-01-02 15:04"),
+			"ExpiresAt": p.ExpiresAt.Format("2006-01-02 15:04"),
+		})
+	}
+	_ = s.tmpl(r).archive.Execute(w, map[string]any{
+		"Query":   q,
+		"Items":   rows,
+		"Total":   resp.Total,
+		"Sort":    resp.Sort,
+		"Page":    resp.Page,
+		"HasPrev": resp.Page > 1,
+		"HasNext": resp.Page*resp.PerPage < resp.Total,
+		"Search":  s.conf().Features.Search,
+		"Feed":    archiveFeed(q),
+	})
+}
+
+// archiveFeed: passender Feed zur Suche – nur für "tag:x" und/oder "lang:y", sonst der allgemeine.
+func archiveFeed(q string) string {
+	var tag, lang string
+	for _, f := range strings.Fields(q) {
+		k, v, _ := strings.Cut(f, ":")
+		switch {
+		case k == "tag" && v != "" && tag == "":
+			tag = v
+		case k == "lang" && v != "" && lang == "":
+			lang = v
+		default:
+			return "/feed.atom"
+		}
+	}
+	if fq := feedQuery(strings.ToLower(tag), util.CanonicalLang(lang)); fq != "" {
+		return "/feed.atom?" + fq
+	}
+	return "/feed.atom"
+}
@@ -0,0 +1,125 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"unglued/internal/audit"
+	"unglued/internal/auth"
+	"unglued/internal/model"
+	"unglued/internal/stats"
+	"unglued/internal/webhook"
+)
+
+// record schreibt einen Audit-Eintrag mit allem, was der Request über den Akteur verrät.
+func (s *Server) record(r *http.Request, action, pasteID, author, detail string) {
+	if s.Audit == nil {
+		return
+	}
+	e := audit.Entry{
+		Action:  action,
+		PasteID: pasteID,
+		Author:  author,
+		Detail:  detail,
+	}
+	if ip := s.clientIP(r); ip.IsValid() {
+		e.IP = ip.String()
+	}
+	if u, ok := s.currentUser(r); ok {
+		e.UserID = userID(u)
+		e.Via = "session"
+		if u.Issuer == auth.TokenIssuer {
+			e.Via = "api-token"
+			e.Token = audit.Fingerprint(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		}
+	}
+	// Credential: Admin-Header, signierter Edit-Link oder Edit-Cookie
+	switch a, isAdmin := auth.AdminFrom(r.Context()); {
+	case isAdmin:
+		e.Via = "admin-" + a.Method
+		if a.Method == "basic" {
+			e.Author = a.Name
+		} else {
+			e.Token = audit.Fingerprint(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		}
+	case r.URL.Query().Get("key") != "":
+		e.Via = "edit-token"
+		e.Token = audit.Fingerprint(r.URL.Query().Get("key"))
+	case pasteID != "":
+		if c, err := r.Cookie("npk_" + pasteID); err == nil && action == audit.ActionEdit {
+			e.Via = "edit-cookie"
+			e.Token = audit.Fingerprint(c.Value)
+		}
+	}
+	s.Audit.Record(e)
+}
+
+// recordSave protokolliert und zählt Anlegen und Bearbeiten (aufgerufen aus save).
+func (s *Server) recordSave(r *http.Request, typ string, p model.Paste) {
+	action, event := audit.ActionCreate, stats.Created
+	if typ == webhook.EventEdited {
+		action, event = audit.ActionEdit, stats.Edited
+	}
+	s.Stats.Add(event, 1)
+	if event == stats.Created {
+		s.metrics.pasteCreated(p)
+	}
+	s.prerender(p)
+	last := p.Versions[len(p.Versions)-1]
+	s.record(r, action, p.ID, last.Author, "version "+strconv.Itoa(len(p.Versions)))
+}
+
+// recordView zählt jeden Aufruf; ins Audit-Log kommen nur private Pastes.
+func (s *Server) recordView(r *http.Request, p model.Paste) {
+	if r.Method != http.MethodHead {
+		s.Stats.Add(stats.Viewed, 1)
+		s.Hits.Add(p.ID, s.clientIP(r).String()+" "+r.UserAgent())
+	}
+	if p.Private {
+		s.record(r, audit.ActionViewPrivate, p.ID, "", r.URL.Path)
+	}
+	s.noteView(r, p)
+}
+
+// GET /api/admin/audit?action=&paste=&ip=&user=&since=&until=&limit=
+func (s *Server) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	if s.Audit == nil {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "audit log is disabled")
+		return
+	}
+	q := r.URL.Query()
+	f := audit.Filter{
+		Action:  q.Get("action"),
+		PasteID: q.Get("paste"),
+		IP:      q.Get("ip"),
+		UserID:  q.Get("user"),
+	}
+	for name, dst := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, name+" must be RFC 3339")
+				return
+			}
+			*dst = t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, "limit must be 1..1000")
+			return
+		}
+		f.Limit = n
+	}
+	entries, err := s.Audit.Query(f)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, "could not read audit log")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"entries": entries})
+}
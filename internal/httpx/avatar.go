@@ -0,0 +1,28 @@
+package httpx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// AvatarServices: Werte für -avatars und woher deren Bilder kommen.
+var AvatarServices = map[string]string{
+	"gravatar":   "https://www.gravatar.com",
+	"libravatar": "https://seccdn.libravatar.org",
+}
+
+/*
+emailAvatar: Bild-URL zur E-Mail-Adresse eines Autors beim konfigurierten
+Dienst; leer, wenn -avatars aus ist. Gespeichert wird nur diese URL – sie
+enthält den SHA-256 der Adresse, nie die Adresse selbst.
+*/
+func (s *Server) emailAvatar(email string) string {
+	base, ok := AvatarServices[s.Config.Avatars]
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !ok || !strings.Contains(email, "@") {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(email))
+	return base + "/avatar/" + hex.EncodeToString(sum[:]) + "?s=64&d=identicon"
+}
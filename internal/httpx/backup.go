@@ -0,0 +1,58 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"unglued/internal/audit"
+	"unglued/internal/backup"
+	"unglued/internal/model"
+	"unglued/internal/util"
+)
+
+// GET /api/admin/backup: konsistenter Snapshot aller Pastes als tar (Klartext inkl. Edit-Keys!)
+func (s *Server) handleAdminBackup(w http.ResponseWriter, r *http.Request) {
+	ps := s.Store.Snapshot()
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", `attachment; filename="unglued-`+time.Now().UTC().Format("20060102-150405")+`.tar"`)
+	if err := backup.Write(w, ps); err != nil {
+		logf(r, "backup: %v", err)
+		return
+	}
+	s.record(r, audit.ActionAdmin, "", "", "backup "+strconv.Itoa(len(ps))+" pastes")
+}
+
+/*
+POST /api/admin/restore[?overwrite=1]: spielt ein Backup (tar im Body) ein.
+Abgelaufene Pastes werden übersprungen, vorhandene IDs nur mit overwrite ersetzt.
+*/
+func (s *Server) handleAdminRestore(w http.ResponseWriter, r *http.Request) {
+	overwrite := util.IsTruthy(r.URL.Query().Get("overwrite"))
+	now := time.Now()
+	var restored, skipped, expired int
+	_, err := backup.Read(r.Body, func(p model.Paste) error {
+		if !now.Before(p.ExpiresAt) {
+			expired++
+			return nil
+		}
+		if s.Store.Has(p.ID) && !overwrite {
+			skipped++
+			return nil
+		}
+		s.Store.Put(p)
+		if s.Search != nil && !p.Quarantined {
+			s.Search.Add(p)
+		}
+		restored++
+		return nil
+	})
+	s.record(r, audit.ActionAdmin, "", "", "restore "+strconv.Itoa(restored)+" pastes")
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, "restore stopped after "+strconv.Itoa(restored)+" pastes: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"restored": restored, "skipped": skipped, "expired": expired})
+}
@@ -0,0 +1,122 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/store/storetest"
+)
+
+const benchPastes = 100
+
+// benchServer: ein Server wie aus main, nur ohne Anmeldung, Limits und Extras.
+func benchServer(st storetest.Env) http.Handler {
+	index, view, edit := LoadTemplates()
+	srv := NewServer(Config{Reloadable: Reloadable{MaxPasteBytes: 1 << 20, Features: AllFeatures()}}, st.Store, index, view, edit)
+	r := chi.NewRouter()
+	MountRoutes(r, srv)
+	return r
+}
+
+func benchDo(b *testing.B, h http.Handler, method, path string, body any) []byte {
+	var rd *bytes.Reader
+	if body != nil {
+		j, _ := json.Marshal(body)
+		rd = bytes.NewReader(j)
+	} else {
+		rd = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, rd)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code/100 != 2 {
+		b.Fatalf("%s %s: %d %s", method, path, w.Code, strings.TrimSpace(w.Body.String()))
+	}
+	return w.Body.Bytes()
+}
+
+type benchTarget struct{ id, key string }
+
+func benchCreate(b *testing.B, h http.Handler, i int) benchTarget {
+	out := benchDo(b, h, http.MethodPost, "/api/v1/paste", map[string]any{
+		"code": storetest.Code(i), "lang": "go", "ttl": "1h", "editable": true, "title": "bench",
+	})
+	var resp struct {
+		ID      string `json:"id"`
+		EditURL string `json:"edit_url"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		b.Fatal(err)
+	}
+	t := benchTarget{id: resp.ID}
+	if u, err := url.Parse(resp.EditURL); err == nil {
+		t.key = u.Query().Get("key")
+	}
+	return t
+}
+
+func benchSeed(b *testing.B, h http.Handler, st storetest.Env) []benchTarget {
+	ts := make([]benchTarget, benchPastes)
+	for i := range ts {
+		ts[i] = benchCreate(b, h, i)
+	}
+	st.Flush()
+	return ts
+}
+
+func BenchmarkCreate(b *testing.B) {
+	for _, be := range storetest.Backends() {
+		b.Run(be.Name, func(b *testing.B) {
+			st := be.Open(b)
+			h := benchServer(st)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				benchCreate(b, h, i)
+			}
+			st.Flush()
+		})
+	}
+}
+
+// BenchmarkView misst die HTML-Seite; ohne Prerender-Cache wird jedes Mal neu hervorgehoben.
+func BenchmarkView(b *testing.B) {
+	for _, be := range storetest.Backends() {
+		b.Run(be.Name, func(b *testing.B) {
+			st := be.Open(b)
+			h := benchServer(st)
+			ts := benchSeed(b, h, st)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				benchDo(b, h, http.MethodGet, "/p/"+ts[i%len(ts)].id, nil)
+			}
+		})
+	}
+}
+
+func BenchmarkEdit(b *testing.B) {
+	for _, be := range storetest.Backends() {
+		b.Run(be.Name, func(b *testing.B) {
+			st := be.Open(b)
+			h := benchServer(st)
+			ts := benchSeed(b, h, st)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				t := ts[i%len(ts)]
+				benchDo(b, h, http.MethodPost, "/api/v1/paste/"+t.id+"/edit?key="+url.QueryEscape(t.key), map[string]any{"code": storetest.Code(i)})
+			}
+			st.Flush()
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Feldnamen im HTML-Formular
+const (
+	honeypotField = "website"
+	fillTimeField = "form_ts"
+)
+
+/*
+BotTraps: leichte Heuristiken gegen Drive-by-Spam am HTML-Formular, geprüft vor
+dem CAPTCHA. Angemeldete Benutzer und API-Clients sind ausgenommen.
+*/
+type BotTraps struct {
+	Honeypot  bool          // verstecktes Feld, das nur Bots ausfüllen
+	MinFill   time.Duration // Mindestzeit zwischen Formularaufruf und Absenden; 0 = aus
+	UserAgent bool          // leere User-Agents und HTTP-Bibliotheken abweisen
+	LogOnly   bool          // nur protokollieren, nicht abweisen
+}
+
+// UA-Fragmente von Skript-Clients, die kein Mensch im Browser benutzt
+var botUAs = []string{
+	"python-requests", "python-urllib", "aiohttp", "go-http-client", "curl/", "wget/",
+	"libwww-perl", "scrapy", "okhttp", "java/", "apache-httpclient", "node-fetch", "axios/",
+	"headlesschrome", "phantomjs",
+}
+
+// formToken: signierter Zeitstempel des Formularaufrufs für die Mindest-Ausfüllzeit.
+func (s *Server) formToken() string {
+	if s.conf().Bots.MinFill <= 0 {
+		return ""
+	}
+	tok, _ := s.Auth.Seal("form", time.Now().UnixMilli(), 24*time.Hour)
+	return tok
+}
+
+// botReason liefert den Grund, warum der Request nach Bot aussieht, sonst "".
+func (s *Server) botReason(r *http.Request) string {
+	b := s.conf().Bots
+	if b.Honeypot && r.FormValue(honeypotField) != "" {
+		return "honeypot filled"
+	}
+	if b.MinFill > 0 {
+		var ms int64
+		if !s.Auth.Open("form", r.FormValue(fillTimeField), &ms) {
+			return "missing or expired form token"
+		}
+		if d := time.Since(time.UnixMilli(ms)); d < b.MinFill {
+			return "submitted after " + d.Round(time.Millisecond).String()
+		}
+	}
+	if b.UserAgent {
+		ua := strings.ToLower(r.UserAgent())
+		if strings.TrimSpace(ua) == "" {
+			return "empty user agent"
+		}
+		for _, frag := range botUAs {
+			if strings.Contains(ua, frag) {
+				return "scripted user agent"
+			}
+		}
+	}
+	return ""
+}
+
+// checkBots: bei false ist die Antwort geschrieben.
+func (s *Server) checkBots(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := s.currentUser(r); ok {
+		return true
+	}
+	reason := s.botReason(r)
+	if reason == "" {
+		return true
+	}
+	logf(r, "bot trap: %s (ip=%s ua=%q log-only=%t)", reason, s.clientIP(r), r.UserAgent(), s.conf().Bots.LogOnly)
+	if s.conf().Bots.LogOnly {
+		return true
+	}
+	httpError(w, r, "Anfrage sieht automatisiert aus – bitte Seite neu laden und erneut absenden.", http.StatusBadRequest)
+	return false
+}
@@ -0,0 +1,100 @@
+package httpx
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/model"
+)
+
+// Obergrenze für immutable Antworten (1 Jahr), sonst begrenzt durch den Ablauf der Paste.
+const maxImmutableAge = 365 * 24 * time.Hour
+
+/*
+versionIndex liest /…/v/{n} oder ?v=N (1-basiert) und liefert den 0-basierten Index.
+pinned = true, wenn explizit eine existierende Version angefragt wurde (Permalink).
+*/
+func versionIndex(r *http.Request, p model.Paste) (idx int, pinned bool) {
+	idx = len(p.Versions) - 1
+	vParam := chi.URLParam(r, "n")
+	if vParam == "" {
+		vParam = strings.TrimSpace(r.URL.Query().Get("v"))
+	}
+	if vParam == "" {
+		return idx, false
+	}
+	if n, err := strconv.Atoi(vParam); err == nil && n >= 1 && n <= len(p.Versions) {
+		return n - 1, true
+	}
+	return idx, false
+}
+
+// badVersionPath: /…/v/{n} mit einer Version, die es nicht gibt – 404 statt still die neueste.
+func badVersionPath(r *http.Request, pinned bool) bool {
+	return chi.URLParam(r, "n") != "" && !pinned
+}
+
+// versionPath: Permalink-Pfad von Version n (1-basiert) unter /p oder /raw.
+func versionPath(prefix, id string, n int) string {
+	return prefix + "/" + id + "/v/" + strconv.Itoa(n)
+}
+
+/*
+setCacheHeaders: Versions-Permalinks ändern sich nie mehr und dürfen bis zum Ablauf
+der Paste gecacht werden; alles andere muss revalidiert werden (Last-Modified).
+Private Pastes nur im Browser, nie in geteilten Caches (CDN). Im Dev-Modus wird
+gar nichts gecacht.
+*/
+func (s *Server) setCacheHeaders(w http.ResponseWriter, p model.Paste, immutable bool) {
+	if s.Config.DevDir != "" {
+		w.Header().Set("Cache-Control", "no-store")
+		return
+	}
+	if immutable {
+		age := time.Until(p.ExpiresAt)
+		if age > maxImmutableAge {
+			age = maxImmutableAge
+		}
+		if age < 0 {
+			age = 0
+		}
+		scope := "public"
+		if p.Private {
+			scope = "private"
+		}
+		w.Header().Set("Cache-Control", scope+", max-age="+strconv.Itoa(int(age/time.Second))+", immutable")
+	} else if p.Private {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("Expires", p.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+/*
+setPageCacheHeaders: HTML-Ansichten enthalten Edit- und Lösch-Links des
+jeweiligen Betrachters, dazu Antworten und Moderationshinweise, die sich ändern.
+Darum nie in geteilte Caches und immer revalidieren, auch bei Permalinks.
+*/
+func (s *Server) setPageCacheHeaders(w http.ResponseWriter, p model.Paste) {
+	if s.Config.DevDir != "" {
+		w.Header().Set("Cache-Control", "no-store")
+		return
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Expires", p.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+/*
+serveBody schreibt body über http.ServeContent: korrekte Content-Length,
+HEAD ohne Body, If-Modified-Since → 304 und Range-Requests gibt es damit gratis.
+*/
+func serveBody(w http.ResponseWriter, r *http.Request, contentType string, mod time.Time, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, "", mod, bytes.NewReader(body))
+}
@@ -0,0 +1,41 @@
+package httpx
+
+import (
+	"net/http"
+
+	"unglued/internal/captcha"
+)
+
+/*
+captchaFor: das Widget bekommen nur anonyme Besucher des HTML-Formulars.
+Angemeldete Benutzer und API-Clients (/api/..., POST /) sind ausgenommen.
+*/
+func (s *Server) captchaFor(r *http.Request) *captcha.Verifier {
+	if s.Captcha == nil {
+		return nil
+	}
+	if _, ok := s.currentUser(r); ok {
+		return nil
+	}
+	return s.Captcha
+}
+
+// checkCaptcha prüft das Token aus dem Formular; bei false ist die Antwort geschrieben.
+func (s *Server) checkCaptcha(w http.ResponseWriter, r *http.Request) bool {
+	v := s.captchaFor(r)
+	if v == nil {
+		return true
+	}
+	ip := ""
+	if a := s.clientIP(r); a.IsValid() {
+		ip = a.String()
+	}
+	if err := v.Verify(r.Context(), r.FormValue(v.Field), ip); err != nil {
+		if err != captcha.ErrMissing {
+			logf(r, "captcha: %v", err)
+		}
+		httpError(w, r, "CAPTCHA-Prüfung fehlgeschlagen – bitte erneut bestätigen.", http.StatusForbidden)
+		return false
+	}
+	return true
+}
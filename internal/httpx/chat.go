@@ -0,0 +1,186 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"unglued/internal/model"
+	"unglued/internal/secrets"
+	"unglued/internal/util"
+	"unglued/internal/webhook"
+)
+
+// Vorschau in Chats: so viele Zeilen bzw. Bytes vom Anfang der Paste.
+const (
+	previewLines = 12
+	previewBytes = 1500
+
+	// Ersatz für umgewandelte Nachrichten: bewusst kürzer
+	replacementLines = 5
+	replacementBytes = 500
+)
+
+/*
+parseChatText zerlegt "/paste lang=go ttl=1h redact <code>": führende Optionen, dann
+der Code, gern als ```-Block (```go setzt die Sprache, wenn lang= fehlt).
+*/
+func parseChatText(text string) (code string, o pasteOpts, redact bool) {
+	rest := strings.TrimSpace(text)
+	for {
+		word, tail, _ := strings.Cut(rest, " ")
+		k, v, isOpt := strings.Cut(word, "=")
+		switch {
+		case isOpt && k == "lang":
+			o.Lang = v
+		case isOpt && k == "ttl":
+			o.TTL = v
+		case word == "redact":
+			redact = true
+		default:
+			code = rest
+			if body, ok := strings.CutPrefix(code, "```"); ok {
+				first, after, multiline := strings.Cut(body, "\n")
+				if hint := strings.TrimSpace(first); multiline && !strings.ContainsAny(hint, " `") {
+					if o.Lang == "" {
+						o.Lang = hint
+					}
+					body = after
+				}
+				code = strings.TrimSuffix(strings.TrimSpace(body), "```")
+			}
+			return code, o, redact
+		}
+		rest = strings.TrimLeft(tail, " ")
+	}
+}
+
+/*
+chatPaste legt aus einem Chat-Befehl eine Paste an (source landet als Tag daran).
+Die Fehler sind als Antwort an den Benutzer formuliert.
+*/
+func (s *Server) chatPaste(r *http.Request, text, author, source string) (model.Paste, error) {
+	code, o, redact := parseChatText(text)
+	if strings.TrimSpace(code) == "" {
+		return model.Paste{}, errors.New("usage: /paste [lang=go] [ttl=1h] [redact] <code>")
+	}
+	if redact {
+		code, o.Redacted = secrets.Redact(code)
+	} else if fs := secrets.Scan(code); len(fs) > 0 {
+		s.metrics.secretsBlocked(fs)
+		return model.Paste{}, fmt.Errorf("blocked: potential secrets detected (add `redact` to mask them):\n%s", secrets.Brief(fs, 6))
+	}
+	o.Code, o.Author, o.Tags = code, author, []string{source}
+	p, err := s.buildPaste(o)
+	if isTooLarge(err) {
+		return model.Paste{}, errors.New("paste too large")
+	}
+	if err != nil {
+		return model.Paste{}, err
+	}
+	if reason, ok := s.review(r, webhook.EventCreated, &p); !ok {
+		return model.Paste{}, fmt.Errorf("rejected by content moderation: %s", reason)
+	}
+	s.save(r, webhook.EventCreated, p)
+	return p, nil
+}
+
+/*
+chatPreview: Paste und Anfang des Inhalts für einen /p/…- oder /raw/…-Link (/v/N
+und ?v=N beachtet). Private, markierte und Pastes in Quarantäne bekommen keine Vorschau.
+*/
+func (s *Server) chatPreview(link string) (p model.Paste, snippet string, ok bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return p, "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) == 4 && parts[2] == "v" {
+		q := u.Query()
+		q.Set("v", parts[3])
+		u.RawQuery = q.Encode()
+		parts = parts[:2]
+	}
+	if len(parts) != 2 || (parts[0] != "p" && parts[0] != "raw") {
+		return p, "", false
+	}
+	if p, ok = s.Store.GetMeta(parts[1]); !ok || p.Private || p.Flagged || p.Quarantined {
+		return model.Paste{}, "", false
+	}
+	var code string
+	if len(p.Versions) == 0 {
+		full, _ := s.Store.Get(p.ID)
+		code = full.Code
+	} else {
+		idx, _ := versionIndex(&http.Request{URL: u}, p)
+		_, ver, found := s.Store.GetVersion(p.ID, idx)
+		if !found {
+			return model.Paste{}, "", false
+		}
+		if code, err = util.GzipDecode(ver.ZCode); err != nil {
+			return model.Paste{}, "", false
+		}
+	}
+	return p, chatSnippet(code, previewLines, previewBytes), true
+}
+
+// chatSnippet: die ersten n Zeilen, höchstens max Bytes, gekürzt mit "…".
+func chatSnippet(code string, n, max int) string {
+	lines := strings.SplitN(code, "\n", n+1)
+	cut := len(lines) > n
+	if cut {
+		lines = lines[:n]
+	}
+	snippet := strings.Join(lines, "\n")
+	if len(snippet) > max {
+		snippet, cut = strings.ToValidUTF8(snippet[:max], ""), true
+	}
+	if cut {
+		snippet += "\n…"
+	}
+	return snippet
+}
+
+/*
+chatReplacement: Markdown für Discord und Mattermost, das eine umgewandelte Nachricht
+ersetzt – Autor, Link und eine kurze Vorschau.
+*/
+func (s *Server) chatReplacement(r *http.Request, p model.Paste) string {
+	code := p.Code
+	if len(p.Versions) > 0 {
+		code, _ = util.GzipDecode(p.Versions[len(p.Versions)-1].ZCode)
+	}
+	lang := p.Lang
+	if lang == "plaintext" {
+		lang = ""
+	}
+	snippet := strings.ReplaceAll(chatSnippet(code, replacementLines, replacementBytes), "```", "`\u200b``")
+	head := strconv.Itoa(strings.Count(code, "\n")+1) + " lines · " + s.makeURL(r, "/p/"+p.ID)
+	if strings.HasPrefix(head, "1 lines") {
+		head = strings.Replace(head, "lines", "line", 1)
+	}
+	if p.Author != "" {
+		head = "**" + p.Author + "** · " + head
+	}
+	return head + "\n```" + lang + "\n" + snippet + "\n```"
+}
+
+// chatTitle: Titel oder ID, für Vorschauen.
+func chatTitle(p model.Paste) string {
+	if p.Title != "" {
+		return p.Title
+	}
+	return "Paste " + p.ID
+}
+
+/*
+internalRequest: für Pastes, die nicht über HTTP hereinkommen (Matrix, Mail). Er hat
+keine Client-IP; Links brauchen deshalb -public.
+*/
+func (s *Server) internalRequest(path string) *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, s.makeURL(nil, path), nil)
+	return r
+}
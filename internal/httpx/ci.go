@@ -0,0 +1,211 @@
+package httpx
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"unglued/internal/secrets"
+	"unglued/internal/util"
+	"unglued/internal/webhook"
+)
+
+// ciJob: woher ein Log stammt; landet in Titel und Tags der Paste.
+type ciJob struct {
+	Repo, Job, Run, Status, Ref, SHA, URL string
+}
+
+/*
+Feldnamen im JSON, je zuerst der eigene, dann die Namen aus GitHub-Actions- und
+GitLab-Payloads (Punkt = verschachtelt). Zahlen sind erlaubt, etwa für run_id.
+*/
+var ciFields = map[string][]string{
+	"repo":   {"repo", "repository.full_name", "repository", "project.path_with_namespace", "project_path", "project"},
+	"job":    {"job", "job_name", "build_name", "workflow_job.name"},
+	"run":    {"run", "run_id", "pipeline_id", "build_id", "job_id", "workflow_job.run_id"},
+	"status": {"status", "conclusion", "build_status", "workflow_job.conclusion"},
+	"ref":    {"ref", "head_branch", "workflow_job.head_branch"},
+	"sha":    {"sha", "commit", "head_sha", "workflow_job.head_sha"},
+	"url":    {"url", "html_url", "web_url", "workflow_job.html_url"},
+	"log":    {"log", "trace", "text"},
+}
+
+// ciAuthorized: Bearer-Token, X-Gitlab-Token oder X-Hub-Signature-256 über den Body.
+func (s *Server) ciAuthorized(r *http.Request, body []byte) bool {
+	secret := s.Config.CIToken
+	eq := func(a string) bool { return subtle.ConstantTimeCompare([]byte(a), []byte(secret)) == 1 }
+	if tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && eq(tok) {
+		return true
+	}
+	if tok := r.Header.Get("X-Gitlab-Token"); tok != "" && eq(tok) {
+		return true
+	}
+	if sig, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256="); ok {
+		return hmac.Equal([]byte(sig), []byte(webhook.Sign(secret, body)))
+	}
+	return false
+}
+
+/*
+parseCIPayload: JSON mit Log und Metadaten oder der rohe Log als Text; die Metadaten
+kommen dann aus X-CI-Repo, X-CI-Job, X-CI-Run, X-CI-Status, X-CI-Ref, X-CI-SHA, X-CI-URL.
+*/
+func parseCIPayload(r *http.Request, body []byte) (string, ciJob, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") && !bytesHasJSONPrefix(body) {
+		h := r.Header
+		return string(body), ciJob{
+			Repo: h.Get("X-CI-Repo"), Job: h.Get("X-CI-Job"), Run: h.Get("X-CI-Run"),
+			Status: h.Get("X-CI-Status"), Ref: h.Get("X-CI-Ref"), SHA: h.Get("X-CI-SHA"), URL: h.Get("X-CI-URL"),
+		}, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return "", ciJob{}, err
+	}
+	get := func(name string) string {
+		for _, path := range ciFields[name] {
+			if v := lookupPath(m, path); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	return get("log"), ciJob{
+		Repo: get("repo"), Job: get("job"), Run: get("run"), Status: get("status"),
+		Ref: get("ref"), SHA: get("sha"), URL: get("url"),
+	}, nil
+}
+
+// lookupPath folgt "a.b.c" durch verschachtelte Objekte; nur Strings und Zahlen zählen.
+func lookupPath(m map[string]any, path string) string {
+	var v any = m
+	for _, k := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+		v = obj[k]
+	}
+	switch x := v.(type) {
+	case string:
+		return strings.TrimSpace(x)
+	case json.Number:
+		return x.String()
+	}
+	return ""
+}
+
+func (j ciJob) title() string {
+	parts := []string{}
+	for _, p := range []string{j.Repo, j.Job} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	t := strings.Join(parts, " · ")
+	if j.Run != "" {
+		t += " #" + j.Run
+	}
+	if j.Status != "" {
+		t += " (" + j.Status + ")"
+	}
+	if t == "" {
+		return "CI log"
+	}
+	return strings.TrimSpace(t)
+}
+
+func (j ciJob) tags() []string {
+	tags := "ci"
+	for _, kv := range [][2]string{{"repo", j.Repo}, {"job", j.Job}, {"run", j.Run}, {"status", j.Status}} {
+		if v := strings.NewReplacer(",", "", " ", "-").Replace(kv[1]); v != "" {
+			tags += "," + kv[0] + ":" + v
+		}
+	}
+	return util.ParseTags(tags)
+}
+
+/*
+POST /api/ingest/ci: nimmt Job-Logs aus CI-Pipelines an (Token aus -ci-token) und legt
+eine Paste mit Repo/Job/Run in Titel und Tags an. Secrets werden geschwärzt statt
+abgelehnt, damit ein Log nie verloren geht. ?ttl= bzw. X-CI-TTL setzt die Laufzeit.
+*/
+func (s *Server) handleCIIngest(w http.ResponseWriter, r *http.Request) {
+	if s.Config.CIToken == "" {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "CI ingestion is disabled")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if !s.ciAuthorized(r, body) {
+		writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, "invalid or missing CI token")
+		return
+	}
+	log, job, err := parseCIPayload(r, body)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
+		return
+	}
+	code, redacted := secrets.Redact(log)
+	ttl := r.URL.Query().Get("ttl")
+	if ttl == "" {
+		ttl = r.Header.Get("X-CI-TTL")
+	}
+	p, err := s.buildPaste(pasteOpts{
+		Code:     code,
+		Lang:     "plaintext", // Logs nicht raten lassen, da steckt von allem etwas drin
+		TTL:      ttl,
+		Title:    job.title(),
+		Tags:     job.tags(),
+		Author:   "CI",
+		Redacted: redacted,
+	})
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
+	if err != nil {
+		writeProblemErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if !s.moderate(w, r, webhook.EventCreated, &p) {
+		return
+	}
+	s.save(r, webhook.EventCreated, p)
+
+	url := s.makeURL(r, "/p/"+p.ID)
+	markdown := fmt.Sprintf("[%s](%s)", strings.NewReplacer("[", "(", "]", ")").Replace(job.title()), url)
+	if job.URL != "" {
+		markdown += fmt.Sprintf(" · [run](%s)", job.URL)
+	}
+	out := map[string]any{
+		"id":         p.ID,
+		"url":        url,
+		"raw_url":    s.makeURL(r, "/raw/"+p.ID),
+		"expires_at": p.ExpiresAt.Format(time.RFC3339),
+		"title":      p.Title,
+		"tags":       p.Tags,
+		"markdown":   markdown,
+	}
+	if len(p.Redacted) > 0 {
+		out["redacted"] = p.Redacted
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(out)
+}
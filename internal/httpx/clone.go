@@ -0,0 +1,147 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/model"
+	"unglued/internal/secrets"
+	"unglued/internal/util"
+	"unglued/internal/webhook"
+)
+
+/*
+"Kopie bearbeiten": wer eine Paste nicht editieren darf, bekommt statt 403 eine
+bearbeitbare Kopie, die ihm gehört (eigener Edit-Key), mit Verweis aufs Original.
+*/
+
+// cloneable: der Aufrufer darf p lesen und neue Pastes anlegen.
+func (s *Server) cloneable(r *http.Request, p model.Paste) bool {
+	return s.conf().Features.Edit && !p.Quarantined && s.canView(r, p) && s.mayCreate(r)
+}
+
+// cloneURL: Ziel des Kopie-Buttons für Besucher ohne Edit-Zugriff; leer = kein Button.
+func (s *Server) cloneURL(r *http.Request, p model.Paste, canEdit, pinned bool, v int) string {
+	if canEdit || !s.cloneable(r, p) {
+		return ""
+	}
+	if pinned {
+		return "/p/" + p.ID + "/clone?v=" + strconv.Itoa(v+1)
+	}
+	return "/p/" + p.ID + "/clone"
+}
+
+// cloneOrigin: das Original, soweit der Aufrufer es sehen darf; sonst nur die ID.
+func (s *Server) cloneOrigin(r *http.Request, p model.Paste) *model.Paste {
+	if p.CloneOf == "" {
+		return nil
+	}
+	if o, ok := s.Store.GetMeta(p.CloneOf); ok && !o.Quarantined && s.canView(r, o) {
+		return &o
+	}
+	return &model.Paste{ID: p.CloneOf}
+}
+
+// cloneForm: Edit-Formular ohne Edit-Zugriff – Speichern legt die Kopie an.
+func (s *Server) cloneForm(w http.ResponseWriter, r *http.Request, p model.Paste) {
+	data := s.editFormData(r, p, "")
+	data["Clone"] = true
+	data["Key"] = ""
+	data["Author"] = readAuthorCookie(r)
+	_ = s.tmpl(r).edit.Execute(w, data)
+}
+
+/*
+POST /p/{id}/clone[?v=N]: legt eine bearbeitbare Kopie an und leitet auf deren
+Edit-Formular. Aus dem Kopie-Formular kommen Code und Sprache gleich mit.
+*/
+func (s *Server) handleClone(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	src, ok := s.Store.GetMeta(id)
+	if !ok {
+		s.notFound(w, r, id, true)
+		return
+	}
+	if !s.canView(r, src) {
+		s.denyView(w, r)
+		return
+	}
+	if s.quarantined(w, r, src) {
+		return
+	}
+	if !s.mayCreate(r) {
+		httpError(w, r, "Anmeldung erforderlich", http.StatusUnauthorized)
+		return
+	}
+	if err := parseAnyForm(r); isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	} else if err != nil {
+		httpError(w, r, "Bad form", http.StatusBadRequest)
+		return
+	}
+
+	vIdx, _ := versionIndex(r, src)
+	_, ver, ok := s.Store.GetVersion(src.ID, vIdx)
+	if !ok {
+		s.notFound(w, r, id, true)
+		return
+	}
+	code, err := util.GzipDecode(ver.ZCode)
+	if err != nil {
+		logf(r, "clone %s: %v", src.ID, err)
+		httpError(w, r, "Renderfehler", http.StatusInternalServerError)
+		return
+	}
+	lang := ver.Lang
+	if c := strings.TrimSpace(r.FormValue("code")); c != "" {
+		code = c
+		lang = s.normalizeLang(strings.TrimSpace(r.FormValue("lang")))
+	}
+	if fs := secrets.Scan(code); len(fs) > 0 {
+		s.writeSecretBlock(w, r, fs)
+		return
+	}
+
+	author := strings.TrimSpace(r.FormValue("author"))
+	if author == "" {
+		author = readAuthorCookie(r)
+	}
+	by := s.identify(r, author, r.FormValue("email"))
+	p, err := s.buildPaste(pasteOpts{
+		Code: code, Lang: lang, Theme: src.Theme, Title: src.Title, Tags: src.Tags,
+		Author: by.Name, AuthorID: by.ID, Avatar: by.Avatar, Profile: by.Profile,
+		Editable: true,
+		// privat bleibt privat, sofern es jemanden gibt, dem die Kopie gehört
+		Private: src.Private && by.ID != "",
+		Format:  util.IsTruthy(r.FormValue("format")),
+		CloneOf: src.ID,
+	})
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, errText(r, err), http.StatusBadRequest)
+		return
+	}
+	if !s.moderate(w, r, webhook.EventCreated, &p) {
+		return
+	}
+	s.save(r, webhook.EventCreated, p)
+
+	if by.Name != "" {
+		s.setCookie(w, r, "np_author", by.Name, 180*24*time.Hour)
+	}
+	s.setCookie(w, r, "npk_"+p.ID, p.EditKey, 365*24*time.Hour)
+	// aus dem Kopie-Formular ist schon bearbeitet: gleich zur Kopie, sonst zum Bearbeiten
+	if r.FormValue("code") != "" {
+		http.Redirect(w, r, "/p/"+p.ID, http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/p/"+p.ID+"/edit", http.StatusSeeOther)
+}
@@ -0,0 +1,108 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/audit"
+	"unglued/internal/blob"
+	"unglued/internal/util"
+)
+
+// Standard-Laufzeit einer aus dem Archiv geholten Paste.
+const restoreTTL = 24 * time.Hour
+
+func (s *Server) coldStoreOn(w http.ResponseWriter, r *http.Request) bool {
+	if s.ColdStore == nil {
+		writeProblem(w, r, http.StatusNotFound, codeFeatureDisabled, "archiving to object storage is not configured")
+		return false
+	}
+	return true
+}
+
+// GET /api/admin/archived: archivierte Pastes, neueste zuerst.
+func (s *Server) handleAdminArchived(w http.ResponseWriter, r *http.Request) {
+	if !s.coldStoreOn(w, r) {
+		return
+	}
+	list, err := s.ColdStore.List(r.Context())
+	if err != nil {
+		logf(r, "coldstore: %v", err)
+		writeProblem(w, r, http.StatusBadGateway, codeArchiveUnavailable, "cannot list the archive")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"pastes": list})
+}
+
+/*
+POST /api/admin/archived/{id}/restore: holt eine Paste aus dem Archiv zurück, mit
+neuer Laufzeit ({"ttl": "48h"}, Standard 24h). Das Objekt im Bucket bleibt liegen;
+läuft die Paste wieder ab, wird es überschrieben.
+*/
+func (s *Server) handleAdminArchivedRestore(w http.ResponseWriter, r *http.Request) {
+	if !s.coldStoreOn(w, r) {
+		return
+	}
+	var req struct {
+		TTL string `json:"ttl"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, "invalid JSON body")
+		return
+	}
+	ttl := restoreTTL
+	if req.TTL != "" {
+		d, err := util.ParseTTL(req.TTL)
+		if err != nil || d < time.Minute {
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidTTL, "ttl must be a duration of at least 1m")
+			return
+		}
+		ttl = d
+	}
+	id := chi.URLParam(r, "id")
+	if s.Store.Has(id) {
+		writeProblem(w, r, http.StatusConflict, codeAlreadyExists, "a paste with this id exists; delete it first")
+		return
+	}
+	p, err := s.ColdStore.Get(r.Context(), id)
+	if errors.Is(err, blob.ErrNotFound) {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "no archived paste with this id")
+		return
+	}
+	if err != nil {
+		logf(r, "coldstore: %s: %v", id, err)
+		writeProblem(w, r, http.StatusBadGateway, codeArchiveUnavailable, "cannot read the archived paste")
+		return
+	}
+	p.ExpiresAt = time.Now().Add(ttl)
+	s.Store.Put(p)
+	if s.Search != nil && !p.Quarantined {
+		s.Search.Add(p)
+	}
+	s.record(r, audit.ActionAdmin, p.ID, "", "restore from archive")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"id": p.ID, "url": s.makeURL(r, "/p/"+p.ID), "expires_at": p.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+// DELETE /api/admin/archived/{id}: entfernt eine Paste endgültig aus dem Archiv.
+func (s *Server) handleAdminArchivedDelete(w http.ResponseWriter, r *http.Request) {
+	if !s.coldStoreOn(w, r) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if err := s.ColdStore.Delete(r.Context(), id); err != nil {
+		logf(r, "coldstore: %s: %v", id, err)
+		writeProblem(w, r, http.StatusBadGateway, codeArchiveUnavailable, "cannot delete the archived paste")
+		return
+	}
+	s.record(r, audit.ActionDelete, id, "", "admin archive")
+	w.WriteHeader(http.StatusNoContent)
+}
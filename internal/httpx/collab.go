@@ -0,0 +1,232 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/audit"
+	"unglued/internal/model"
+	"unglued/internal/store"
+	"unglued/internal/util"
+)
+
+/*
+Mitbearbeiter-Keys: statt den einen Edit-Link im Team herumzureichen, stellt
+der Besitzer (Haupt-Key) benannte Keys aus. Die dürfen nur editieren – nicht
+löschen, keine Keys verwalten, keine Freigabelinks – und ihr Name steht als
+Autor an jeder Version, die mit ihnen gespeichert wird.
+*/
+
+const maxEditKeys = 20
+
+var keyName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,31}$`)
+
+// editAccess: mit welchem Key der Aufrufer p editieren darf; name leer = Haupt-Key.
+func (s *Server) editAccess(r *http.Request, p model.Paste) (key, name string, ok bool) {
+	if !p.Editable || !s.conf().Features.Edit {
+		return "", "", false
+	}
+	// Links tragen signierte, ablaufende Tokens; das Cookie den eigentlichen Key.
+	if tok := r.URL.Query().Get("key"); tok != "" {
+		return s.matchEditToken(tok, p)
+	}
+	if c, err := r.Cookie("npk_" + p.ID); err == nil && c.Value != "" {
+		if c.Value == p.EditKey {
+			return p.EditKey, "", true
+		}
+		for _, k := range p.EditKeys {
+			if c.Value == k.Key {
+				return k.Key, k.Name, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// matchEditToken: zu welchem Key der Paste passt tok?
+func (s *Server) matchEditToken(tok string, p model.Paste) (key, name string, ok bool) {
+	if s.Auth.VerifyEditToken(tok, p.ID, p.EditKey) {
+		return p.EditKey, "", true
+	}
+	for _, k := range p.EditKeys {
+		if s.Auth.VerifyEditToken(tok, p.ID, k.Key) {
+			return k.Key, k.Name, true
+		}
+	}
+	return "", "", false
+}
+
+// holdsEditKey: Aufrufer hat den Haupt-Key, nicht nur einen Mitbearbeiter-Key.
+func (s *Server) holdsEditKey(r *http.Request, p model.Paste) bool {
+	_, name, ok := s.editAccess(r, p)
+	return ok && name == ""
+}
+
+// ownerKey prüft ?key gegen den Haupt-Key und schreibt sonst das passende Problem.
+func (s *Server) ownerKey(w http.ResponseWriter, r *http.Request, p model.Paste) bool {
+	tok := r.URL.Query().Get("key")
+	if tok == "" {
+		writeProblem(w, r, http.StatusUnauthorized, codeMissingKey, "missing ?key")
+		return false
+	}
+	if !p.Editable || !s.Auth.VerifyEditToken(tok, p.ID, p.EditKey) {
+		writeProblem(w, r, http.StatusForbidden, codeInvalidKey, "invalid or expired edit token")
+		return false
+	}
+	return true
+}
+
+var (
+	errKeyExists   = errors.New("key exists")
+	errTooManyKeys = errors.New("too many keys")
+	errNoSuchKey   = errors.New("no such key")
+)
+
+/*
+updateAsOwner ändert Metadaten über Store.Update und prüft den Besitzer-Token
+dabei am aktuellen Stand (wie ownerKey), damit eine gleichzeitige Key-Rotation
+nicht mit einem alten Stand überschrieben wird.
+*/
+func (s *Server) updateAsOwner(r *http.Request, id string, fn func(p *model.Paste) error) (model.Paste, error) {
+	tok := r.URL.Query().Get("key")
+	return s.Store.Update(id, func(cur *model.Paste) error {
+		if !cur.Editable || !s.Auth.VerifyEditToken(tok, cur.ID, cur.EditKey) {
+			return errEditRevoked
+		}
+		return fn(cur)
+	})
+}
+
+// writeOwnerUpdateError: Antwort auf die Fehler von updateAsOwner, die nicht vom Aufrufer stammen.
+func (s *Server) writeOwnerUpdateError(w http.ResponseWriter, r *http.Request, id string, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		s.apiNotFound(w, r, id)
+	case errors.Is(err, errEditRevoked):
+		writeProblem(w, r, http.StatusForbidden, codeInvalidKey, "invalid or expired edit token")
+	default:
+		logf(r, "update %s: %v", id, err)
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, "saving failed")
+	}
+}
+
+type apiEditKey struct {
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	Key       string `json:"key,omitempty"` // nur direkt nach dem Anlegen
+	EditURL   string `json:"edit_url,omitempty"`
+}
+
+// GET /api/paste/{id}/keys?key=…: Namen der Mitbearbeiter-Keys, ohne die Keys selbst.
+func (s *Server) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.GetMeta(id)
+	if !ok {
+		s.apiNotFound(w, r, id)
+		return
+	}
+	if !s.ownerKey(w, r, p) {
+		return
+	}
+	keys := make([]apiEditKey, 0, len(p.EditKeys))
+	for _, k := range p.EditKeys {
+		keys = append(keys, apiEditKey{Name: k.Name, CreatedAt: k.CreatedAt.Format(time.RFC3339)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"id": p.ID, "keys": keys})
+}
+
+// POST /api/paste/{id}/keys?key=… {"name":"alice"}: neuen Mitbearbeiter-Key ausstellen.
+func (s *Server) handleAPIAddKey(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.Get(id)
+	if !ok {
+		s.apiNotFound(w, r, id)
+		return
+	}
+	if !s.ownerKey(w, r, p) {
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
+		return
+	}
+	if !keyName.MatchString(req.Name) {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, "name must be 1-32 letters, digits, '.', '_' or '-'")
+		return
+	}
+	k := model.EditKey{Name: req.Name, Key: util.NewID(12), CreatedAt: time.Now()}
+	p, err := s.updateAsOwner(r, id, func(cur *model.Paste) error {
+		switch {
+		case slices.ContainsFunc(cur.EditKeys, func(k model.EditKey) bool { return k.Name == req.Name }):
+			return errKeyExists
+		case len(cur.EditKeys) >= maxEditKeys:
+			return errTooManyKeys
+		}
+		cur.EditKeys = append(cur.EditKeys, k) // keine neue Version, nur Metadaten
+		return nil
+	})
+	switch {
+	case errors.Is(err, errKeyExists):
+		writeProblem(w, r, http.StatusConflict, codeAlreadyExists, "a key with this name already exists")
+		return
+	case errors.Is(err, errTooManyKeys):
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, "too many collaborator keys")
+		return
+	case err != nil:
+		s.writeOwnerUpdateError(w, r, id, err)
+		return
+	}
+	s.record(r, audit.ActionEditKeys, p.ID, "", "key.add "+k.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(apiEditKey{
+		Name:      k.Name,
+		CreatedAt: k.CreatedAt.Format(time.RFC3339),
+		Key:       s.Auth.EditToken(p.ID, k.Key, p.ExpiresAt),
+		EditURL:   s.makeURL(r, s.editURLFor(p, k.Key)),
+	})
+}
+
+// DELETE /api/paste/{id}/keys/{name}?key=…: einen Mitbearbeiter-Key widerrufen.
+func (s *Server) handleAPIRevokeKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.Get(id)
+	if !ok {
+		s.apiNotFound(w, r, id)
+		return
+	}
+	if !s.ownerKey(w, r, p) {
+		return
+	}
+	name := chi.URLParam(r, "name")
+	_, err := s.updateAsOwner(r, id, func(cur *model.Paste) error {
+		n := len(cur.EditKeys)
+		cur.EditKeys = slices.DeleteFunc(cur.EditKeys, func(k model.EditKey) bool { return k.Name == name })
+		if len(cur.EditKeys) == n {
+			return errNoSuchKey
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errNoSuchKey):
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "no key with this name")
+		return
+	case err != nil:
+		s.writeOwnerUpdateError(w, r, id, err)
+		return
+	}
+	s.record(r, audit.ActionEditKeys, id, "", "key.revoke "+name)
+	w.WriteHeader(http.StatusNoContent)
+}
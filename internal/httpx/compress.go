@@ -0,0 +1,226 @@
+package httpx
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+)
+
+// CompressConfig für Compress; MinSize 0 = 1 KiB.
+type CompressConfig struct {
+	MinSize int
+}
+
+// Stufen für dynamische Antworten: schnell genug pro Request, deutlich kleiner als ungepackt.
+const (
+	gzipLevel   = 5
+	brotliLevel = 4
+)
+
+var (
+	gzipPool   = sync.Pool{New: func() any { w, _ := gzip.NewWriterLevel(io.Discard, gzipLevel); return w }}
+	brotliPool = sync.Pool{New: func() any { return brotli.NewWriterLevel(io.Discard, brotliLevel) }}
+)
+
+/*
+Compress packt HTML-, JSON-, Text- und ähnliche Antworten mit Brotli oder gzip,
+je nachdem, was der Client in Accept-Encoding anbietet (Brotli bevorzugt).
+Antworten unter MinSize, bereits kodierte, Range-Anfragen und Binärformate
+(Bilder, Archive, …) gehen unverändert raus.
+*/
+func Compress(cfg CompressConfig) func(http.Handler) http.Handler {
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = 1024
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			enc := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if enc == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, enc: enc, min: cfg.MinSize}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding: "br", "gzip" oder "" nach Accept-Encoding (q=0 schließt aus).
+func negotiateEncoding(accept string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		v := 1.0
+		if k, val, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			v, _ = strconv.ParseFloat(strings.TrimSpace(val), 64)
+		}
+		q[strings.ToLower(strings.TrimSpace(name))] = v
+	}
+	best, bestQ := "", 0.0
+	for _, enc := range []string{"br", "gzip"} {
+		v, ok := q[enc]
+		if !ok {
+			v, ok = q["*"]
+		}
+		if ok && v > bestQ {
+			best, bestQ = enc, v
+		}
+	}
+	return best
+}
+
+// compressible: Textformate lohnen sich; Bilder, Archive usw. sind schon gepackt.
+func compressible(ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mt, "text/"),
+		strings.HasSuffix(mt, "+json"), strings.HasSuffix(mt, "+xml"):
+		return true
+	}
+	switch mt {
+	case "application/json", "application/xml", "application/javascript",
+		"application/x-ndjson", "image/svg+xml", "application/wasm":
+		return true
+	}
+	return false
+}
+
+/*
+compressWriter puffert bis min Bytes und entscheidet dann: packen oder
+durchreichen. Kleine Antworten kommen so nie in den Kompressor.
+*/
+type compressWriter struct {
+	http.ResponseWriter
+	enc    string
+	min    int
+	status int
+
+	buf     bytes.Buffer
+	decided bool
+	zw      io.WriteCloser // nil = unverändert durchreichen
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if code >= 100 && code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		w.buf.Write(b)
+		if w.buf.Len() < w.min {
+			return len(b), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.zw != nil {
+		return w.zw.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// decide legt Header und Kodierung fest und schreibt den Puffer.
+func (w *compressWriter) decide() error {
+	w.decided = true
+	h := w.Header()
+	ok := w.status != http.StatusNoContent && w.status != http.StatusNotModified &&
+		h.Get("Content-Encoding") == "" && w.buf.Len() >= w.min && compressible(h.Get("Content-Type"))
+	if compressible(h.Get("Content-Type")) {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if ok {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.enc)
+		// gepackter Body ≠ ungepackter: starke ETags werden schwach
+		if et := h.Get("ETag"); et != "" && !strings.HasPrefix(et, "W/") {
+			h.Set("ETag", "W/"+et)
+		}
+		switch w.enc {
+		case "br":
+			bw := brotliPool.Get().(*brotli.Writer)
+			bw.Reset(w.ResponseWriter)
+			w.zw = bw
+		default:
+			gw := gzipPool.Get().(*gzip.Writer)
+			gw.Reset(w.ResponseWriter)
+			w.zw = gw
+		}
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.zw != nil {
+		_, err = w.zw.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// Flush entscheidet sofort, auch unter min, damit gestreamte Antworten nicht hängen.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.min = 0
+		_ = w.decide()
+	}
+	switch zw := w.zw.(type) {
+	case *gzip.Writer:
+		_ = zw.Flush()
+	case *brotli.Writer:
+		_ = zw.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close schreibt den Rest; ohne Write und WriteHeader bleibt die Antwort unberührt.
+func (w *compressWriter) Close() {
+	if !w.decided {
+		if w.status == 0 {
+			return
+		}
+		_ = w.decide()
+	}
+	switch zw := w.zw.(type) {
+	case *gzip.Writer:
+		_ = zw.Close()
+		zw.Reset(io.Discard)
+		gzipPool.Put(zw)
+	case *brotli.Writer:
+		_ = zw.Close()
+		zw.Reset(io.Discard)
+		brotliPool.Put(zw)
+	}
+	w.zw = nil
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
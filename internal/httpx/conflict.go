@@ -0,0 +1,117 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"unglued/internal/merge"
+	"unglued/internal/model"
+	"unglued/internal/util"
+	"unglued/internal/webhook"
+)
+
+/*
+Gleichzeitige Bearbeitungen: das Formular merkt sich, auf welcher Version es
+aufsetzt. Hat inzwischen jemand anderes gespeichert, wird weder überschrieben
+noch einfach abgelehnt, sondern dreiseitig zusammengeführt und zur Kontrolle
+zurückgegeben.
+*/
+
+type mergeView struct {
+	From, To            int
+	Conflicts           int
+	Base, Yours, Theirs string
+}
+
+type mergeProposal struct {
+	Text      string `json:"text"`
+	Conflicts int    `json:"conflicts"`
+}
+
+// staleMerge: nil, wenn base noch aktuell ist oder sich nichts beißt; sonst das Ergebnis des Zusammenführens.
+func staleMerge(r *http.Request, p model.Paste, base int, yours string) (*merge.Result, string) {
+	if base <= 0 || base >= len(p.Versions) || yours == p.Code {
+		return nil, ""
+	}
+	baseCode, err := util.GzipDecode(p.Versions[base-1].ZCode)
+	if err != nil || baseCode == p.Code {
+		return nil, ""
+	}
+	m := merge.Merge(baseCode, yours, p.Code, tr(r, "deine Änderung"), tr(r, "Version %d", len(p.Versions)))
+	return &m, baseCode
+}
+
+// editConflict zeigt statt des Speicherns die Merge-Seite (409), wenn base veraltet ist.
+func (s *Server) editConflict(w http.ResponseWriter, r *http.Request, p model.Paste, base int, collab, yours, lang string) bool {
+	m, baseCode := staleMerge(r, p, base, yours)
+	if m == nil {
+		return false
+	}
+	data := s.editFormData(r, p, collab)
+	data["Code"] = m.Text
+	data["Lang"] = lang
+	data["Message"] = r.FormValue("message")
+	if collab == "" {
+		data["Author"] = strings.TrimSpace(r.FormValue("author"))
+	}
+	data["Merge"] = mergeView{From: base, To: len(p.Versions), Conflicts: m.Conflicts, Base: baseCode, Yours: yours, Theirs: p.Code}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusConflict)
+	_ = s.tmpl(r).edit.Execute(w, data)
+	return true
+}
+
+// apiEditConflict: dasselbe für die API, als Problem mit Merge-Vorschlag.
+func (s *Server) apiEditConflict(w http.ResponseWriter, r *http.Request, p model.Paste, base int, yours string) bool {
+	m, _ := staleMerge(r, p, base, yours)
+	if m == nil {
+		return false
+	}
+	writeProblemBody(w, r, problem{
+		Status:  http.StatusConflict,
+		Code:    codeEditConflict,
+		Detail:  "the paste changed since your base version; resolve the merge and retry with base set to current_version",
+		Current: len(p.Versions),
+		Merge:   &mergeProposal{Text: m.Text, Conflicts: m.Conflicts},
+	})
+	return true
+}
+
+var (
+	errEditRevoked = errors.New("edit access revoked")
+	errEditStale   = errors.New("paste changed meanwhile")
+	errEditBlocked = errors.New("blocked by moderation")
+)
+
+/*
+commitEdit speichert eine Bearbeitung über Store.Update: Zugriff (allowed) und
+Versionsstand werden am aktuellen Stand erneut geprüft. So schreibt eine
+Bearbeitung, die vor einer Key-Rotation geladen wurde, nie den alten Key zurück.
+base ist die Versionsanzahl, auf der die Prüfungen des Handlers beruhen; ist
+inzwischen eine dazugekommen, gibt es errEditStale. Bei errEditBlocked steht der
+Grund in reason.
+*/
+func (s *Server) commitEdit(r *http.Request, id string, base int, allowed func(model.Paste) bool, e editOpts, now time.Time) (p model.Paste, reason string, err error) {
+	p, err = s.Store.Update(id, func(cur *model.Paste) error {
+		switch {
+		case !allowed(*cur):
+			return errEditRevoked
+		case len(cur.Versions) != base:
+			return errEditStale
+		}
+		s.applyEdit(cur, e, now)
+		var ok bool
+		if reason, ok = s.review(r, webhook.EventEdited, cur); !ok {
+			return errEditBlocked
+		}
+		return nil
+	})
+	if err == nil {
+		s.afterSave(r, webhook.EventEdited, p)
+	}
+	return p, reason, err
+}
@@ -0,0 +1,43 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"unglued/internal/util"
+)
+
+// Werte für Config.CookieSecure.
+const (
+	CookieSecureAuto   = "auto" // Secure, wenn der Request per HTTPS kam (auch via Proxy/-public)
+	CookieSecureAlways = "always"
+	CookieSecureNever  = "never"
+)
+
+/*
+setCookie schreibt Edit-Key-, Autor- und Session-Cookies: immer HttpOnly (kein
+Script braucht sie), Secure je nach Config, SameSite wie konfiguriert (Default Lax).
+*/
+func (s *Server) setCookie(w http.ResponseWriter, r *http.Request, name, value string, life time.Duration) {
+	util.WriteCookieWith(w, s.cookieFlags(r), name, value, life)
+}
+
+func (s *Server) cookieFlags(r *http.Request) util.CookieFlags {
+	f := util.CookieFlags{HttpOnly: true, SameSite: s.Config.CookieSameSite}
+	if f.SameSite == 0 {
+		f.SameSite = http.SameSiteLaxMode
+	}
+	switch s.Config.CookieSecure {
+	case CookieSecureAlways:
+		f.Secure = true
+	case CookieSecureNever:
+	default:
+		f.Secure = strings.HasPrefix(s.makeURL(r, "/"), "https://")
+	}
+	// Browser verwerfen SameSite=None ohne Secure
+	if f.SameSite == http.SameSiteNoneMode {
+		f.Secure = true
+	}
+	return f
+}
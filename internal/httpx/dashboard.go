@@ -0,0 +1,347 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/audit"
+	"unglued/internal/auth"
+	"unglued/internal/model"
+	"unglued/internal/stats"
+	"unglued/internal/store"
+	"unglued/internal/util"
+)
+
+const (
+	adminPerPage = 50
+	adminCSRFTTL = 12 * time.Hour
+)
+
+type adminPasteItem struct {
+	apiListItem
+	Owner       string `json:"owner,omitempty"`
+	Public      bool   `json:"public"`
+	Private     bool   `json:"private,omitempty"`
+	Flagged     bool   `json:"flagged,omitempty"`
+	Quarantined bool   `json:"quarantined,omitempty"`
+}
+
+type adminStats struct {
+	Store       store.Stats `json:"store"`
+	Public      int         `json:"public"`
+	Private     int         `json:"private"`
+	Flagged     int         `json:"flagged"`
+	Quarantined int         `json:"quarantined"`
+	HeapAlloc   uint64      `json:"heap_alloc"`
+	Sys         uint64      `json:"sys"`
+	Goroutines  int         `json:"goroutines"`
+	Uptime      string      `json:"uptime"`
+}
+
+func (s *Server) adminStats() adminStats {
+	st := adminStats{Store: s.Store.Stats(), Goroutines: runtime.NumGoroutine(), Uptime: time.Since(started).Round(time.Second).String()}
+	s.Store.Find(func(p *model.Paste) bool {
+		switch {
+		case p.Quarantined:
+			st.Quarantined++
+		case p.Flagged:
+			st.Flagged++
+		case p.Private:
+			st.Private++
+		case p.Public:
+			st.Public++
+		}
+		return false
+	})
+	st.HeapAlloc, st.Sys = util.MemUsage()
+	return st
+}
+
+/*
+adminPastes: alle aktiven Pastes, gefiltert (?q= auf ID, Titel, Autor, Owner),
+sortiert (?sort=created|expires|size) und seitenweise (?page=, ?per_page=).
+*/
+func (s *Server) adminPastes(r *http.Request) (apiListResp, []adminPasteItem) {
+	q := r.URL.Query()
+	needle := strings.ToLower(strings.TrimSpace(q.Get("q")))
+	ps := s.Store.Find(func(p *model.Paste) bool {
+		if needle == "" {
+			return true
+		}
+		for _, f := range []string{p.ID, p.Title, p.Author, p.Owner} {
+			if strings.Contains(strings.ToLower(f), needle) {
+				return true
+			}
+		}
+		return false
+	})
+	sortBy := q.Get("sort")
+	switch sortBy {
+	case store.SortExpires:
+		sort.SliceStable(ps, func(i, j int) bool { return ps[i].ExpiresAt.Before(ps[j].ExpiresAt) })
+	case "size":
+		sort.SliceStable(ps, func(i, j int) bool { return len(ps[i].Code) > len(ps[j].Code) })
+	default:
+		sortBy = store.SortCreated // Find liefert schon neueste zuerst
+	}
+	page, _ := strconv.Atoi(q.Get("page"))
+	page = max(page, 1)
+	per, _ := strconv.Atoi(q.Get("per_page"))
+	if per < 1 || per > archiveMaxPerPage {
+		per = adminPerPage
+	}
+	resp := apiListResp{Page: page, PerPage: per, Total: len(ps), Sort: sortBy}
+	from := min((page-1)*per, len(ps))
+	to := min(from+per, len(ps))
+	items := make([]adminPasteItem, 0, to-from)
+	for _, p := range ps[from:to] {
+		items = append(items, s.adminItem(r, p))
+	}
+	return resp, items
+}
+
+func (s *Server) adminItem(r *http.Request, p model.Paste) adminPasteItem {
+	return adminPasteItem{
+		apiListItem: s.listItem(r, p),
+		Owner:       p.Owner,
+		Public:      p.Public,
+		Private:     p.Private,
+		Flagged:     p.Flagged,
+		Quarantined: p.Quarantined,
+	}
+}
+
+// GET /api/admin/pastes
+func (s *Server) handleAdminPastes(w http.ResponseWriter, r *http.Request) {
+	resp, items := s.adminPastes(r)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"page": resp.Page, "per_page": resp.PerPage, "total": resp.Total, "sort": resp.Sort, "items": items,
+	})
+}
+
+func (s *Server) adminDelete(r *http.Request, id string) bool {
+	if !s.Store.Delete(id) {
+		return false
+	}
+	if s.Search != nil {
+		s.Search.Remove(id)
+	}
+	s.record(r, audit.ActionDelete, id, "", "admin")
+	s.Stats.Add(stats.Deleted, 1)
+	return true
+}
+
+type adminVersion struct {
+	Version  int    `json:"version"`
+	Lang     string `json:"lang"`
+	Author   string `json:"author,omitempty"`
+	AuthorID string `json:"author_id,omitempty"`
+	At       string `json:"at"`
+	Size     int    `json:"size"`
+
+	Formatted bool   `json:"formatted,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+type adminPasteDetail struct {
+	adminPasteItem
+	Editable         bool           `json:"editable"`
+	Expired          bool           `json:"expired,omitempty"` // in der Schonfrist, per Undelete zurückholbar
+	Redacted         []string       `json:"redacted,omitempty"`
+	FlagReason       string         `json:"flag_reason,omitempty"`
+	QuarantineReason string         `json:"quarantine_reason,omitempty"`
+	History          []adminVersion `json:"history"`
+}
+
+// GET /api/admin/pastes/{id}: Metadaten samt Versionen, ohne Inhalt; auch abgelaufene in der Schonfrist.
+func (s *Server) handleAdminInspect(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.Get(id)
+	expired := false
+	if !ok {
+		if p, ok = s.Store.Expired(id); !ok {
+			writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not found")
+			return
+		}
+		expired = true
+	}
+	out := adminPasteDetail{
+		adminPasteItem:   s.adminItem(r, p),
+		Editable:         p.Editable,
+		Expired:          expired,
+		Redacted:         p.Redacted,
+		FlagReason:       p.FlagReason,
+		QuarantineReason: p.QuarantineReason,
+		History:          make([]adminVersion, 0, len(p.Versions)),
+	}
+	for i, v := range p.Versions {
+		code, _ := util.GzipDecode(v.ZCode)
+		out.History = append(out.History, adminVersion{
+			Version: i + 1, Lang: v.Lang, Author: v.Author, AuthorID: v.AuthorID,
+			At: v.At.UTC().Format(time.RFC3339), Size: len(code), Formatted: v.Formatted,
+			Message: v.Message,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// POST /api/admin/purge-expired: abgelaufene Pastes sofort entfernen statt nach der Schonfrist.
+func (s *Server) handleAdminPurgeExpired(w http.ResponseWriter, r *http.Request) {
+	n := s.Store.PurgeExpired()
+	s.record(r, audit.ActionAdmin, "", "", "purge-expired "+strconv.Itoa(n)+" pastes")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"purged": n})
+}
+
+// DELETE /api/admin/pastes/{id}
+func (s *Server) handleAdminDelete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !s.adminDelete(r, id) {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+setExpiry: ttl ab jetzt ("48h", "30m") oder expiresAt (RFC 3339). Verkürzen
+geht bis auf eine Minute; wer sofort weg will, löscht.
+*/
+func (s *Server) setExpiry(r *http.Request, id, ttl, expiresAt string) (model.Paste, error) {
+	p, ok := s.Store.Get(id)
+	if !ok {
+		return model.Paste{}, errNotFound
+	}
+	var until time.Time
+	if expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return model.Paste{}, errInvalidTTL
+		}
+		until = t
+	} else {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return model.Paste{}, errInvalidTTL
+		}
+		until = time.Now().Add(d)
+	}
+	if time.Until(until) < time.Minute {
+		return model.Paste{}, errInvalidTTL
+	}
+	old := p.ExpiresAt
+	p.ExpiresAt = until
+	s.Store.Put(p)
+	s.record(r, audit.ActionAdmin, id, "", "expiry "+old.UTC().Format(time.RFC3339)+" -> "+until.UTC().Format(time.RFC3339))
+	return p, nil
+}
+
+// POST /api/admin/pastes/{id}/expiry {"ttl":"72h"} oder {"expires_at":"2030-01-01T00:00:00Z"}
+func (s *Server) handleAdminExpiry(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TTL       string `json:"ttl"`
+		ExpiresAt string `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, "invalid JSON body")
+		return
+	}
+	p, err := s.setExpiry(r, chi.URLParam(r, "id"), req.TTL, req.ExpiresAt)
+	switch {
+	case err == errNotFound:
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not found")
+		return
+	case err != nil:
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidTTL, "ttl must be a duration of at least 1m, or expires_at an RFC 3339 time at least 1m ahead")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": p.ID, "expires_at": p.ExpiresAt.Format(time.RFC3339)})
+}
+
+/* ---------- HTML ---------- */
+
+// Basic-Auth schickt der Browser bei jedem Request mit – daher CSRF-Token in allen Formularen.
+func (s *Server) adminCSRF(r *http.Request) string {
+	a, _ := auth.AdminFrom(r.Context())
+	tok, _ := s.Auth.Seal("admincsrf", a.Name, adminCSRFTTL)
+	return tok
+}
+
+func (s *Server) checkAdminCSRF(w http.ResponseWriter, r *http.Request) bool {
+	a, _ := auth.AdminFrom(r.Context())
+	var name string
+	if !s.Auth.Open("admincsrf", r.FormValue("csrf"), &name) || name != a.Name {
+		httpError(w, r, "Formular abgelaufen – bitte Seite neu laden.", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// GET /admin
+func (s *Server) handleAdminDashboard(w http.ResponseWriter, r *http.Request) {
+	resp, items := s.adminPastes(r)
+	q := r.URL.Query()
+	st := s.adminStats()
+	trash := s.Store.Trash()
+	shown := make([]trashItem, 0, min(len(trash), adminPerPage))
+	for _, p := range trash[:min(len(trash), adminPerPage)] {
+		shown = append(shown, s.trashItem(r, p))
+	}
+	_ = s.tmpl(r).admin.Execute(w, map[string]any{
+		"Trash":      shown,
+		"TrashTotal": len(trash),
+		"Stats":      st,
+		"StoreBytes": util.HumanBytes(uint64(st.Store.Bytes)),
+		"HeapAlloc":  util.HumanBytes(st.HeapAlloc),
+		"Sys":        util.HumanBytes(st.Sys),
+		"Items":      items,
+		"Page":       resp,
+		"Pages":      max((resp.Total+resp.PerPage-1)/resp.PerPage, 1),
+		"Q":          q.Get("q"),
+		"Sort":       resp.Sort,
+		"Msg":        q.Get("msg"),
+		"CSRF":       s.adminCSRF(r),
+	})
+}
+
+// POST /admin/pastes/{id}/delete
+func (s *Server) handleAdminDeleteForm(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAdminCSRF(w, r) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	msg := tr(r, "Paste %s gelöscht.", id)
+	if !s.adminDelete(r, id) {
+		msg = tr(r, "Paste %s nicht gefunden.", id)
+	}
+	http.Redirect(w, r, "/admin?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// POST /admin/pastes/{id}/expiry (ttl=72h)
+func (s *Server) handleAdminExpiryForm(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAdminCSRF(w, r) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	msg := ""
+	switch p, err := s.setExpiry(r, id, strings.TrimSpace(r.FormValue("ttl")), ""); {
+	case err == errNotFound:
+		msg = tr(r, "Paste %s nicht gefunden.", id)
+	case err != nil:
+		msg = tr(r, "Ungültige Laufzeit (z.B. 30m, 48h; mindestens 1m).")
+	default:
+		msg = tr(r, "Paste %s läuft jetzt ab: %s", id, p.ExpiresAt.Format("2006-01-02 15:04"))
+	}
+	http.Redirect(w, r, "/admin?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
@@ -0,0 +1,79 @@
+package httpx
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/auth"
+)
+
+var started = time.Now()
+
+/*
+DebugHandler: pprof, expvar und Laufzeit-/Store-Statistiken für einen eigenen,
+internen Listener (-debug-listen). Nur mit Admin-Login (und ggf. zweitem Faktor).
+*/
+func DebugHandler(s *Server) http.Handler {
+	r := chi.NewRouter()
+	r.Use(RequestID, auth.RequireAdmin(s.Config.Admin, denyAdmin), s.require2FA)
+	r.Get("/debug/runtime", s.handleDebugRuntime)
+	r.HandleFunc("/debug/vars", handleDebugVars)
+	r.HandleFunc("/debug/pprof/", pprof.Index)
+	// cmdline bewusst nicht: Flags können Tokens und Schlüssel enthalten
+	r.HandleFunc("/debug/pprof/cmdline", http.NotFound)
+	r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	r.Handle("/debug/pprof/{name}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pprof.Handler(chi.URLParam(r, "name")).ServeHTTP(w, r)
+	}))
+	return r
+}
+
+// handleDebugVars: wie expvar.Handler, aber ohne cmdline (s.o.)
+func handleDebugVars(w http.ResponseWriter, r *http.Request) {
+	vars := map[string]json.RawMessage{}
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key != "cmdline" {
+			vars[kv.Key] = json.RawMessage(kv.Value.String())
+		}
+	})
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(vars)
+}
+
+// GET /debug/runtime: Heap, GC, Goroutinen und Store-Größe auf einen Blick
+func (s *Server) handleDebugRuntime(w http.ResponseWriter, r *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	out := map[string]any{
+		"uptime_seconds": int(time.Since(started).Seconds()),
+		"goroutines":     runtime.NumGoroutine(),
+		"go_version":     runtime.Version(),
+		"memory": map[string]any{
+			"heap_alloc":    m.HeapAlloc,
+			"heap_inuse":    m.HeapInuse,
+			"heap_objects":  m.HeapObjects,
+			"sys":           m.Sys,
+			"total_alloc":   m.TotalAlloc,
+			"num_gc":        m.NumGC,
+			"last_gc":       time.Unix(0, int64(m.LastGC)).UTC(),
+			"gc_pause_last": time.Duration(m.PauseNs[(m.NumGC+255)%256]).String(),
+		},
+		"store": s.Store.Stats(),
+	}
+	if s.Search != nil {
+		docs, terms := s.Search.Len()
+		out["search"] = map[string]int{"docs": docs, "terms": terms}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(out)
+}
@@ -0,0 +1,49 @@
+package httpx
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+/*
+Dev-Modus (Config.DevDir gesetzt): Templates und static/ kommen bei jedem Request
+frisch aus dem Quellverzeichnis, HTTP-Caching ist aus. Nur zum Arbeiten an der UI.
+*/
+
+// devTemplates parst TemplateDir bzw. DevDir/templates neu; bei Fehlern gilt der geladene Stand.
+func (s *Server) devTemplates(lang string) (templateSet, bool) {
+	dir := s.conf().TemplateDir
+	if dir == "" {
+		dir = filepath.Join(s.Config.DevDir, "templates")
+	}
+	set, err := s.parseTemplates(dir)
+	if err != nil {
+		log.Printf("dev: templates: %v", err)
+		return templateSet{}, false
+	}
+	return localizeAs(set, lang), true
+}
+
+// devStatic liefert DevDir/static von der Platte; nil, wenn es das Verzeichnis nicht gibt.
+func (s *Server) devStatic() http.Handler {
+	dir := filepath.Join(s.Config.DevDir, "static")
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return nil
+	}
+	return http.StripPrefix("/static/", http.FileServer(http.Dir(dir)))
+}
+
+// noCache: im Dev-Modus kein Browser-Cache und keine 304 auf alte Stände.
+func (s *Server) noCache(next http.Handler) http.Handler {
+	if s.Config.DevDir == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("If-Modified-Since")
+		r.Header.Del("If-None-Match")
+		w.Header().Set("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
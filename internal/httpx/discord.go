@@ -0,0 +1,87 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"unglued/internal/discord"
+)
+
+/*
+POST /api/integrations/discord: Interactions-Endpunkt der Discord-App (Ed25519-signiert).
+"/paste code:<…>" legt eine Paste an; der Nachrichten-Befehl wandelt eine vorhandene
+Nachricht um und ersetzt sie mit Bot-Token durch Link und Vorschau.
+*/
+func (s *Server) handleDiscord(w http.ResponseWriter, r *http.Request) {
+	if s.Discord == nil {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "Discord integration is disabled")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if !s.Discord.Verify(r.Header, body) {
+		writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, "invalid request signature")
+		return
+	}
+	var in discord.Interaction
+	if err := json.Unmarshal(body, &in); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
+		return
+	}
+	var res discord.Response
+	switch in.Type {
+	case discord.TypePing:
+		res = discord.Response{Type: discord.ResponsePong}
+	case discord.TypeCommand:
+		res = s.discordCommand(r, in)
+	default:
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, "unsupported interaction type")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *Server) discordCommand(r *http.Request, in discord.Interaction) discord.Response {
+	if in.Data.Type != discord.CommandMessage {
+		p, err := s.chatPaste(r, in.Option("code"), in.Caller(), "discord")
+		if err != nil {
+			return discord.Reply(err.Error(), true)
+		}
+		return discord.Reply(s.chatReplacement(r, p), false)
+	}
+	msg, ok := in.Data.Resolved.Messages[in.Data.TargetID]
+	if !ok {
+		return discord.Reply("message not found", true)
+	}
+	author := msg.Author.Name()
+	if author == "" {
+		author = in.Caller()
+	}
+	p, err := s.chatPaste(r, msg.Content, author, "discord")
+	if err != nil {
+		return discord.Reply(err.Error(), true)
+	}
+	if s.Discord.CanDelete() {
+		// erst antworten, dann löschen: Discord wartet höchstens 3 s
+		go func(channel, id string) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := s.Discord.DeleteMessage(ctx, channel, id); err != nil {
+				log.Printf("discord: %v", err)
+			}
+		}(in.ChannelID, msg.ID)
+	}
+	return discord.Reply(s.chatReplacement(r, p), false)
+}
@@ -0,0 +1,102 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/errreport"
+)
+
+// causes sammelt, was logf während eines Requests schreibt; das ist bei 5xx die Ursache.
+type causes struct {
+	mu   sync.Mutex
+	list []string
+	shed bool // 503 wegen Last (writeBusy): gewollt, kein Fehler
+}
+
+const maxCauses, maxCauseLen = 5, 500
+
+func noteCause(ctx context.Context, msg string) {
+	c, _ := ctx.Value(causesKey).(*causes)
+	if c == nil {
+		return
+	}
+	if len(msg) > maxCauseLen {
+		msg = msg[:maxCauseLen] + "…"
+	}
+	c.mu.Lock()
+	if len(c.list) < maxCauses {
+		c.list = append(c.list, msg)
+	}
+	c.mu.Unlock()
+}
+
+// noteShed: diese Antwort ist eine bewusste Abweisung unter Last und wird nicht gemeldet.
+func noteShed(ctx context.Context) {
+	if c, _ := ctx.Value(causesKey).(*causes); c != nil {
+		c.mu.Lock()
+		c.shed = true
+		c.mu.Unlock()
+	}
+}
+
+/*
+reportErrors meldet Panics und Antworten ab 500 an s.Errors. Panics werden
+hier abgefangen und als 500 beantwortet, statt die Verbindung abzubrechen.
+*/
+func (s *Server) reportErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Errors == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		c := &causes{}
+		sw := &statusWriter{ResponseWriter: w}
+		r = r.WithContext(context.WithValue(r.Context(), causesKey, c))
+		defer func() {
+			rec := recover()
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			ev := errreport.Event{Method: r.Method, RequestID: RequestIDFrom(r.Context()), Route: "unmatched"}
+			if rc := chi.RouteContext(r.Context()); rc != nil {
+				if rc.RoutePattern() != "" {
+					ev.Route = rc.RoutePattern()
+				}
+				ev.PasteID = rc.URLParam("id")
+			}
+			switch {
+			case rec != nil:
+				ev.Level, ev.Status = "fatal", http.StatusInternalServerError
+				ev.Message = fmt.Sprintf("panic: %v", rec)
+				ev.Stack = string(debug.Stack())
+				logf(r, "panic: %v", rec)
+				if sw.status == 0 {
+					httpError(sw, r, "Interner Fehler", http.StatusInternalServerError)
+				}
+			case sw.code() >= 500:
+				ev.Status = sw.code()
+				ev.Message = fmt.Sprintf("%d %s", ev.Status, http.StatusText(ev.Status))
+			default:
+				return
+			}
+			c.mu.Lock()
+			ev.Causes = c.list
+			shed := c.shed
+			c.mu.Unlock()
+			if shed && rec == nil {
+				return
+			}
+			if rec == nil && len(ev.Causes) > 0 {
+				ev.Message = ev.Causes[len(ev.Causes)-1]
+			}
+			s.Errors.Capture(ev)
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
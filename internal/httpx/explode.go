@@ -0,0 +1,100 @@
+package httpx
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"unglued/internal/bundle"
+	"unglued/internal/lint"
+	"unglued/internal/model"
+	"unglued/internal/util"
+)
+
+// höchstens so viele Textdateien aus einem hochgeladenen Archiv
+const maxArchiveFiles = 100
+
+var (
+	errNotArchive      = errors.New("Kein .zip- oder .tar.gz-Archiv")
+	errArchiveTooMany  = errors.New("Archiv enthält zu viele Dateien (höchstens 100)")
+	errArchiveTooLarge = errors.New("Archiv ist entpackt zu groß")
+	errArchiveEmpty    = errors.New("Archiv enthält keine Textdateien")
+	errArchiveBroken   = errors.New("Archiv ist beschädigt")
+)
+
+/*
+explodeUpload: liegt im multipart-Feld "archive" ein .zip oder .tar.gz, wird
+es zu einer Multi-File-Paste ausgepackt (siehe bundle). code leer und err nil =
+kein Archiv im Request. lang ist die gemeinsame Sprache der Dateien, sonst plaintext.
+*/
+func (s *Server) explodeUpload(r *http.Request) (code, lang string, err error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return "", "", nil
+	}
+	if err := parseAnyForm(r); err != nil {
+		return "", "", err
+	}
+	if len(r.MultipartForm.File["archive"]) == 0 {
+		return "", "", nil
+	}
+	if !s.conf().Features.Uploads {
+		return "", "", errFeatureDisabled
+	}
+	f, _, err := r.FormFile("archive")
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return "", "", err
+	}
+	res, err := bundle.Explode(b, bundle.Limits{MaxFiles: maxArchiveFiles, MaxBytes: s.conf().MaxPasteBytes})
+	switch {
+	case errors.Is(err, bundle.ErrNotArchive):
+		return "", "", errNotArchive
+	case errors.Is(err, bundle.ErrTooMany):
+		return "", "", errArchiveTooMany
+	case errors.Is(err, bundle.ErrTooLarge):
+		return "", "", errArchiveTooLarge
+	case errors.Is(err, bundle.ErrEmpty):
+		return "", "", errArchiveEmpty
+	case err != nil:
+		logf(r, "archive upload: %v", err)
+		return "", "", errArchiveBroken
+	}
+	if res.Skipped > 0 {
+		logf(r, "archive upload: %d binary files skipped", res.Skipped)
+	}
+	return bundle.Join(res.Files), commonLang(res.Files), nil
+}
+
+// commonLang: Sprache, wenn alle Dateien dieselbe Endung-Sprache haben; sonst plaintext.
+func commonLang(files []bundle.File) string {
+	lang := ""
+	for i, f := range files {
+		l := util.LangForFile(f.Name)
+		if l == "" || (i > 0 && l != lang) {
+			return "plaintext"
+		}
+		lang = l
+	}
+	return lang
+}
+
+/*
+checkVersion: Syntaxprüfung und Inhaltsverzeichnis einer neuen Version. Bei
+Multi-File-Pasten gibt es kein Lint – die Kopfzeilen sind in keiner Sprache gültig.
+*/
+func checkVersion(code, lang string) (*model.Problem, []model.File) {
+	idx := bundle.Index(code)
+	if len(idx) < 2 {
+		return lint.Check(code, lang), nil
+	}
+	files := make([]model.File, len(idx))
+	for i, e := range idx {
+		files[i] = model.File{Name: e.Name, Line: e.Line}
+	}
+	return nil, files
+}
@@ -0,0 +1,45 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+)
+
+/*
+Features schaltet Fähigkeiten pro Instanz ab, z.B. ein öffentliches Archiv nur intern.
+Abgeschaltete Routen antworten 404, die Templates blenden die zugehörigen Elemente aus.
+*/
+type Features struct {
+	Archive bool `json:"archive"` // /archive, GET /api/pastes und "öffentlich" beim Anlegen
+	Search  bool `json:"search"`  // Volltextsuche (Archiv und /api/search)
+	Uploads bool `json:"uploads"` // Datei-Uploads per multipart an POST /
+	Edit    bool `json:"edit"`    // editierbare Pastes und alle Edit-Routen
+	Private bool `json:"private"` // private Pastes mit Freigabelinks
+}
+
+// AllFeatures: alles an, wie vor den Schaltern.
+func AllFeatures() Features {
+	return Features{Archive: true, Search: true, Uploads: true, Edit: true, Private: true}
+}
+
+var errFeatureDisabled = errors.New("Funktion auf dieser Instanz deaktiviert")
+
+// feature hängt next hinter einen Schalter; aus = 404, als gäbe es die Route nicht.
+func (s *Server) feature(on func(Features) bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if on(s.conf().Features) {
+			next(w, r)
+			return
+		}
+		if isAPIPath(r.URL.Path) {
+			writeProblem(w, r, http.StatusNotFound, codeFeatureDisabled, "this feature is disabled on this instance")
+			return
+		}
+		http.NotFound(w, r)
+	}
+}
+
+func archiveOn(f Features) bool { return f.Archive }
+func searchOn(f Features) bool  { return f.Search }
+func editOn(f Features) bool    { return f.Edit }
+func privateOn(f Features) bool { return f.Private }
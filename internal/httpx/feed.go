@@ -0,0 +1,158 @@
+package httpx
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"unglued/internal/model"
+	"unglued/internal/store"
+	"unglued/internal/util"
+)
+
+const (
+	feedEntries      = 50
+	feedExcerptLines = 10
+	feedExcerptBytes = 1000
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Link       atomLink       `xml:"link"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    atomText       `xml:"summary"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+/*
+GET /feed.atom[?tag=…][&lang=…]: die neuesten öffentlichen Pastes als Atom-Feed,
+optional nur mit einem Tag bzw. einer Sprache. Wie das Archiv nur mit Features.Archive.
+*/
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))
+	lang := util.CanonicalLang(r.URL.Query().Get("lang"))
+	var items []model.Paste
+	if tag == "" && lang == "" {
+		items, _ = s.Store.ListPublic(store.SortCreated, 0, feedEntries)
+	} else {
+		items = s.Store.Find(func(p *model.Paste) bool {
+			return p.Public && (lang == "" || p.Lang == lang) &&
+				(tag == "" || slices.ContainsFunc(p.Tags, func(t string) bool { return strings.EqualFold(t, tag) }))
+		})
+		items = items[:min(len(items), feedEntries)]
+	}
+
+	self := "/feed.atom"
+	title := "unglued"
+	if q := feedQuery(tag, lang); q != "" {
+		self += "?" + q
+	}
+	if tag != "" {
+		title += " – #" + tag
+	}
+	if lang != "" {
+		title += " – " + lang
+	}
+	feed := atomFeed{
+		ID:     s.makeURL(r, self),
+		Title:  title,
+		Author: atomAuthor{Name: "unglued"},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: s.makeURL(r, self)},
+			{Rel: "alternate", Type: "text/html", Href: s.makeURL(r, "/archive")},
+		},
+		Entries: make([]atomEntry, 0, len(items)),
+	}
+	updated := time.Unix(0, 0)
+	for _, p := range items {
+		feed.Entries = append(feed.Entries, s.feedEntry(r, p))
+		if p.UpdatedAt.After(updated) {
+			updated = p.UpdatedAt
+		}
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	serveBody(w, r, "application/atom+xml; charset=utf-8", updated, append([]byte(xml.Header), out...))
+}
+
+func feedQuery(tag, lang string) string {
+	q := url.Values{}
+	if tag != "" {
+		q.Set("tag", tag)
+	}
+	if lang != "" {
+		q.Set("lang", lang)
+	}
+	return q.Encode()
+}
+
+func (s *Server) feedEntry(r *http.Request, p model.Paste) atomEntry {
+	code, lang := p.Code, p.Lang
+	if len(p.Versions) > 0 {
+		last := p.Versions[len(p.Versions)-1]
+		code, _ = util.GzipDecode(last.ZCode)
+		lang = last.Lang
+	}
+	title := p.Title
+	if title == "" {
+		title = p.ID
+	}
+	e := atomEntry{
+		ID:         s.makeURL(r, "/p/"+p.ID),
+		Title:      title + " (" + lang + ")",
+		Updated:    p.UpdatedAt.UTC().Format(time.RFC3339),
+		Published:  p.CreatedAt.UTC().Format(time.RFC3339),
+		Link:       atomLink{Rel: "alternate", Type: "text/html", Href: s.makeURL(r, "/p/"+p.ID)},
+		Categories: []atomCategory{{Term: "lang:" + lang, Label: lang}},
+		Summary:    atomText{Type: "text", Body: chatSnippet(code, feedExcerptLines, feedExcerptBytes)},
+	}
+	if p.Author != "" {
+		e.Author = &atomAuthor{Name: p.Author}
+	}
+	for _, t := range p.Tags {
+		e.Categories = append(e.Categories, atomCategory{Term: t, Label: "#" + t})
+	}
+	return e
+}
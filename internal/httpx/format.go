@@ -0,0 +1,24 @@
+package httpx
+
+import (
+	"strings"
+
+	"unglued/internal/pretty"
+)
+
+/*
+format formatiert code für lang kanonisch. Geht das nicht (andere Sprache,
+Syntaxfehler) oder würde das Ergebnis die Größengrenze sprengen, bleibt code
+unverändert und ok ist false – gespeichert wird trotzdem.
+*/
+func (s *Server) format(code, lang string) (out string, ok bool) {
+	f, err := pretty.Format(code, lang)
+	if err != nil {
+		return code, false
+	}
+	f = strings.TrimSpace(f)
+	if s.checkSize(f) != nil {
+		return code, false
+	}
+	return f, true
+}
@@ -0,0 +1,247 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/audit"
+	"unglued/internal/model"
+	"unglued/internal/stats"
+	"unglued/internal/util"
+)
+
+/*
+expired: Paste id ist abgelaufen, liegt aber noch in der Schonfrist. Private Pastes
+verraten ihren Ablauf nur Key-Inhabern.
+*/
+func (s *Server) expired(r *http.Request, id string) (p model.Paste, canUndelete, ok bool) {
+	p, ok = s.Store.Expired(id)
+	if !ok {
+		return model.Paste{}, false, false
+	}
+	canUndelete = s.canUndelete(r, p)
+	if p.Private && !canUndelete {
+		return model.Paste{}, false, false
+	}
+	return p, canUndelete, true
+}
+
+/*
+canUndelete: Login-Besitzer, Edit-Cookie oder ein Edit-Link, der bis zum Ablauf
+gültig war (Tokens laufen spätestens mit der Paste ab).
+*/
+func (s *Server) canUndelete(r *http.Request, p model.Paste) bool {
+	if u, ok := s.currentUser(r); ok && p.Owner != "" && p.Owner == userID(u) {
+		return true
+	}
+	// wer per Lösch-Link gelöscht hat, darf es mit demselben Link rückgängig machen
+	if tok := r.PostFormValue("delete_key"); tok != "" && !p.DeletedAt.IsZero() {
+		var claim deleteClaim
+		return s.Auth.Open("delete", tok, &claim) && s.deleteClaimOK(claim, p)
+	}
+	if !p.Editable {
+		return false
+	}
+	if tok := r.URL.Query().Get("key"); tok != "" {
+		return s.Auth.VerifyEditTokenAt(tok, p.ID, p.EditKey, p.ExpiresAt.Add(-time.Second))
+	}
+	c, err := r.Cookie("npk_" + p.ID)
+	return err == nil && c.Value != "" && c.Value == p.EditKey
+}
+
+// notFound antwortet 410 für Pastes in der Schonfrist, sonst 404; page = HTML statt Text.
+func (s *Server) notFound(w http.ResponseWriter, r *http.Request, id string, page bool) {
+	p, canUndelete, ok := s.expired(r, id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if !page {
+		msg := "Paste abgelaufen am %s"
+		if !p.DeletedAt.IsZero() {
+			msg = "Paste gelöscht am %s"
+		}
+		http.Error(w, tr(r, msg, p.ExpiresAt.Format("2006-01-02 15:04:05 -0700")), http.StatusGone)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusGone)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_ = s.tmpl(r).gone.Execute(w, map[string]any{
+		"ID":          p.ID,
+		"Title":       p.Title,
+		"Deleted":     !p.DeletedAt.IsZero(),
+		"ExpiredAt":   p.ExpiresAt.Format("2006-01-02 15:04:05 -0700"),
+		"Until":       p.ExpiresAt.Add(s.Store.GraceFor(&p)).Format("2006-01-02 15:04:05 -0700"),
+		"CanUndelete": canUndelete,
+		"Key":         r.URL.Query().Get("key"),
+	})
+}
+
+// apiNotFound: wie notFound, als Problem-JSON.
+func (s *Server) apiNotFound(w http.ResponseWriter, r *http.Request, id string) {
+	p, _, ok := s.expired(r, id)
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not found or expired")
+		return
+	}
+	what := "expired"
+	if !p.DeletedAt.IsZero() {
+		what = "been deleted"
+	}
+	writeProblemBody(w, r, problem{
+		Status:    http.StatusGone,
+		Code:      codeGone,
+		Detail:    "paste has " + what + "; key holders can undelete it until " + p.ExpiresAt.Add(s.Store.GraceFor(&p)).UTC().Format(time.RFC3339),
+		ExpiredAt: p.ExpiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+/*
+undelete gibt p eine neue Laufzeit: ttl wie beim Anlegen, leer = so lange wie
+ursprünglich, höchstens MaxTTL. Eine gelöschte Paste bekommt ohne ttl ihren
+alten Ablauf zurück, sofern der noch aussteht.
+*/
+func (s *Server) undelete(r *http.Request, p model.Paste, ttl string) (model.Paste, error) {
+	now := time.Now()
+	end := p.ExpiresAt
+	if !p.DeletedAt.IsZero() {
+		end = p.DeletedExpiry
+	}
+	if ttl == "" && !p.DeletedAt.IsZero() && end.After(now) {
+		p.ExpiresAt = end
+	} else {
+		dur := end.Sub(p.CreatedAt)
+		if ttl != "" {
+			d, err := util.ParseTTL(ttl)
+			if err != nil || d <= 0 {
+				return p, errInvalidTTL
+			}
+			dur = d
+		}
+		dur, err := s.boundTTL(dur, s.conf().MaxTTL, ttl != "")
+		if err != nil {
+			return p, err
+		}
+		p.ExpiresAt = now.Add(dur)
+	}
+	p.DeletedAt, p.DeletedExpiry = time.Time{}, time.Time{}
+	s.Store.Put(p)
+	if s.Search != nil && !p.Quarantined {
+		s.Search.Add(p)
+	}
+	s.record(r, audit.ActionUndelete, p.ID, "", "expires "+p.ExpiresAt.UTC().Format(time.RFC3339))
+	return p, nil
+}
+
+// POST /p/{id}/undelete (Formular auf der 410-Seite)
+func (s *Server) handleUndelete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, canUndelete, ok := s.expired(r, id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if !canUndelete {
+		httpError(w, r, "Forbidden", http.StatusForbidden)
+		return
+	}
+	p, err := s.undelete(r, p, r.PostFormValue("ttl"))
+	if err != nil {
+		http.Error(w, errText(r, err), http.StatusBadRequest)
+		return
+	}
+	if p.Editable {
+		// der alte Edit-Link ist mit der Paste abgelaufen
+		s.setCookie(w, r, "npk_"+p.ID, p.EditKey, 365*24*time.Hour)
+	}
+	http.Redirect(w, r, "/p/"+p.ID, http.StatusSeeOther)
+}
+
+// POST /api/paste/{id}/undelete?key=…[&ttl=…]
+func (s *Server) handleAPIUndelete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if s.Store.Has(id) {
+		writeProblem(w, r, http.StatusConflict, codeInvalidRequest, "paste has not expired")
+		return
+	}
+	p, canUndelete, ok := s.expired(r, id)
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not found or past its grace period")
+		return
+	}
+	if !canUndelete {
+		writeProblem(w, r, http.StatusForbidden, codeInvalidKey, "invalid edit token")
+		return
+	}
+	p, err := s.undelete(r, p, r.URL.Query().Get("ttl"))
+	if err != nil {
+		writeProblemErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	out := map[string]any{
+		"id":         p.ID,
+		"url":        s.makeURL(r, "/p/"+p.ID),
+		"expires_at": p.ExpiresAt.Format(time.RFC3339),
+	}
+	if p.Editable {
+		// der alte Edit-Link ist mit der Paste abgelaufen
+		out["edit_url"] = s.makeURL(r, s.editURL(p))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// POST /api/admin/pastes/{id}/undelete[?ttl=…]: gelöschte oder abgelaufene Paste in der Schonfrist zurückholen.
+func (s *Server) handleAdminUndelete(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.Store.Expired(chi.URLParam(r, "id"))
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not found or past its grace period")
+		return
+	}
+	p, err := s.undelete(r, p, r.URL.Query().Get("ttl"))
+	if err != nil {
+		writeProblemErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": p.ID, "expires_at": p.ExpiresAt.Format(time.RFC3339)})
+}
+
+/*
+softDelete nimmt p sofort aus dem Verkehr, hält sie aber -delete-grace lang zum
+Wiederherstellen bereit (wie eine abgelaufene Paste); ohne Schonfrist ist sie
+gleich ganz weg.
+*/
+func (s *Server) softDelete(r *http.Request, p model.Paste, via string) {
+	if s.Store.DeleteGrace() <= 0 {
+		s.Store.Delete(p.ID)
+	} else {
+		// atomar, damit ein älterer Stand (etwa mit einem inzwischen rotierten Key) nicht zurückkommt
+		now := time.Now()
+		_, _ = s.Store.Update(p.ID, func(cur *model.Paste) error {
+			cur.DeletedAt, cur.DeletedExpiry, cur.ExpiresAt = now, cur.ExpiresAt, now
+			return nil
+		})
+	}
+	if s.Search != nil {
+		s.Search.Remove(p.ID)
+	}
+	s.record(r, audit.ActionDelete, p.ID, "", via)
+	s.Stats.Add(stats.Deleted, 1)
+}
+
+// restoreUntil: bis wann eine jetzt gelöschte Paste wiederherstellbar ist; leer ohne Schonfrist.
+func (s *Server) restoreUntil() string {
+	d := s.Store.DeleteGrace()
+	if d <= 0 {
+		return ""
+	}
+	return time.Now().Add(d).Format("2006-01-02 15:04:05 -0700")
+}
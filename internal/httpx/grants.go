@@ -0,0 +1,159 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/audit"
+	"unglued/internal/model"
+)
+
+const defaultGrantTTL = 7 * 24 * time.Hour
+
+/*
+Share-Grants: der Besitzer einer privaten Paste gibt sie per signiertem Link
+(?grant=…) ohne Login frei. Das Token bindet Paste-ID und GrantGen; Widerrufen
+zählt GrantGen hoch und macht damit alle bisherigen Links ungültig.
+*/
+type grantClaim struct {
+	ID  string `json:"id"`
+	Gen int    `json:"gen"`
+}
+
+func grantCookie(id string) string { return "npg_" + id }
+
+// grantToken liefert den Grant aus Query oder (nach dem ersten Besuch) aus dem Cookie.
+func grantToken(r *http.Request, id string) string {
+	if g := r.URL.Query().Get("grant"); g != "" {
+		return g
+	}
+	if c, err := r.Cookie(grantCookie(id)); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+func (s *Server) validGrant(tok string, p model.Paste) bool {
+	var c grantClaim
+	return tok != "" && s.Auth.Open("grant", tok, &c) && c.ID == p.ID && c.Gen == p.GrantGen
+}
+
+// ownsPaste: Ersteller per Login oder wer den Edit-Key hat (nicht nur einen Mitbearbeiter-Key).
+func (s *Server) ownsPaste(r *http.Request, p model.Paste) bool {
+	if u, ok := s.currentUser(r); ok && p.Owner != "" && p.Owner == userID(u) {
+		return true
+	}
+	return s.holdsEditKey(r, p)
+}
+
+func (s *Server) issueGrant(p model.Paste, ttl time.Duration) (string, time.Time, error) {
+	if ttl <= 0 {
+		ttl = defaultGrantTTL
+	}
+	if until := time.Until(p.ExpiresAt); ttl > until {
+		ttl = until
+	}
+	tok, err := s.Auth.Seal("grant", grantClaim{ID: p.ID, Gen: p.GrantGen}, ttl)
+	return tok, time.Now().Add(ttl), err
+}
+
+// rememberGrant: ein gültiger ?grant= wird als Cookie gemerkt, damit Raw-/Versions-Links ohne Token funktionieren.
+func (s *Server) rememberGrant(w http.ResponseWriter, r *http.Request, p model.Paste) {
+	if g := r.URL.Query().Get("grant"); g != "" && s.validGrant(g, p) {
+		s.setCookie(w, r, grantCookie(p.ID), g, time.Until(p.ExpiresAt))
+	}
+}
+
+// denyView: ohne Login-Möglichkeit gibt es nichts umzuleiten.
+func (s *Server) denyView(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case isAPIPath(r.URL.Path):
+		writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, "private paste: log in or use a share link")
+	case len(s.Logins) > 0 && r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/p/"):
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+	default:
+		httpError(w, r, "Private Paste – Anmeldung oder Freigabelink erforderlich", http.StatusUnauthorized)
+	}
+}
+
+// POST /api/paste/{id}/grants?ttl=72h (Besitzer: Login oder ?key=)
+func (s *Server) handleAPIGrant(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.Store.Get(chi.URLParam(r, "id"))
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not found or expired")
+		return
+	}
+	if !s.ownsPaste(r, p) {
+		writeProblem(w, r, http.StatusForbidden, codeInvalidKey, "only the owner can share this paste")
+		return
+	}
+	var ttl time.Duration
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidTTL, "invalid ttl")
+			return
+		}
+		ttl = d
+	}
+	tok, until, err := s.issueGrant(p, ttl)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+	s.record(r, audit.ActionShare, p.ID, "", "grant.issue until "+until.UTC().Format(time.RFC3339))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"url":        s.makeURL(r, "/p/"+p.ID+"?grant="+tok),
+		"raw_url":    s.makeURL(r, "/raw/"+p.ID+"?grant="+tok),
+		"expires_at": until.UTC().Format(time.RFC3339),
+	})
+}
+
+// DELETE /api/paste/{id}/grants: alle Freigabelinks widerrufen.
+func (s *Server) handleAPIRevokeGrants(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.Store.Get(chi.URLParam(r, "id"))
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not found or expired")
+		return
+	}
+	if !s.ownsPaste(r, p) {
+		writeProblem(w, r, http.StatusForbidden, codeInvalidKey, "only the owner can revoke share links")
+		return
+	}
+	s.revokeGrants(r, p)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /p/{id}/grants/revoke aus der Paste-Ansicht.
+func (s *Server) handleRevokeGrants(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.Store.Get(chi.URLParam(r, "id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.ownsPaste(r, p) {
+		httpError(w, r, "Forbidden (nur für den Besitzer)", http.StatusForbidden)
+		return
+	}
+	s.revokeGrants(r, p)
+	http.Redirect(w, r, "/p/"+p.ID, http.StatusSeeOther)
+}
+
+func (s *Server) revokeGrants(r *http.Request, p model.Paste) {
+	// atomar, sonst könnte eine gleichzeitige Änderung die alte Generation zurückschreiben
+	if _, err := s.Store.Update(p.ID, func(cur *model.Paste) error {
+		cur.GrantGen++ // keine neue Version, nur Metadaten
+		return nil
+	}); err != nil {
+		logf(r, "revoke grants %s: %v", p.ID, err)
+		return
+	}
+	s.record(r, audit.ActionShare, p.ID, "", "grant.revoke")
+}
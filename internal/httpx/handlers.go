@@ -1,7 +1,9 @@
 package httpx
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -13,10 +15,13 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"unglued/internal/detect"
 	"unglued/internal/model"
 	"unglued/internal/render"
 	"unglued/internal/util"
 	"unglued/internal/secrets"
+	"unglued/internal/store"
+	"unglued/internal/webhook"
 )
 
 /* ======================
@@ -24,6 +29,7 @@ import (
    ====================== */
 
 func (s *Server) normalizeLang(lang string) string {
+	lang = util.CanonicalLang(lang)
 	if !slices.Contains(Langs, lang) {
 		return "plaintext"
 	}
@@ -55,73 +61,282 @@ func orDash(s string) string {
 	return s
 }
 
+// canEditPaste: Haupt-Key oder Mitbearbeiter-Key (siehe collab.go).
 func (s *Server) canEditPaste(r *http.Request, p model.Paste) bool {
-	if !p.Editable {
-		return false
-	}
-	key := r.URL.Query().Get("key")
-	if key == "" {
-		if c, err := r.Cookie("npk_" + p.ID); err == nil {
-			key = c.Value
-		}
-	}
-	return key != "" && key == p.EditKey
+	_, _, ok := s.editAccess(r, p)
+	return ok
+}
+
+// editURL liefert einen frisch signierten Edit-Link (relativ).
+func (s *Server) editURL(p model.Paste) string {
+	return s.editURLFor(p, p.EditKey)
 }
 
-func (s *Server) buildPaste(code, lang, ttl, theme string, editable bool, author string) (model.Paste, error) {
-	code = strings.TrimSpace(code)
+// editURLFor: wie editURL, für einen bestimmten Key (Haupt- oder Mitbearbeiter-Key).
+func (s *Server) editURLFor(p model.Paste, key string) string {
+	return "/p/" + p.ID + "/edit?key=" + s.Auth.EditToken(p.ID, key, p.ExpiresAt)
+}
+
+// pasteOpts bündelt die Eingaben beim Anlegen (Form, API, …).
+type pasteOpts struct {
+	Code, Lang, TTL, Theme, Author string
+	Title                          string
+	Tags                           []string
+	Redacted                       []string // Regeln, die vorab geschwärzt haben
+	Editable, Public, Private      bool
+	Indexable                      bool
+	Format                         bool // vor dem Speichern formatieren (gofmt, JSON, YAML)
+	ReplyTo                        string // geprüft per replyTarget
+	CloneOf                        string // Original bei "Kopie bearbeiten"
+
+	// verifizierte Identität des Erstellers (Login), leer = anonym
+	AuthorID        string
+	Avatar, Profile string
+}
+
+func (s *Server) buildPaste(o pasteOpts) (model.Paste, error) {
+	code := strings.TrimSpace(o.Code)
 	if code == "" {
-		return model.Paste{}, fmt.Errorf("Code darf nicht leer sein")
+		return model.Paste{}, errEmptyCode
+	}
+	if err := s.checkSize(code); err != nil {
+		return model.Paste{}, err
+	}
+	lang := s.normalizeLang(o.Lang)
+	if strings.TrimSpace(o.Lang) == "" {
+		lang = detect.Guess(code, Langs).Lang
 	}
-	lang = s.normalizeLang(lang)
+	formatted := false
+	if o.Format {
+		code, formatted = s.format(code, lang)
+	}
+	lintProblem, files := checkVersion(code, lang)
+	theme := o.Theme
 	if !slices.Contains(Themes, theme) {
 		theme = "dark"
 	}
-	dur, err := util.ParseTTL(ttl)
+	f := s.conf().Features
+	if (o.Editable && !f.Edit) || (o.Private && !f.Private) {
+		return model.Paste{}, errFeatureDisabled
+	}
+	dur, err := util.ParseTTL(o.TTL)
 	if err != nil {
-		return model.Paste{}, fmt.Errorf("Ungültige TTL")
+		return model.Paste{}, errInvalidTTL
+	}
+	max := s.conf().MaxTTL
+	if def, own := s.accountTTLs(o.AuthorID); own > 0 || def > 0 {
+		if own > 0 {
+			max = own
+		}
+		if o.TTL == "" && def > 0 {
+			dur = def
+		}
+	}
+	// ohne Angabe gelten die Grenzen, explizit außerhalb ist ein Fehler
+	if dur, err = s.boundTTL(dur, max, o.TTL != ""); err != nil {
+		return model.Paste{}, err
 	}
 	now := time.Now()
 	id := util.NewID(8)
+	title := strings.TrimSpace(o.Title)
+	if len(title) > 120 {
+		title = title[:120]
+	}
 	p := model.Paste{
 		ID:        id,
+		Title:     title,
+		Tags:      o.Tags,
 		Lang:      lang,
 		Code:      code,
 		Theme:     theme,
 		ExpiresAt: now.Add(dur),
 
-		Editable: editable,
+		Editable: o.Editable,
 		EditKey:  "",
-		Author:   author,
+		Author:   o.Author,
+		Public:   o.Public && !o.Private && f.Archive,
+		Private:  o.Private,
+		Owner:    o.AuthorID,
 
-		Versions:  []model.Version{{ZCode: util.GzipEncode(code), Lang: lang, Author: author, At: now}},
+		Redacted: o.Redacted,
+		ReplyTo:  o.ReplyTo,
+		CloneOf:  o.CloneOf,
+
+		Versions:  []model.Version{{ZCode: util.GzipEncode(code), Lang: lang, Author: o.Author, AuthorID: o.AuthorID, AuthorAvatar: o.Avatar, AuthorURL: o.Profile, At: now, Lint: lintProblem, Formatted: formatted, Files: files}},
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
-	if editable {
+	if o.Editable {
 		p.EditKey = util.NewID(12)
 	}
+	p.Indexable = o.Indexable && p.Public && s.Config.AllowIndexing
 	return p, nil
 }
 
+// editOpts: Eingaben einer Bearbeitung (Form oder API).
+type editOpts struct {
+	Code, Lang       string
+	Author, AuthorID string
+	Avatar, Profile  string
+	Format           bool
+	KeyName          string // Mitbearbeiter-Key, mit dem gespeichert wird
+	Message          string // Änderungsnotiz, siehe versionMessage
+}
+
+// applyEdit hängt eine neue Version an, aber nur, wenn sich Code oder Sprache geändert haben.
+func (s *Server) applyEdit(p *model.Paste, e editOpts, now time.Time) {
+	last := p.Versions[len(p.Versions)-1]
+	prevCode, _ := util.GzipDecode(last.ZCode)
+	formatted := false
+	if e.Format {
+		e.Code, formatted = s.format(e.Code, e.Lang)
+	}
+
+	if e.Code != prevCode || e.Lang != last.Lang {
+		lintProblem, files := checkVersion(e.Code, e.Lang)
+		p.Versions = append(p.Versions, model.Version{
+			ZCode:    util.GzipEncode(e.Code),
+			Lang:     e.Lang,
+			Author:   e.Author,
+			AuthorID: e.AuthorID,
+			At:       now,
+
+			AuthorAvatar: e.Avatar,
+			AuthorURL:    e.Profile,
+
+			Lint:      lintProblem,
+			Formatted: formatted,
+			KeyName:   e.KeyName,
+			Files:     files,
+			Message:   versionMessage(e.Message),
+		})
+		// (optional) Deckeln:
+		// if len(p.Versions) > maxVersions { p.Versions = p.Versions[len(p.Versions)-maxVersions:] }
+	}
+
+	p.Code = e.Code
+	p.Lang = e.Lang
+	if e.Author != "" {
+		p.Author = e.Author
+	}
+	p.UpdatedAt = now
+}
+
+// save legt p im Store ab und stößt Suchindex und Webhooks an.
+func (s *Server) save(r *http.Request, typ string, p model.Paste) {
+	s.Store.Put(p)
+	s.afterSave(r, typ, p)
+}
+
+// afterSave: alles nach dem Speichern – Suchindex, Audit, Webhooks, Beobachter.
+func (s *Server) afterSave(r *http.Request, typ string, p model.Paste) {
+	if s.Search != nil {
+		if p.Quarantined {
+			s.Search.Remove(p.ID)
+		} else {
+			s.Search.Add(p)
+		}
+	}
+	s.recordSave(r, typ, p)
+	s.emit(r, typ, p)
+	s.notifyWatchers(r, typ, p)
+}
+
+// emit schickt ein Webhook-Event für die aktuelle Version von p.
+func (s *Server) emit(r *http.Request, typ string, p model.Paste) {
+	if s.Hooks == nil {
+		return
+	}
+	last := p.Versions[len(p.Versions)-1]
+	s.Hooks.Send(webhook.Event{
+		Type:    typ,
+		ID:      p.ID,
+		URL:     s.makeURL(r, "/p/"+p.ID),
+		Title:   p.Title,
+		Lang:    last.Lang,
+		Author:  last.Author,
+		Version: len(p.Versions),
+		Size:    len(p.Code),
+		Code:    p.Code,
+		Flagged: p.Flagged,
+		At:      p.UpdatedAt,
+	})
+}
+
+// emitExpired: Webhook für eine vom Janitor entfernte Paste. Ohne Request gibt es die URL nur mit -public.
+func (s *Server) emitExpired(p model.Paste) {
+	// gelöschte Pastes sind nicht abgelaufen, nur jetzt endgültig weg
+	if s.Hooks == nil || len(p.Versions) == 0 || !p.DeletedAt.IsZero() {
+		return
+	}
+	last := p.Versions[len(p.Versions)-1]
+	ev := webhook.Event{
+		Type:    webhook.EventExpired,
+		ID:      p.ID,
+		Title:   p.Title,
+		Lang:    last.Lang,
+		Author:  last.Author,
+		Version: len(p.Versions),
+		Size:    len(p.Code),
+		Flagged: p.Flagged,
+		At:      p.ExpiresAt,
+	}
+	if s.Config.PublicBase != "" {
+		ev.URL = s.makeURL(nil, "/p/"+p.ID)
+	}
+	s.Hooks.Send(ev)
+}
+
 /* =============
    API Payloads
    ============= */
 
 type apiReq struct {
-	Code     string `json:"code"`
-	Lang     string `json:"lang"`
-	TTL      string `json:"ttl"`
-	Theme    string `json:"theme"`
-	Editable bool   `json:"editable"`
-	Author   string `json:"author"`
+	Code      string   `json:"code"`
+	Title     string   `json:"title"`
+	Tags      []string `json:"tags"`
+	Lang      string   `json:"lang"`
+	TTL       string   `json:"ttl"`
+	Theme     string   `json:"theme"`
+	Editable  bool     `json:"editable"`
+	Public    bool     `json:"public"`
+	Indexable bool     `json:"indexable"`
+	Redact    bool     `json:"redact"`
+	Private   bool     `json:"private"`
+	Author    string   `json:"author"`
+	Email     string   `json:"email"` // nur für das Avatar, wird nicht gespeichert
+	Format    bool     `json:"format"`
+	ReplyTo   string   `json:"reply_to"`
+	Message   string   `json:"message"` // nur beim Bearbeiten: Änderungsnotiz der neuen Version
+	Base      int      `json:"base"`    // nur beim Bearbeiten: Version, auf der die Änderung aufsetzt; 0 = egal
 }
 type apiResp struct {
-	ID        string `json:"id"`
-	URL       string `json:"url"`
-	RawURL    string `json:"raw_url"`
-	EditURL   string `json:"edit_url,omitempty"`
-	ExpiresAt string `json:"expires_at"`
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	RawURL    string   `json:"raw_url"`
+	EditURL   string   `json:"edit_url,omitempty"`
+	ExpiresAt string   `json:"expires_at"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+	Size      int      `json:"size"`
+	Versions  int      `json:"versions"`
+	Redacted  []string `json:"redacted,omitempty"`
+	Flagged   bool     `json:"flagged,omitempty"`
+
+	VersionSizes []int64 `json:"version_sizes"`
+
+	Quarantined bool `json:"quarantined,omitempty"`
+
+	// Lang: die Sprache, wie sie gespeichert wurde (Aliasse aufgelöst, Unbekanntes = plaintext)
+	Lang string `json:"lang"`
+	// Detected: nur ohne lang – so wurde geraten; falsch geraten korrigiert ein Edit mit lang
+	Detected *detect.Result `json:"detected,omitempty"`
+
+	// Syntaxfehler bei JSON/YAML/TOML; die Paste wird trotzdem angelegt
+	Lint      *model.Problem `json:"lint,omitempty"`
+	Formatted bool           `json:"formatted,omitempty"`
+
+	ReplyTo string `json:"reply_to,omitempty"`
 }
 
 /* ==========
@@ -131,58 +346,123 @@ type apiResp struct {
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	author := readAuthorCookie(r)
 	alloc, sys := util.MemUsage()
-	_ = s.IndexTmpl.Execute(w, map[string]any{
+	user, loggedIn := s.currentUser(r)
+	_ = s.tmpl(r).index.Execute(w, map[string]any{
 		"Langs":  Langs,
 		"Themes": Themes,
 		"Author": author,
 		"Alloc":  util.HumanBytes(alloc),
 		"Sys":    util.HumanBytes(sys),
 		"Count":  s.Store.CountActive(),
+
+		"Login":     len(s.Logins) > 0,
+		"LoggedIn":  loggedIn,
+		"User":      user.Display(),
+		"CanCreate": s.mayCreate(r),
+		"Captcha":   s.captchaFor(r),
+		"Honeypot":  s.conf().Bots.Honeypot,
+		"FormToken": s.formToken(),
+		"Features":  s.conf().Features,
+		"Indexing":  s.Config.AllowIndexing,
+		"Avatars":   s.Config.Avatars != "",
+		"MinTTL":    ttlHint(s.conf().MinTTL),
+		"MaxTTL":    ttlHint(s.conf().MaxTTL),
+		"Reply":     s.replyPrefill(r),
 	})
 }
 
-func writeSecretBlock(w http.ResponseWriter, fs []secrets.Finding) {
+func (s *Server) writeSecretBlock(w http.ResponseWriter, r *http.Request, fs []secrets.Finding) {
+    s.metrics.secretsBlocked(fs)
     w.Header().Set("Content-Type", "text/plain; charset=utf-8")
     w.WriteHeader(http.StatusBadRequest)
-    _, _ = io.WriteString(w, "Blocked: potential secrets detected:\n"+secrets.Brief(fs, 6))
+    _, _ = io.WriteString(w, tr(r, "Blocked: potential secrets detected:")+"\n"+secrets.Brief(fs, 6))
 }
 
 func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
-	if err := parseAnyForm(r); err != nil {
-	http.Error(w, "Bad form", http.StatusBadRequest)
+	if !s.mayCreate(r) {
+		httpError(w, r, "Anmeldung erforderlich", http.StatusUnauthorized)
+		return
+	}
+	if err := parseAnyForm(r); isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	} else if err != nil {
+	httpError(w, r, "Bad form", http.StatusBadRequest)
 	return
 }
+	if !s.checkBots(w, r) || !s.checkCaptcha(w, r) {
+		return
+	}
 
 	code := strings.TrimSpace(r.FormValue("code"))
 	lang := strings.TrimSpace(r.FormValue("lang"))
+	// hochgeladenes Archiv ersetzt das Textfeld
+	if bundled, bundleLang, err := s.explodeUpload(r); err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	} else if bundled != "" {
+		code = bundled
+		if lang == "" || lang == "plaintext" {
+			lang = bundleLang
+		}
+	}
 	ttl := strings.TrimSpace(r.FormValue("ttl"))
 	theme := strings.TrimSpace(r.FormValue("theme"))
 	editable := util.IsTruthy(r.FormValue("editable"))
+	public := util.IsTruthy(r.FormValue("public"))
 	author := strings.TrimSpace(r.FormValue("author"))
-
-if fs := secrets.Scan(code); len(fs) > 0 {
-	writeSecretBlock(w, fs)
-	return
-}
+	title := r.FormValue("title")
+	tags := util.ParseTags(r.FormValue("tags"))
+
+	// redact=true: Secrets schwärzen statt blockieren
+	var redacted []string
+	if util.IsTruthy(r.FormValue("redact")) {
+		code, redacted = secrets.Redact(code)
+	} else if fs := secrets.Scan(code); len(fs) > 0 {
+		s.writeSecretBlock(w, r, fs)
+		return
+	}
 
 
 	if author == "" {
 		author = readAuthorCookie(r)
 	}
+	by := s.identify(r, author, r.FormValue("email"))
+	author = by.Name
+	replyTo, err := s.replyTarget(r, r.FormValue("reply_to"))
+	if err != nil {
+		http.Error(w, errText(r, err), http.StatusBadRequest)
+		return
+	}
 
-	p, err := s.buildPaste(code, lang, ttl, theme, editable, author)
+	p, err := s.buildPaste(pasteOpts{
+		Code: code, Lang: lang, TTL: ttl, Theme: theme, Author: author,
+		Title: title, Tags: tags, Redacted: redacted, AuthorID: by.ID,
+		Avatar: by.Avatar, Profile: by.Profile,
+		Editable: editable, Public: public, Private: util.IsTruthy(r.FormValue("private")),
+		Indexable: util.IsTruthy(r.FormValue("indexable")),
+		Format:    util.IsTruthy(r.FormValue("format")),
+		ReplyTo:   replyTo,
+	})
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, errText(r, err), http.StatusBadRequest)
 		return
 	}
-	s.Store.Put(p)
+	if !s.moderate(w, r, webhook.EventCreated, &p) {
+		return
+	}
+	s.save(r, webhook.EventCreated, p)
 
 	// Cookies
 	if author != "" {
-		util.WriteCookie(w, "np_author", author, 180*24*time.Hour)
+		s.setCookie(w, r, "np_author", author, 180*24*time.Hour)
 	}
 	if p.Editable {
-		util.WriteCookie(w, "npk_"+p.ID, p.EditKey, 365*24*time.Hour)
+		s.setCookie(w, r, "npk_"+p.ID, p.EditKey, 365*24*time.Hour)
 	}
 
 	http.Redirect(w, r, "/p/"+p.ID, http.StatusSeeOther)
@@ -190,21 +470,27 @@ if fs := secrets.Scan(code); len(fs) > 0 {
 
 func (s *Server) handleView(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	p, ok := s.Store.Get(id)
+	p, ok := s.Store.GetMeta(id)
 	if !ok {
-		http.NotFound(w, r)
+		s.notFound(w, r, id, true)
 		return
 	}
-	// Version wählen: default = letzte
-	vParam := strings.TrimSpace(r.URL.Query().Get("v"))
-	vIdx := len(p.Versions) - 1
-	if vParam != "" {
-		if n, err := strconv.Atoi(vParam); err == nil && n >= 1 && n <= len(p.Versions) {
-			vIdx = n - 1
-		}
+	if !s.canView(r, p) {
+		s.denyView(w, r)
+		return
+	}
+	s.rememberGrant(w, r, p)
+	if s.quarantined(w, r, p) {
+		return
+	}
+	s.recordView(r, p)
+	// Version wählen: default = letzte; deren Inhalt holt codeHTML nur, wenn nichts vorgerendert ist
+	vIdx, pinned := versionIndex(r, p)
+	if badVersionPath(r, pinned) {
+		s.notFound(w, r, id, true)
+		return
 	}
 	currVer := p.Versions[vIdx]
-	code, _ := util.GzipDecode(currVer.ZCode)
 	lang := currVer.Lang
 
 	// Theme-Override via ?t=light|dark
@@ -213,98 +499,240 @@ func (s *Server) handleView(w http.ResponseWriter, r *http.Request) {
 		currTheme = tOverride
 	}
 
+	if wantsANSI(r) {
+		s.serveANSI(w, r, p, vIdx, currTheme, pinned)
+		return
+	}
+
 	// Highlights via ?hl=…
 	hlParam := strings.TrimSpace(r.URL.Query().Get("hl"))
 	hlSet := util.ParseHL(hlParam)
 
-	html, err := render.CodeHTML(code, lang, currTheme, hlSet)
+	html, plain, err := s.codeHTML(r.Context(), p, vIdx, currTheme, hlSet)
+	if errors.Is(err, errNotFound) {
+		s.notFound(w, r, id, true)
+		return
+	}
+	if errors.Is(err, errBusy) {
+		writeBusy(w, r)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Renderfehler", http.StatusInternalServerError)
+		logf(r, "render %s: %v", p.ID, err)
+		httpError(w, r, "Renderfehler", http.StatusInternalServerError)
 		return
 	}
+	if l := currVer.Lint; l != nil && l.Line > 0 {
+		html = render.MarkLine(html, l.Line, "lint")
+	}
+	if len(currVer.Files) > 0 {
+		heads := make(map[int]bool, len(currVer.Files))
+		for _, f := range currVer.Files {
+			heads[f.Line] = true
+		}
+		html = render.MarkLinesAs(html, heads, "file")
+	}
 
-	editURL := ""
-	if p.Editable {
-		editURL = "/p/" + p.ID + "/edit?key=" + p.EditKey
+	editKey, collab, canEdit := s.editAccess(r, p)
+	editURL, deleteURL := "", ""
+	if canEdit {
+		editURL = s.editURLFor(p, editKey)
+	}
+	if canEdit && collab == "" {
+		// führt auf die Rückfrage; gelöscht wird erst dort, und nur weich
+		deleteURL, _ = s.deletionURL(r, p)
+	}
+	parent, replies := s.replyLinks(r, p)
+	shareURL := ""
+	if p.Private && s.ownsPaste(r, p) {
+		if tok, _, err := s.issueGrant(p, 0); err == nil {
+			shareURL = s.makeURL(r, "/p/"+p.ID+"?grant="+tok)
+		}
 	}
 	data := map[string]any{
 		"ID":        p.ID,
+		"Title":     p.Title,
+		"Tags":      p.Tags,
+		"Redacted":  p.Redacted,
+		"Flagged":   p.Flagged,
+		"FlagNote":  p.FlagReason,
+		"Quarantine": p.QuarantineReason,
+		"ShareURL":  shareURL,
+		"TagLinks":  s.conf().Features.Archive && s.conf().Features.Search,
 		"Lang":      lang,
 		"Theme":     currTheme,
 		"ExpiresAt": p.ExpiresAt.Format("2006-01-02 15:04:05 -0700"),
+		"Created":   p.CreatedAt.Format("2006-01-02 15:04:05 -0700"),
+		"Updated":   p.UpdatedAt.Format("2006-01-02 15:04:05 -0700"),
+		"Size":      int64(pasteSize(p)),
 		"HTML":      template.HTML(html),
+		"Plain":     plain,
 		"HL":        hlParam,
+		"Lint":      currVer.Lint,
+		"Formatted": currVer.Formatted,
+		"Files":     currVer.Files,
 
 		"HasHistory": len(p.Versions) > 1,
 		"VIndex":     vIdx + 1,
+		"Pinned":     pinned,
 		"VTotal":     len(p.Versions),
 		"VAuthor":    orDash(currVer.Author),
+		"VVerified":  currVer.AuthorID != "",
+		"VAvatar":    currVer.AuthorAvatar,
+		"VProfile":   currVer.AuthorURL,
 		"VTime":      currVer.At.Format("2006-01-02 15:04:05 -0700"),
+		"VSize":      currVer.Size,
+		"VMessage":   currVer.Message,
+		"Versions":   p.Versions,
 
 		"Editable": p.Editable,
-		"CanEdit":  s.canEditPaste(r, p),
+		"CanEdit":  canEdit,
 		"EditURL":  editURL,
+		"DeleteURL": deleteURL,
+		"Snippets":  s.shellSnippets(r, p, vIdx, pinned),
+		"ReplyTo":   parent,
+		"CloneOf":   s.cloneOrigin(r, p),
+		"CloneURL":  s.cloneURL(r, p, canEdit, pinned, vIdx),
+		"AccessURL": s.accessURL(r, p),
+		"Replies":   replies,
+
+		"Playground": s.playgroundOK(lang, p.Private),
+		"Indexable":  s.indexable(p),
+	}
+	var buf bytes.Buffer
+	if err := s.tmpl(r).view.Execute(&buf, data); err != nil {
+		logf(r, "view template: %v", err)
+		httpError(w, r, "Renderfehler", http.StatusInternalServerError)
+		return
+	}
+	s.setPageCacheHeaders(w, p)
+	if s.indexable(p) {
+		w.Header().Set("X-Robots-Tag", "index, follow")
 	}
-	_ = s.ViewTmpl.Execute(w, data)
+	w.Header().Add("Vary", "Accept, Accept-Language, Cookie")
+	// neue Antworten ändern die Seite, ohne dass es eine neue Version gibt
+	mod := currVer.At
+	if len(replies) > 0 && replies[0].CreatedAt.After(mod) {
+		mod = replies[0].CreatedAt
+	}
+	serveBody(w, r, "text/html; charset=utf-8", mod, buf.Bytes())
 }
 
 func (s *Server) handleRaw(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	p, ok := s.Store.Get(id)
+	p, ok := s.Store.GetMeta(id)
 	if !ok {
-		http.NotFound(w, r)
+		s.notFound(w, r, id, false)
 		return
 	}
-	// letzte Version
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	if len(p.Versions) > 0 {
-		sText, _ := util.GzipDecode(p.Versions[len(p.Versions)-1].ZCode)
-		_, _ = io.WriteString(w, sText)
+	if !s.canView(r, p) {
+		s.denyView(w, r)
+		return
+	}
+	s.rememberGrant(w, r, p)
+	if s.quarantined(w, r, p) {
+		return
+	}
+	s.recordView(r, p)
+	// default = letzte Version, /raw/{id}/v/{n} oder ?v=N für einen Permalink
+	vIdx, pinned := versionIndex(r, p)
+	if badVersionPath(r, pinned) {
+		s.notFound(w, r, id, false)
+		return
+	}
+	if len(p.Versions) == 0 {
+		p, _ = s.Store.Get(id)
+		s.setCacheHeaders(w, p, false)
+		serveBody(w, r, rawContentType(r, p.Lang), p.UpdatedAt, []byte(p.Code))
 		return
 	}
-	_, _ = io.WriteString(w, p.Code)
+	_, ver, ok := s.Store.GetVersion(id, vIdx)
+	if !ok {
+		s.notFound(w, r, id, false)
+		return
+	}
+	// gestreamt statt ganz entpackt: große Pastes belegen pro Request nur das gzip-Fenster
+	body, err := util.NewGzipSeeker(ver.ZCode)
+	if err != nil {
+		logf(r, "raw %s: %v", p.ID, err)
+		httpError(w, r, "Paste konnte nicht entpackt werden", http.StatusInternalServerError)
+		return
+	}
+	s.setCacheHeaders(w, p, pinned)
+	w.Header().Set("Content-Type", rawContentType(r, ver.Lang))
+	http.ServeContent(w, r, "", ver.At, body)
+}
+
+// rawContentType: text/plain, außer mit ?ct=auto – dann passend zur Sprache (JSON für jq & Co.)
+func rawContentType(r *http.Request, lang string) string {
+	if r.URL.Query().Get("ct") != "auto" {
+		return "text/plain; charset=utf-8"
+	}
+	return util.MIMEForLang(lang)
 }
 
 func (s *Server) handleEditForm(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	p, ok := s.Store.Get(id)
 	if !ok {
-		http.NotFound(w, r)
+		s.notFound(w, r, id, true)
+		return
+	}
+	_, collab, ok := s.editAccess(r, p)
+	if !ok && s.cloneable(r, p) {
+		s.cloneForm(w, r, p)
 		return
 	}
-	if !s.canEditPaste(r, p) {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+	if !ok {
+		httpError(w, r, "Forbidden", http.StatusForbidden)
 		return
 	}
 
+	_ = s.tmpl(r).edit.Execute(w, s.editFormData(r, p, collab))
+}
+
+// editFormData: Daten fürs Edit-Formular mit der aktuellen Fassung; Base merkt sich, worauf die Bearbeitung aufsetzt.
+func (s *Server) editFormData(r *http.Request, p model.Paste, collab string) map[string]any {
 	curr := p.Versions[len(p.Versions)-1]
 	code, _ := util.GzipDecode(curr.ZCode)
 	author := readAuthorCookie(r)
 	if author == "" {
 		author = p.Author
 	}
+	if collab != "" {
+		author = collab
+	}
 	key := r.URL.Query().Get("key")
+	_, loggedIn := s.currentUser(r)
 
-	_ = s.EditTmpl.Execute(w, map[string]any{
-		"ID": id, "Code": code, "Langs": Langs, "Lang": curr.Lang,
+	return map[string]any{
+		"ID": p.ID, "Code": code, "Langs": Langs, "Lang": curr.Lang,
 		"Author": author,
 		"Key":    key,
-	})
+		"Collab": collab,
+		"Base":   len(p.Versions),
+
+		"Avatars": s.Config.Avatars != "" && !loggedIn,
+	}
 }
 
 func (s *Server) handleEditSave(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	p, ok := s.Store.Get(id)
 	if !ok {
-		http.NotFound(w, r)
+		s.notFound(w, r, id, true)
 		return
 	}
-	if !s.canEditPaste(r, p) {
-		http.Error(w, "Forbidden (kein Edit-Zugriff)", http.StatusForbidden)
+	editKey, collab, ok := s.editAccess(r, p)
+	if !ok {
+		httpError(w, r, "Forbidden (kein Edit-Zugriff)", http.StatusForbidden)
 		return
 	}
-	if err := parseAnyForm(r); err != nil {
-	http.Error(w, "Bad form", http.StatusBadRequest)
+	if err := parseAnyForm(r); isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	} else if err != nil {
+	httpError(w, r, "Bad form", http.StatusBadRequest)
 	return
 }
 
@@ -314,96 +742,168 @@ func (s *Server) handleEditSave(w http.ResponseWriter, r *http.Request) {
 	author := strings.TrimSpace(r.FormValue("author"))
 
 if fs := secrets.Scan(code); len(fs) > 0 {
-	writeSecretBlock(w, fs)
+	s.writeSecretBlock(w, r, fs)
 	return
 }
 
 
 	if code == "" {
-		http.Error(w, "Code darf nicht leer sein", http.StatusBadRequest)
+		httpError(w, r, "Code darf nicht leer sein", http.StatusBadRequest)
 		return
 	}
-
-	last := p.Versions[len(p.Versions)-1]
-	prevCode, _ := util.GzipDecode(last.ZCode)
-
-	// nur neue Version, wenn sich etwas geändert hat
-	if code != prevCode || lang != last.Lang {
-		p.Versions = append(p.Versions, model.Version{
-			ZCode:  util.GzipEncode(code),
-			Lang:   lang,
-			Author: author,
-			At:     now,
-		})
-		// (optional) Deckeln:
-		// if len(p.Versions) > maxVersions { p.Versions = p.Versions[len(p.Versions)-maxVersions:] }
+	if s.checkSize(code) != nil {
+		s.writeTooLarge(w, r)
+		return
 	}
 
-	p.Code = code
-	p.Lang = lang
-	if author != "" {
-		p.Author = author
+	by := s.identify(r, author, r.FormValue("email"))
+	author = by.Name
+	if collab != "" && by.ID == "" {
+		author = collab
+	}
+	// inzwischen hat jemand anderes gespeichert: zusammenführen lassen statt überschreiben
+	if base, err := strconv.Atoi(r.FormValue("base")); err == nil && s.editConflict(w, r, p, base, collab, code, lang) {
+		return
+	}
+	base := len(p.Versions)
+	p, reason, err := s.commitEdit(r, id, base, func(cur model.Paste) bool {
+		_, c, ok := s.editAccess(r, cur)
+		return ok && c == collab
+	}, editOpts{Code: code, Lang: lang, Author: author, AuthorID: by.ID, Avatar: by.Avatar, Profile: by.Profile,
+		Format: util.IsTruthy(r.FormValue("format")), KeyName: collab, Message: r.FormValue("message")}, now)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		s.notFound(w, r, id, true)
+		return
+	case errors.Is(err, errEditRevoked):
+		httpError(w, r, "Forbidden (kein Edit-Zugriff)", http.StatusForbidden)
+		return
+	case errors.Is(err, errEditStale):
+		if !s.editConflict(w, r, p, base, collab, code, lang) {
+			httpError(w, r, "Die Paste wurde inzwischen geändert – bitte neu laden.", http.StatusConflict)
+		}
+		return
+	case errors.Is(err, errEditBlocked):
+		writeBlocked(w, r, reason)
+		return
+	case err != nil:
+		logf(r, "edit %s: %v", id, err)
+		httpError(w, r, "Speichern fehlgeschlagen", http.StatusInternalServerError)
+		return
 	}
-	p.UpdatedAt = now
-	s.Store.Put(p)
 
 	// Cookies
 	if author != "" {
-		util.WriteCookie(w, "np_author", author, 180*24*time.Hour)
+		s.setCookie(w, r, "np_author", author, 180*24*time.Hour)
 	}
-	if k := r.URL.Query().Get("key"); k != "" && k == p.EditKey {
-		util.WriteCookie(w, "npk_"+p.ID, p.EditKey, 365*24*time.Hour)
+	if r.URL.Query().Get("key") != "" {
+		s.setCookie(w, r, "npk_"+p.ID, editKey, 365*24*time.Hour)
 	}
 
-	http.Redirect(w, r, "/p/"+p.ID+"?v="+strconv.Itoa(len(p.Versions)), http.StatusSeeOther)
+	http.Redirect(w, r, versionPath("/p", p.ID, len(p.Versions)), http.StatusSeeOther)
 }
 
 func (s *Server) handleAPIPaste(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
+	if !s.mayCreate(r) {
+		writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, "login required to create pastes")
+		return
+	}
 
 	ct := r.Header.Get("Content-Type")
 	accept := r.Header.Get("Accept")
 
-	var code, lang, ttl, theme, author string
-	var editable bool
+	var code, lang, ttl, theme, author, email, title, replyTo string
+	var tags []string
+	var editable, public, redact, private, indexable, format bool
 
-	body, _ := io.ReadAll(r.Body)
+	body, err := io.ReadAll(r.Body)
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
 	if strings.HasPrefix(ct, "application/json") ||
 		(len(body) > 0 && bytesHasJSONPrefix(body)) {
 		var req apiReq
 		if err := json.Unmarshal(body, &req); err != nil {
-			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
 			return
 		}
 		code, lang, ttl, theme = req.Code, req.Lang, req.TTL, req.Theme
-		editable, author = req.Editable, strings.TrimSpace(req.Author)
+		editable, public, redact, author = req.Editable, req.Public, req.Redact, strings.TrimSpace(req.Author)
+		email = req.Email
+		private, indexable, format = req.Private, req.Indexable, req.Format
+		title, tags = req.Title, util.ParseTags(strings.Join(req.Tags, ","))
+		replyTo = req.ReplyTo
 	} else {
 		code = string(body)
 		lang = r.URL.Query().Get("lang")
 		ttl = r.URL.Query().Get("ttl")
 		theme = r.URL.Query().Get("theme")
 		editable = util.IsTruthy(r.URL.Query().Get("editable"))
+		public = util.IsTruthy(r.URL.Query().Get("public"))
+		redact = util.IsTruthy(r.URL.Query().Get("redact"))
+		private = util.IsTruthy(r.URL.Query().Get("private"))
+		indexable = util.IsTruthy(r.URL.Query().Get("indexable"))
+		// ?format=json wählt weiter die Antwort; nur true/1/yes formatiert
+		format = util.IsTruthy(r.URL.Query().Get("format"))
+		title = r.URL.Query().Get("title")
+		tags = util.ParseTags(r.URL.Query().Get("tags"))
 		author = strings.TrimSpace(r.URL.Query().Get("author"))
+		email = r.URL.Query().Get("email")
+		replyTo = r.URL.Query().Get("reply_to")
 	}
 
-	p, err := s.buildPaste(code, lang, ttl, theme, editable, author)
+	var redacted []string
+	if redact {
+		code, redacted = secrets.Redact(code)
+	}
+
+	by := s.identify(r, author, email)
+	if spoofedAuthor(w, r, by, author) {
+		return
+	}
+	author = by.Name
+	if replyTo, err = s.replyTarget(r, replyTo); err != nil {
+		writeProblemErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	var guessed *detect.Result
+	if strings.TrimSpace(lang) == "" {
+		g := detect.Guess(code, Langs)
+		guessed, lang = &g, g.Lang
+	}
+	p, err := s.buildPaste(pasteOpts{
+		Code: code, Lang: lang, TTL: ttl, Theme: theme, Author: author,
+		Title: title, Tags: tags, Redacted: redacted, AuthorID: by.ID,
+		Avatar: by.Avatar, Profile: by.Profile,
+		Editable: editable, Public: public, Private: private, Indexable: indexable,
+		Format: format, ReplyTo: replyTo,
+	})
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblemErr(w, r, http.StatusBadRequest, err)
 		return
 	}
-	s.Store.Put(p)
+	if !s.moderate(w, r, webhook.EventCreated, &p) {
+		return
+	}
+	s.save(r, webhook.EventCreated, p)
 
 	// Cookies
 	if author != "" {
-		util.WriteCookie(w, "np_author", author, 180*24*time.Hour)
+		s.setCookie(w, r, "np_author", author, 180*24*time.Hour)
 	}
 
 	url := s.makeURL(r, "/p/"+p.ID)
 	raw := s.makeURL(r, "/raw/"+p.ID)
 	edit := ""
 	if p.Editable {
-		edit = s.makeURL(r, "/p/"+p.ID+"/edit?key="+p.EditKey)
-		util.WriteCookie(w, "npk_"+p.ID, p.EditKey, 365*24*time.Hour)
+		edit = s.makeURL(r, s.editURL(p))
+		s.setCookie(w, r, "npk_"+p.ID, p.EditKey, 365*24*time.Hour)
 	}
 
 	if strings.Contains(accept, "application/json") || r.URL.Query().Get("format") == "json" {
@@ -414,10 +914,33 @@ func (s *Server) handleAPIPaste(w http.ResponseWriter, r *http.Request) {
 			RawURL:    raw,
 			EditURL:   edit,
 			ExpiresAt: p.ExpiresAt.Format(time.RFC3339),
+			CreatedAt: p.CreatedAt.Format(time.RFC3339),
+			UpdatedAt: p.UpdatedAt.Format(time.RFC3339),
+			Size:      pasteSize(p),
+			Versions:  len(p.Versions),
+			Redacted:  p.Redacted,
+			Flagged:   p.Flagged,
+
+			Quarantined:  p.Quarantined,
+			VersionSizes: versionSizes(p),
+			Lang:         p.Lang,
+			Detected:     guessed,
+			Lint:         p.Versions[0].Lint,
+			Formatted:    p.Versions[0].Formatted,
+			ReplyTo:      p.ReplyTo,
 		})
 		return
 	}
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if len(p.Redacted) > 0 {
+		fmt.Fprintf(w, "# redacted: %s\n", strings.Join(p.Redacted, ", "))
+	}
+	if guessed != nil && guessed.By != detect.ByNone {
+		fmt.Fprintf(w, "# lang: %s (detected by %s, confidence %.2f)\n", guessed.Lang, guessed.By, guessed.Confidence)
+	}
+	if l := p.Versions[0].Lint; l != nil {
+		fmt.Fprintf(w, "# %s syntax error: %s\n", p.Lang, lintText(l))
+	}
 	if edit != "" {
 		fmt.Fprintf(w, "%s\n# edit: %s\n", url, edit)
 	} else {
@@ -431,70 +954,111 @@ func (s *Server) handleAPIEdit(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	p, ok := s.Store.Get(id)
 	if !ok {
-		http.NotFound(w, r)
+		s.apiNotFound(w, r, id)
 		return
 	}
 	key := r.URL.Query().Get("key")
 	if key == "" {
-		http.Error(w, "missing ?key", http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, codeMissingKey, "missing ?key")
 		return
 	}
-	if !p.Editable || key != p.EditKey {
-		http.Error(w, "invalid key", http.StatusForbidden)
+	_, collab, ok := s.matchEditToken(key, p)
+	if !p.Editable || !ok {
+		writeProblem(w, r, http.StatusForbidden, codeInvalidKey, "invalid or expired edit token")
 		return
 	}
 
 	var req apiReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+	if err := json.NewDecoder(r.Body).Decode(&req); isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	} else if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
 		return
 	}
 
 	code := strings.TrimSpace(req.Code)
 
-if fs := secrets.Scan(code); len(fs) > 0 {
-	writeSecretBlock(w, fs)
-	return
-}
+	if fs := secrets.Scan(code); len(fs) > 0 {
+		s.writeSecretProblem(w, r, fs)
+		return
+	}
 
 	if code == "" {
-		http.Error(w, "code empty", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, codeEmptyCode, "code empty")
 		return
 	}
-	lang := s.normalizeLang(req.Lang)
+	if s.checkSize(code) != nil {
+		s.writeTooLarge(w, r)
+		return
+	}
+	// ohne lang bleibt die bisherige Sprache
+	lang := p.Lang
+	if req.Lang != "" {
+		lang = s.normalizeLang(req.Lang)
+	}
 	author := strings.TrimSpace(req.Author)
 	now := time.Now()
 
-	// letzte Version zum Vergleich
-	last := p.Versions[len(p.Versions)-1]
-	prevCode, _ := util.GzipDecode(last.ZCode)
-
-	if code != prevCode || lang != last.Lang {
-		p.Versions = append(p.Versions, model.Version{
-			ZCode:  util.GzipEncode(code),
-			Lang:   lang,
-			Author: author,
-			At:     now,
-		})
+	by := s.identify(r, author, req.Email)
+	if spoofedAuthor(w, r, by, author) {
+		return
 	}
-
-	p.Code = code
-	p.Lang = lang
-	if author != "" {
-		p.Author = author
+	author = by.Name
+	if collab != "" && by.ID == "" {
+		author = collab
+	}
+	if s.apiEditConflict(w, r, p, req.Base, code) {
+		return
+	}
+	base := len(p.Versions)
+	p, reason, err := s.commitEdit(r, id, base, func(cur model.Paste) bool {
+		_, c, ok := s.matchEditToken(key, cur)
+		return cur.Editable && ok && c == collab
+	}, editOpts{Code: code, Lang: lang, Author: author, AuthorID: by.ID, Avatar: by.Avatar, Profile: by.Profile, Format: req.Format, KeyName: collab, Message: req.Message}, now)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		s.apiNotFound(w, r, id)
+		return
+	case errors.Is(err, errEditRevoked):
+		writeProblem(w, r, http.StatusForbidden, codeInvalidKey, "invalid or expired edit token")
+		return
+	case errors.Is(err, errEditStale):
+		if !s.apiEditConflict(w, r, p, base, code) {
+			writeProblemBody(w, r, problem{Status: http.StatusConflict, Code: codeEditConflict, Detail: "the paste changed while saving; retry with base set to current_version", Current: len(p.Versions)})
+		}
+		return
+	case errors.Is(err, errEditBlocked):
+		writeBlocked(w, r, reason)
+		return
+	case err != nil:
+		logf(r, "edit %s: %v", id, err)
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, "saving failed")
+		return
 	}
-	p.UpdatedAt = now
-	s.Store.Put(p)
 
 	if author != "" {
-		util.WriteCookie(w, "np_author", author, 180*24*time.Hour)
+		s.setCookie(w, r, "np_author", author, 180*24*time.Hour)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{
 		"id":       p.ID,
 		"versions": len(p.Versions),
-		"url":      s.makeURL(r, "/p/"+p.ID+"?v="+strconv.Itoa(len(p.Versions))),
+		"url":      s.makeURL(r, versionPath("/p", p.ID, len(p.Versions))),
+		"author":   author,
+		"verified": by.ID != "",
+		"key_name": collab,
+		"message":  p.Versions[len(p.Versions)-1].Message,
+
+		"created_at":    p.CreatedAt.Format(time.RFC3339),
+		"updated_at":    p.UpdatedAt.Format(time.RFC3339),
+		"expires_at":    p.ExpiresAt.Format(time.RFC3339),
+		"size":          len(p.Code),
+		"version_sizes": versionSizes(p),
+		"lint":          p.Versions[len(p.Versions)-1].Lint,
+		"formatted":     p.Versions[len(p.Versions)-1].Formatted,
+		"lang":          p.Lang,
 	})
 }
 
@@ -0,0 +1,106 @@
+package httpx
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"time"
+
+	"unglued/internal/i18n"
+)
+
+type langKey struct{}
+
+// localize klont set je Sprache und hängt die passenden T/Lang-Funktionen ein.
+func localize(set templateSet) map[string]templateSet {
+	out := make(map[string]templateSet, len(i18n.Langs))
+	for _, lang := range i18n.Langs {
+		out[lang] = localizeAs(set, lang)
+	}
+	return out
+}
+
+func localizeAs(set templateSet, lang string) templateSet {
+	funcs := template.FuncMap{
+		"T":    i18n.Func(lang),
+		"Lang": func() string { return lang },
+	}
+	clone := func(t *template.Template) *template.Template {
+		return template.Must(t.Clone()).Funcs(funcs)
+	}
+	return templateSet{
+		index: clone(set.index), view: clone(set.view), edit: clone(set.edit),
+		archive: clone(set.archive), me: clone(set.me), admin: clone(set.admin),
+		stats: clone(set.stats), gone: clone(set.gone), delete: clone(set.delete),
+		login: clone(set.login),
+	}
+}
+
+// tmpl: die Templates in der Sprache des Requests; im Dev-Modus frisch von der Platte.
+func (s *Server) tmpl(r *http.Request) templateSet {
+	lang := s.lang(r)
+	if s.Config.DevDir != "" {
+		if set, ok := s.devTemplates(lang); ok {
+			return set
+		}
+	}
+	set, ok := s.conf().tmpl[lang]
+	if !ok {
+		set = s.conf().tmpl[i18n.Langs[0]]
+	}
+	return set
+}
+
+/*
+withLang legt die Sprache des Requests fest: ?lang= (nur GET/HEAD – beim Anlegen
+meint lang die Sprache des Codes, wird dann als Cookie gemerkt), Cookie np_lang,
+Accept-Language, sonst UILang. Die API bleibt ohne Wunsch des Clients Englisch.
+*/
+func (s *Server) withLang(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := ""
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			if lang = i18n.Supported(r.URL.Query().Get("lang")); lang != "" && !isAPIPath(r.URL.Path) {
+				s.setCookie(w, r, "np_lang", lang, 365*24*time.Hour)
+			}
+		}
+		if c, err := r.Cookie("np_lang"); lang == "" && err == nil {
+			lang = i18n.Supported(c.Value)
+		}
+		if lang == "" {
+			lang = i18n.Match(r.Header.Get("Accept-Language"))
+		}
+		if lang == "" && isAPIPath(r.URL.Path) {
+			lang = "en"
+		}
+		if lang == "" {
+			lang = s.defaultLang()
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), langKey{}, lang)))
+	})
+}
+
+func (s *Server) defaultLang() string {
+	if l := i18n.Supported(s.conf().UILang); l != "" {
+		return l
+	}
+	return i18n.Langs[0]
+}
+
+func (s *Server) lang(r *http.Request) string {
+	if l, ok := r.Context().Value(langKey{}).(string); ok {
+		return l
+	}
+	return s.defaultLang()
+}
+
+// tr übersetzt key in die Sprache des Requests (ohne withLang: Default-Sprache).
+func tr(r *http.Request, key string, args ...any) string {
+	lang, _ := r.Context().Value(langKey{}).(string)
+	return i18n.T(lang, key, args...)
+}
+
+// httpError: http.Error mit übersetzter Meldung.
+func httpError(w http.ResponseWriter, r *http.Request, msg string, status int) {
+	http.Error(w, tr(r, msg), status)
+}
@@ -0,0 +1,166 @@
+package httpx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const maxIdempotencyKeyLen = 255
+
+/*
+idemCache merkt sich erfolgreiche Antworten pro Client und Idempotency-Key, damit
+Retries (z.B. aus wackeligen CI-Netzen) dieselbe Paste zurückbekommen statt eines
+Duplikats. Wer einen fremden Key errät, bekommt so nicht dessen Antwort samt Edit-Key.
+*/
+type idemCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]*idemEntry
+	lastSweep time.Time
+}
+
+type idemEntry struct {
+	fingerprint [32]byte
+	expires     time.Time
+	done        bool // false = Request läuft noch
+
+	status int
+	header http.Header
+	body   []byte
+}
+
+func newIdemCache(ttl time.Duration) *idemCache {
+	return &idemCache{ttl: ttl, entries: make(map[string]*idemEntry)}
+}
+
+// begin reserviert key; liefert den fertigen Eintrag (Replay) oder nil, wenn der Aufrufer ausführen soll.
+func (c *idemCache) begin(key string, fp [32]byte) (e *idemEntry, conflict string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.lastSweep) > time.Minute {
+		for k, e := range c.entries {
+			if e.done && now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	if e, ok := c.entries[key]; ok && (!e.done || now.Before(e.expires)) {
+		switch {
+		case e.fingerprint != fp:
+			return nil, codeIdempotencyMismatch
+		case !e.done:
+			return nil, codeIdempotencyInProgress
+		}
+		return e, ""
+	}
+	c.entries[key] = &idemEntry{fingerprint: fp}
+	return nil, ""
+}
+
+// finish speichert 2xx-Antworten; alles andere gibt den Key wieder frei (Retry erlaubt).
+func (c *idemCache) finish(key string, rec *recorder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if rec.status < 200 || rec.status >= 300 {
+		delete(c.entries, key)
+		return
+	}
+	e := c.entries[key]
+	if e == nil {
+		return
+	}
+	e.done = true
+	e.expires = time.Now().Add(c.ttl)
+	e.status = rec.status
+	e.header = rec.Header().Clone()
+	// Cookies tragen den Edit-Key; ein Replay setzt sie nicht noch einmal
+	e.header.Del("Set-Cookie")
+	e.body = rec.buf.Bytes()
+}
+
+// idempotent wickelt h so ein, dass ein Idempotency-Key-Header beachtet wird.
+func (s *Server) idempotent(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || s.idem == nil {
+			h(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, "Idempotency-Key too long")
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if isTooLarge(err) {
+			s.writeTooLarge(w, r)
+			return
+		}
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		// Fingerprint über alles, was das Ergebnis beeinflusst
+		fp := sha256.Sum256(append([]byte(r.URL.RawQuery+"\x00"+r.Header.Get("Content-Type")+"\x00"), body...))
+
+		key = s.idemScope(r) + "\x00" + key
+		e, conflict := s.idem.begin(key, fp)
+		switch conflict {
+		case codeIdempotencyMismatch:
+			writeProblem(w, r, http.StatusUnprocessableEntity, conflict, "Idempotency-Key was already used with a different request")
+			return
+		case codeIdempotencyInProgress:
+			writeProblem(w, r, http.StatusConflict, conflict, "a request with this Idempotency-Key is still in progress")
+			return
+		}
+		if e != nil {
+			for k, v := range e.header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(e.status)
+			_, _ = w.Write(e.body)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r)
+		s.idem.finish(key, rec)
+	}
+}
+
+// idemScope: angemeldete Benutzer über ihre ID, alle anderen über die Client-IP.
+func (s *Server) idemScope(r *http.Request) string {
+	if u, ok := s.currentUser(r); ok {
+		return "user:" + userID(u)
+	}
+	return "ip:" + s.clientIP(r).String()
+}
+
+// recorder schreibt durch und behält Status + Body für spätere Replays.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+	buf    bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(code int) {
+	if !rec.wrote {
+		rec.status = code
+		rec.wrote = true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	rec.wrote = true
+	rec.buf.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
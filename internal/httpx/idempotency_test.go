@@ -0,0 +1,55 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/store"
+)
+
+func TestIdempotencyKeyIsPerClient(t *testing.T) {
+	st := store.New(time.Hour)
+	defer st.Close()
+	index, view, edit := LoadTemplates()
+	srv := NewServer(Config{IdempotencyTTL: time.Hour, Reloadable: Reloadable{MaxPasteBytes: 1 << 20, Features: AllFeatures()}}, st, index, view, edit)
+	h := chi.NewRouter()
+	MountRoutes(h, srv)
+
+	post := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/paste", strings.NewReader(`{"code":"x","editable":true}`))
+		req.RemoteAddr = remote
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Idempotency-Key", "retry-1")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code/100 != 2 {
+			t.Fatalf("%s: %d %s", remote, w.Code, w.Body)
+		}
+		return w
+	}
+	id := func(w *httptest.ResponseRecorder) string {
+		var out struct{ ID string }
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return out.ID
+	}
+
+	first := post("192.0.2.1:1000")
+	replay := post("192.0.2.1:2000")
+	if replay.Header().Get("Idempotent-Replayed") != "true" || id(replay) != id(first) {
+		t.Errorf("same client: got %q, want a replay of %q", id(replay), id(first))
+	}
+	if replay.Header().Get("Set-Cookie") != "" {
+		t.Errorf("replay sets cookies: %q", replay.Header().Values("Set-Cookie"))
+	}
+	other := post("198.51.100.7:1000")
+	if other.Header().Get("Idempotent-Replayed") != "" || id(other) == id(first) {
+		t.Errorf("other client got the first client's paste %q", id(other))
+	}
+}
@@ -0,0 +1,115 @@
+package httpx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Platz für die übrigen Formular-/JSON-Felder neben dem eigentlichen Code.
+const bodySlack = 64 << 10
+
+var errTooLarge = errors.New("Paste zu groß")
+
+/*
+limitBody begrenzt den Request-Body auf MaxPasteBytes (+ etwas Luft für Felder),
+bevor irgendwer ihn liest. urlencoded darf das Dreifache sein (%XX pro Byte),
+JSON das Doppelte (\n, \" usw.).
+*/
+func (s *Server) limitBody(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		max := s.conf().MaxPasteBytes
+		if max <= 0 {
+			next(w, r)
+			return
+		}
+		limit := max + bodySlack
+		switch ct := r.Header.Get("Content-Type"); {
+		case strings.HasPrefix(ct, "application/x-www-form-urlencoded"):
+			limit = 3*max + bodySlack
+		case strings.HasPrefix(ct, "application/json"):
+			limit = 2*max + bodySlack
+		}
+		if r.ContentLength > limit {
+			s.writeTooLarge(w, r)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next(w, r)
+	}
+}
+
+/*
+limitSmall ist das Gegenstück für Schreib-Routen ohne Paste-Inhalt (Löschen,
+Freigaben, Admin-Aktionen): bodySlack reicht. Der Body wird vorab gelesen,
+damit jede dieser Routen bei Überlänge dasselbe 413 liefert, statt dass jeder
+Handler den Lesefehler anders (oder gar nicht) meldet.
+*/
+func (s *Server) limitSmall(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > bodySlack {
+			writeBodyTooLarge(w, r)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, bodySlack))
+		if isTooLarge(err) {
+			writeBodyTooLarge(w, r)
+			return
+		}
+		if err != nil {
+			if isAPIPath(r.URL.Path) {
+				writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, "cannot read request body")
+				return
+			}
+			httpError(w, r, "Ungültige Anfrage", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next(w, r)
+	}
+}
+
+// checkSize: Größe einer einzelnen Version nach dem Dekodieren.
+func (s *Server) checkSize(code string) error {
+	if max := s.conf().MaxPasteBytes; max > 0 && int64(len(code)) > max {
+		return fmt.Errorf("%w (max. %s)", errTooLarge, formatBytes(max))
+	}
+	return nil
+}
+
+// isTooLarge erkennt sowohl den abgeschnittenen Body als auch checkSize-Fehler.
+func isTooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe) || errors.Is(err, errTooLarge)
+}
+
+func (s *Server) writeTooLarge(w http.ResponseWriter, r *http.Request) {
+	max := formatBytes(s.conf().MaxPasteBytes)
+	if isAPIPath(r.URL.Path) {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, codeTooLarge, "paste exceeds the limit of "+max)
+		return
+	}
+	http.Error(w, tr(r, "Paste zu groß (max. %s)", max), http.StatusRequestEntityTooLarge)
+}
+
+func writeBodyTooLarge(w http.ResponseWriter, r *http.Request) {
+	max := formatBytes(bodySlack)
+	if isAPIPath(r.URL.Path) {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, codeBodyTooLarge, "request body exceeds the limit of "+max)
+		return
+	}
+	http.Error(w, tr(r, "Anfrage zu groß (max. %s)", max), http.StatusRequestEntityTooLarge)
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%d MiB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%d KiB", n>>10)
+	}
+	return fmt.Sprintf("%d B", n)
+}
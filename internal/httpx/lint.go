@@ -0,0 +1,18 @@
+package httpx
+
+import (
+	"strconv"
+
+	"unglued/internal/model"
+)
+
+// lintText: Syntaxfehler für Text-Antworten ("line 3, column 8: …").
+func lintText(l *model.Problem) string {
+	switch {
+	case l.Line > 0 && l.Col > 0:
+		return "line " + strconv.Itoa(l.Line) + ", column " + strconv.Itoa(l.Col) + ": " + l.Msg
+	case l.Line > 0:
+		return "line " + strconv.Itoa(l.Line) + ": " + l.Msg
+	}
+	return l.Msg
+}
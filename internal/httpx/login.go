@@ -0,0 +1,210 @@
+package httpx
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"unglued/internal/auth"
+	"unglued/internal/model"
+	"unglued/internal/util"
+)
+
+const (
+	sessionCookie = "ug_session"
+	loginCookie   = "ug_login" // state/nonce/PKCE während des Redirects
+)
+
+type loginState struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	Next     string `json:"next"`
+}
+
+/*
+currentUser liefert den per OIDC, GitHub oder OAuth2 angemeldeten Benutzer
+(Session-Cookie) oder den Account eines API-Tokens (Authorization: Bearer).
+*/
+func (s *Server) currentUser(r *http.Request) (auth.User, bool) {
+	if a, ok := s.account(r); ok {
+		return a.User(), true
+	}
+	if len(s.Logins) == 0 {
+		return auth.User{}, false
+	}
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return auth.User{}, false
+	}
+	var u auth.User
+	if !s.Auth.Open("session", c.Value, &u) || u.Sub == "" {
+		return auth.User{}, false
+	}
+	return u, true
+}
+
+// userID ist die stabile Kennung für Owner/AuthorID.
+func userID(u auth.User) string { return u.Issuer + "|" + u.Sub }
+
+// authorship: wer eine Version schreibt; Avatar und Profil gibt es nur bei Logins wie GitHub.
+type authorship struct {
+	Name, ID        string
+	Avatar, Profile string
+}
+
+/*
+identify: Angemeldete Benutzer werden mit ihrer verifizierten Identität
+eingetragen, der frei eingegebene Name zählt dann nicht. email (optional)
+liefert anonymen Autoren ein Avatar, siehe emailAvatar.
+*/
+func (s *Server) identify(r *http.Request, name, email string) authorship {
+	if u, ok := s.currentUser(r); ok {
+		return authorship{Name: u.Display(), ID: userID(u), Avatar: u.Avatar, Profile: u.Profile}
+	}
+	return authorship{Name: name, Avatar: s.emailAvatar(email)}
+}
+
+/*
+canView: private Pastes nur für den Besitzer (Login oder Edit-Key) und mit
+gültigem Share-Grant (siehe grants.go). Ein Login allein reicht nicht – bei
+GitHub & Co. kann sich jeder anmelden.
+*/
+func (s *Server) canView(r *http.Request, p model.Paste) bool {
+	if !p.Private {
+		return true
+	}
+	if u, ok := s.currentUser(r); ok && p.Owner != "" && p.Owner == userID(u) {
+		return true
+	}
+	return s.validGrant(grantToken(r, p.ID), p) || s.ownsPaste(r, p)
+}
+
+// mayCreate: mit -oidc-require-login dürfen nur angemeldete Benutzer anlegen.
+func (s *Server) mayCreate(r *http.Request) bool {
+	if !s.Config.RequireLogin || (len(s.Logins) == 0 && s.Tokens == nil) {
+		return true
+	}
+	_, ok := s.currentUser(r)
+	return ok
+}
+
+/*
+handleLogin leitet zum Provider weiter (?provider=github); gibt es mehrere und
+ist keiner gewählt, kommt erst eine Auswahl.
+*/
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if len(s.Logins) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	name := r.URL.Query().Get("provider")
+	if name == "" && len(s.Logins) > 1 {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = s.tmpl(r).login.Execute(w, map[string]any{
+			"Providers": s.Logins,
+			"Next":      safeNext(r.URL.Query().Get("next")),
+		})
+		return
+	}
+	p, err := auth.Find(s.Logins, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	st := loginState{
+		Provider: p.ID(),
+		State:    util.NewID(16),
+		Nonce:    util.NewID(16),
+		Verifier: util.NewID(32),
+		Next:     safeNext(r.URL.Query().Get("next")),
+	}
+	target, err := p.AuthURL(r.Context(), st.State, st.Nonce, st.Verifier)
+	if err != nil {
+		logf(r, "%s: %v", p.ID(), err)
+		httpError(w, r, "Login-Provider nicht erreichbar", http.StatusBadGateway)
+		return
+	}
+	v, _ := s.Auth.Seal("login", st, 10*time.Minute)
+	s.writeLoginCookie(w, r, v, 10*time.Minute)
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (s *Server) handleAuthCallback(w http.ResponseWriter, r *http.Request) {
+	if len(s.Logins) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	var st loginState
+	c, err := r.Cookie(loginCookie)
+	if err != nil || !s.Auth.Open("login", c.Value, &st) || st.State != r.URL.Query().Get("state") {
+		httpError(w, r, "Login abgelaufen oder ungültig – bitte erneut anmelden", http.StatusBadRequest)
+		return
+	}
+	p, err := auth.Find(s.Logins, st.Provider)
+	if err != nil {
+		httpError(w, r, "Login abgelaufen oder ungültig – bitte erneut anmelden", http.StatusBadRequest)
+		return
+	}
+	s.writeLoginCookie(w, r, "", -1)
+	if e := r.URL.Query().Get("error"); e != "" {
+		http.Error(w, tr(r, "Login abgebrochen: %s", e), http.StatusUnauthorized)
+		return
+	}
+	u, err := p.Exchange(r.Context(), r.URL.Query().Get("code"), st.Verifier, st.Nonce)
+	if err != nil {
+		logf(r, "%s: %v", p.ID(), err)
+		httpError(w, r, "Login fehlgeschlagen", http.StatusUnauthorized)
+		return
+	}
+	v, _ := s.Auth.Seal("session", u, s.Config.SessionTTL)
+	s.setCookie(w, r, sessionCookie, v, s.Config.SessionTTL)
+	http.Redirect(w, r, st.Next, http.StatusSeeOther)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	s.setCookie(w, r, sessionCookie, "", -1)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// Das Login-State-Cookie muss den Rücksprung vom IdP (cross-site) überleben,
+// deshalb bleibt es unabhängig von -cookie-samesite bei Lax.
+func (s *Server) writeLoginCookie(w http.ResponseWriter, r *http.Request, value string, life time.Duration) {
+	f := s.cookieFlags(r)
+	f.SameSite = http.SameSiteLaxMode
+	util.WriteCookieWith(w, f, loginCookie, value, life)
+}
+
+/*
+safeNext erlaubt nur lokale Pfade als Rücksprungziel (kein Open Redirect).
+Browser machen aus "/\evil.com" ein "//evil.com"; darum sind Backslashes und
+Steuerzeichen ganz verboten, und auf das erste "/" darf kein zweites folgen.
+*/
+func safeNext(next string) string {
+	if next == "" || next[0] != '/' || strings.HasPrefix(next, "//") ||
+		strings.ContainsRune(next, '\\') || strings.IndexFunc(next, unicode.IsControl) >= 0 {
+		return "/"
+	}
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "/"
+	}
+	return next
+}
+
+// AvatarOrigins: woher Profilbilder kommen dürfen (für img-src in der CSP).
+func (s *Server) AvatarOrigins() []string {
+	var out []string
+	if base, ok := AvatarServices[s.Config.Avatars]; ok {
+		out = append(out, base)
+	}
+	for _, p := range s.Logins {
+		if o, ok := p.(interface{ AvatarOrigins() []string }); ok {
+			out = append(out, o.AvatarOrigins()...)
+		}
+	}
+	return out
+}
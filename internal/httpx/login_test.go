@@ -0,0 +1,23 @@
+package httpx
+
+import "testing"
+
+func TestSafeNext(t *testing.T) {
+	for next, want := range map[string]string{
+		"":                 "/",
+		"/":                "/",
+		"/p/abc?x=1":       "/p/abc?x=1",
+		"//evil.com":       "/",
+		"/\\evil.com":      "/",
+		"/p\\..\\x":        "/",
+		"\\\\evil.com":     "/",
+		"https://evil.com": "/",
+		"evil.com":         "/",
+		"/\tevil.com":      "/",
+		"/p/a\r\nX: y":     "/",
+	} {
+		if got := safeNext(next); got != want {
+			t.Errorf("safeNext(%q) = %q, want %q", next, got, want)
+		}
+	}
+}
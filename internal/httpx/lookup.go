@@ -0,0 +1,43 @@
+package httpx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type apiLookupResp struct {
+	SHA256 string        `json:"sha256"`
+	Found  bool          `json:"found"`
+	Pastes []apiListItem `json:"pastes"`
+}
+
+/*
+handleAPILookup beantwortet, ob es genau diesen Inhalt schon gibt, ohne dass
+er geschickt werden muss. Gemeldet werden nur gelistete Pastes und die, die
+der Aufrufer editieren darf – sonst ließe sich per Hash raten, was in
+privaten Pastes steht.
+*/
+func (s *Server) handleAPILookup(w http.ResponseWriter, r *http.Request) {
+	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("sha256")))
+	raw, err := hex.DecodeString(q)
+	if err != nil || len(raw) != sha256.Size {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, "sha256 must be 64 hex characters")
+		return
+	}
+	resp := apiLookupResp{SHA256: q, Pastes: []apiListItem{}}
+	for _, p := range s.Store.FindSHA256([sha256.Size]byte(raw)) {
+		if p.Quarantined || !s.canView(r, p) {
+			continue
+		}
+		if (!p.Public || p.Flagged) && !s.canEditPaste(r, p) {
+			continue
+		}
+		resp.Pastes = append(resp.Pastes, s.listItem(r, p))
+	}
+	resp.Found = len(resp.Pastes) > 0
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
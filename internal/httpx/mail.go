@@ -0,0 +1,233 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"unglued/internal/abuse"
+	"unglued/internal/secrets"
+	"unglued/internal/smtpd"
+	"unglued/internal/util"
+	"unglued/internal/webhook"
+)
+
+// mailPart: Text-Body oder ein Anhang (Name gesetzt) einer eingehenden Mail.
+type mailPart struct {
+	Name, Text string
+}
+
+var headerDecoder = mime.WordDecoder{}
+
+/*
+MailHandler verarbeitet Mails aus dem SMTP-Gateway: Text-Body und jeder Text-Anhang
+werden zu einer Paste (Secrets geschwärzt, wie bei CI-Logs). Sperrliste und Rate-Limit
+gelten pro IP und pro Absender. Mit Relay geht eine Antwort mit den Links an Reply-To
+bzw. From, aber nur, wenn die Adresse als Absender in -smtp-allow steht. Optionen
+stehen im Empfänger hinter "+", etwa logs+1h+bash@paste.example.com.
+*/
+func (s *Server) MailHandler(relay smtpd.Relay) smtpd.Handler {
+	return func(env smtpd.Envelope, data []byte) error {
+		from, to := env.From, env.To
+		msg, err := mail.ReadMessage(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		// keine Antwort auf Abwesenheitsnotizen und Bounces, sonst drohen Mail-Schleifen
+		if as := msg.Header.Get("Auto-Submitted"); (as != "" && as != "no") || from == "" {
+			return nil
+		}
+		if s.Abuse != nil {
+			for _, v := range []abuse.Verdict{s.Abuse.Check(env.IP), s.Abuse.CheckSender(from)} {
+				switch {
+				case v.Banned:
+					return errors.New("sender is banned from creating pastes")
+				case v.Limited:
+					return errors.New("too many pastes, retry in " + strconv.Itoa(int((v.RetryAfter+time.Second-1)/time.Second)) + "s")
+				}
+			}
+		}
+		subject, err := headerDecoder.DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil {
+			subject = msg.Header.Get("Subject")
+		}
+		author := from
+		if a, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+			author = a.Address
+			if a.Name != "" {
+				author = a.Name
+			}
+		}
+		parts, skipped := mailParts(textproto.MIMEHeader(msg.Header), msg.Body)
+		if len(parts) == 0 {
+			return errors.New(strings.Join(append([]string{"no text body or text attachments"}, skipped...), "; "))
+		}
+		o := mailOptions(to)
+		r := s.internalRequest("/api/ingest/mail")
+		var lines []string
+		var expires time.Time
+		for _, part := range parts {
+			po := o
+			po.Title, po.Author, po.Tags = subject, author, []string{"email"}
+			if part.Name != "" {
+				po.Title = part.Name
+				if l := util.LangForFile(part.Name); l != "" && o.Lang == "" {
+					po.Lang = l
+				}
+			}
+			po.Code, po.Redacted = secrets.Redact(part.Text)
+			p, err := s.buildPaste(po)
+			if err == nil {
+				if reason, ok := s.review(r, webhook.EventCreated, &p); !ok {
+					err = errors.New("rejected by content moderation: " + reason)
+				}
+			}
+			if err != nil {
+				skipped = append(skipped, orDash(part.Name)+": "+err.Error())
+				continue
+			}
+			s.save(r, webhook.EventCreated, p)
+			lines = append(lines, chatTitle(p)+": "+s.makeURL(r, "/p/"+p.ID))
+			expires = p.ExpiresAt
+		}
+		if len(lines) == 0 {
+			return errors.New(strings.Join(skipped, "; "))
+		}
+		if rcpt := replyTo(env, msg.Header); relay.Enabled() && rcpt != "" {
+			body := "Your mail was turned into these pastes:\n\n" + strings.Join(lines, "\n") +
+				"\n\nThey expire at " + expires.UTC().Format("2006-01-02 15:04 MST") + ".\n"
+			if len(skipped) > 0 {
+				body += "\nSkipped:\n" + strings.Join(skipped, "\n") + "\n"
+			}
+			go func() {
+				if err := relay.Send(rcpt, "Re: "+strings.NewReplacer("\r", " ", "\n", " ").Replace(subject), body); err != nil {
+					log.Printf("smtp: reply to %s: %v", rcpt, err)
+				}
+			}()
+		}
+		return nil
+	}
+}
+
+// replyTo: Reply-To, From oder Umschlag-Absender – die erste Adresse, die in der Allowlist steht; sonst leer.
+func replyTo(env smtpd.Envelope, h mail.Header) string {
+	for _, name := range []string{"Reply-To", "From"} {
+		if a, err := mail.ParseAddress(h.Get(name)); err == nil && env.SenderAllowed(a.Address) {
+			return a.Address
+		}
+	}
+	if env.SenderAllowed(env.From) {
+		return env.From
+	}
+	return ""
+}
+
+// mailOptions: "+1h" setzt die Laufzeit, jedes andere "+wort" die Sprache.
+func mailOptions(to []string) pasteOpts {
+	var o pasteOpts
+	for _, addr := range to {
+		local, _, _ := strings.Cut(addr, "@")
+		opts := strings.Split(local, "+")
+		for _, opt := range opts[1:] {
+			if _, err := util.ParseTTL(opt); err == nil && opt != "" {
+				o.TTL = opt
+			} else {
+				o.Lang = opt
+			}
+		}
+	}
+	return o
+}
+
+/*
+mailParts läuft rekursiv durch die MIME-Struktur: der erste text/plain-Teil ohne
+Dateinamen ist der Body (ohne Signatur), Teile mit Dateinamen sind Anhänge. Binäre
+Anhänge und HTML-only-Mails landen in skipped.
+*/
+func mailParts(h textproto.MIMEHeader, body io.Reader) (parts []mailPart, skipped []string) {
+	var walk func(h textproto.MIMEHeader, body io.Reader, alternative bool) bool
+	haveBody := false
+	walk = func(h textproto.MIMEHeader, body io.Reader, alternative bool) bool {
+		ct, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+		if err != nil {
+			ct, params = "text/plain", nil
+		}
+		if strings.HasPrefix(ct, "multipart/") {
+			mr := multipart.NewReader(body, params["boundary"])
+			for {
+				p, err := mr.NextPart()
+				if err != nil {
+					return false
+				}
+				// bei multipart/alternative reicht der erste brauchbare Teil
+				if walk(p.Header, p, ct == "multipart/alternative") && ct == "multipart/alternative" {
+					return true
+				}
+			}
+		}
+		name := ""
+		if _, dp, err := mime.ParseMediaType(h.Get("Content-Disposition")); err == nil {
+			name = dp["filename"]
+		}
+		if name == "" {
+			name = params["name"]
+		}
+		if name != "" {
+			if n, err := headerDecoder.DecodeHeader(name); err == nil {
+				name = n
+			}
+		}
+		raw, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+		if err != nil {
+			skipped = append(skipped, orDash(name)+": "+err.Error())
+			return false
+		}
+		switch {
+		case name != "" && utf8.Valid(raw) && bytes.IndexByte(raw, 0) < 0:
+			parts = append(parts, mailPart{Name: name, Text: string(raw)})
+		case name != "":
+			skipped = append(skipped, name+": not a text file")
+		case ct == "text/plain" && !haveBody:
+			text := strings.ReplaceAll(strings.ToValidUTF8(string(raw), "�"), "\r\n", "\n")
+			if i := strings.Index(text, "\n-- \n"); i >= 0 {
+				text = text[:i]
+			}
+			haveBody = true
+			if strings.TrimSpace(text) != "" {
+				parts = append(parts, mailPart{Text: text})
+			}
+			return true
+		case ct == "text/html" && !haveBody && !alternative:
+			skipped = append(skipped, "HTML-only mail body, please send plain text")
+		}
+		return false
+	}
+	walk(h, body, false)
+	if len(parts) > 1 && parts[0].Name == "" && len(strings.TrimSpace(parts[0].Text)) < 200 {
+		// kurzer Begleittext neben Anhängen ("siehe Anhang") wird keine eigene Paste
+		parts = parts[1:]
+	}
+	return parts, skipped
+}
+
+// decodeTransfer: base64 und quoted-printable; multipart.Reader erledigt letzteres schon selbst.
+func decodeTransfer(enc string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(enc)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
@@ -0,0 +1,44 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+
+	"unglued/internal/matrix"
+	"unglued/internal/model"
+	"unglued/internal/util"
+	"unglued/internal/webhook"
+)
+
+// MatrixCallbacks verbindet den Matrix-Bot mit diesem Server.
+func (s *Server) MatrixCallbacks() matrix.Callbacks {
+	return matrix.Callbacks{
+		Paste: func(sender, block string) (string, string, string, error) {
+			r := s.internalRequest("/api/integrations/matrix")
+			p, err := s.chatPaste(r, block, sender, "matrix")
+			if err != nil {
+				return "", "", "", err
+			}
+			return s.makeURL(r, "/p/"+p.ID), chatSnippet(p.Code, replacementLines, replacementBytes), p.Lang, nil
+		},
+		Resolve: func(link string) (string, bool) {
+			if !strings.HasPrefix(link, s.makeURL(nil, "/")) {
+				return "", false
+			}
+			p, _, ok := s.chatPreview(link)
+			return p.ID, ok
+		},
+	}
+}
+
+// notifyWatchers schickt das Diff einer Bearbeitung an Räume, die die Paste beobachten.
+func (s *Server) notifyWatchers(r *http.Request, typ string, p model.Paste) {
+	if s.Matrix == nil || typ != webhook.EventEdited || p.Private || p.Quarantined || len(p.Versions) < 2 {
+		return
+	}
+	prev, err := util.GzipDecode(p.Versions[len(p.Versions)-2].ZCode)
+	if err != nil {
+		return
+	}
+	s.Matrix.Changed(p.ID, chatTitle(p), s.makeURL(r, "/p/"+p.ID), len(p.Versions), util.LineDiff(prev, p.Code))
+}
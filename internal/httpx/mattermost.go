@@ -0,0 +1,99 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// mmPayload: gemeinsame Felder von Outgoing Webhook und Slash-Command.
+type mmPayload struct {
+	Token       string `json:"token"`
+	UserName    string `json:"user_name"`
+	PostID      string `json:"post_id"`
+	Text        string `json:"text"`
+	TriggerWord string `json:"trigger_word"`
+	Command     string `json:"command"`
+}
+
+/*
+POST /api/integrations/mattermost: Slash-Command "/paste …" und Outgoing Webhook.
+Der Webhook wandelt Beiträge ab -mattermost-min-lines Zeilen (oder mit Trigger-Wort)
+um; mit -mattermost-bot-token wird der Beitrag durch Link und Vorschau ersetzt.
+*/
+func (s *Server) handleMattermost(w http.ResponseWriter, r *http.Request) {
+	if s.Mattermost == nil {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "Mattermost integration is disabled")
+		return
+	}
+	var in mmPayload
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			if isTooLarge(err) {
+				s.writeTooLarge(w, r)
+				return
+			}
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			if isTooLarge(err) {
+				s.writeTooLarge(w, r)
+				return
+			}
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+			return
+		}
+		f := r.PostForm
+		in = mmPayload{
+			Token: f.Get("token"), UserName: f.Get("user_name"), PostID: f.Get("post_id"),
+			Text: f.Get("text"), TriggerWord: f.Get("trigger_word"), Command: f.Get("command"),
+		}
+	}
+	if !s.Mattermost.Valid(in.Token) {
+		writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, "invalid or missing token")
+		return
+	}
+	reply := map[string]string{}
+	if in.Command != "" {
+		reply["response_type"] = "in_channel"
+		if p, err := s.chatPaste(r, in.Text, in.UserName, "mattermost"); err != nil {
+			reply["response_type"], reply["text"] = "ephemeral", err.Error()
+		} else {
+			reply["text"] = s.chatReplacement(r, p)
+		}
+	} else if text, ok := s.mmOversized(in); ok {
+		// Fehler (etwa Secrets) nicht in den Kanal posten; der Beitrag bleibt dann einfach stehen
+		if p, err := s.chatPaste(r, text, in.UserName, "mattermost"); err == nil {
+			reply["text"] = s.chatReplacement(r, p)
+			if s.Mattermost.CanDelete() && in.PostID != "" {
+				go s.mmDelete(in.PostID)
+			} else {
+				reply["response_type"] = "comment"
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(reply)
+}
+
+// mmOversized: Text ohne Trigger-Wort und ob der Beitrag umgewandelt werden soll.
+func (s *Server) mmOversized(in mmPayload) (string, bool) {
+	if in.TriggerWord != "" {
+		text, _ := strings.CutPrefix(in.Text, in.TriggerWord)
+		return text, true
+	}
+	return in.Text, strings.Count(in.Text, "\n")+1 >= s.Mattermost.MinLines
+}
+
+func (s *Server) mmDelete(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.Mattermost.DeletePost(ctx, id); err != nil {
+		log.Printf("mattermost: %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"unglued/internal/audit"
+	"unglued/internal/model"
+	"unglued/internal/stats"
+	"unglued/internal/util"
+)
+
+// Bestätigungs-Token für das Löschen gilt nur kurz und nur für genau diese Pastes.
+const eraseConfirmTTL = 10 * time.Minute
+
+type eraseClaim struct {
+	IDs []string `json:"ids"`
+}
+
+/*
+myPastes: alles, was dem Request gehört – per Login (Owner) oder per Edit-Cookie
+(npk_<id>) aus diesem Browser. ok = false, wenn der Request sich gar nicht ausweist.
+*/
+func (s *Server) myPastes(r *http.Request) (ps []model.Paste, ok bool) {
+	uid := ""
+	if u, loggedIn := s.currentUser(r); loggedIn {
+		uid = userID(u)
+	}
+	keys := map[string]string{}
+	for _, c := range r.Cookies() {
+		if id, found := strings.CutPrefix(c.Name, "npk_"); found && c.Value != "" {
+			keys[id] = c.Value
+		}
+	}
+	if uid == "" && len(keys) == 0 {
+		return nil, false
+	}
+	return s.Store.Find(func(p *model.Paste) bool {
+		if uid != "" && p.Owner == uid {
+			return true
+		}
+		k, has := keys[p.ID]
+		return has && p.EditKey != "" && k == p.EditKey
+	}), true
+}
+
+type exportPaste struct {
+	ID        string          `json:"id"`
+	Title     string          `json:"title,omitempty"`
+	Tags      []string        `json:"tags,omitempty"`
+	Lang      string          `json:"lang"`
+	Author    string          `json:"author,omitempty"`
+	Public    bool            `json:"public"`
+	Private   bool            `json:"private,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Versions  []exportVersion `json:"versions"`
+
+	// Quarantäne: Inhalt wird nicht mit exportiert
+	Quarantined bool `json:"quarantined,omitempty"`
+}
+
+type exportVersion struct {
+	Version int       `json:"version"`
+	Lang    string    `json:"lang"`
+	Author  string    `json:"author,omitempty"`
+	At      time.Time `json:"at"`
+	Size    int       `json:"size"`
+	Message string    `json:"message,omitempty"`
+	Code    string    `json:"code"`
+}
+
+func toExport(p model.Paste) exportPaste {
+	out := exportPaste{
+		ID: p.ID, Title: p.Title, Tags: p.Tags, Lang: p.Lang, Author: p.Author,
+		Public: p.Public, Private: p.Private, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt, ExpiresAt: p.ExpiresAt,
+		Quarantined: p.Quarantined,
+	}
+	for i, v := range p.Versions {
+		if p.Quarantined {
+			break
+		}
+		code, _ := util.GzipDecode(v.ZCode)
+		out.Versions = append(out.Versions, exportVersion{Version: i + 1, Lang: v.Lang, Author: v.Author, At: v.At, Size: len(code), Message: v.Message, Code: code})
+	}
+	return out
+}
+
+// GET /api/me/pastes
+func (s *Server) handleAPIMyPastes(w http.ResponseWriter, r *http.Request) {
+	ps, ok := s.myPastes(r)
+	if !ok {
+		writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, "log in or use the browser that created the pastes")
+		return
+	}
+	items := make([]apiListItem, 0, len(ps))
+	for _, p := range ps {
+		items = append(items, s.listItem(r, p))
+	}
+	out := map[string]any{"total": len(items), "items": items}
+	if a, ok := s.account(r); ok {
+		out["account"] = s.usage(a)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// GET /api/me/export: alle eigenen Pastes mit sämtlichen Versionen als Download.
+func (s *Server) handleAPIMyExport(w http.ResponseWriter, r *http.Request) {
+	ps, ok := s.myPastes(r)
+	if !ok {
+		writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, "log in or use the browser that created the pastes")
+		return
+	}
+	out := make([]exportPaste, 0, len(ps))
+	for _, p := range ps {
+		out = append(out, toExport(p))
+	}
+	s.record(r, audit.ActionExport, "", "", strconv.Itoa(len(out))+" pastes")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="unglued-export-`+time.Now().UTC().Format("20060102")+`.json"`)
+	w.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(map[string]any{"exported_at": time.Now().UTC(), "pastes": out})
+}
+
+/*
+DELETE /api/me/pastes ist zweistufig: ohne ?confirm= kommt 428 mit einem Token, das
+genau die aktuell eigenen Pastes festhält; erst der zweite Aufruf mit diesem Token löscht.
+*/
+func (s *Server) handleAPIMyDelete(w http.ResponseWriter, r *http.Request) {
+	ps, ok := s.myPastes(r)
+	if !ok {
+		writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, "log in or use the browser that created the pastes")
+		return
+	}
+	tok := r.URL.Query().Get("confirm")
+	if tok == "" {
+		confirm, err := s.eraseToken(ps)
+		if err != nil {
+			writeProblem(w, r, http.StatusInternalServerError, codeInternal, err.Error())
+			return
+		}
+		writeProblemBody(w, r, problem{
+			Status:  http.StatusPreconditionRequired,
+			Code:    codeConfirmRequired,
+			Detail:  "repeat the request with ?confirm=<token> within 10 minutes to delete " + strconv.Itoa(len(ps)) + " pastes",
+			Confirm: confirm,
+			Pastes:  len(ps),
+		})
+		return
+	}
+	n, ok := s.eraseConfirmed(w, r, ps, tok)
+	if !ok {
+		writeProblem(w, r, http.StatusForbidden, codeInvalidKey, "invalid or expired confirmation token")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"deleted": n})
+}
+
+func (s *Server) eraseToken(ps []model.Paste) (string, error) {
+	ids := make([]string, 0, len(ps))
+	for _, p := range ps {
+		ids = append(ids, p.ID)
+	}
+	return s.Auth.Seal("erase", eraseClaim{IDs: ids}, eraseConfirmTTL)
+}
+
+// eraseConfirmed löscht die im Token festgehaltenen Pastes, soweit sie noch dem Request gehören.
+func (s *Server) eraseConfirmed(w http.ResponseWriter, r *http.Request, mine []model.Paste, tok string) (int, bool) {
+	var claim eraseClaim
+	if !s.Auth.Open("erase", tok, &claim) {
+		return 0, false
+	}
+	owned := make(map[string]bool, len(mine))
+	for _, p := range mine {
+		owned[p.ID] = true
+	}
+	n := 0
+	for _, id := range claim.IDs {
+		if !owned[id] || !s.Store.Delete(id) {
+			continue
+		}
+		if s.Search != nil {
+			s.Search.Remove(id)
+		}
+		s.setCookie(w, r, "npk_"+id, "", -time.Second)
+		s.record(r, audit.ActionDelete, id, "", "self-service erasure")
+		s.Stats.Add(stats.Deleted, 1)
+		n++
+	}
+	return n, true
+}
+
+// GET /me: eigene Pastes, Export-Link und Löschen mit Bestätigung.
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	ps, ok := s.myPastes(r)
+	s.renderMe(w, r, ps, ok, "")
+}
+
+// POST /me/delete: ohne confirm Rückfrage, mit confirm wird gelöscht.
+func (s *Server) handleMeDelete(w http.ResponseWriter, r *http.Request) {
+	ps, ok := s.myPastes(r)
+	if !ok {
+		httpError(w, r, "Keine eigenen Pastes gefunden", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		httpError(w, r, "Bad form", http.StatusBadRequest)
+		return
+	}
+	tok := r.PostForm.Get("confirm")
+	if tok == "" {
+		confirm, err := s.eraseToken(ps)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.renderMe(w, r, ps, true, confirm)
+		return
+	}
+	n, ok := s.eraseConfirmed(w, r, ps, tok)
+	if !ok {
+		httpError(w, r, "Bestätigung ungültig oder abgelaufen – bitte erneut versuchen.", http.StatusForbidden)
+		return
+	}
+	http.Redirect(w, r, "/me?deleted="+strconv.Itoa(n), http.StatusSeeOther)
+}
+
+func (s *Server) renderMe(w http.ResponseWriter, r *http.Request, ps []model.Paste, known bool, confirm string) {
+	items := make([]apiListItem, 0, len(ps))
+	for _, p := range ps {
+		items = append(items, s.listItem(r, p))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = s.tmpl(r).me.Execute(w, map[string]any{
+		"Known":   known,
+		"Items":   items,
+		"Confirm": confirm,
+		"Deleted": r.URL.Query().Get("deleted"),
+	})
+}
@@ -0,0 +1,143 @@
+package httpx
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/metrics"
+	"unglued/internal/model"
+	"unglued/internal/secrets"
+)
+
+/*
+serverMetrics: Zahlen für Kapazitätsplanung und Missbrauchserkennung unter
+/metrics (Prometheus). Gezählt wird ab dem Start des Prozesses, wie bei stats.
+*/
+type serverMetrics struct {
+	reg      *metrics.Registry
+	created  *metrics.Counter   // lang, visibility
+	size     *metrics.Histogram // Bytes beim Anlegen
+	ttl      *metrics.Histogram // gewählte Laufzeit in Sekunden
+	blocked  *metrics.Counter   // rule
+	redacted *metrics.Counter   // rule
+	requests *metrics.Counter   // route, code
+	latency  *metrics.Histogram // route
+}
+
+func newServerMetrics(s *Server) *serverMetrics {
+	reg := metrics.NewRegistry()
+	m := &serverMetrics{
+		reg: reg,
+		created: reg.Counter("unglued_pastes_created_total",
+			"Pastes created, by language and visibility (public, unlisted, private).", "lang", "visibility"),
+		size: reg.Histogram("unglued_paste_size_bytes", "Size of new pastes in bytes.",
+			[]float64{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20}),
+		ttl: reg.Histogram("unglued_paste_ttl_seconds", "Lifetime chosen for new pastes.",
+			[]float64{600, 3600, 6 * 3600, 86400, 7 * 86400, 30 * 86400, 365 * 86400}),
+		blocked: reg.Counter("unglued_secrets_blocked_total",
+			"Pastes rejected by the secrets scanner, by rule (a paste can match several rules).", "rule"),
+		redacted: reg.Counter("unglued_secrets_redacted_total",
+			"Pastes created with secrets redacted, by rule.", "rule"),
+		requests: reg.Counter("unglued_http_requests_total", "HTTP requests by route and status code.", "route", "code"),
+		latency: reg.Histogram("unglued_http_request_duration_seconds", "HTTP request latency by route.",
+			[]float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}, "route"),
+	}
+	reg.CounterFunc("unglued_paste_events_total", "Paste events (created, viewed, edited, expired, deleted).", func() []metrics.Sample {
+		_, totals := s.Stats.Totals()
+		out := make([]metrics.Sample, 0, len(totals))
+		for e, n := range totals {
+			out = append(out, metrics.Sample{Labels: []string{e}, Value: float64(n)})
+		}
+		return out
+	}, "event")
+	reg.GaugeFunc("unglued_pastes_active", "Pastes that have not expired, by language.", func() []metrics.Sample {
+		var out []metrics.Sample
+		for lang, n := range s.Store.ActiveByLang() {
+			out = append(out, metrics.Sample{Labels: []string{lang}, Value: float64(n)})
+		}
+		return out
+	}, "lang")
+	reg.GaugeFunc("unglued_store_bytes", "Bytes held in the store (code and compressed versions).", func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(s.Store.Stats().Bytes)}}
+	})
+	reg.CounterFunc("unglued_render_cache_requests_total", "Lookups in the prerendered HTML cache, by result (hit, miss).", func() []metrics.Sample {
+		st := s.Prerender.Stats()
+		return []metrics.Sample{{Labels: []string{"hit"}, Value: float64(st.Hits)}, {Labels: []string{"miss"}, Value: float64(st.Misses)}}
+	}, "result")
+	reg.GaugeFunc("unglued_renders_in_progress", "Highlighting jobs currently holding a render slot (-max-renders).", func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(s.Renders.InUse())}}
+	})
+	reg.GaugeFunc("unglued_render_cache_bytes", "Bytes of prerendered HTML held in memory.", func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(s.Prerender.Stats().Bytes)}}
+	})
+	return m
+}
+
+func visibility(p model.Paste) string {
+	switch {
+	case p.Private:
+		return "private"
+	case p.Public:
+		return "public"
+	}
+	return "unlisted"
+}
+
+// pasteCreated: aus recordSave, für jede neue Paste.
+func (m *serverMetrics) pasteCreated(p model.Paste) {
+	m.created.Inc(p.Lang, visibility(p))
+	m.size.Observe(float64(len(p.Code)))
+	m.ttl.Observe(p.ExpiresAt.Sub(p.CreatedAt).Seconds())
+	for _, rule := range p.Redacted {
+		m.redacted.Inc(rule)
+	}
+}
+
+func (m *serverMetrics) secretsBlocked(fs []secrets.Finding) {
+	seen := map[string]bool{}
+	for _, f := range fs {
+		if !seen[f.Rule] {
+			seen[f.Rule] = true
+			m.blocked.Inc(f.Rule)
+		}
+	}
+}
+
+// measure zählt Requests je Route (Muster wie /p/{id}, nicht die konkrete URL).
+func (s *Server) measure(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		route := "unmatched"
+		if rc := chi.RouteContext(r.Context()); rc != nil && rc.RoutePattern() != "" {
+			route = rc.RoutePattern()
+		}
+		s.metrics.requests.Inc(route, strconv.Itoa(sw.code()))
+		s.metrics.latency.Observe(time.Since(start).Seconds(), route)
+	})
+}
+
+// GET /metrics: nur mit -metrics-token (Bearer), sonst 404.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	want := s.Config.MetricsToken
+	tok, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if want == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(tok), []byte(want)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		httpError(w, r, "Nicht autorisiert", http.StatusUnauthorized)
+		return
+	}
+	s.metrics.reg.ServeHTTP(w, r)
+}
+
+// Metrics: die Registry hinter /metrics, etwa für einen zusätzlichen StatsD-Sink.
+func (s *Server) Metrics() *metrics.Registry { return s.metrics.reg }
@@ -0,0 +1,73 @@
+package httpx
+
+import (
+	"net/http"
+
+	"unglued/internal/abuse"
+	"unglued/internal/model"
+	"unglued/internal/moderation"
+)
+
+/*
+moderate prüft die aktuelle Version von p gegen Blocklist und Virenscanner und legt
+sie dann dem externen Moderations-Hook vor, bevor sie gespeichert wird. Bei "block" ist die Antwort schon
+geschrieben und es kommt false zurück; "flag" markiert die Paste und nimmt sie aus
+Archiv und Suche.
+*/
+func (s *Server) moderate(w http.ResponseWriter, r *http.Request, typ string, p *model.Paste) bool {
+	if reason, ok := s.review(r, typ, p); !ok {
+		writeBlocked(w, r, reason)
+		return false
+	}
+	return true
+}
+
+// review ist moderate ohne Antwort: ok = false heißt abgelehnt, reason sagt warum.
+func (s *Server) review(r *http.Request, typ string, p *model.Paste) (reason string, ok bool) {
+	if rule, ok := s.Blocklist.Match(p.Title + "\n" + p.Code); ok {
+		if rule.Action == abuse.ActionReject {
+			return "blocklisted content", false
+		}
+		p.Flagged, p.FlagReason = true, "Blocklist"
+		p.Public = false
+	}
+	s.scan(r, p)
+	if s.Moderator == nil {
+		return "", true
+	}
+	last := p.Versions[len(p.Versions)-1]
+	v := s.Moderator.Check(r.Context(), moderation.Request{
+		Event:   typ,
+		ID:      p.ID,
+		Title:   p.Title,
+		Lang:    last.Lang,
+		Author:  last.Author,
+		Version: len(p.Versions),
+		Code:    p.Code,
+		At:      p.UpdatedAt,
+	})
+	switch v.Action {
+	case moderation.Block:
+		return v.Reason, false
+	case moderation.Flag:
+		p.Flagged, p.FlagReason = true, v.Reason
+		p.Public = false
+	}
+	return "", true
+}
+
+func writeBlocked(w http.ResponseWriter, r *http.Request, reason string) {
+	if isAPIPath(r.URL.Path) {
+		detail := "paste rejected by content moderation"
+		if reason != "" {
+			detail += ": " + reason
+		}
+		writeProblem(w, r, http.StatusUnprocessableEntity, codeContentBlocked, detail)
+		return
+	}
+	msg := tr(r, "Blocked: Inhalt von der Moderation abgelehnt")
+	if reason != "" {
+		msg += " – " + reason
+	}
+	http.Error(w, msg, http.StatusUnprocessableEntity)
+}
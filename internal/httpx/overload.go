@@ -0,0 +1,116 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errBusy: keine Kapazität frei; wird zu 503 mit Retry-After.
+var errBusy = errors.New("server busy")
+
+// Wie lange ein abgewiesener Client warten soll.
+const retryAfter = 2 * time.Second
+
+/*
+Limiter begrenzt, wie viel teure Arbeit gleichzeitig läuft (Highlighting großer
+Pastes). Wer keinen Platz bekommt, wartet höchstens wait und bekommt dann 503 –
+besser als dass alle Anfragen gemeinsam langsam werden. Nil-sicher: ein
+nil-Limiter lässt alles durch.
+*/
+type Limiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// NewLimiter: nil, wenn n <= 0 (unbegrenzt).
+func NewLimiter(n int, wait time.Duration) *Limiter {
+	if n <= 0 {
+		return nil
+	}
+	return &Limiter{slots: make(chan struct{}, n), wait: wait}
+}
+
+// Acquire belegt einen Platz; false, wenn binnen wait keiner frei wurde oder ctx endet.
+func (l *Limiter) Acquire(ctx context.Context) bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if l.wait <= 0 {
+		return false
+	}
+	t := time.NewTimer(l.wait)
+	defer t.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-t.C:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+// Block belegt einen Platz und wartet dafür beliebig lange (Hintergrundarbeit).
+func (l *Limiter) Block() {
+	if l != nil {
+		l.slots <- struct{}{}
+	}
+}
+
+func (l *Limiter) Release() {
+	if l != nil {
+		<-l.slots
+	}
+}
+
+// InUse: belegte Plätze (für /metrics).
+func (l *Limiter) InUse() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
+
+/*
+LimitInFlight weist Anfragen sofort mit 503 ab, solange schon max gleichzeitig
+laufen. /metrics zählt nicht mit: gerade unter Last soll das Monitoring weiter
+eine Antwort bekommen.
+*/
+func LimitInFlight(max int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if max <= 0 {
+			return next
+		}
+		l := NewLimiter(max, 0)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/metrics" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !l.Acquire(r.Context()) {
+				writeBusy(w, r)
+				return
+			}
+			defer l.Release()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeBusy(w http.ResponseWriter, r *http.Request) {
+	noteShed(r.Context())
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+	if isAPIPath(r.URL.Path) || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeProblem(w, r, http.StatusServiceUnavailable, codeOverloaded, "server is at capacity, retry later")
+		return
+	}
+	httpError(w, r, "Server ausgelastet – bitte gleich noch einmal versuchen.", http.StatusServiceUnavailable)
+}
@@ -0,0 +1,55 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type pasteStats struct {
+	ID            string `json:"id"`
+	Views         int64  `json:"views"`
+	UniqueViewers int64  `json:"unique_viewers"` // geschätzt, siehe hits
+	Versions      int    `json:"versions"`
+	Size          int64  `json:"size"`         // aktuelle Version, entpackt
+	StoredBytes   int64  `json:"stored_bytes"` // alle Versionen, gepackt
+	ExpiresAt     string `json:"expires_at"`
+	ExpiresIn     int64  `json:"expires_in"` // Sekunden
+}
+
+/*
+GET /api/paste/{id}/stats: Zahlen für Bots, die entscheiden, ob sie eine
+geteilte Paste auffrischen oder löschen. Aufrufe zählen seit dem Start des
+Servers; sichtbar für alle, die die Paste sehen dürfen.
+*/
+func (s *Server) handleAPIPasteStats(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.GetMeta(id)
+	if !ok {
+		s.apiNotFound(w, r, id)
+		return
+	}
+	if p.Quarantined || !s.canView(r, p) {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not found or expired")
+		return
+	}
+	stored, _ := s.Store.ZSize(id)
+	views, unique := s.Hits.Get(id)
+	out := pasteStats{
+		ID:            p.ID,
+		Views:         views,
+		UniqueViewers: unique,
+		Versions:      len(p.Versions),
+		StoredBytes:   stored,
+		ExpiresAt:     p.ExpiresAt.UTC().Format(time.RFC3339),
+		ExpiresIn:     int64(max(time.Until(p.ExpiresAt), 0) / time.Second),
+	}
+	if n := len(p.Versions); n > 0 {
+		out.Size = p.Versions[n-1].Size
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(out)
+}
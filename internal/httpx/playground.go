@@ -0,0 +1,65 @@
+package httpx
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/util"
+)
+
+// Das Playground nimmt nur Snippets bis 64 KiB an.
+const playgroundMaxBytes = 64 << 10
+
+// playgroundOK: nur Go, nicht privat (das Playground-Snippet wäre für jeden lesbar).
+func (s *Server) playgroundOK(lang string, private bool) bool {
+	return s.Playground != nil && lang == "go" && !private
+}
+
+/*
+POST /p/{id}/playground?v=N: teilt die Version über die Share-API des Go Playground
+und leitet auf das Snippet weiter. Per POST, damit Link-Vorschauen und Crawler
+nichts nach außen schicken.
+*/
+func (s *Server) handlePlayground(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.GetMeta(id)
+	if !ok {
+		s.notFound(w, r, id, true)
+		return
+	}
+	if !s.canView(r, p) {
+		s.denyView(w, r)
+		return
+	}
+	if s.quarantined(w, r, p) {
+		return
+	}
+	vIdx, _ := versionIndex(r, p)
+	_, ver, ok := s.Store.GetVersion(id, vIdx)
+	if !ok {
+		s.notFound(w, r, id, true)
+		return
+	}
+	if !s.playgroundOK(ver.Lang, p.Private) {
+		http.NotFound(w, r)
+		return
+	}
+	code, err := util.GzipDecode(ver.ZCode)
+	if err != nil {
+		httpError(w, r, "Renderfehler", http.StatusInternalServerError)
+		return
+	}
+	if len(code) > playgroundMaxBytes {
+		httpError(w, r, "Zu groß für den Go Playground (max. 64 KiB)", http.StatusRequestEntityTooLarge)
+		return
+	}
+	link, err := s.Playground.Share(r.Context(), code)
+	if err != nil {
+		log.Printf("playground: %s: %v", p.ID, err)
+		httpError(w, r, "Go Playground nicht erreichbar", http.StatusBadGateway)
+		return
+	}
+	http.Redirect(w, r, link, http.StatusSeeOther)
+}
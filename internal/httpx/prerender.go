@@ -0,0 +1,89 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"unglued/internal/audit"
+	"unglued/internal/model"
+	"unglued/internal/render"
+	"unglued/internal/util"
+)
+
+// prerender rendert eine neue Version im Hintergrund für alle vorgerenderten Themes.
+func (s *Server) prerender(p model.Paste) {
+	if s.Prerender == nil || len(p.Versions) == 0 {
+		return
+	}
+	v := len(p.Versions) - 1
+	lang := p.Versions[v].Lang
+	go func() {
+		// im Hintergrund darf gewartet werden, bis ein Platz frei ist
+		s.Renders.Block()
+		defer s.Renders.Release()
+		for _, theme := range s.Prerender.Themes() {
+			html, plain, err := render.Highlight(p.Code, lang, theme, nil, s.RenderLimits)
+			if err != nil {
+				log.Printf("prerender %s: %v", p.ID, err)
+				return
+			}
+			s.Prerender.Put(p.ID, v, theme, html, plain)
+		}
+	}()
+}
+
+/*
+codeHTML liefert das HTML für Version v: aus dem Cache, sonst gerendert (und für
+vorgerenderte Themes abgelegt, etwa nach einem Neustart). ?hl= kommt erst danach
+dazu, damit der Cache für alle Varianten derselbe bleibt. plain: die Version
+war zu groß oder zu langsam fürs Highlighting (RenderLimits). errBusy: alle
+Render-Plätze belegt (Renders).
+*/
+func (s *Server) codeHTML(ctx context.Context, p model.Paste, v int, theme string, hl map[int]bool) (html template.HTML, plain bool, err error) {
+	if html, plain, ok := s.Prerender.Get(p.ID, v, theme); ok {
+		return render.MarkLines(html, hl), plain, nil
+	}
+	if !s.Renders.Acquire(ctx) {
+		return "", false, errBusy
+	}
+	defer s.Renders.Release()
+	_, ver, ok := s.Store.GetVersion(p.ID, v)
+	if !ok {
+		return "", false, errNotFound
+	}
+	code, err := util.GzipDecode(ver.ZCode)
+	if err != nil {
+		return "", false, err
+	}
+	if !s.Prerender.Wants(theme) {
+		return render.Highlight(code, ver.Lang, theme, hl, s.RenderLimits)
+	}
+	html, plain, err = render.Highlight(code, ver.Lang, theme, nil, s.RenderLimits)
+	if err != nil {
+		return "", false, err
+	}
+	s.Prerender.Put(p.ID, v, theme, html, plain)
+	return render.MarkLines(html, hl), plain, nil
+}
+
+// GET /api/admin/render-cache: Größe und Trefferquote des Render-Caches.
+func (s *Server) handleAdminRenderCache(w http.ResponseWriter, r *http.Request) {
+	if s.Prerender == nil {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "render cache is disabled (-prerender-themes)")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"themes": s.Prerender.Themes(), "stats": s.Prerender.Stats()})
+}
+
+// POST /api/admin/render-cache/flush: alles neu rendern, etwa nach einem Update von Chroma oder den Themes.
+func (s *Server) handleAdminRenderCacheFlush(w http.ResponseWriter, r *http.Request) {
+	n := s.Prerender.Flush()
+	s.record(r, audit.ActionAdmin, "", "", "render-cache flush "+strconv.Itoa(n)+" entries")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"flushed": n})
+}
@@ -0,0 +1,134 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"unglued/internal/secrets"
+)
+
+/*
+API-Fehler als RFC 7807 application/problem+json. Code ist maschinenlesbar und
+Teil des stabilen /api/v1-Vertrags; Title/Detail dürfen sich ändern.
+*/
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+
+	// zum Zitieren bei Fehlermeldungen, steht auch im Log
+	RequestID string `json:"request_id,omitempty"`
+
+	Findings []problemFinding `json:"findings,omitempty"`
+
+	// Bestätigungs-Token für zweistufige Aktionen (z.B. DELETE /api/me/pastes)
+	Confirm string `json:"confirm,omitempty"`
+	Pastes  int    `json:"pastes,omitempty"`
+
+	// 410: wann die Paste abgelaufen ist
+	ExpiredAt string `json:"expired_at,omitempty"`
+
+	// 409 edit_conflict: aktuelle Version und Merge-Vorschlag
+	Current int            `json:"current_version,omitempty"`
+	Merge   *mergeProposal `json:"merge,omitempty"`
+}
+
+type problemFinding struct {
+	Rule string `json:"rule"`
+	Line int    `json:"line"`
+}
+
+// Fehlercodes der API.
+const (
+	codeNotFound        = "not_found"
+	codeGone            = "gone"
+	codeFeatureDisabled = "feature_disabled"
+	codeInvalidJSON     = "invalid_json"
+	codeEmptyCode       = "empty_code"
+	codeInvalidTTL      = "invalid_ttl"
+	codeMissingKey      = "missing_key"
+	codeInvalidKey      = "invalid_key"
+	codeAuthorMismatch  = "author_mismatch"
+	codeMissingQuery    = "missing_query"
+	codeSecretsDetected = "secrets_detected"
+	codeInvalidRequest  = "invalid_request"
+	codeUnauthorized    = "unauthorized"
+	codeInternal        = "internal_error"
+	codeRateLimited     = "rate_limited"
+	codeBanned          = "banned"
+	codeQuotaExceeded   = "quota_exceeded"
+	codeTooLarge        = "paste_too_large"
+	codeContentBlocked  = "content_blocked"
+	codeConfirmRequired = "confirmation_required"
+	codeQuarantined     = "quarantined"
+	codeOTPRequired     = "otp_required"
+	codeOTPInvalid      = "otp_invalid"
+
+	codeMethodNotAllowed      = "method_not_allowed"
+	codeIdempotencyMismatch   = "idempotency_key_reused"
+	codeIdempotencyInProgress = "idempotency_key_in_progress"
+	codeAlreadyExists         = "already_exists"
+	codeBodyTooLarge          = "request_too_large"
+	codeOverloaded            = "overloaded"
+	codeArchiveUnavailable    = "archive_unavailable"
+	codeInvalidReplyTo        = "invalid_reply_to"
+	codeEditConflict          = "edit_conflict"
+)
+
+var (
+	errEmptyCode  = errors.New("Code darf nicht leer sein")
+	errInvalidTTL = errors.New("Ungültige TTL")
+	errNotFound   = errors.New("Paste nicht gefunden")
+)
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	writeProblemBody(w, r, problem{Status: status, Code: code, Detail: detail})
+}
+
+func writeProblemBody(w http.ResponseWriter, r *http.Request, p problem) {
+	p.Type = "urn:unglued:problem:" + p.Code
+	p.Title = http.StatusText(p.Status)
+	p.Instance = r.URL.Path
+	p.Detail = tr(r, p.Detail)
+	p.RequestID = RequestIDFrom(r.Context())
+	if p.Status >= 500 {
+		noteCause(r.Context(), p.Code+": "+p.Detail)
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// writeProblemErr bildet bekannte Validierungsfehler auf Codes ab.
+func writeProblemErr(w http.ResponseWriter, r *http.Request, status int, err error) {
+	code := codeInvalidRequest
+	switch {
+	case errors.Is(err, errEmptyCode):
+		code = codeEmptyCode
+	case errors.Is(err, errInvalidTTL):
+		code = codeInvalidTTL
+	case errors.Is(err, errFeatureDisabled):
+		code = codeFeatureDisabled
+	case errors.Is(err, errInvalidReplyTo):
+		code = codeInvalidReplyTo
+	}
+	writeProblem(w, r, status, code, errText(r, err))
+}
+
+func (s *Server) writeSecretProblem(w http.ResponseWriter, r *http.Request, fs []secrets.Finding) {
+	s.metrics.secretsBlocked(fs)
+	p := problem{
+		Status: http.StatusBadRequest,
+		Code:   codeSecretsDetected,
+		Detail: "Blocked: potential secrets detected",
+	}
+	for _, f := range fs {
+		p.Findings = append(p.Findings, problemFinding{Rule: f.Rule, Line: f.Line})
+	}
+	writeProblemBody(w, r, p)
+}
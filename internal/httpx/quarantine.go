@@ -0,0 +1,93 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/audit"
+	"unglued/internal/model"
+)
+
+/*
+scan schickt die aktuelle Version an clamd. Bei einem Fund (oder mit
+-clamav-fail-closed auch bei Scanner-Fehlern) wandert die Paste in Quarantäne:
+gespeichert, aber nur noch für Admins abrufbar.
+*/
+func (s *Server) scan(r *http.Request, p *model.Paste) {
+	if s.ClamAV == nil {
+		return
+	}
+	sig, err := s.ClamAV.Scan(r.Context(), []byte(p.Code))
+	switch {
+	case err != nil:
+		logf(r, "clamav: %s: %v", p.ID, err)
+		if s.Config.ClamAVFailClosed {
+			s.quarantine(p, "scan failed")
+		}
+	case sig != "":
+		s.quarantine(p, sig)
+	}
+}
+
+func (s *Server) quarantine(p *model.Paste, reason string) {
+	p.Quarantined, p.QuarantineReason = true, reason
+	p.Public = false
+}
+
+// isAdmin: Request trägt gültige Admin-Credentials (auch außerhalb von /admin).
+func (s *Server) isAdmin(r *http.Request) bool {
+	if !s.Config.Admin.Enabled() {
+		return false
+	}
+	_, ok := s.Config.Admin.CheckAdmin(r)
+	return ok
+}
+
+// quarantined schreibt 403 für alle außer Admins; true = Zugriff gesperrt.
+func (s *Server) quarantined(w http.ResponseWriter, r *http.Request, p model.Paste) bool {
+	if !p.Quarantined || s.isAdmin(r) {
+		return false
+	}
+	if isAPIPath(r.URL.Path) {
+		writeProblem(w, r, http.StatusForbidden, codeQuarantined, "paste is quarantined")
+		return true
+	}
+	httpError(w, r, "Diese Paste ist in Quarantäne (Malware-Verdacht) und nur für Admins abrufbar.", http.StatusForbidden)
+	return true
+}
+
+type quarantineItem struct {
+	apiListItem
+	Reason string `json:"reason"`
+}
+
+// GET /api/admin/quarantine
+func (s *Server) handleAdminQuarantine(w http.ResponseWriter, r *http.Request) {
+	ps := s.Store.Find(func(p *model.Paste) bool { return p.Quarantined })
+	items := make([]quarantineItem, 0, len(ps))
+	for _, p := range ps {
+		items = append(items, quarantineItem{apiListItem: s.listItem(r, p), Reason: p.QuarantineReason})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+// POST /api/admin/quarantine/{id}/release: Fehlalarm, Paste wieder freigeben (bleibt ungelistet).
+func (s *Server) handleAdminRelease(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.Get(id)
+	if !ok || !p.Quarantined {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "no quarantined paste "+id)
+		return
+	}
+	reason := p.QuarantineReason
+	p.Quarantined, p.QuarantineReason = false, ""
+	s.Store.Put(p)
+	if s.Search != nil {
+		s.Search.Add(p)
+	}
+	s.record(r, audit.ActionAdmin, id, "", "quarantine.release "+reason)
+	w.WriteHeader(http.StatusNoContent)
+}
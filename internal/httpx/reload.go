@@ -0,0 +1,91 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"unglued/internal/audit"
+)
+
+type templateSet struct {
+	index, view, edit, archive, me, admin, stats, gone, delete, login *template.Template
+}
+
+// liveConfig wird bei jedem Reload komplett ersetzt, nie verändert.
+type liveConfig struct {
+	Reloadable
+	tmpl map[string]templateSet // je Sprache
+}
+
+// conf liefert den aktuell gültigen Reloadable-Stand samt Templates.
+func (s *Server) conf() *liveConfig {
+	return s.live.Load()
+}
+
+/*
+Reconfigure übernimmt rc atomar. Templates aus rc.TemplateDir werden vorher
+komplett geparst; bei einem Fehler bleibt alles beim Alten.
+*/
+func (s *Server) Reconfigure(rc Reloadable) error {
+	set, err := s.parseTemplates(rc.TemplateDir)
+	if err != nil {
+		return err
+	}
+	s.live.Store(&liveConfig{Reloadable: rc, tmpl: localize(set)})
+	return nil
+}
+
+// parseTemplates: die eingebetteten Templates, überschrieben von *.html aus dir (leer = keine).
+func (s *Server) parseTemplates(dir string) (templateSet, error) {
+	set := s.base
+	if dir == "" {
+		return set, nil
+	}
+	for _, t := range []struct {
+		dst  **template.Template
+		name string
+	}{
+		{&set.index, "index"},
+		{&set.view, "view"},
+		{&set.edit, "edit"},
+		{&set.archive, "archive"},
+		{&set.me, "me"},
+		{&set.admin, "admin"},
+		{&set.stats, "stats"},
+		{&set.gone, "gone"},
+		{&set.delete, "delete"},
+		{&set.login, "login"},
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, t.name+".html"))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return set, err
+		}
+		if *t.dst, err = template.New(t.name).Funcs(tmplFuncs).Parse(string(b)); err != nil {
+			return set, err
+		}
+	}
+	return set, nil
+}
+
+// POST /api/admin/reload – wie SIGHUP
+func (s *Server) handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	if s.Reload == nil {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "reload is not available")
+		return
+	}
+	if err := s.Reload(); err != nil {
+		writeProblem(w, r, http.StatusUnprocessableEntity, codeInvalidRequest, "reload failed: "+err.Error())
+		return
+	}
+	s.record(r, audit.ActionAdmin, "", "", "config.reload")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]bool{"reloaded": true})
+}
@@ -0,0 +1,107 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/model"
+)
+
+var errInvalidReplyTo = errors.New("Antwort auf eine unbekannte Paste")
+
+/*
+replyTarget prüft reply_to beim Anlegen: ID oder Link (/p/ID, auch mit /v/N)
+einer Paste, die es gibt und die der Aufrufer sehen darf. Leer bleibt leer.
+*/
+func (s *Server) replyTarget(r *http.Request, raw string) (string, error) {
+	id := strings.TrimSpace(raw)
+	if id == "" {
+		return "", nil
+	}
+	if _, rest, ok := strings.Cut(id, "/p/"); ok {
+		id, _, _ = strings.Cut(rest, "/")
+		id, _, _ = strings.Cut(id, "?")
+	}
+	p, ok := s.Store.GetMeta(id)
+	if !ok || p.Quarantined || !s.canView(r, p) {
+		return "", errInvalidReplyTo
+	}
+	return p.ID, nil
+}
+
+/*
+replyLinks: die Paste, auf die p antwortet, und die Antworten auf p – jeweils
+nur, was der Aufrufer sehen darf. Nicht gelistete Antworten zeigt die Ausgangs-
+Paste nur, wenn der Aufrufer eine der beiden editieren darf; sonst würde ihr
+Link an alle verteilt, die die Ausgangs-Paste lesen.
+*/
+func (s *Server) replyLinks(r *http.Request, p model.Paste) (parent *model.Paste, replies []model.Paste) {
+	if p.ReplyTo != "" {
+		if pp, ok := s.Store.GetMeta(p.ReplyTo); ok && !pp.Quarantined && s.canView(r, pp) {
+			parent = &pp
+		}
+	}
+	ownParent := s.canEditPaste(r, p)
+	for _, c := range s.Store.Replies(p.ID) {
+		if c.Quarantined || !s.canView(r, c) {
+			continue
+		}
+		if (!c.Public || c.Flagged) && !ownParent && !s.canEditPaste(r, c) {
+			continue
+		}
+		replies = append(replies, c)
+	}
+	return parent, replies
+}
+
+type apiRepliesResp struct {
+	ID      string        `json:"id"`
+	ReplyTo *apiListItem  `json:"reply_to"`
+	Replies []apiListItem `json:"replies"`
+}
+
+// GET /api/paste/{id}/replies: beide Richtungen der Verknüpfung, wie auf der Ansicht.
+func (s *Server) handleAPIReplies(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.GetMeta(id)
+	if !ok {
+		s.apiNotFound(w, r, id)
+		return
+	}
+	if p.Quarantined || !s.canView(r, p) {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not found or expired")
+		return
+	}
+	parent, replies := s.replyLinks(r, p)
+	resp := apiRepliesResp{ID: p.ID, Replies: make([]apiListItem, 0, len(replies))}
+	if parent != nil {
+		it := s.listItem(r, *parent)
+		resp.ReplyTo = &it
+	}
+	for _, c := range replies {
+		resp.Replies = append(resp.Replies, s.listItem(r, c))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// replyPrefill: /?reply_to=ID füllt das Formular mit der Ausgangs-Paste vor.
+func (s *Server) replyPrefill(r *http.Request) map[string]string {
+	id, err := s.replyTarget(r, r.URL.Query().Get("reply_to"))
+	if err != nil || id == "" {
+		return nil
+	}
+	p, ok := s.Store.Get(id)
+	if !ok {
+		return nil
+	}
+	title := p.Title
+	if title == "" {
+		title = p.ID
+	}
+	return map[string]string{"ID": p.ID, "Title": "Re: " + title, "Code": p.Code, "Lang": p.Lang}
+}
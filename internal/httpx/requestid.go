@@ -0,0 +1,96 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"unglued/internal/util"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	causesKey           // *causes, siehe reportErrors
+)
+
+/*
+RequestID übernimmt ein X-Request-ID vom Proxy (wenn es harmlos aussieht) oder
+erzeugt eines, legt es in den Context und schickt es in der Antwort zurück.
+Einfache Text-Fehlerseiten (http.Error) bekommen es als letzte Zeile angehängt,
+damit Benutzer es bei Rückfragen nennen können.
+*/
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = util.NewID(12)
+		}
+		w.Header().Set(requestIDHeader, id)
+		ew := &errorPageWriter{ResponseWriter: w}
+		next.ServeHTTP(ew, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+		if ew.plainError && r.Method != http.MethodHead {
+			_, _ = io.WriteString(w, "Request-ID: "+id+"\n")
+		}
+	})
+}
+
+// RequestIDFrom liefert die ID des laufenden Requests ("" ohne RequestID-Middleware).
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// logf: log.Printf mit vorangestellter Request-ID, damit Log und Fehlermeldung zusammenfinden.
+func logf(r *http.Request, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	noteCause(r.Context(), msg)
+	if id := RequestIDFrom(r.Context()); id != "" {
+		msg = "[" + id + "] " + msg
+	}
+	log.Output(2, msg)
+}
+
+// errorPageWriter erkennt Text-Fehlerantworten (Status >= 400, text/plain).
+type errorPageWriter struct {
+	http.ResponseWriter
+	wrote      bool
+	plainError bool
+}
+
+func (w *errorPageWriter) WriteHeader(code int) {
+	if !w.wrote && code >= 100 && code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if !w.wrote {
+		w.wrote = true
+		w.plainError = code >= 400 && strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *errorPageWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *errorPageWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
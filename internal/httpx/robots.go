@@ -0,0 +1,47 @@
+package httpx
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+/*
+GET /robots.txt: aus der Konfiguration erzeugt. Standard ist "alles verboten"; mit
+öffentlichem Archiv dürfen Crawler Archiv und Feed lesen, mit -allow-indexing auch
+die Pastes (ohne Edit-/Lösch-Seiten), plus Verweis auf die Sitemap. -robots-file
+ersetzt das Ganze durch eine eigene Datei.
+*/
+func (s *Server) handleRobots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	if path := s.conf().RobotsFile; path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			logf(r, "robots: %v", err)
+			httpError(w, r, "robots.txt nicht verfügbar", http.StatusInternalServerError)
+			return
+		}
+		serveBody(w, r, "text/plain; charset=utf-8", time.Time{}, b)
+		return
+	}
+	serveBody(w, r, "text/plain; charset=utf-8", time.Time{}, []byte(s.robotsTxt(r)))
+}
+
+func (s *Server) robotsTxt(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	archive := s.conf().Features.Archive
+	if archive {
+		b.WriteString("Allow: /archive\nAllow: /feed.atom\n")
+	}
+	index := archive && s.Config.AllowIndexing
+	if index {
+		b.WriteString("Allow: /p/\nDisallow: /p/*/edit\nDisallow: /p/*/delete\n")
+	}
+	b.WriteString("Disallow: /\n")
+	if index {
+		b.WriteString("\nSitemap: " + s.makeURL(r, "/sitemap.xml") + "\n")
+	}
+	return b.String()
+}
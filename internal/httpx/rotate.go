@@ -0,0 +1,54 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/audit"
+	"unglued/internal/model"
+	"unglued/internal/util"
+)
+
+/*
+POST /api/paste/{id}/rotate-key?key=…: neuer Edit-Key für eine Paste, deren
+Edit-Link irgendwo gelandet ist, wo er nicht hingehört. Edit-Links, Edit-Cookies
+und Lösch-Links hängen alle am Key und sind danach ungültig; zurück kommen die
+neuen Links. Inhalt und Versionen bleiben unverändert.
+*/
+func (s *Server) handleAPIRotateKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.Get(id)
+	if !ok {
+		s.apiNotFound(w, r, id)
+		return
+	}
+	if !s.ownerKey(w, r, p) {
+		return
+	}
+	// atomar: eine gleichzeitige Bearbeitung darf den alten Key nicht zurückschreiben
+	p, err := s.updateAsOwner(r, id, func(cur *model.Paste) error {
+		cur.EditKey = util.NewID(12) // keine neue Version, nur Metadaten
+		return nil
+	})
+	if err != nil {
+		s.writeOwnerUpdateError(w, r, id, err)
+		return
+	}
+	s.record(r, audit.ActionRotateKey, p.ID, "", "")
+
+	s.setCookie(w, r, "npk_"+p.ID, p.EditKey, 365*24*time.Hour)
+	out := map[string]any{
+		"id":         p.ID,
+		"edit_url":   s.makeURL(r, s.editURL(p)),
+		"key":        s.Auth.EditToken(p.ID, p.EditKey, p.ExpiresAt),
+		"rotated_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	if del, err := s.deletionURL(r, p); err == nil {
+		out["deletion_url"] = del
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,67 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/store"
+)
+
+func apiCall(t *testing.T, h http.Handler, method, path string, body any) (int, map[string]any) {
+	t.Helper()
+	j, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(j))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	var out map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	return w.Code, out
+}
+
+func editToken(t *testing.T, editURL any) string {
+	t.Helper()
+	u, err := url.Parse(editURL.(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("key")
+}
+
+func TestRotatedKeyCannotEdit(t *testing.T) {
+	st := store.New(time.Hour)
+	defer st.Close()
+	index, view, edit := LoadTemplates()
+	srv := NewServer(Config{Reloadable: Reloadable{MaxPasteBytes: 1 << 20, Features: AllFeatures()}}, st, index, view, edit)
+	h := chi.NewRouter()
+	MountRoutes(h, srv)
+
+	code, created := apiCall(t, h, http.MethodPost, "/api/v1/paste", map[string]any{"code": "one", "editable": true})
+	if code != http.StatusOK && code != http.StatusCreated {
+		t.Fatalf("create: %d %v", code, created)
+	}
+	id, old := created["id"].(string), editToken(t, created["edit_url"])
+
+	code, rotated := apiCall(t, h, http.MethodPost, "/api/v1/paste/"+id+"/rotate-key?key="+url.QueryEscape(old), nil)
+	if code != http.StatusOK {
+		t.Fatalf("rotate: %d %v", code, rotated)
+	}
+	if code, _ := apiCall(t, h, http.MethodPost, "/api/v1/paste/"+id+"/edit?key="+url.QueryEscape(old), map[string]any{"code": "two"}); code != http.StatusForbidden {
+		t.Errorf("edit with the old key: %d, want 403", code)
+	}
+	fresh := editToken(t, rotated["edit_url"])
+	if code, out := apiCall(t, h, http.MethodPost, "/api/v1/paste/"+id+"/edit?key="+url.QueryEscape(fresh), map[string]any{"code": "two"}); code != http.StatusOK {
+		t.Errorf("edit with the new key: %d %v", code, out)
+	}
+	if p, _ := st.Get(id); p.Code != "two" || len(p.Versions) != 2 {
+		t.Errorf("code %q with %d versions, want the edit as version 2", p.Code, len(p.Versions))
+	}
+}
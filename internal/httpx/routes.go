@@ -2,21 +2,104 @@ package httpx
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 )
 
 func MountRoutes(r chi.Router, s *Server) {
+	r.Use(s.reportErrors)
+	r.Use(s.measure)
+	r.Use(s.withLang)
+	r.Use(s.noCache)
 	r.Get("/", s.handleIndex)
-	r.Post("/paste", s.handleCreate)
+	r.Post("/", s.limitBody(s.guardCreate(s.handleRootPost)))
+	r.Post("/paste", s.limitBody(s.guardCreate(s.handleCreate)))
 	r.Get("/p/{id}", s.handleView)
 	r.Get("/raw/{id}", s.handleRaw)
-	r.Get("/p/{id}/edit", s.handleEditForm)
-	r.Post("/p/{id}/edit", s.handleEditSave)
+	r.Head("/p/{id}", s.handleView)
+	r.Head("/raw/{id}", s.handleRaw)
+	// Versions-Permalinks: ändern sich nie, daher immutable gecacht
+	r.Get("/p/{id}/v/{n}", s.handleView)
+	r.Get("/raw/{id}/v/{n}", s.handleRaw)
+	r.Head("/p/{id}/v/{n}", s.handleView)
+	r.Head("/raw/{id}/v/{n}", s.handleRaw)
+	// ANSI-Farben fürs Terminal (curl | less -R); alternativ Accept: text/x-ansi
+	r.Get("/p/{id}.ansi", s.handleView)
+	r.Get("/p/{id}/v/{n}.ansi", s.handleView)
+	r.Get("/p/{id}/snippets", s.handleSnippets)
+	r.Get("/p/{id}/access", s.handleAccess)
+	r.Get("/p/{id}/edit", s.feature(editOn, s.handleEditForm))
+	r.Post("/p/{id}/edit", s.feature(editOn, s.limitBody(s.handleEditSave)))
+	r.Post("/p/{id}/clone", s.feature(editOn, s.limitBody(s.guardCreate(s.handleClone))))
+	r.Post("/p/{id}/grants/revoke", s.feature(privateOn, s.limitSmall(s.handleRevokeGrants)))
+	r.Post("/p/{id}/undelete", s.limitSmall(s.handleUndelete))
+	r.Get("/p/{id}/delete", s.handleDeleteForm)
+	r.Post("/p/{id}/delete", s.limitSmall(s.handleDelete))
+	r.Post("/p/{id}/playground", s.limitSmall(s.handlePlayground))
+	r.Get("/sharex.sxcu", s.handleShareXConfig)
+	r.Get("/archive", s.feature(archiveOn, s.handleArchive))
+	r.Get("/feed.atom", s.feature(archiveOn, s.handleFeed))
+	r.Get("/sitemap.xml", s.handleSitemap)
+	r.Get("/robots.txt", s.handleRobots)
+	r.Get("/metrics", s.handleMetrics)
+	r.Get("/me", s.handleMe)
+	r.Post("/me/delete", s.limitSmall(s.handleMeDelete))
+	r.Get("/login", s.handleLogin)
+	r.Get("/auth/callback", s.handleAuthCallback)
+	r.Get("/logout", s.handleLogout)
+	r.Get("/static/chroma-{theme}.css", s.handleThemeCSS)
+	r.Handle("/static/*", s.staticHandler())
 
-	// API
-	r.Post("/api/paste", s.handleAPIPaste)
-	r.Post("/api/paste/{id}/edit", s.handleAPIEdit)
+	// API: /api/v1 ist der stabile Vertrag, /api/... bleibt als Alias bestehen.
+	for _, prefix := range []string{"/api/v1", "/api"} {
+		r.Post(prefix+"/paste", s.limitBody(s.idempotent(s.guardCreate(s.handleAPIPaste))))
+		r.Post(prefix+"/paste/{id}/edit", s.feature(editOn, s.limitBody(s.handleAPIEdit)))
+		r.Post(prefix+"/paste/{id}/rotate-key", s.feature(editOn, s.limitSmall(s.handleAPIRotateKey)))
+		r.Get(prefix+"/paste/{id}/keys", s.feature(editOn, s.handleAPIKeys))
+		r.Post(prefix+"/paste/{id}/keys", s.feature(editOn, s.limitSmall(s.handleAPIAddKey)))
+		r.Delete(prefix+"/paste/{id}/keys/{name}", s.feature(editOn, s.limitSmall(s.handleAPIRevokeKey)))
+		r.Post(prefix+"/paste/{id}/grants", s.feature(privateOn, s.limitSmall(s.handleAPIGrant)))
+		r.Post(prefix+"/paste/{id}/undelete", s.limitSmall(s.handleAPIUndelete))
+		r.Get(prefix+"/paste/{id}/replies", s.handleAPIReplies)
+		r.Get(prefix+"/paste/{id}/access", s.handleAPIAccess)
+		r.Get(prefix+"/paste/{id}/versions", s.handleAPIVersions)
+		r.Get(prefix+"/paste/{id}/stats", s.handleAPIPasteStats)
+		r.Post(prefix+"/sharex", s.limitBody(s.guardCreate(s.handleShareX)))
+		r.Post(prefix+"/ingest/ci", s.limitBody(s.handleCIIngest))
+		r.Post(prefix+"/integrations/slack", s.limitBody(s.handleSlack))
+		r.Post(prefix+"/integrations/discord", s.limitBody(s.handleDiscord))
+		r.Post(prefix+"/integrations/mattermost", s.limitBody(s.handleMattermost))
+		r.Delete(prefix+"/paste/{id}/grants", s.feature(privateOn, s.limitSmall(s.handleAPIRevokeGrants)))
+		r.Get(prefix+"/pastes", s.feature(archiveOn, s.handleAPIList))
+		r.Get(prefix+"/search", s.feature(searchOn, s.handleAPISearch))
+		r.Get(prefix+"/lookup", s.handleAPILookup)
+		r.Get(prefix+"/version", s.handleAPIVersion)
+		r.Get(prefix+"/me/pastes", s.handleAPIMyPastes)
+		r.Get(prefix+"/me/export", s.handleAPIMyExport)
+		r.Delete(prefix+"/me/pastes", s.limitSmall(s.handleAPIMyDelete))
+	}
+
+	s.mountAdmin(r)
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		if isAPIPath(r.URL.Path) {
+			writeProblem(w, r, http.StatusNotFound, codeNotFound, "no such endpoint")
+			return
+		}
+		http.NotFound(w, r)
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		if isAPIPath(r.URL.Path) {
+			writeProblem(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, r.Method+" not allowed here")
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	})
+}
+
+func isAPIPath(p string) bool {
+	return p == "/api" || strings.HasPrefix(p, "/api/")
 }
 
 func NoIndex(next http.Handler) http.Handler {
@@ -0,0 +1,92 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"unglued/internal/model"
+	"unglued/internal/search"
+)
+
+const (
+	searchDefaultResults = 20
+	searchMaxResults     = 100
+)
+
+/*
+searchPastes: Treffer aus dem Index, gefiltert auf öffentliche Pastes und solche,
+die der Besucher editieren darf (Key/Cookie). Abgelaufene IDs fliegen aus dem Index.
+*/
+func (s *Server) searchPastes(r *http.Request, q string, limit int) []model.Paste {
+	if s.Search == nil {
+		return nil
+	}
+	var out []model.Paste
+	for _, id := range s.Search.Search(q) {
+		p, ok := s.Store.Get(id)
+		if !ok {
+			s.Search.Remove(id)
+			continue
+		}
+		if (!p.Public && !s.canEditPaste(r, p)) || !s.canView(r, p) {
+			continue
+		}
+		out = append(out, p)
+		if len(out) == limit {
+			break
+		}
+	}
+	return out
+}
+
+func (s *Server) handleAPISearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeProblem(w, r, http.StatusBadRequest, codeMissingQuery, "missing ?q")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = searchDefaultResults
+	}
+	if limit > searchMaxResults {
+		limit = searchMaxResults
+	}
+	hits := s.searchPastes(r, q, limit)
+	items := make([]apiListItem, 0, len(hits))
+	for _, p := range hits {
+		it := s.listItem(r, p)
+		it.Snippet = snippet(p.Code, q)
+		items = append(items, it)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"query": q,
+		"total": len(items),
+		"items": items,
+	})
+}
+
+// snippet liefert die erste Zeile, die einen der Suchbegriffe enthält.
+func snippet(code, q string) string {
+	terms := search.Tokenize(q)
+	for _, ln := range strings.Split(code, "\n") {
+		low := strings.ToLower(ln)
+		for _, t := range terms {
+			if strings.Contains(low, t) {
+				return truncateRunes(strings.TrimSpace(ln), 160)
+			}
+		}
+	}
+	return ""
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,50 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+)
+
+/*
+SecurityHeaders setzt CSP und die üblichen Schutz-Header. frameAncestors landet
+1:1 in der CSP-Direktive (Default "'none'"); für Einbettungen z.B.
+"'self' https://wiki.example.com". images sind zusätzliche Bild-Origins (Avatare
+beim GitHub-Login), thirdParty Origins, die Scripts, Styles und Frames liefern
+dürfen (z.B. das CAPTCHA-Widget).
+*/
+func SecurityHeaders(frameAncestors string, images []string, thirdParty ...string) func(http.Handler) http.Handler {
+	if strings.TrimSpace(frameAncestors) == "" {
+		frameAncestors = "'none'"
+	}
+	extra := ""
+	if len(thirdParty) > 0 {
+		extra = " " + strings.Join(thirdParty, " ")
+	}
+	directives := []string{
+		"default-src 'self'",
+		"script-src 'self'" + extra,
+		"style-src 'self'" + extra,
+		strings.Join(append([]string{"img-src 'self' data:"}, images...), " "),
+		"object-src 'none'",
+		"base-uri 'none'",
+		"form-action 'self'",
+		"frame-ancestors " + frameAncestors,
+	}
+	if extra != "" {
+		directives = append(directives, "frame-src"+extra, "connect-src 'self'"+extra)
+	}
+	csp := strings.Join(directives, "; ")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Content-Security-Policy", csp)
+			h.Set("X-Content-Type-Options", "nosniff")
+			// Edit-Links tragen Tokens in der URL: nie als Referer weitergeben
+			h.Set("Referrer-Policy", "no-referrer")
+			if frameAncestors == "'none'" {
+				h.Set("X-Frame-Options", "DENY")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,20 +2,110 @@ package httpx
 
 import (
 	"html/template"
+	"log"
     "net/http"
     "strings"
+	"sync/atomic"
+    "time"
+	"unglued/internal/abuse"
+	"unglued/internal/audit"
+	"unglued/internal/auth"
+	"unglued/internal/captcha"
+	"unglued/internal/coldstore"
+	"unglued/internal/clamav"
+	"unglued/internal/discord"
+	"unglued/internal/errreport"
+	"unglued/internal/hits"
+	"unglued/internal/matrix"
+	"unglued/internal/model"
+	"unglued/internal/mattermost"
+	"unglued/internal/playground"
+	"unglued/internal/moderation"
+	"unglued/internal/render"
+	"unglued/internal/search"
+	"unglued/internal/slack"
+	"unglued/internal/stats"
 	"unglued/internal/store"
+	"unglued/internal/viewlog"
+	"unglued/internal/webhook"
 )
 
 /*
 Server hält Store, Config und bereits geparste Templates.
 */
 type Server struct {
-	Store     *store.Store
-	Config    Config
-	IndexTmpl *template.Template
-	ViewTmpl  *template.Template
-	EditTmpl  *template.Template
+	Store  *store.Store
+	Config Config
+
+	Search *search.Index
+
+	// signiert Edit-Links und Sessions; NewServer setzt einen Signer mit Zufallskey
+	Auth *auth.Signer
+	// Login-Methoden für Benutzer (OIDC, GitHub, OAuth2); leer = kein Login
+	Logins []auth.Provider
+	// API-Tokens mit eigenem Namensraum, Quoten und Laufzeiten; nil = keine
+	Tokens *auth.Tokens
+
+	// optional; nil = keine Webhooks
+	Hooks *webhook.Dispatcher
+
+	// Append-only-Protokoll; nil = aus
+	Audit *audit.Log
+
+	// CAPTCHA fürs HTML-Formular; nil = aus
+	Captcha *captcha.Verifier
+
+	// clamd-Scan vor dem Speichern; nil = aus
+	ClamAV *clamav.Client
+
+	// externer Scanner vor dem Speichern; nil = aus
+	Moderator *moderation.Moderator
+
+	// Slash-Command und Link-Vorschauen für Slack; nil = aus
+	Slack *slack.Client
+	// Interactions-Endpunkt für Discord; nil = aus
+	Discord *discord.Client
+	// Outgoing Webhook und Slash-Command für Mattermost; nil = aus
+	Mattermost *mattermost.Client
+	// Bot in Matrix-Räumen; nil = aus
+	Matrix *matrix.Bot
+	// "Im Go Playground ausführen" für Go-Pastes; nil = aus
+	Playground *playground.Client
+	// Archiv abgelaufener Pastes in S3 (zugleich Store.Archiver); nil = aus
+	ColdStore *coldstore.Archive
+	// Panics und 5xx an Sentry oder einen Webhook; nil = aus
+	Errors *errreport.Reporter
+	// beim Schreiben vorgerendertes HTML je Version und Theme; nil = immer bei Bedarf rendern
+	Prerender *render.Cache
+	// Größe und Zeitbudget fürs Highlighting; darüber gibt es reinen Text
+	RenderLimits render.Limits
+	// gleichzeitiges Highlighting (Cache-Treffer zählen nicht); nil = unbegrenzt
+	Renders *Limiter
+
+	// Rate-Limit/Sperrliste beim Anlegen; nil = aus
+	Abuse *abuse.Guard
+	// Spam-Regeln für Anlegen/Bearbeiten; nil = aus
+	Blocklist *abuse.Blocklist
+	// TOTP als zweiter Faktor für den Admin-Bereich; nil = aus
+	TwoFactor *auth.TwoFactor
+
+	// Aufrufe je Paste für den Besitzer (/p/{id}/access); nil = aus
+	Views *viewlog.Log
+	// Aufrufe und ungefähre Besucher je Paste (/api/paste/{id}/stats); NewServer legt es an
+	Hits *hits.Counter
+
+	// Zeitreihen für /admin/stats; NewServer legt eine Woche in Stunden an
+	Stats *stats.Recorder
+
+	// von main gesetzt: liest die Konfiguration neu (POST /api/admin/reload); nil = aus
+	Reload func() error
+
+	idem    *idemCache
+	metrics *serverMetrics
+
+	// eingebettete Templates und der aktuell gültige Reloadable-Stand
+	base templateSet
+	live atomic.Pointer[liveConfig]
 }
 
 /*
@@ -23,19 +113,133 @@ Config: aktuell nur PublicBase; bei Bedarf erweiterbar (ListenAddr usw.).
 */
 type Config struct {
 	PublicBase string
+
+	// wie lange ein Idempotency-Key dieselbe Antwort liefert; 0 = Header ignorieren
+	IdempotencyTTL time.Duration
+
+	// Zugang zu /admin und /api/admin; leer = Admin-Bereich deaktiviert
+	Admin auth.AdminConfig
+
+	// optionaler Login für Benutzer: OIDC und/oder OAuth2-Dienste wie GitHub
+	OIDC         auth.OIDCConfig
+	OAuth2       []auth.OAuth2Config
+	RequireLogin bool // Anlegen nur mit Login
+	SessionTTL   time.Duration
+
+	// Cookie-Härtung: CookieSecure auto|always|never, SameSite 0 = Lax
+	CookieSecure   string
+	CookieSameSite http.SameSite
+
+	// öffentliche Pastes dürfen sich für Suchmaschinen freigeben (sonst überall noindex)
+	AllowIndexing bool
+
+	// Avatare zur optionalen E-Mail anonymer Autoren: "gravatar", "libravatar", leer = aus
+	Avatars string
+
+	// Scanner-Fehler → Quarantäne statt durchlassen
+	ClamAVFailClosed bool
+
+	// Startwerte; zur Laufzeit gilt, was Reconfigure zuletzt gesetzt hat
+	Reloadable
+
+	// Proxies, deren X-Forwarded-For als Client-IP gilt
+	TrustedProxies abuse.Proxies
+
+	// Shared Secret für POST /api/ingest/ci; leer = aus
+	CIToken string
+
+	// Bearer-Token für GET /metrics (Prometheus); leer = aus
+	MetricsToken string
+
+	// Header mit dem Ländercode des Clients (CF-IPCountry & Co.) fürs Zugriffsprotokoll; leer = kein Land
+	CountryHeader string
+
+	// Dev-Modus: Templates und static/ unter diesem Quellverzeichnis pro Request neu lesen, keine Caches; leer = aus
+	DevDir string
+}
+
+/*
+Reloadable: der Teil der Config, der per SIGHUP oder POST /api/admin/reload ohne
+Neustart wechselt. Laufende Requests behalten ihren Stand, der Store bleibt unberührt.
+*/
+type Reloadable struct {
+	// Obergrenze pro Paste-Version in Bytes; 0 = unbegrenzt
+	MaxPasteBytes int64
+
+	// längste erlaubte Ablaufzeit beim Anlegen; 0 = unbegrenzt
+	MaxTTL time.Duration
+	// kürzeste; 0 = beliebig kurz
+	MinTTL time.Duration
+
+	// Honeypot & Co. am HTML-Formular
+	Bots BotTraps
+
+	// *.html hier ersetzen die eingebetteten Templates gleichen Namens; leer = eingebettet
+	TemplateDir string
+
+	// abschaltbare Fähigkeiten; main startet mit AllFeatures
+	Features Features
+
+	// eigene robots.txt statt der erzeugten; leer = aus Features und AllowIndexing erzeugt
+	RobotsFile string
+
+	// Sprache der Oberfläche, wenn weder ?lang=, Cookie noch Accept-Language passen; leer = de
+	UILang string
 }
 
 /*
 NewServer: du gibst geparste Templates rein (siehe MustParseTemplates in templates.go).
 */
 func NewServer(cfg Config, st *store.Store, index, view, edit *template.Template) *Server {
-	return &Server{
-		Store:     st,
-		Config:    cfg,
-		IndexTmpl: index,
-		ViewTmpl:  view,
-		EditTmpl:  edit,
+	srv := &Server{
+		Store:  st,
+		Config: cfg,
+
+		Search: search.New(),
+		Auth:   auth.NewSigner(nil, 30*24*time.Hour),
+		Stats:  stats.New(time.Hour, 7*24),
+		Hits:   hits.New(),
+
+		base: templateSet{
+			index:   index,
+			view:    view,
+			edit:    edit,
+			archive: template.Must(template.New("archive").Funcs(tmplFuncs).Parse(archiveHTML)),
+			me:      template.Must(template.New("me").Funcs(tmplFuncs).Parse(meHTML)),
+			admin:   template.Must(template.New("admin").Funcs(tmplFuncs).Parse(adminHTML)),
+			stats:   template.Must(template.New("stats").Funcs(tmplFuncs).Parse(statsHTML)),
+			gone:    template.Must(template.New("gone").Funcs(tmplFuncs).Parse(goneHTML)),
+			delete:  template.Must(template.New("delete").Funcs(tmplFuncs).Parse(deleteHTML)),
+			login:   template.Must(template.New("login").Funcs(tmplFuncs).Parse(loginHTML)),
+		},
+	}
+	if err := srv.Reconfigure(cfg.Reloadable); err != nil {
+		// eingebettete Templates bleiben, damit der Server trotzdem hochkommt
+		log.Printf("templates: %v", err)
+		srv.live.Store(&liveConfig{Reloadable: cfg.Reloadable, tmpl: localize(srv.base)})
+	}
+	if cfg.OIDC.Enabled() {
+		srv.Logins = append(srv.Logins, auth.NewOIDC(cfg.OIDC))
+	}
+	for _, c := range cfg.OAuth2 {
+		srv.Logins = append(srv.Logins, auth.NewOAuth2(c))
+	}
+	if len(srv.Logins) > 0 {
+		if srv.Config.SessionTTL <= 0 {
+			srv.Config.SessionTTL = 7 * 24 * time.Hour
+		}
+	}
+	srv.metrics = newServerMetrics(srv)
+	st.OnExpire(func(n int) { srv.Stats.Add(stats.Expired, n) })
+	st.OnExpirePaste(func(p model.Paste) {
+		srv.emitExpired(p)
+		srv.forgetViews(p.ID)
+		srv.Hits.Forget(p.ID)
+	})
+	if cfg.IdempotencyTTL > 0 {
+		srv.idem = newIdemCache(cfg.IdempotencyTTL)
 	}
+	return srv
 }
 
 func parseAnyForm(r *http.Request) error {
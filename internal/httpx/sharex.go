@@ -0,0 +1,206 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/model"
+	"unglued/internal/secrets"
+	"unglued/internal/util"
+	"unglued/internal/webhook"
+)
+
+/*
+deleteClaim steckt im Lösch-Link: gilt nur, solange die Paste denselben Key hat.
+Der Link ist nur signiert, nicht verschlüsselt – darum steht statt des Keys ein
+HMAC darüber drin.
+*/
+type deleteClaim struct {
+	ID      string `json:"id"`
+	KeyHash string `json:"kh"`
+}
+
+// deleteClaimOK: Claim gehört zu p und deren aktuellem Edit-Key.
+func (s *Server) deleteClaimOK(c deleteClaim, p model.Paste) bool {
+	return c.ID == p.ID && s.Auth.MatchKeyDigest("delete", p.ID, p.EditKey, c.KeyHash)
+}
+
+type shareXResp struct {
+	URL         string `json:"url"`
+	RawURL      string `json:"raw_url"`
+	DeletionURL string `json:"deletion_url"`
+	ExpiresAt   string `json:"expires_at"`
+	CreatedAt   string `json:"created_at"`
+	Size        int    `json:"size"`
+}
+
+// deletionURL: signierter Link auf die Lösch-Bestätigung, gültig bis zum Ablauf der Paste.
+func (s *Server) deletionURL(r *http.Request, p model.Paste) (string, error) {
+	tok, err := s.Auth.Seal("delete", deleteClaim{ID: p.ID, KeyHash: s.Auth.KeyDigest("delete", p.ID, p.EditKey)}, time.Until(p.ExpiresAt))
+	if err != nil {
+		return "", err
+	}
+	return s.makeURL(r, "/p/"+p.ID+"/delete?key="+url.QueryEscape(tok)), nil
+}
+
+/*
+POST /api/sharex: Text-Uploader für ShareX und ähnliche Tools. Inhalt als Multipart-
+oder Formularfeld (text, file, …) wie bei POST /, Optionen als Query (?lang=, ?ttl=,
+?title=, ?redact=). Antwort ist JSON mit url und deletion_url, Fehler Problem-JSON.
+*/
+func (s *Server) handleShareX(w http.ResponseWriter, r *http.Request) {
+	if !s.mayCreate(r) {
+		writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, "login required to create pastes")
+		return
+	}
+	code, err := rootPasteBody(r, s.conf().Features.Uploads)
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
+	if err != nil {
+		writeProblemErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	q := r.URL.Query()
+	var redacted []string
+	if util.IsTruthy(q.Get("redact")) {
+		code, redacted = secrets.Redact(code)
+	} else if fs := secrets.Scan(code); len(fs) > 0 {
+		s.writeSecretProblem(w, r, fs)
+		return
+	}
+	by := s.identify(r, "", "")
+	p, err := s.buildPaste(pasteOpts{
+		Code:     code,
+		Lang:     q.Get("lang"),
+		TTL:      q.Get("ttl"),
+		Title:    q.Get("title"),
+		Author:   by.Name,
+		AuthorID: by.ID,
+		Avatar:   by.Avatar,
+		Profile:  by.Profile,
+		Redacted: redacted,
+	})
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
+	if err != nil {
+		writeProblemErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	// nicht editierbar, der Key dient nur dem Lösch-Link
+	p.EditKey = util.NewID(12)
+	if !s.moderate(w, r, webhook.EventCreated, &p) {
+		return
+	}
+	s.save(r, webhook.EventCreated, p)
+
+	del, err := s.deletionURL(r, p)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(shareXResp{
+		URL:         s.makeURL(r, "/p/"+p.ID),
+		RawURL:      s.makeURL(r, "/raw/"+p.ID),
+		DeletionURL: del,
+		ExpiresAt:   p.ExpiresAt.Format(time.RFC3339),
+		CreatedAt:   p.CreatedAt.Format(time.RFC3339),
+		Size:        len(p.Code),
+	})
+}
+
+/*
+GET /sharex.sxcu: fertige ShareX-Konfiguration für diese Instanz; ?ttl= und ?lang=
+landen als feste Parameter darin.
+*/
+func (s *Server) handleShareXConfig(w http.ResponseWriter, r *http.Request) {
+	endpoint := s.makeURL(r, "/api/sharex")
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil {
+		host = u.Host
+	}
+	cfg := map[string]any{
+		"Version":         "15.0.0",
+		"Name":            "unglued (" + host + ")",
+		"DestinationType": "TextUploader",
+		"RequestMethod":   "POST",
+		"RequestURL":      endpoint,
+		"Body":            "MultipartFormData",
+		"Arguments":       map[string]string{"text": "{input}"},
+		"URL":             "{json:url}",
+		"DeletionURL":     "{json:deletion_url}",
+		"ErrorMessage":    "{json:detail}",
+	}
+	params := map[string]string{}
+	for _, k := range []string{"ttl", "lang"} {
+		if v := strings.TrimSpace(r.URL.Query().Get(k)); v != "" {
+			params[k] = v
+		}
+	}
+	if len(params) > 0 {
+		cfg["Parameters"] = params
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="unglued.sxcu"`)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(cfg)
+}
+
+// pasteToDelete prüft den Lösch-Link aus ?key= bzw. dem Formularfeld key.
+func (s *Server) pasteToDelete(r *http.Request) (model.Paste, string, bool) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.Get(id)
+	if !ok {
+		return model.Paste{}, "", false
+	}
+	tok := r.FormValue("key")
+	var claim deleteClaim
+	if !s.Auth.Open("delete", tok, &claim) || !s.deleteClaimOK(claim, p) {
+		return p, "", false
+	}
+	return p, tok, true
+}
+
+// GET /p/{id}/delete?key=…: Rückfrage, damit Link-Vorschauen nichts löschen.
+func (s *Server) handleDeleteForm(w http.ResponseWriter, r *http.Request) {
+	p, tok, ok := s.pasteToDelete(r)
+	if p.ID == "" {
+		s.notFound(w, r, chi.URLParam(r, "id"), true)
+		return
+	}
+	if !ok {
+		httpError(w, r, "Lösch-Link ungültig oder abgelaufen", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = s.tmpl(r).delete.Execute(w, map[string]any{"ID": p.ID, "Title": p.Title, "Key": tok, "Until": s.restoreUntil()})
+}
+
+// POST /p/{id}/delete
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	p, _, ok := s.pasteToDelete(r)
+	if p.ID == "" {
+		s.notFound(w, r, chi.URLParam(r, "id"), true)
+		return
+	}
+	if !ok {
+		httpError(w, r, "Lösch-Link ungültig oder abgelaufen", http.StatusForbidden)
+		return
+	}
+	s.softDelete(r, p, "deletion link")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = s.tmpl(r).delete.Execute(w, map[string]any{"ID": p.ID, "Title": p.Title, "Deleted": true,
+		"Key": r.FormValue("key"), "Until": s.restoreUntil()})
+}
@@ -0,0 +1,59 @@
+package httpx
+
+import (
+	"encoding/xml"
+	"net/http"
+	"time"
+
+	"unglued/internal/model"
+)
+
+// Obergrenze des Sitemap-Protokolls pro Datei.
+const sitemapMaxURLs = 50000
+
+/*
+indexable: Suchmaschinen dürfen p indexieren – nur mit -allow-indexing, nur öffentlich
+gelistete Pastes, deren Ersteller das gewählt hat, und nie Markiertes oder Quarantäne.
+*/
+func (s *Server) indexable(p model.Paste) bool {
+	return s.Config.AllowIndexing && s.conf().Features.Archive &&
+		p.Indexable && p.Public && !p.Private && !p.Flagged && !p.Quarantined
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// GET /sitemap.xml: alle indexierbaren Pastes; ohne -allow-indexing 404.
+func (s *Server) handleSitemap(w http.ResponseWriter, r *http.Request) {
+	if !s.Config.AllowIndexing || !s.conf().Features.Archive {
+		http.NotFound(w, r)
+		return
+	}
+	items := s.Store.Find(func(p *model.Paste) bool { return s.indexable(*p) })
+	items = items[:min(len(items), sitemapMaxURLs)]
+	set := sitemapURLSet{URLs: make([]sitemapURL, 0, len(items))}
+	updated := time.Unix(0, 0)
+	for _, p := range items {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     s.makeURL(r, "/p/"+p.ID),
+			LastMod: p.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+		if p.UpdatedAt.After(updated) {
+			updated = p.UpdatedAt
+		}
+	}
+	out, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	serveBody(w, r, "application/xml; charset=utf-8", updated, append([]byte(xml.Header), out...))
+}
@@ -0,0 +1,106 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"unglued/internal/slack"
+)
+
+/*
+POST /api/integrations/slack: Slash-Command (Formular) und Events API (JSON) an
+einer URL. Beides ist mit dem Signing Secret signiert; ohne -slack-signing-secret 404.
+*/
+func (s *Server) handleSlack(w http.ResponseWriter, r *http.Request) {
+	if s.Slack == nil {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "Slack integration is disabled")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if err := s.Slack.Verify(r.Header, body, time.Now()); err != nil {
+		writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, err.Error())
+		return
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		s.slackCommand(w, r, body)
+		return
+	}
+	var env slack.Envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
+		return
+	}
+	switch env.Type {
+	case "url_verification":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"challenge": env.Challenge})
+		return
+	case "event_callback":
+		if env.Event.Type == "link_shared" && s.Slack.CanUnfurl() {
+			// Slack will binnen 3 s eine Antwort, das Unfurling läuft danach
+			go s.slackUnfurl(env.Event)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// slackCommand: "/paste …" legt an; die Antwort geht in den Kanal, Fehler nur an den Aufrufer.
+func (s *Server) slackCommand(w http.ResponseWriter, r *http.Request, body []byte) {
+	vals, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	// Slack maskiert &, < und > im Befehlstext
+	text := strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(vals.Get("text"))
+	reply := map[string]string{"response_type": "in_channel"}
+	p, err := s.chatPaste(r, text, vals.Get("user_name"), "slack")
+	if err != nil {
+		reply["response_type"], reply["text"] = "ephemeral", err.Error()
+	} else {
+		reply["text"] = s.makeURL(r, "/p/"+p.ID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(reply)
+}
+
+func (s *Server) slackUnfurl(ev slack.Event) {
+	unfurls := map[string]slack.Attachment{}
+	for _, l := range ev.Links {
+		p, snippet, ok := s.chatPreview(l.URL)
+		if !ok {
+			continue
+		}
+		footer := "unglued · " + p.Lang + " · " + strconv.Itoa(len(p.Versions)) + " version(s) · expires " + p.ExpiresAt.UTC().Format("2006-01-02 15:04 MST")
+		unfurls[l.URL] = slack.Attachment{
+			Title:     chatTitle(p),
+			TitleLink: l.URL,
+			Text:      "```" + slack.Escape(snippet) + "```",
+			Footer:    footer,
+			MrkdwnIn:  []string{"text"},
+		}
+	}
+	if len(unfurls) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.Slack.Unfurl(ctx, ev, unfurls); err != nil {
+		log.Printf("slack: %v", err)
+	}
+}
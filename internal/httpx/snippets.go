@@ -0,0 +1,77 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/model"
+	"unglued/internal/util"
+)
+
+type shellSnippet struct {
+	Name    string `json:"name"`
+	Title   string `json:"title"`
+	Command string `json:"command"`
+}
+
+/*
+shellSnippets: fertige Shell-Einzeiler für p, gepinnt auf Version v, wenn pinned. Der
+Edit-Token steht nie drin, nur $EDIT_TOKEN – die Liste landet in geteilten Seiten.
+*/
+func (s *Server) shellSnippets(r *http.Request, p model.Paste, v int, pinned bool) []shellSnippet {
+	raw, view := "/raw/"+p.ID, "/p/"+p.ID
+	if pinned {
+		raw, view = versionPath("/raw", p.ID, v+1), versionPath("/p", p.ID, v+1)
+	}
+	raw, view = s.makeURL(r, raw), s.makeURL(r, view)
+	out := []shellSnippet{
+		{"raw", tr(r, "Inhalt ausgeben"), "curl -fsSL '" + raw + "'"},
+		{"download", tr(r, "Als Datei speichern"), "wget -O '" + p.ID + util.ExtForLang(p.Versions[v].Lang) + "' '" + raw + "'"},
+		{"terminal", tr(r, "Eingefärbt im Terminal ansehen"), "curl -fsS '" + view + ".ansi'"},
+	}
+	if p.Editable && s.conf().Features.Edit {
+		out = append(out, shellSnippet{"edit", tr(r, "Neue Version hochladen (Edit-Token aus dem Edit-Link)"),
+			"jq -Rs '{code: .}' < FILE | curl -fsS -H 'Content-Type: application/json' --data-binary @- \"" +
+				s.makeURL(r, "/api/paste/"+p.ID+"/edit") + "?key=$EDIT_TOKEN\""})
+	}
+	out = append(out, shellSnippet{"create", tr(r, "Neue Paste in derselben Sprache"),
+		"curl -fsS --data-binary @FILE '" + s.makeURL(r, "/api/paste?lang="+p.Versions[v].Lang) + "'"})
+	return out
+}
+
+// GET /p/{id}/snippets[?v=N]: die Einzeiler als Text (Kommentar + Befehl) oder JSON.
+func (s *Server) handleSnippets(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.GetMeta(id)
+	if !ok {
+		s.notFound(w, r, id, false)
+		return
+	}
+	if !s.canView(r, p) {
+		s.denyView(w, r)
+		return
+	}
+	if s.quarantined(w, r, p) {
+		return
+	}
+	v, pinned := versionIndex(r, p)
+	list := s.shellSnippets(r, p, v, pinned)
+	if strings.Contains(r.Header.Get("Accept"), "application/json") || r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": p.ID, "private": p.Private, "snippets": list})
+		return
+	}
+	var b strings.Builder
+	if p.Private {
+		b.WriteString("# " + tr(r, "Private Paste: ohne Login nur mit ?grant=… aus dem Freigabelink abrufbar.") + "\n\n")
+	}
+	for _, sn := range list {
+		b.WriteString("# " + sn.Title + "\n" + sn.Command + "\n\n")
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Add("Vary", "Accept, Accept-Language")
+	_, _ = w.Write([]byte(b.String()))
+}
@@ -0,0 +1,168 @@
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"unglued/internal/secrets"
+	"unglued/internal/util"
+	"unglued/internal/webhook"
+)
+
+// Feldnamen, die sprunge/ix.io-Clients typischerweise benutzen.
+var rootPasteFields = []string{"f:1", "sprunge", "code", "paste", "text"}
+
+/*
+handleRootPost: POST / im Stil von sprunge/ix.io, z.B.
+
+	curl -F 'f:1=<-' https://unglued.example
+	curl --data-binary @file.txt https://unglued.example
+
+Antwort ist nur die Raw-URL als text/plain. Optionen kommen als Query (?lang=, ?ttl=).
+*/
+func (s *Server) handleRootPost(w http.ResponseWriter, r *http.Request) {
+	if !s.mayCreate(r) {
+		httpError(w, r, "Anmeldung erforderlich", http.StatusUnauthorized)
+		return
+	}
+	code, bundleLang, err := s.explodeUpload(r)
+	if err == nil && code == "" {
+		code, err = rootPasteBody(r, s.conf().Features.Uploads)
+	}
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, tr(r, "Ungültige Anfrage: %s", err), http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	lang := q.Get("lang")
+	if lang == "" {
+		lang = bundleLang
+	}
+	var redacted []string
+	if util.IsTruthy(q.Get("redact")) {
+		code, redacted = secrets.Redact(code)
+	} else if fs := secrets.Scan(code); len(fs) > 0 {
+		s.writeSecretBlock(w, r, fs)
+		return
+	}
+	by := s.identify(r, "", "")
+	p, err := s.buildPaste(pasteOpts{
+		Code:     code,
+		Lang:     lang,
+		TTL:      q.Get("ttl"),
+		Theme:    q.Get("theme"),
+		Author:   by.Name,
+		AuthorID: by.ID,
+		Avatar:   by.Avatar,
+		Profile:  by.Profile,
+		Redacted: redacted,
+	})
+	if isTooLarge(err) {
+		s.writeTooLarge(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.moderate(w, r, webhook.EventCreated, &p) {
+		return
+	}
+	s.save(r, webhook.EventCreated, p)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, s.makeURL(r, "/raw/"+p.ID))
+}
+
+// rootPasteBody holt den Inhalt aus Formularfeld, Datei-Upload (wenn uploads) oder Raw-Body.
+func rootPasteBody(r *http.Request, uploads bool) (string, error) {
+	ct := r.Header.Get("Content-Type")
+	if strings.HasPrefix(ct, "multipart/form-data") {
+		if err := parseAnyForm(r); err != nil {
+			return "", err
+		}
+		if v := pickFormValue(r.MultipartForm.Value); v != "" {
+			return v, nil
+		}
+		if !uploads && len(r.MultipartForm.File) > 0 {
+			return "", errFeatureDisabled
+		}
+		for _, f := range rootPasteFields {
+			if len(r.MultipartForm.File[f]) > 0 {
+				return readFormFile(r, f)
+			}
+		}
+		for name := range r.MultipartForm.File {
+			return readFormFile(r, name)
+		}
+		return "", fmt.Errorf("no paste content")
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	// curl --data-binary schickt x-www-form-urlencoded, auch wenn es nur Text ist
+	if strings.HasPrefix(ct, "application/x-www-form-urlencoded") {
+		if v, ok := formPaste(b); ok {
+			return v, nil
+		}
+	}
+	return string(b), nil
+}
+
+/*
+formPaste: nur ein Body, der genau ein bekanntes Feld enthält ("sprunge=…"), ist
+ein Formular. Alles andere – etwa ein Shell-Skript mit "FOO=bar" – ist Text, den
+ParseQuery sonst zerschneiden und an "+" verändern würde.
+*/
+func formPaste(b []byte) (string, bool) {
+	vals, err := url.ParseQuery(string(b))
+	if err != nil || len(vals) != 1 {
+		return "", false
+	}
+	for _, f := range rootPasteFields {
+		if v := vals[f]; len(v) == 1 && v[0] != "" {
+			return v[0], true
+		}
+	}
+	return "", false
+}
+
+// pickFormValue: bekannte Feldnamen zuerst, sonst das erste (alphabetisch) nicht-leere Feld.
+func pickFormValue(vals map[string][]string) string {
+	for _, f := range rootPasteFields {
+		if v := vals[f]; len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	names := make([]string, 0, len(vals))
+	for k := range vals {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if v := vals[k]; len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+func readFormFile(r *http.Request, name string) (string, error) {
+	f, _, err := r.FormFile(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	return string(b), err
+}
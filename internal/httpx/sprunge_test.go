@@ -0,0 +1,30 @@
+package httpx
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRootPasteBody(t *testing.T) {
+	for _, tc := range []struct{ name, body, want string }{
+		{"shell script", "#!/bin/sh\nFOO=bar+baz\necho $FOO\n", "#!/bin/sh\nFOO=bar+baz\necho $FOO\n"},
+		{"query string", "a=1&b=2", "a=1&b=2"},
+		{"unknown field", "data=hello", "data=hello"},
+		{"plain text", "hello world", "hello world"},
+		{"sprunge field", "sprunge=hello+world", "hello world"},
+		{"known field plus other", "sprunge=x&lang=go", "sprunge=x&lang=go"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			got, err := rootPasteBody(r, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
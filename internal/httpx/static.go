@@ -0,0 +1,53 @@
+package httpx
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/render"
+)
+
+// CSS/JS liegen als Dateien vor (statt inline), damit die CSP ohne 'unsafe-inline' auskommt.
+//
+//go:embed static/*
+var staticFS embed.FS
+
+// beim Start gesetzt; Basis für Last-Modified der eingebetteten Dateien
+var startedAt = time.Now()
+
+func (s *Server) staticHandler() http.Handler {
+	if dev := s.devStatic(); dev != nil {
+		return dev
+	}
+	sub, _ := fs.Sub(staticFS, "static")
+	fsrv := http.StripPrefix("/static/", http.FileServer(http.FS(sub)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Config.DevDir == "" {
+			w.Header().Set("Cache-Control", "public, max-age=3600")
+		}
+		fsrv.ServeHTTP(w, r)
+	})
+}
+
+// handleThemeCSS liefert /static/chroma-{theme}.css, generiert aus dem Chroma-Style.
+func (s *Server) handleThemeCSS(w http.ResponseWriter, r *http.Request) {
+	theme := chi.URLParam(r, "theme")
+	if !slices.Contains(Themes, theme) {
+		http.NotFound(w, r)
+		return
+	}
+	css, err := render.ThemeCSS(theme)
+	if err != nil {
+		httpError(w, r, "Theme-CSS fehlgeschlagen", http.StatusInternalServerError)
+		return
+	}
+	if s.Config.DevDir == "" {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	}
+	serveBody(w, r, "text/css; charset=utf-8", startedAt, css)
+}
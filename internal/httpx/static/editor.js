@@ -0,0 +1,89 @@
+// Editor-Komfort für <textarea id="code">: Tab/Shift+Tab, Auto-Indent, Ctrl+Enter
+(function(){
+  var ta = document.getElementById('code');
+  if(!ta) return;
+
+  var TAB = "  "; // Soft-Tab: 2 Spaces (ggf. "    " für 4)
+  function getLineStart(text, pos){
+    var i = text.lastIndexOf("\n", pos-1);
+    return i === -1 ? 0 : i+1;
+  }
+
+  ta.addEventListener('keydown', function(e){
+    // Ctrl/Cmd+Enter -> submit
+    if ((e.ctrlKey || e.metaKey) && e.key === "Enter") {
+      e.preventDefault();
+      if (ta.form) ta.form.submit();
+      return;
+    }
+
+    // Tab/Shift+Tab -> indent/outdent
+    if (e.key === "Tab") {
+      e.preventDefault();
+      var val = ta.value, start = ta.selectionStart, end = ta.selectionEnd;
+
+      // Selektion über mehrere Zeilen?
+      if (start !== end) {
+        var selStart = getLineStart(val, start);
+        var sel = val.slice(selStart, end);
+        var lines = sel.split("\n");
+
+        if (e.shiftKey) {
+          // ausrücken
+          for (var i=0;i<lines.length;i++){
+            if (lines[i].startsWith(TAB)) lines[i] = lines[i].slice(TAB.length);
+            else if (lines[i].startsWith("\t")) lines[i] = lines[i].slice(1);
+          }
+        } else {
+          // einrücken
+          for (var i=0;i<lines.length;i++){
+            lines[i] = TAB + lines[i];
+          }
+        }
+
+        var replaced = lines.join("\n");
+        var before = val.slice(0, selStart);
+        var after  = val.slice(end);
+        ta.value = before + replaced + after;
+
+        // Selektion neu setzen: umfasst weiter alle geänderten Zeilen
+        ta.selectionStart = selStart;
+        ta.selectionEnd = selStart + replaced.length;
+      } else {
+        // Caret-Indent
+        var before = val.slice(0, start);
+        var after  = val.slice(end);
+        if (e.shiftKey) {
+          // ausrücken an Zeilenanfang
+          var ls = getLineStart(val, start);
+          if (val.slice(ls, ls+TAB.length) === TAB) {
+            ta.value = val.slice(0, ls) + val.slice(ls+TAB.length);
+            var delta = TAB.length;
+            ta.selectionStart = ta.selectionEnd = Math.max(start - delta, ls);
+          } else if (val[ls] === "\t") {
+            ta.value = val.slice(0, ls) + val.slice(ls+1);
+            ta.selectionStart = ta.selectionEnd = Math.max(start - 1, ls);
+          }
+        } else {
+          ta.value = before + TAB + after;
+          ta.selectionStart = ta.selectionEnd = start + TAB.length;
+        }
+      }
+      return;
+    }
+
+    // Enter -> Auto-Indent
+    if (e.key === "Enter") {
+      e.preventDefault();
+      var val = ta.value, start = ta.selectionStart, end = ta.selectionEnd;
+      var ls = getLineStart(val, start);
+      var linePrefix = val.slice(ls, start);
+      var m = linePrefix.match(/^[ \t]+/);
+      var indent = m ? m[0] : "";
+      var insert = "\n" + indent;
+      ta.value = val.slice(0, start) + insert + val.slice(end);
+      ta.selectionStart = ta.selectionEnd = start + insert.length;
+      return;
+    }
+  });
+})();
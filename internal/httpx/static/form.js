@@ -0,0 +1,56 @@
+// Formulare mit data-ajax per fetch abschicken und Fehler (z.B. Secret-Funde) im Modal zeigen.
+(function () {
+  const dlg   = document.getElementById('msgDialog');
+  const title = document.getElementById('msgTitle');
+  const body  = document.getElementById('msgBody');
+  if (!dlg) return;
+  // Texte kommen übersetzt aus dem Template
+  const msg = (k, dflt) => dlg.dataset[k] || dflt;
+
+  function showMsg(t, txt) {
+    title.textContent = t;
+    body.textContent  = txt;
+    dlg.showModal();
+  }
+
+  dlg.addEventListener('close', () => document.getElementById('code')?.focus());
+  dlg.addEventListener('cancel', (e) => { e.preventDefault(); dlg.close(); });
+
+  document.querySelectorAll('form[data-ajax]').forEach((form) => {
+    form.addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const fd = new FormData(form);
+
+      try {
+        const res = await fetch(form.getAttribute('action'), { method: 'POST', body: fd });
+
+        // veraltete Basis: der Server schickt die Merge-Seite
+        if (res.status === 409 && (res.headers.get('Content-Type') || '').startsWith('text/html')) {
+          document.open();
+          document.write(await res.text());
+          document.close();
+          return;
+        }
+
+        if (!res.ok) {
+          // CAPTCHA-Tokens gelten nur einmal
+          window.hcaptcha?.reset();
+          window.turnstile?.reset();
+          const txt = await res.text();
+          if (txt.toLowerCase().includes('secret')) {
+            showMsg(msg('secrets', 'Potential secrets detected'), txt);
+          } else {
+            showMsg(msg('error', 'Error'), txt);
+          }
+          return;
+        }
+
+        // Erfolg: Redirect übernehmen
+        if (res.redirected) window.location.href = res.url;
+        else window.location.reload(); // Fallback
+      } catch {
+        showMsg(msg('network', 'Network error'), msg('retry', 'Bitte später erneut versuchen.'));
+      }
+    });
+  });
+})();
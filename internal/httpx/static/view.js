@@ -0,0 +1,79 @@
+// hl=… / #L… markieren & Click-Range
+(function(){
+  function parseHLParam(str){
+    var set = new Set(); if(!str) return set;
+    var parts = str.split(',');
+    for(var k=0;k<parts.length;k++){
+      var part = parts[k].trim(); if(!part) continue;
+      if(part.indexOf('-') !== -1){
+        var ab = part.split('-',2); var a = +ab[0], b = +ab[1];
+        if(Number.isInteger(a) && Number.isInteger(b)){
+          var lo = Math.min(a,b), hi = Math.max(a,b);
+          for(var i=lo;i<=hi;i++) set.add(i);
+        }
+      } else {
+        var n = +part; if(Number.isInteger(n)) set.add(n);
+      }
+    }
+    return set;
+  }
+  function apply(set){
+    var marked = document.querySelectorAll('.line.hl');
+    for(var i=0;i<marked.length;i++) marked[i].classList.remove('hl');
+    set.forEach(function(n){
+      var el = document.getElementById('L'+n);
+      if(el) el.classList.add('hl');
+    });
+  }
+  var params = new URLSearchParams(location.search);
+  var set = parseHLParam(params.get('hl'));
+  apply(set);
+  if(location.hash.slice(0,2) === '#L'){
+    var nHash = +location.hash.slice(2);
+    if(Number.isInteger(nHash)){ set.add(nHash); apply(set); }
+  }
+  var last = null;
+  var links = document.querySelectorAll('.line .ln');
+  for(var i=0;i<links.length;i++){
+    links[i].addEventListener('click', function(e){
+      e.preventDefault();
+      var n = +this.getAttribute('href').slice(2);
+      if(!Number.isInteger(n)) return;
+      if(e.shiftKey && last !== null){
+        var lo = Math.min(last,n), hi = Math.max(last,n);
+        for(var j=lo;j<=hi;j++) set.add(j);
+      } else {
+        if(set.has(n)) set.delete(n); else set.add(n);
+        last = n;
+      }
+      apply(set);
+      var list = Array.from(set).sort(function(a,b){return a-b;});
+      var out = [];
+      for(var p=0;p<list.length;p++){
+        var q=p;
+        while(q+1<list.length && list[q+1]===list[q]+1) q++;
+        if(q>p) out.push(String(list[p]) + '-' + String(list[q]));
+        else out.push(String(list[p]));
+        p=q;
+      }
+      params.set('hl', out.join(','));
+      var url = location.pathname + '?' + params.toString() + location.hash;
+      history.replaceState(null, '', url);
+    });
+  }
+})();
+
+// "Kopieren" neben den Shell-Einzeilern
+(function(){
+  var buttons = document.querySelectorAll('button[data-copy]');
+  for(var i=0;i<buttons.length;i++){
+    buttons[i].addEventListener('click', function(){
+      var btn = this, code = btn.parentNode.querySelector('code');
+      if(!code || !navigator.clipboard) return;
+      navigator.clipboard.writeText(code.textContent).then(function(){
+        var old = btn.textContent; btn.textContent = '✓';
+        setTimeout(function(){ btn.textContent = old; }, 1200);
+      });
+    });
+  }
+})();
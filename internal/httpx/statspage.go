@@ -0,0 +1,96 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"unglued/internal/stats"
+)
+
+const (
+	statsDefaultBuckets = 48
+	chartHeight         = 80
+	chartBarWidth       = 10
+)
+
+type chartBar struct {
+	X, Y, H int
+	Label   string
+	Count   int
+}
+
+type chart struct {
+	Event string
+	Sum   int
+	Max   int
+	Width int
+	Bars  []chartBar
+}
+
+// statsBuckets liest ?buckets= (Anzahl Stunden, Default 48, max. eine Woche).
+func statsBuckets(r *http.Request) int {
+	n, err := strconv.Atoi(r.URL.Query().Get("buckets"))
+	if err != nil || n <= 0 {
+		return statsDefaultBuckets
+	}
+	return n
+}
+
+// GET /api/admin/stats: Momentaufnahme plus Summen und Zeitreihe (?buckets=)
+func (s *Server) handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	since, totals := s.Stats.Totals()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"current": s.adminStats(),
+		"since":   since.UTC(),
+		"totals":  totals,
+		"step":    s.Stats.Step().String(),
+		"series":  s.Stats.Series(statsBuckets(r)),
+	})
+}
+
+// charts baut pro Ereignis ein Balkendiagramm (SVG im Template, kein JS nötig).
+func charts(series []stats.Point, step time.Duration) []chart {
+	out := make([]chart, 0, len(stats.Events))
+	for _, e := range stats.Events {
+		c := chart{Event: e, Width: len(series) * chartBarWidth}
+		for _, p := range series {
+			c.Sum += p.Counts[e]
+			c.Max = max(c.Max, p.Counts[e])
+		}
+		for i, p := range series {
+			h := 0
+			if c.Max > 0 {
+				h = p.Counts[e] * chartHeight / c.Max
+			}
+			c.Bars = append(c.Bars, chartBar{
+				X:     i * chartBarWidth,
+				Y:     chartHeight - h,
+				H:     h,
+				Label: p.Start.Format("02.01. 15:04") + " – " + p.Start.Add(step).Format("15:04"),
+				Count: p.Counts[e],
+			})
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// GET /admin/stats
+func (s *Server) handleAdminStatsPage(w http.ResponseWriter, r *http.Request) {
+	st := s.adminStats()
+	since, totals := s.Stats.Totals()
+	n := statsBuckets(r)
+	series := s.Stats.Series(n)
+	_ = s.tmpl(r).stats.Execute(w, map[string]any{
+		"Stats":   st,
+		"Since":   since.Format("2006-01-02 15:04"),
+		"Totals":  totals,
+		"Charts":  charts(series, s.Stats.Step()),
+		"Buckets": len(series),
+		"Height":  chartHeight,
+		"BarW":    chartBarWidth - 2,
+	})
+}
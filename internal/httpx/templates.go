@@ -3,6 +3,9 @@ package httpx
 import (
 	"embed"
 	"html/template"
+
+	"unglued/internal/i18n"
+	"unglued/internal/util"
 )
 
 //go:embed templates/*.html
@@ -11,20 +14,28 @@ var tplFS embed.FS
 var tmplFuncs = template.FuncMap{
 	"inc": func(i int) int { return i + 1 },
 	"dec": func(i int) int { return i - 1 },
+	// Bytegröße lesbar; negativ = unbekannt (Größe fehlt)
+	"bytes": func(n int64) string {
+		if n < 0 {
+			return "?"
+		}
+		return util.HumanBytes(uint64(n))
+	},
+
+	// Platzhalter; localize setzt pro Sprache die echten (siehe i18n.go)
+	"T":    i18n.Func(i18n.Langs[0]),
+	"Lang": func() string { return i18n.Langs[0] },
 }
 
 func LoadTemplates() (index, view, edit *template.Template) {
-	index = template.Must(template.New("index").Parse(indexHTML))
+	index = template.Must(template.New("index").Funcs(tmplFuncs).Parse(indexHTML))
 	view  = template.Must(template.New("view").Funcs(tmplFuncs).Parse(viewHTML))
-	edit  = template.Must(template.New("edit").Parse(editHTML))
+	edit  = template.Must(template.New("edit").Funcs(tmplFuncs).Parse(editHTML))
 	return
 }
 
 func MustParseTemplates() (*template.Template, *template.Template, *template.Template) {
-	funcs := template.FuncMap{
-		"inc": func(i int) int { return i + 1 },
-		"dec": func(i int) int { return i - 1 },
-	}
+	funcs := tmplFuncs
 	index := template.Must(template.New("index").Funcs(funcs).ParseFS(tplFS, "templates/index.html"))
 	view  := template.Must(template.New("view").Funcs(funcs).ParseFS(tplFS, "templates/view.html"))
 	edit  := template.Must(template.New("edit").Funcs(funcs).ParseFS(tplFS, "templates/edit.html"))
@@ -12,3 +12,23 @@ var viewHTML string
 //go:embed templates/edit.html
 var editHTML string
 
+//go:embed templates/archive.html
+var archiveHTML string
+
+//go:embed templates/me.html
+var meHTML string
+
+//go:embed templates/admin.html
+var adminHTML string
+
+//go:embed templates/stats.html
+var statsHTML string
+
+//go:embed templates/gone.html
+var goneHTML string
+
+//go:embed templates/delete.html
+var deleteHTML string
+
+//go:embed templates/login.html
+var loginHTML string
@@ -0,0 +1,101 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/audit"
+	"unglued/internal/model"
+)
+
+type trashItem struct {
+	adminPasteItem
+	Deleted bool   `json:"deleted"`           // sonst abgelaufen
+	GoneAt  string `json:"gone_at"`           // gelöscht bzw. abgelaufen
+	PurgeAt string `json:"purge_at"`          // dann räumt der Janitor sie weg
+	WasDue  string `json:"was_due,omitempty"` // ursprünglicher Ablauf einer gelöschten Paste
+}
+
+func (s *Server) trashItem(r *http.Request, p model.Paste) trashItem {
+	it := trashItem{
+		adminPasteItem: s.adminItem(r, p),
+		Deleted:        !p.DeletedAt.IsZero(),
+		GoneAt:         p.ExpiresAt.Format(time.RFC3339),
+		PurgeAt:        p.ExpiresAt.Add(s.Store.GraceFor(&p)).Format(time.RFC3339),
+	}
+	if it.Deleted {
+		it.GoneAt = p.DeletedAt.Format(time.RFC3339)
+		it.WasDue = p.DeletedExpiry.Format(time.RFC3339)
+	}
+	return it
+}
+
+// GET /api/admin/trash: gelöschte und abgelaufene Pastes, die noch wiederherstellbar sind.
+func (s *Server) handleAdminTrash(w http.ResponseWriter, r *http.Request) {
+	ps := s.Store.Trash()
+	items := make([]trashItem, 0, len(ps))
+	for _, p := range ps {
+		items = append(items, s.trashItem(r, p))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"grace":        s.Store.Grace().String(),
+		"delete_grace": s.Store.DeleteGrace().String(),
+		"items":        items,
+	})
+}
+
+// purge: Paste aus dem Papierkorb endgültig entfernen.
+func (s *Server) purge(r *http.Request, id string) bool {
+	if !s.Store.Purge(id) {
+		return false
+	}
+	s.record(r, audit.ActionDelete, id, "", "admin purge")
+	return true
+}
+
+// DELETE /api/admin/trash/{id}: nicht auf den Janitor warten.
+func (s *Server) handleAdminTrashPurge(w http.ResponseWriter, r *http.Request) {
+	if !s.purge(r, chi.URLParam(r, "id")) {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not in the trash")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* ---------- HTML ---------- */
+
+// POST /admin/trash/{id}/restore (ttl leer = alter Ablauf bzw. ursprüngliche Laufzeit)
+func (s *Server) handleAdminTrashRestoreForm(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAdminCSRF(w, r) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	msg := ""
+	if p, ok := s.Store.Expired(id); !ok {
+		msg = tr(r, "Paste %s nicht gefunden.", id)
+	} else if p, err := s.undelete(r, p, strings.TrimSpace(r.FormValue("ttl"))); err != nil {
+		msg = errText(r, err)
+	} else {
+		msg = tr(r, "Paste %s wiederhergestellt, läuft ab: %s", id, p.ExpiresAt.Format("2006-01-02 15:04"))
+	}
+	http.Redirect(w, r, "/admin?msg="+url.QueryEscape(msg)+"#trash", http.StatusSeeOther)
+}
+
+// POST /admin/trash/{id}/purge
+func (s *Server) handleAdminTrashPurgeForm(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAdminCSRF(w, r) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	msg := tr(r, "Paste %s endgültig gelöscht.", id)
+	if !s.purge(r, id) {
+		msg = tr(r, "Paste %s nicht gefunden.", id)
+	}
+	http.Redirect(w, r, "/admin?msg="+url.QueryEscape(msg)+"#trash", http.StatusSeeOther)
+}
@@ -0,0 +1,61 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"unglued/internal/util"
+)
+
+// ttlError: TTL außerhalb der Grenzen der Instanz; zählt als errInvalidTTL.
+type ttlError struct {
+	msg   string // Katalog-Schlüssel mit %s für die Grenze
+	limit time.Duration
+}
+
+func (e ttlError) Error() string { return fmt.Sprintf(e.msg, util.FormatTTL(e.limit)) }
+
+func (e ttlError) Is(target error) bool { return target == errInvalidTTL }
+
+/*
+boundTTL hält dur zwischen MinTTL und max (0 = ohne Obergrenze). explicit: vom
+Benutzer so angegeben – dann ist alles außerhalb ein Fehler, der die Grenze
+nennt; ein Default wird stattdessen auf die Grenze gezogen.
+*/
+func (s *Server) boundTTL(dur, max time.Duration, explicit bool) (time.Duration, error) {
+	if dur <= 0 {
+		return 0, errInvalidTTL
+	}
+	if min := s.conf().MinTTL; min > 0 && dur < min {
+		if explicit {
+			return 0, ttlError{"TTL zu kurz (min. %s)", min}
+		}
+		dur = min
+	}
+	if max > 0 && dur > max {
+		if explicit {
+			return 0, ttlError{"TTL zu lang (max. %s)", max}
+		}
+		dur = max
+	}
+	return dur, nil
+}
+
+// errText: übersetzte Fehlermeldung; ttlError samt Grenze.
+func errText(r *http.Request, err error) string {
+	var te ttlError
+	if errors.As(err, &te) {
+		return tr(r, te.msg, util.FormatTTL(te.limit))
+	}
+	return tr(r, err.Error())
+}
+
+// ttlHint: Grenze fürs Formular; leer, wenn es keine gibt.
+func ttlHint(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return util.FormatTTL(d)
+}
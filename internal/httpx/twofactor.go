@@ -0,0 +1,198 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"unglued/internal/audit"
+	"unglued/internal/auth"
+)
+
+const (
+	otpHeader      = "X-Unglued-OTP"
+	otpSessionName = "unglued_admin_2fa"
+	otpSessionTTL  = 12 * time.Hour
+)
+
+/*
+require2FA sitzt hinter dem Admin-Guard: ist TOTP aktiv, braucht jeder Request
+zusätzlich einen Code im X-Unglued-OTP-Header oder eine per /api/admin/2fa/verify
+ausgestellte 2FA-Session (Header oder Cookie), gebunden an den Admin-Namen.
+*/
+func (s *Server) require2FA(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.TwoFactor.Enabled() || s.has2FASession(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		code := r.Header.Get(otpHeader)
+		if code == "" {
+			writeProblem(w, r, http.StatusUnauthorized, codeOTPRequired, "second factor required: send "+otpHeader+" or verify via /api/admin/2fa/verify")
+			return
+		}
+		if !s.checkOTP(w, r, code) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) has2FASession(r *http.Request) bool {
+	tok := r.Header.Get("X-Unglued-Admin-Session")
+	if tok == "" {
+		if c, err := r.Cookie(otpSessionName); err == nil {
+			tok = c.Value
+		}
+	}
+	var name string
+	a, _ := auth.AdminFrom(r.Context())
+	return tok != "" && s.Auth.Open("admin2fa", tok, &name) && name == a.Name
+}
+
+// checkOTP prüft code und schreibt bei Fehler/Sperre die passende Antwort.
+func (s *Server) checkOTP(w http.ResponseWriter, r *http.Request, code string) bool {
+	err := s.TwoFactor.Verify(code, time.Now())
+	if err == nil {
+		return true
+	}
+	s.writeOTPError(w, r, err)
+	return false
+}
+
+func (s *Server) writeOTPError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, auth.ErrLocked):
+		_, _, until := s.TwoFactor.Status()
+		if secs := int(time.Until(until).Seconds()) + 1; secs > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+		}
+		s.record(r, audit.ActionAdmin, "", "", "2fa.locked")
+		writeProblem(w, r, http.StatusTooManyRequests, codeRateLimited, "too many failed second-factor attempts, try again later")
+	case errors.Is(err, auth.ErrBadCode):
+		s.record(r, audit.ActionAdmin, "", "", "2fa.failed")
+		writeProblem(w, r, http.StatusUnauthorized, codeOTPInvalid, "invalid one-time or recovery code")
+	case errors.Is(err, auth.ErrNotEnabled):
+		writeProblem(w, r, http.StatusConflict, codeInvalidRequest, "two-factor authentication is not enrolled")
+	default:
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, "could not persist two-factor state")
+	}
+}
+
+func (s *Server) mount2FA(r chi.Router) {
+	r.Get("/", s.handle2FAStatus)
+	r.Post("/enroll", s.limitSmall(s.handle2FAEnroll))
+	r.Post("/confirm", s.limitSmall(s.handle2FAConfirm))
+	r.Post("/verify", s.limitSmall(s.handle2FAVerify))
+	r.Delete("/", s.limitSmall(s.handle2FADisable))
+}
+
+func (s *Server) twoFactorEnabled(w http.ResponseWriter, r *http.Request) bool {
+	if s.TwoFactor == nil {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "two-factor authentication is not configured")
+		return false
+	}
+	return true
+}
+
+func otpCode(r *http.Request) string {
+	if c := r.Header.Get(otpHeader); c != "" {
+		return c
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	return req.Code
+}
+
+// GET /api/admin/2fa
+func (s *Server) handle2FAStatus(w http.ResponseWriter, r *http.Request) {
+	if !s.twoFactorEnabled(w, r) {
+		return
+	}
+	enabled, left, until := s.TwoFactor.Status()
+	out := map[string]any{"enabled": enabled, "recovery_codes_left": left}
+	if time.Now().Before(until) {
+		out["locked_until"] = until.UTC()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// POST /api/admin/2fa/enroll – neues Secret, aktiv erst nach /confirm
+func (s *Server) handle2FAEnroll(w http.ResponseWriter, r *http.Request) {
+	if !s.twoFactorEnabled(w, r) {
+		return
+	}
+	if s.TwoFactor.Enabled() {
+		writeProblem(w, r, http.StatusConflict, codeInvalidRequest, "two-factor authentication is already enabled; disable it first")
+		return
+	}
+	a, _ := auth.AdminFrom(r.Context())
+	secret, uri, err := s.TwoFactor.Enroll("unglued", a.Name)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, "could not persist two-factor state")
+		return
+	}
+	s.record(r, audit.ActionAdmin, "", "", "2fa.enroll")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"secret": secret, "otpauth_uri": uri})
+}
+
+// POST /api/admin/2fa/confirm {"code":"123456"} → Recovery-Codes (nur dieses eine Mal)
+func (s *Server) handle2FAConfirm(w http.ResponseWriter, r *http.Request) {
+	if !s.twoFactorEnabled(w, r) {
+		return
+	}
+	if s.TwoFactor.Enabled() {
+		writeProblem(w, r, http.StatusConflict, codeInvalidRequest, "two-factor authentication is already enabled")
+		return
+	}
+	codes, err := s.TwoFactor.Confirm(otpCode(r), time.Now())
+	if err != nil {
+		s.writeOTPError(w, r, err)
+		return
+	}
+	s.record(r, audit.ActionAdmin, "", "", "2fa.enable")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"enabled": true, "recovery_codes": codes})
+}
+
+// POST /api/admin/2fa/verify {"code":"123456"} → 2FA-Session als Token und Cookie
+func (s *Server) handle2FAVerify(w http.ResponseWriter, r *http.Request) {
+	if !s.twoFactorEnabled(w, r) || !s.checkOTP(w, r, otpCode(r)) {
+		return
+	}
+	a, _ := auth.AdminFrom(r.Context())
+	tok, err := s.Auth.Seal("admin2fa", a.Name, otpSessionTTL)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, "could not issue session")
+		return
+	}
+	s.record(r, audit.ActionAdmin, "", "", "2fa.verify")
+	s.setCookie(w, r, otpSessionName, tok, otpSessionTTL)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"session": tok,
+		"expires": time.Now().Add(otpSessionTTL).UTC(),
+	})
+}
+
+// DELETE /api/admin/2fa – braucht einen frischen Code (Header oder Body), Session reicht nicht
+func (s *Server) handle2FADisable(w http.ResponseWriter, r *http.Request) {
+	if !s.twoFactorEnabled(w, r) {
+		return
+	}
+	if err := s.TwoFactor.Disable(otpCode(r), time.Now()); err != nil {
+		s.writeOTPError(w, r, err)
+		return
+	}
+	s.record(r, audit.ActionAdmin, "", "", "2fa.disable")
+	s.setCookie(w, r, otpSessionName, "", -1)
+	w.WriteHeader(http.StatusNoContent)
+}
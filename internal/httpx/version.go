@@ -0,0 +1,83 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"unglued/internal/store"
+	"unglued/internal/version"
+)
+
+// versionInfo: Build plus, was an dieser Instanz eingeschaltet ist – für Bug-Reports.
+type versionInfo struct {
+	version.Info
+	Features features `json:"features"`
+}
+
+type features struct {
+	Storage       string   `json:"storage"`
+	EncryptAtRest bool     `json:"encryption_at_rest"`
+	Auth          []string `json:"auth"`                 // Benutzer-Login
+	AdminAuth     []string `json:"admin_auth,omitempty"` // nur für Admins, der Bereich soll sonst nicht auffallen
+	Search        bool     `json:"search"`
+	Captcha       bool     `json:"captcha"`
+	ClamAV        bool     `json:"clamav"`
+	Moderation    bool     `json:"moderation"`
+	Webhooks      bool     `json:"webhooks"`
+	Audit         bool     `json:"audit"`
+	RateLimit     bool     `json:"rate_limit"`
+	Blocklist     bool     `json:"blocklist"`
+	RequireLogin  bool     `json:"require_login,omitempty"`
+	Enabled       Features `json:"enabled"`
+}
+
+func (s *Server) features(r *http.Request) features {
+	f := features{
+		Storage:       storage(s.Store),
+		EncryptAtRest: s.Store.Sealer != nil,
+		Auth:          []string{},
+		Search:        s.Search != nil,
+		Captcha:       s.Captcha != nil,
+		ClamAV:        s.ClamAV != nil,
+		Moderation:    s.Moderator != nil,
+		Webhooks:      s.Hooks != nil,
+		Audit:         s.Audit != nil,
+		RateLimit:     s.Abuse != nil,
+		Blocklist:     s.Blocklist != nil,
+		RequireLogin:  s.Config.RequireLogin,
+		Enabled:       s.conf().Features,
+	}
+	for _, p := range s.Logins {
+		f.Auth = append(f.Auth, p.ID())
+	}
+	if s.Tokens != nil {
+		f.Auth = append(f.Auth, "token")
+	}
+	if _, ok := s.Config.Admin.CheckAdmin(r); !ok {
+		return f
+	}
+	if a := s.Config.Admin; a.Token != "" {
+		f.AdminAuth = append(f.AdminAuth, "token")
+	}
+	if a := s.Config.Admin; a.User != "" && a.Password != "" {
+		f.AdminAuth = append(f.AdminAuth, "basic")
+	}
+	if s.TwoFactor.Enabled() {
+		f.AdminAuth = append(f.AdminAuth, "totp")
+	}
+	return f
+}
+
+// GET /api/version
+func (s *Server) handleAPIVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(versionInfo{Info: version.Get(), Features: s.features(r)})
+}
+
+func storage(st *store.Store) string {
+	if st.Backend != nil {
+		return "git"
+	}
+	return "memory"
+}
@@ -0,0 +1,69 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxMessage: Änderungsnotizen sind Commit-Betreffzeilen, keine Beschreibungen.
+const maxMessage = 120
+
+// versionMessage: eine Zeile, Whitespace zusammengezogen, höchstens maxMessage Zeichen.
+func versionMessage(m string) string {
+	m = strings.Join(strings.Fields(m), " ")
+	if utf8.RuneCountInString(m) <= maxMessage {
+		return m
+	}
+	return string([]rune(m)[:maxMessage-1]) + "…"
+}
+
+type apiVersion struct {
+	Version   int    `json:"version"`
+	URL       string `json:"url"`
+	RawURL    string `json:"raw_url"`
+	Lang      string `json:"lang"`
+	Author    string `json:"author,omitempty"`
+	Verified  bool   `json:"verified,omitempty"`
+	KeyName   string `json:"key_name,omitempty"`
+	Message   string `json:"message,omitempty"`
+	At        string `json:"at"`
+	Size      int64  `json:"size"`
+	Formatted bool   `json:"formatted,omitempty"`
+}
+
+// GET /api/paste/{id}/versions: die Historie ohne Inhalt, älteste zuerst.
+func (s *Server) handleAPIVersions(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, ok := s.Store.GetMeta(id)
+	if !ok {
+		s.apiNotFound(w, r, id)
+		return
+	}
+	if p.Quarantined || !s.canView(r, p) {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "paste not found or expired")
+		return
+	}
+	out := make([]apiVersion, 0, len(p.Versions))
+	for i, v := range p.Versions {
+		out = append(out, apiVersion{
+			Version:   i + 1,
+			URL:       s.makeURL(r, versionPath("/p", p.ID, i+1)),
+			RawURL:    s.makeURL(r, versionPath("/raw", p.ID, i+1)),
+			Lang:      v.Lang,
+			Author:    v.Author,
+			Verified:  v.AuthorID != "",
+			KeyName:   v.KeyName,
+			Message:   v.Message,
+			At:        v.At.UTC().Format(time.RFC3339),
+			Size:      v.Size,
+			Formatted: v.Formatted,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"id": p.ID, "versions": out})
+}
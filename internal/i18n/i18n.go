@@ -0,0 +1,95 @@
+/*
+Package i18n übersetzt UI- und Fehlertexte. Schlüssel ist der Quelltext selbst – die
+Oberfläche ist auf Deutsch geschrieben, die API auf Englisch –, die Kataloge unter
+catalogs/ liefern die jeweils andere Sprache. Fehlt ein Eintrag, bleibt der Quelltext.
+*/
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed catalogs/*.json
+var catalogFS embed.FS
+
+// Langs: unterstützte Sprachen, die erste ist der Default.
+var Langs = []string{"de", "en"}
+
+var catalogs = map[string]map[string]string{}
+
+func init() {
+	for _, l := range Langs {
+		b, err := catalogFS.ReadFile("catalogs/" + l + ".json")
+		if err != nil {
+			panic(err)
+		}
+		m := map[string]string{}
+		if err := json.Unmarshal(b, &m); err != nil {
+			panic(fmt.Sprintf("i18n: catalogs/%s.json: %v", l, err))
+		}
+		catalogs[l] = m
+	}
+}
+
+// Supported normalisiert l ("en-US" → "en"); "" bei unbekannter Sprache.
+func Supported(l string) string {
+	l = strings.ToLower(strings.TrimSpace(l))
+	if i := strings.IndexAny(l, "-_"); i >= 0 {
+		l = l[:i]
+	}
+	if _, ok := catalogs[l]; ok {
+		return l
+	}
+	return ""
+}
+
+/*
+Match wählt aus einem Accept-Language-Header die am höchsten gewichtete unterstützte
+Sprache; "" wenn keine passt.
+*/
+func Match(header string) string {
+	type pref struct {
+		lang string
+		q    float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if l := Supported(tag); l != "" && q > 0 {
+			prefs = append(prefs, pref{l, q})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	if len(prefs) == 0 {
+		return ""
+	}
+	return prefs[0].lang
+}
+
+// T übersetzt key nach lang; mit args ist key ein fmt-Format.
+func T(lang, key string, args ...any) string {
+	msg := key
+	if v, ok := catalogs[lang][key]; ok && v != "" {
+		msg = v
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Func liefert T für eine feste Sprache, z.B. als Template-Funktion.
+func Func(lang string) func(key string, args ...any) string {
+	return func(key string, args ...any) string { return T(lang, key, args...) }
+}
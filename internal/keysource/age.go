@@ -0,0 +1,323 @@
+package keysource
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+/*
+ageSource entschlüsselt eine Datei im age-Format (age-encryption.org/v1, auch
+ASCII-armored). Unterstützt werden X25519-Identitäten (AGE-SECRET-KEY-1…) und
+Passphrasen – das, was "age -r" bzw. "age -p" erzeugen. SSH- und
+Plugin-Empfänger nicht.
+*/
+type ageSource struct {
+	path       string
+	identity   string
+	passphrase string
+}
+
+func (a *ageSource) String() string { return "age:" + a.path }
+
+func (a *ageSource) Fetch(context.Context) (Value, error) {
+	b, err := os.ReadFile(a.path)
+	if err != nil {
+		return Value{}, err
+	}
+	var ids []*ecdh.PrivateKey
+	if a.identity != "" {
+		if ids, err = readIdentities(a.identity); err != nil {
+			return Value{}, err
+		}
+	}
+	plain, err := ageDecrypt(b, ids, a.passphrase)
+	if err != nil {
+		return Value{}, fmt.Errorf("%s: %w", a.path, err)
+	}
+	return Value{Secret: strings.TrimSpace(string(plain))}, nil
+}
+
+const (
+	ageIntro      = "age-encryption.org/v1\n"
+	ageArmorBegin = "-----BEGIN AGE ENCRYPTED FILE-----"
+	ageArmorEnd   = "-----END AGE ENCRYPTED FILE-----"
+	ageChunk      = 64 * 1024
+	maxScryptLogN = 22 // wie age: mehr ist eher ein Angriff als eine Vorsicht
+)
+
+type stanza struct {
+	args []string
+	body []byte
+}
+
+func ageDecrypt(b []byte, ids []*ecdh.PrivateKey, passphrase string) ([]byte, error) {
+	if t := bytes.TrimSpace(b); bytes.HasPrefix(t, []byte(ageArmorBegin)) {
+		inner, ok := bytes.CutSuffix(t, []byte(ageArmorEnd))
+		if !ok {
+			return nil, errors.New("age: armor without end line")
+		}
+		raw, err := base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(inner[len(ageArmorBegin):]), nil)))
+		if err != nil {
+			return nil, fmt.Errorf("age: armor: %w", err)
+		}
+		b = raw
+	}
+	stanzas, header, mac, payload, err := parseAgeHeader(b)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := unwrapFileKey(stanzas, ids, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	hk, _ := hkdf.Key(sha256.New, fileKey, nil, "header", 32)
+	m := hmac.New(sha256.New, hk)
+	m.Write(header)
+	if !hmac.Equal(m.Sum(nil), mac) {
+		return nil, errors.New("age: header MAC mismatch")
+	}
+	if len(payload) < 16 {
+		return nil, errors.New("age: short payload")
+	}
+	pk, _ := hkdf.Key(sha256.New, fileKey, payload[:16], "payload", chacha20poly1305.KeySize)
+	aead, err := chacha20poly1305.New(pk)
+	if err != nil {
+		return nil, err
+	}
+	// 64-KiB-Chunks, Nonce = 11 Byte Zähler + 1 Byte "letzter Chunk"
+	var out []byte
+	var nonce [chacha20poly1305.NonceSize]byte
+	rest := payload[16:]
+	for i := uint64(0); ; i++ {
+		c := rest
+		last := len(c) <= ageChunk+aead.Overhead()
+		if !last {
+			c = c[:ageChunk+aead.Overhead()]
+		}
+		binary.BigEndian.PutUint64(nonce[3:11], i)
+		nonce[11] = 0
+		if last {
+			nonce[11] = 1
+		}
+		p, err := aead.Open(nil, nonce[:], c, nil)
+		if err != nil {
+			return nil, errors.New("age: payload authentication failed")
+		}
+		out = append(out, p...)
+		if last {
+			return out, nil
+		}
+		rest = rest[len(c):]
+	}
+}
+
+// parseAgeHeader liefert die Stanzas, den vom MAC abgedeckten Teil, den MAC und den Rest.
+func parseAgeHeader(b []byte) (stanzas []stanza, header, mac, payload []byte, err error) {
+	if !bytes.HasPrefix(b, []byte(ageIntro)) {
+		return nil, nil, nil, nil, errors.New("age: not an age file (or unsupported version)")
+	}
+	pos := len(ageIntro)
+	line := func() (string, bool) {
+		i := bytes.IndexByte(b[pos:], '\n')
+		if i < 0 {
+			return "", false
+		}
+		l := string(b[pos : pos+i])
+		pos += i + 1
+		return l, true
+	}
+	for {
+		start := pos
+		l, ok := line()
+		if !ok {
+			return nil, nil, nil, nil, errors.New("age: truncated header")
+		}
+		if m, ok := strings.CutPrefix(l, "--- "); ok {
+			mac, err := base64.RawStdEncoding.DecodeString(m)
+			if err != nil {
+				return nil, nil, nil, nil, errors.New("age: invalid header MAC")
+			}
+			return stanzas, b[:start+3], mac, b[pos:], nil
+		}
+		a, ok := strings.CutPrefix(l, "-> ")
+		if !ok {
+			return nil, nil, nil, nil, errors.New("age: malformed header")
+		}
+		s := stanza{args: strings.Split(a, " ")}
+		// Body in Zeilen zu 64 Zeichen; die letzte ist kürzer (notfalls leer)
+		for {
+			l, ok := line()
+			if !ok {
+				return nil, nil, nil, nil, errors.New("age: truncated header")
+			}
+			chunk, err := base64.RawStdEncoding.DecodeString(l)
+			if err != nil || len(l) > 64 {
+				return nil, nil, nil, nil, errors.New("age: malformed stanza body")
+			}
+			s.body = append(s.body, chunk...)
+			if len(l) < 64 {
+				break
+			}
+		}
+		stanzas = append(stanzas, s)
+	}
+}
+
+func unwrapFileKey(stanzas []stanza, ids []*ecdh.PrivateKey, passphrase string) ([]byte, error) {
+	for _, s := range stanzas {
+		switch {
+		case s.args[0] == "X25519" && len(s.args) == 2:
+			share, err := base64.RawStdEncoding.DecodeString(s.args[1])
+			if err != nil || len(share) != 32 {
+				return nil, errors.New("age: invalid X25519 stanza")
+			}
+			pub, err := ecdh.X25519().NewPublicKey(share)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				shared, err := id.ECDH(pub)
+				if err != nil {
+					continue
+				}
+				salt := append(append([]byte{}, share...), id.PublicKey().Bytes()...)
+				wrap, _ := hkdf.Key(sha256.New, shared, salt, "age-encryption.org/v1/X25519", chacha20poly1305.KeySize)
+				if k, err := openFileKey(wrap, s.body); err == nil {
+					return k, nil
+				}
+			}
+		case s.args[0] == "scrypt" && len(s.args) == 3:
+			if len(stanzas) != 1 {
+				return nil, errors.New("age: scrypt stanza must be the only one")
+			}
+			if passphrase == "" {
+				return nil, errors.New("age: file is passphrase-encrypted, but no passphrase is set")
+			}
+			salt, err := base64.RawStdEncoding.DecodeString(s.args[1])
+			logN, err2 := strconv.Atoi(s.args[2])
+			if err != nil || err2 != nil || len(salt) != 16 || logN <= 0 || logN > maxScryptLogN {
+				return nil, errors.New("age: invalid scrypt stanza")
+			}
+			wrap, err := scrypt.Key([]byte(passphrase), append([]byte("age-encryption.org/v1/scrypt"), salt...), 1<<logN, 8, 1, chacha20poly1305.KeySize)
+			if err != nil {
+				return nil, err
+			}
+			k, err := openFileKey(wrap, s.body)
+			if err != nil {
+				return nil, errors.New("age: wrong passphrase")
+			}
+			return k, nil
+		}
+	}
+	return nil, errors.New("age: no identity matches this file")
+}
+
+func openFileKey(wrap, body []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(wrap)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), body, nil)
+}
+
+// readIdentities liest AGE-SECRET-KEY-1…-Zeilen; Kommentare (#) und Leerzeilen zählen nicht.
+func readIdentities(path string) ([]*ecdh.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ids []*ecdh.PrivateKey
+	for _, l := range strings.Split(string(b), "\n") {
+		if l = strings.TrimSpace(l); l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		hrp, data, err := bech32Decode(l)
+		if err != nil || hrp != "age-secret-key-" || len(data) != 32 {
+			return nil, fmt.Errorf("%s: not an age X25519 identity", path)
+		}
+		k, err := ecdh.X25519().NewPrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, k)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%s: no identities", path)
+	}
+	return ids, nil
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Decode nach BIP 173, ohne die Längengrenze von 90 Zeichen (wie age).
+func bech32Decode(s string) (string, []byte, error) {
+	s = strings.ToLower(s)
+	i := strings.LastIndexByte(s, '1')
+	if i < 1 || i+7 > len(s) {
+		return "", nil, errors.New("bech32: invalid separator")
+	}
+	hrp := s[:i]
+	var values []byte
+	for _, c := range []byte(hrp) {
+		values = append(values, c>>5)
+	}
+	values = append(values, 0)
+	for _, c := range []byte(hrp) {
+		values = append(values, c&31)
+	}
+	var data []byte
+	for _, c := range []byte(s[i+1:]) {
+		v := strings.IndexByte(bech32Charset, c)
+		if v < 0 {
+			return "", nil, errors.New("bech32: invalid character")
+		}
+		data = append(data, byte(v))
+	}
+	if bech32Polymod(append(values, data...)) != 1 {
+		return "", nil, errors.New("bech32: invalid checksum")
+	}
+	// 5 → 8 Bit, ohne Auffüllen
+	var out []byte
+	acc, bits := 0, 0
+	for _, v := range data[:len(data)-6] {
+		acc = acc<<5 | int(v)
+		bits += 5
+		for bits >= 8 {
+			bits -= 8
+			out = append(out, byte(acc>>bits))
+		}
+	}
+	if bits >= 5 || acc&(1<<bits-1) != 0 {
+		return "", nil, errors.New("bech32: invalid padding")
+	}
+	return hrp, out, nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := range 5 {
+			if top>>i&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
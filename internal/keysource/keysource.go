@@ -0,0 +1,118 @@
+/*
+Package keysource holt Schlüssel und Secrets aus externen Quellen statt aus
+Umgebungsvariablen:
+
+	file:/run/secrets/unglued-key           Datei im Klartext
+	age:/etc/unglued/key.age                mit age verschlüsselte Datei
+	vault:secret/data/unglued#encryption_key Feld eines Vault-Secrets (KV v1 oder v2)
+	awskms:/etc/unglued/key.enc             mit AWS KMS verschlüsselter Blob
+
+Hat ein Wert eine Laufzeit (Vault-Lease), holt Watch ihn vor Ablauf neu.
+*/
+package keysource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Value ist ein geholtes Secret; TTL > 0 heißt, es muss vor Ablauf neu geholt werden.
+type Value struct {
+	Secret string
+	TTL    time.Duration
+}
+
+type Source interface {
+	Fetch(ctx context.Context) (Value, error)
+	String() string
+}
+
+type Options struct {
+	AgeIdentity   string // Datei mit AGE-SECRET-KEY-1…-Zeilen
+	AgePassphrase string // alternativ: Passphrase (age -p)
+}
+
+// Parse versteht die Schemata aus der Paketbeschreibung; ohne Schema ist es eine Datei.
+func Parse(uri string, o Options) (Source, error) {
+	scheme, rest, ok := strings.Cut(uri, ":")
+	if !ok {
+		return fileSource(uri), nil
+	}
+	switch scheme {
+	case "file":
+		return fileSource(rest), nil
+	case "age":
+		if o.AgeIdentity == "" && o.AgePassphrase == "" {
+			return nil, errors.New("keysource: age needs an identity file or a passphrase")
+		}
+		return &ageSource{path: rest, identity: o.AgeIdentity, passphrase: o.AgePassphrase}, nil
+	case "vault":
+		return newVault(rest)
+	case "awskms":
+		return newKMS(rest)
+	}
+	return nil, fmt.Errorf("keysource: unknown scheme %q (file, age, vault, awskms)", scheme)
+}
+
+type fileSource string
+
+func (f fileSource) String() string { return "file:" + string(f) }
+
+func (f fileSource) Fetch(context.Context) (Value, error) {
+	b, err := os.ReadFile(string(f))
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{Secret: strings.TrimSpace(string(b))}, nil
+}
+
+// minWait: auch bei sehr kurzen Leases nicht öfter fragen.
+const (
+	minWait    = 10 * time.Second
+	retryAfter = 30 * time.Second
+)
+
+/*
+Watch holt den Wert bei zwei Dritteln seiner Laufzeit neu (oder alle refresh,
+falls gesetzt) und ruft apply, wenn er sich geändert hat. Schlägt das Holen
+fehl, bleibt der alte Wert in Gebrauch und Watch versucht es nach 30s erneut.
+Ohne Laufzeit und refresh kehrt Watch sofort zurück.
+*/
+func Watch(ctx context.Context, src Source, cur Value, refresh time.Duration, apply func(string) error) {
+	failed := false
+	for {
+		wait := refresh
+		if cur.TTL > 0 && (wait == 0 || cur.TTL*2/3 < wait) {
+			wait = cur.TTL * 2 / 3
+		}
+		if failed {
+			wait = retryAfter
+		}
+		if wait == 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(max(wait, minWait)):
+		}
+		next, err := src.Fetch(ctx)
+		if failed = err != nil; failed {
+			log.Printf("keysource: %s: %v (keeping the current value)", src, err)
+			continue
+		}
+		if next.Secret != cur.Secret {
+			if err := apply(next.Secret); err != nil {
+				log.Printf("keysource: %s: %v (keeping the current value)", src, err)
+				continue
+			}
+			log.Printf("keysource: %s: new value in use", src)
+		}
+		cur = next
+	}
+}
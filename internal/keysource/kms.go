@@ -0,0 +1,88 @@
+package keysource
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"unglued/internal/awsv4"
+)
+
+/*
+kmsSource entschlüsselt einen Blob mit AWS KMS (Decrypt). Die Datei enthält den
+CiphertextBlob roh oder base64-kodiert, wie ihn "aws kms encrypt --output text
+--query CiphertextBlob" liefert. Credentials und Region kommen aus der Umgebung,
+AWS_ENDPOINT_URL_KMS zeigt auf einen anderen Endpunkt (z.B. LocalStack).
+*/
+type kmsSource struct {
+	path     string
+	region   string
+	endpoint string
+	creds    awsv4.Credentials
+	client   *http.Client
+}
+
+func newKMS(path string) (*kmsSource, error) {
+	creds, err := awsv4.FromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("keysource: awskms: %w", err)
+	}
+	k := &kmsSource{path: path, region: awsv4.Region(), creds: creds, client: &http.Client{Timeout: 10 * time.Second}}
+	k.endpoint = os.Getenv("AWS_ENDPOINT_URL_KMS")
+	if k.endpoint == "" {
+		k.endpoint = "https://kms." + k.region + ".amazonaws.com"
+	}
+	return k, nil
+}
+
+func (k *kmsSource) String() string { return "awskms:" + k.path }
+
+func (k *kmsSource) Fetch(ctx context.Context) (Value, error) {
+	blob, err := os.ReadFile(k.path)
+	if err != nil {
+		return Value{}, err
+	}
+	if b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(blob))); err == nil {
+		blob = b
+	}
+	body, _ := json.Marshal(map[string][]byte{"CiphertextBlob": blob})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(k.endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return Value{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	awsv4.Sign(req, body, k.creds, k.region, "kms", time.Now())
+	res, err := k.client.Do(req)
+	if err != nil {
+		return Value{}, err
+	}
+	defer res.Body.Close()
+	var out struct {
+		Plaintext []byte `json:"Plaintext"`
+		Type      string `json:"__type"`
+		Message   string `json:"message"`
+	}
+	_ = json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&out)
+	if res.StatusCode >= 300 {
+		return Value{}, fmt.Errorf("kms decrypt: %s %s %s", res.Status, out.Type, out.Message)
+	}
+	return Value{Secret: plaintext(out.Plaintext)}, nil
+}
+
+// plaintext: Text bleibt Text; rohe Bytes (etwa ein 32-Byte-Datenschlüssel) werden base64-kodiert.
+func plaintext(b []byte) string {
+	if utf8.Valid(b) && !strings.ContainsFunc(string(b), func(r rune) bool { return !unicode.IsPrint(r) && !unicode.IsSpace(r) }) {
+		return strings.TrimSpace(string(b))
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,154 @@
+package keysource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+/*
+vaultSource liest ein Feld aus einem Vault-Secret. Adresse und Anmeldung kommen wie
+beim vault-CLI aus der Umgebung: VAULT_ADDR, VAULT_NAMESPACE und VAULT_TOKEN oder
+AppRole (VAULT_ROLE_ID/VAULT_SECRET_ID, dann wird bei jedem Holen neu angemeldet).
+*/
+type vaultSource struct {
+	addr      string
+	namespace string
+	token     string
+	roleID    string
+	secretID  string
+	path      string // API-Pfad ohne /v1/, bei KV v2 mit data/
+	field     string
+	client    *http.Client
+}
+
+func newVault(ref string) (*vaultSource, error) {
+	path, field, _ := strings.Cut(ref, "#")
+	path = strings.Trim(path, "/")
+	if path == "" || field == "" {
+		return nil, errors.New("keysource: vault needs <path>#<field>, e.g. vault:secret/data/unglued#encryption_key")
+	}
+	v := &vaultSource{
+		addr:      strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		token:     os.Getenv("VAULT_TOKEN"),
+		roleID:    os.Getenv("VAULT_ROLE_ID"),
+		secretID:  os.Getenv("VAULT_SECRET_ID"),
+		path:      path,
+		field:     field,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+	if v.addr == "" {
+		v.addr = "https://127.0.0.1:8200"
+	}
+	if v.token == "" && v.roleID == "" {
+		return nil, errors.New("keysource: vault needs VAULT_TOKEN or VAULT_ROLE_ID/VAULT_SECRET_ID")
+	}
+	return v, nil
+}
+
+func (v *vaultSource) String() string { return "vault:" + v.path + "#" + v.field }
+
+type vaultResponse struct {
+	LeaseDuration int             `json:"lease_duration"`
+	Data          json.RawMessage `json:"data"`
+	Auth          *struct {
+		ClientToken string `json:"client_token"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+func (v *vaultSource) call(ctx context.Context, method, path, token string, body any) (*vaultResponse, error) {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.addr+"/v1/"+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	res, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var out vaultResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&out); err != nil && res.StatusCode < 300 {
+		return nil, fmt.Errorf("vault %s: %w", path, err)
+	}
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("vault %s: %s %s", path, res.Status, strings.Join(out.Errors, "; "))
+	}
+	return &out, nil
+}
+
+func (v *vaultSource) Fetch(ctx context.Context) (Value, error) {
+	token := v.token
+	if v.roleID != "" {
+		res, err := v.call(ctx, http.MethodPost, "auth/approle/login", "", map[string]string{"role_id": v.roleID, "secret_id": v.secretID})
+		if err != nil {
+			return Value{}, err
+		}
+		if res.Auth == nil || res.Auth.ClientToken == "" {
+			return Value{}, errors.New("vault approle login: no token in response")
+		}
+		token = res.Auth.ClientToken
+	}
+	res, err := v.call(ctx, http.MethodGet, v.path, token, nil)
+	if err != nil {
+		return Value{}, err
+	}
+	// KV v2 verpackt die Felder ein zweites Mal in data
+	var data struct {
+		Data map[string]any `json:"data"`
+	}
+	var fields map[string]any
+	if json.Unmarshal(res.Data, &data) == nil && data.Data != nil {
+		fields = data.Data
+	} else if err := json.Unmarshal(res.Data, &fields); err != nil {
+		return Value{}, fmt.Errorf("vault %s: %w", v.path, err)
+	}
+	secret, err := fieldString(fields[v.field])
+	if err != nil {
+		return Value{}, fmt.Errorf("vault %s: field %q: %w", v.path, v.field, err)
+	}
+	return Value{Secret: secret, TTL: time.Duration(res.LeaseDuration) * time.Second}, nil
+}
+
+// fieldString: Strings direkt, Listen von Strings kommagetrennt (alter Key hinten, für Rotation).
+func fieldString(f any) (string, error) {
+	switch f := f.(type) {
+	case string:
+		return strings.TrimSpace(f), nil
+	case []any:
+		parts := make([]string, 0, len(f))
+		for _, e := range f {
+			s, ok := e.(string)
+			if !ok {
+				return "", errors.New("list must contain strings")
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	case nil:
+		return "", errors.New("missing")
+	}
+	return "", errors.New("must be a string or a list of strings")
+}
@@ -0,0 +1,110 @@
+// Package lint prüft Konfigurationsformate (JSON, YAML, TOML) auf Syntaxfehler.
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	"unglued/internal/model"
+)
+
+// MaxBytes: größere Pastes werden nicht geprüft; der Parser soll das Anlegen nicht bremsen.
+const MaxBytes = 1 << 20
+
+var checkers = map[string]func(code string) *model.Problem{
+	"json": checkJSON,
+	"yaml": checkYAML,
+	"toml": checkTOML,
+}
+
+// Supports: wird lang geprüft?
+func Supports(lang string) bool {
+	_, ok := checkers[lang]
+	return ok
+}
+
+/*
+Check liefert den ersten Syntaxfehler in code, nil wenn alles passt, die Sprache
+nicht geprüft wird oder code leer oder zu groß ist.
+*/
+func Check(code, lang string) *model.Problem {
+	fn, ok := checkers[lang]
+	if !ok || len(code) > MaxBytes || strings.TrimSpace(code) == "" {
+		return nil
+	}
+	return fn(code)
+}
+
+func checkJSON(code string) *model.Problem {
+	var v any
+	err := json.Unmarshal([]byte(code), &v)
+	if err == nil {
+		return nil
+	}
+	// "unexpected end of JSON input" hat keinen Offset: dann hinter dem letzten Zeichen
+	off := int64(len(strings.TrimRight(code, " \t\r\n")))
+	var se *json.SyntaxError
+	if errors.As(err, &se) && se.Offset > 0 {
+		off = se.Offset - 1 // Offset zeigt hinter das Zeichen, an dem es scheiterte
+	}
+	line, col := position(code, off)
+	return &model.Problem{Line: line, Col: col, Msg: strings.TrimPrefix(err.Error(), "json: ")}
+}
+
+// yaml.v3 hängt die Zeile nur in die Meldung: "yaml: line 3: mapping values are not allowed …"
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+func checkYAML(code string) *model.Problem {
+	dec := yaml.NewDecoder(strings.NewReader(code))
+	for {
+		var v any
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err == nil {
+			continue
+		}
+		msg := err.Error()
+		// doppelte Schlüssel u. ä. kommen als TypeError mit eigener Liste
+		var te *yaml.TypeError
+		if errors.As(err, &te) && len(te.Errors) > 0 {
+			msg = te.Errors[0]
+		}
+		if m := yamlLine.FindStringSubmatch(msg); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return &model.Problem{Line: line, Msg: m[2]}
+		}
+		return &model.Problem{Msg: strings.TrimPrefix(msg, "yaml: ")}
+	}
+}
+
+func checkTOML(code string) *model.Problem {
+	var v map[string]any
+	err := toml.Unmarshal([]byte(code), &v)
+	if err == nil {
+		return nil
+	}
+	var de *toml.DecodeError
+	if errors.As(err, &de) {
+		line, col := de.Position()
+		return &model.Problem{Line: line, Col: col, Msg: strings.TrimPrefix(de.Error(), "toml: ")}
+	}
+	return &model.Problem{Msg: strings.TrimPrefix(err.Error(), "toml: ")}
+}
+
+// position: Zeile und Spalte (1-basiert, in Bytes) zum Offset off in code.
+func position(code string, off int64) (line, col int) {
+	b := []byte(code)[:min(max(off, 0), int64(len(code)))]
+	line = bytes.Count(b, []byte("\n")) + 1
+	col = len(b) - bytes.LastIndexByte(b, '\n')
+	return line, col
+}
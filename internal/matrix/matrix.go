@@ -0,0 +1,364 @@
+/*
+Package matrix ist ein kleiner Bot für die Matrix Client-Server-API: Er tritt den
+konfigurierten Räumen bei, wandelt lange Codeblöcke in unglued-Links um und meldet
+Änderungen an beobachteten Pastes mit einem Diff in die Räume, die sie beobachten.
+*/
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"unglued/internal/util"
+)
+
+type Config struct {
+	Homeserver string // z.B. https://matrix.example.org
+	Token      string // Access-Token des Bot-Kontos
+	Rooms      string // kommagetrennt: Raum-IDs oder Aliase
+	MinLines   int    // Codeblöcke ab so vielen Zeilen umwandeln; 0 = 15
+	StateFile  string // beobachtete Pastes; leer = nur im Speicher
+}
+
+/*
+Callbacks in den Server: Paste legt aus einem Codeblock (mit ```-Zäunen) eine Paste an
+und liefert Link und Vorschau; Resolve prüft einen Link und liefert die Paste-ID.
+*/
+type Callbacks struct {
+	Paste   func(sender, block string) (link, preview, lang string, err error)
+	Resolve func(link string) (id string, ok bool)
+}
+
+// Bot ist nil, wenn Homeserver oder Token fehlen.
+type Bot struct {
+	cfg    Config
+	client *http.Client
+	cb     Callbacks
+
+	self  string
+	rooms map[string]bool // beigetretene Raum-IDs
+
+	mu      sync.Mutex
+	watches map[string][]string // Paste-ID → Raum-IDs
+	txn     int64
+}
+
+func New(cfg Config, cb Callbacks) (*Bot, error) {
+	if cfg.Homeserver == "" || cfg.Token == "" {
+		return nil, nil
+	}
+	if cfg.MinLines <= 0 {
+		cfg.MinLines = 15
+	}
+	cfg.Homeserver = strings.TrimRight(cfg.Homeserver, "/")
+	b := &Bot{
+		cfg: cfg, cb: cb, rooms: map[string]bool{}, watches: map[string][]string{},
+		// länger als der Long-Poll von /sync
+		client: &http.Client{Timeout: 60 * time.Second},
+		txn:    time.Now().UnixNano(),
+	}
+	if cfg.StateFile == "" {
+		return b, nil
+	}
+	raw, err := os.ReadFile(cfg.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &b.watches); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// call schickt einen Request an die Client-Server-API; out darf nil sein.
+func (b *Bot) call(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.cfg.Homeserver+"/_matrix/client/v3"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+b.cfg.Token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		var e struct {
+			Code  string `json:"errcode"`
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&e)
+		return fmt.Errorf("%s %s: %d %s %s", method, path, res.StatusCode, e.Code, e.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// Run meldet den Bot an, tritt den Räumen bei und verarbeitet neue Nachrichten bis ctx endet.
+func (b *Bot) Run(ctx context.Context) error {
+	var who struct {
+		UserID string `json:"user_id"`
+	}
+	for {
+		err := b.call(ctx, http.MethodGet, "/account/whoami", nil, &who)
+		if err == nil {
+			break
+		}
+		log.Printf("matrix: %v", err)
+		if !wait(ctx) {
+			return nil
+		}
+	}
+	b.self = who.UserID
+	for _, room := range strings.Split(b.cfg.Rooms, ",") {
+		if room = strings.TrimSpace(room); room == "" {
+			continue
+		}
+		var joined struct {
+			RoomID string `json:"room_id"`
+		}
+		if err := b.call(ctx, http.MethodPost, "/join/"+url.PathEscape(room), struct{}{}, &joined); err != nil {
+			log.Printf("matrix: join %s: %v", room, err)
+			continue
+		}
+		b.rooms[joined.RoomID] = true
+	}
+	// die erste Synchronisation liefert nur den Startpunkt; alte Nachrichten bleiben liegen
+	since, first := "", true
+	for ctx.Err() == nil {
+		next, err := b.sync(ctx, since, first)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("matrix: %v", err)
+				wait(ctx)
+			}
+			continue
+		}
+		since, first = next, false
+	}
+	return nil
+}
+
+// wait: Pause vor dem nächsten Versuch; false, wenn ctx vorher endet.
+func wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(5 * time.Second):
+		return true
+	}
+}
+
+const syncFilter = `{"room":{"timeline":{"limit":50,"types":["m.room.message"]},"state":{"lazy_load_members":true},"ephemeral":{"not_types":["*"]},"account_data":{"not_types":["*"]}},"presence":{"not_types":["*"]},"account_data":{"not_types":["*"]}}`
+
+type event struct {
+	Type    string `json:"type"`
+	Sender  string `json:"sender"`
+	EventID string `json:"event_id"`
+	Content struct {
+		MsgType   string          `json:"msgtype"`
+		Body      string          `json:"body"`
+		RelatesTo json.RawMessage `json:"m.relates_to"`
+	} `json:"content"`
+}
+
+func (b *Bot) sync(ctx context.Context, since string, first bool) (string, error) {
+	q := url.Values{"filter": {syncFilter}}
+	if since != "" {
+		q.Set("since", since)
+		q.Set("timeout", "30000")
+	}
+	var res struct {
+		NextBatch string `json:"next_batch"`
+		Rooms     struct {
+			Join map[string]struct {
+				Timeline struct {
+					Events []event `json:"events"`
+				} `json:"timeline"`
+			} `json:"join"`
+		} `json:"rooms"`
+	}
+	if err := b.call(ctx, http.MethodGet, "/sync?"+q.Encode(), nil, &res); err != nil {
+		return since, err
+	}
+	if first {
+		return res.NextBatch, nil
+	}
+	for room, r := range res.Rooms.Join {
+		if !b.rooms[room] {
+			continue
+		}
+		for _, ev := range r.Timeline.Events {
+			if ev.Type == "m.room.message" && ev.Sender != b.self && ev.Content.MsgType == "m.text" {
+				b.handle(ctx, room, ev)
+			}
+		}
+	}
+	return res.NextBatch, nil
+}
+
+// handle: "!unglued watch|unwatch <link>" oder lange Codeblöcke in einer Nachricht.
+func (b *Bot) handle(ctx context.Context, room string, ev event) {
+	body := ev.Content.Body
+	// Bearbeitungen ("m.replace") nicht noch einmal umwandeln
+	if bytes.Contains(ev.Content.RelatesTo, []byte(`"m.replace"`)) {
+		return
+	}
+	if cmd, ok := strings.CutPrefix(body, "!unglued "); ok {
+		b.reply(ctx, room, ev.EventID, b.command(room, strings.Fields(cmd)), "")
+		return
+	}
+	for _, block := range codeBlocks(body) {
+		if strings.Count(block, "\n")-1 < b.cfg.MinLines {
+			continue
+		}
+		link, preview, lang, err := b.cb.Paste(ev.Sender, block)
+		if err != nil {
+			b.reply(ctx, room, ev.EventID, err.Error(), "")
+			continue
+		}
+		text := link + "\n" + preview
+		htm := `<a href="` + html.EscapeString(link) + `">` + html.EscapeString(link) + `</a>` +
+			`<pre><code class="language-` + html.EscapeString(lang) + `">` + html.EscapeString(preview) + `</code></pre>`
+		b.reply(ctx, room, ev.EventID, text, htm)
+	}
+}
+
+func (b *Bot) command(room string, args []string) string {
+	if len(args) != 2 || (args[0] != "watch" && args[0] != "unwatch") {
+		return "usage: !unglued watch <link> | !unglued unwatch <link>"
+	}
+	id, ok := b.cb.Resolve(args[1])
+	if !ok {
+		return "no such public paste: " + args[1]
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rooms := slices.DeleteFunc(b.watches[id], func(r string) bool { return r == room })
+	msg := "stopped watching " + id
+	if args[0] == "watch" {
+		rooms, msg = append(rooms, room), "watching "+id+"; changes will be posted here"
+	}
+	if len(rooms) == 0 {
+		delete(b.watches, id)
+	} else {
+		b.watches[id] = rooms
+	}
+	if err := b.saveLocked(); err != nil {
+		log.Printf("matrix: %v", err)
+	}
+	return msg
+}
+
+func (b *Bot) saveLocked() error {
+	if b.cfg.StateFile == "" {
+		return nil
+	}
+	return util.WriteJSONFile(b.cfg.StateFile, b.watches, 0o600)
+}
+
+/*
+codeBlocks: Inhalte der ```-Blöcke einer Nachricht samt Zäunen (damit ```go als
+Sprache erhalten bleibt). Ein nicht geschlossener Block zählt bis zum Ende.
+*/
+func codeBlocks(body string) []string {
+	var out []string
+	for {
+		start := strings.Index(body, "```")
+		if start < 0 {
+			return out
+		}
+		rest := body[start+3:]
+		end := strings.Index(rest, "\n```")
+		if end < 0 {
+			return append(out, body[start:])
+		}
+		out = append(out, body[start:start+3+end+4])
+		body = rest[end+4:]
+	}
+}
+
+// reply schickt eine Notice als Antwort auf eventID; htm darf leer sein.
+func (b *Bot) reply(ctx context.Context, room, eventID, text, htm string) {
+	content := map[string]any{
+		"msgtype":      "m.notice",
+		"body":         text,
+		"m.relates_to": map[string]any{"m.in_reply_to": map[string]string{"event_id": eventID}},
+	}
+	if htm != "" {
+		content["format"], content["formatted_body"] = "org.matrix.custom.html", htm
+	}
+	b.send(ctx, room, content)
+}
+
+func (b *Bot) send(ctx context.Context, room string, content map[string]any) {
+	b.mu.Lock()
+	b.txn++
+	txn := strconv.FormatInt(b.txn, 10)
+	b.mu.Unlock()
+	path := "/rooms/" + url.PathEscape(room) + "/send/m.room.message/" + txn
+	if err := b.call(ctx, http.MethodPut, path, content, nil); err != nil {
+		log.Printf("matrix: %v", err)
+	}
+}
+
+// Changed meldet eine neue Version an alle Räume, die die Paste beobachten.
+func (b *Bot) Changed(id, title, link string, version int, diff []string) {
+	b.mu.Lock()
+	rooms := slices.Clone(b.watches[id])
+	b.mu.Unlock()
+	if len(rooms) == 0 {
+		return
+	}
+	const maxLines = 20
+	more := ""
+	if len(diff) > maxLines {
+		more = "\n… " + strconv.Itoa(len(diff)-maxLines) + " more changed lines"
+		diff = diff[:maxLines]
+	}
+	head := title + " changed (version " + strconv.Itoa(version) + "): " + link
+	d := strings.Join(diff, "\n")
+	content := map[string]any{
+		"msgtype":        "m.notice",
+		"body":           head + "\n" + d + more,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": html.EscapeString(title) + ` changed (version ` + strconv.Itoa(version) + `): <a href="` + html.EscapeString(link) + `">` + html.EscapeString(link) + `</a><pre><code class="language-diff">` + html.EscapeString(d) + `</code></pre>` + html.EscapeString(more),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for _, room := range rooms {
+			b.send(ctx, room, content)
+		}
+	}()
+}
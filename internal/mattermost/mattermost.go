@@ -0,0 +1,80 @@
+/*
+Package mattermost prüft Tokens von Outgoing Webhooks und Slash-Commands und löscht
+auf Wunsch umgewandelte Beiträge über die REST-API.
+*/
+package mattermost
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Tokens   string // kommagetrennt: je Webhook bzw. Slash-Command ein Token
+	URL      string // Basis-URL des Servers, nur zum Löschen
+	BotToken string // optional: ersetzt umgewandelte Beiträge (Recht "Delete Others' Posts")
+	MinLines int    // ab so vielen Zeilen wandelt der Outgoing Webhook um; 0 = 15
+}
+
+// Client ist nil, wenn keine Tokens gesetzt sind.
+type Client struct {
+	tokens   []string
+	url      string
+	botToken string
+	MinLines int
+	client   *http.Client
+}
+
+func New(cfg Config) *Client {
+	var tokens []string
+	for _, t := range strings.Split(cfg.Tokens, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	if cfg.MinLines <= 0 {
+		cfg.MinLines = 15
+	}
+	return &Client{
+		tokens: tokens, url: strings.TrimRight(cfg.URL, "/"), botToken: cfg.BotToken,
+		MinLines: cfg.MinLines, client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Valid: tok ist eines der konfigurierten Tokens.
+func (c *Client) Valid(tok string) bool {
+	ok := false
+	for _, t := range c.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(tok)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+func (c *Client) CanDelete() bool { return c != nil && c.url != "" && c.botToken != "" }
+
+// DeletePost entfernt einen Beitrag.
+func (c *Client) DeletePost(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.url+"/api/v4/posts/"+id, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.botToken)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("delete post: status %d", res.StatusCode)
+	}
+	return nil
+}
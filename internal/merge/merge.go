@@ -0,0 +1,147 @@
+/*
+Package merge führt zwei Bearbeitungen derselben Ausgangsfassung zeilenweise
+zusammen (diff3). Was nur eine Seite geändert hat, wird übernommen; wo beide
+dieselben Zeilen verschieden geändert haben, bleiben Konfliktmarker stehen.
+*/
+package merge
+
+import "strings"
+
+// bis zu dieser Größe (Zeilen × Zeilen) wird echt verglichen, darüber zählen nur gleicher Anfang und gleiches Ende
+const maxCells = 1 << 22
+
+// Marker wie bei git mit merge.conflictStyle=diff3.
+const (
+	MarkYours  = "<<<<<<<"
+	MarkBase   = "|||||||"
+	MarkSplit  = "======="
+	MarkTheirs = ">>>>>>>"
+)
+
+type Result struct {
+	Text      string
+	Conflicts int
+}
+
+/*
+Merge vereint yours und theirs, die beide von base ausgehen. Die Labels stehen
+hinter den Markern, z.B. "deine Änderung" und "Version 3".
+*/
+func Merge(base, yours, theirs, yoursLabel, theirsLabel string) Result {
+	o, a, b := lines(base), lines(yours), lines(theirs)
+	ma, mb := match(o, a), match(o, b)
+
+	var out []string
+	res := Result{}
+	resolve := func(co, ca, cb []string) {
+		switch {
+		case equal(ca, co):
+			out = append(out, cb...)
+		case equal(cb, co), equal(ca, cb):
+			out = append(out, ca...)
+		default:
+			res.Conflicts++
+			out = append(out, MarkYours+" "+yoursLabel)
+			out = append(out, ca...)
+			out = append(out, MarkBase+" base")
+			out = append(out, co...)
+			out = append(out, MarkSplit)
+			out = append(out, cb...)
+			out = append(out, MarkTheirs+" "+theirsLabel)
+		}
+	}
+
+	i, j, k := 0, 0, 0
+	for i < len(o) || j < len(a) || k < len(b) {
+		// stabiler Abschnitt: in allen drei gleich
+		n := 0
+		for i+n < len(o) && ma[i+n] == j+n && mb[i+n] == k+n {
+			n++
+		}
+		if n > 0 {
+			out = append(out, o[i:i+n]...)
+			i, j, k = i+n, j+n, k+n
+			continue
+		}
+		// sonst bis zur nächsten Basiszeile, die beide Seiten noch haben
+		next := i
+		for next < len(o) && (ma[next] < 0 || mb[next] < 0) {
+			next++
+		}
+		if next == len(o) {
+			resolve(o[i:], a[j:], b[k:])
+			break
+		}
+		resolve(o[i:next], a[j:ma[next]], b[k:mb[next]])
+		i, j, k = next, ma[next], mb[next]
+	}
+	res.Text = strings.Join(out, "\n")
+	return res
+}
+
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func equal(x, y []string) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// match: zu jeder Zeile von o die passende Zeile in x (längste gemeinsame Teilfolge), -1 = keine.
+func match(o, x []string) []int {
+	m := make([]int, len(o))
+	for i := range m {
+		m[i] = -1
+	}
+	// gleicher Anfang und gleiches Ende kosten nichts
+	pre := 0
+	for pre < len(o) && pre < len(x) && o[pre] == x[pre] {
+		m[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(o)-pre && suf < len(x)-pre && o[len(o)-1-suf] == x[len(x)-1-suf] {
+		m[len(o)-1-suf] = len(x) - 1 - suf
+		suf++
+	}
+	p, q := o[pre:len(o)-suf], x[pre:len(x)-suf]
+	if len(p)*len(q) > maxCells {
+		return m
+	}
+	lcs := make([][]int32, len(p)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(q)+1)
+	}
+	for i := len(p) - 1; i >= 0; i-- {
+		for j := len(q) - 1; j >= 0; j-- {
+			if p[i] == q[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < len(p) && j < len(q); {
+		switch {
+		case p[i] == q[j]:
+			m[pre+i] = pre + j
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return m
+}
@@ -0,0 +1,329 @@
+/*
+Package metrics schreibt Zähler und Histogramme im Textformat von Prometheus
+(Version 0.0.4). Bewusst klein: Counter, Histogramm und zur Scrape-Zeit
+berechnete Werte, ohne die Client-Bibliothek. Mit einem Sink (etwa StatsD)
+gehen dieselben Werte zusätzlich nach außen, siehe Emit.
+*/
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+	sink     atomic.Pointer[sinkRef]
+}
+
+type family interface {
+	write(w *bufio.Writer)
+}
+
+func NewRegistry() *Registry { return &Registry{} }
+
+func (r *Registry) add(f family) {
+	r.mu.Lock()
+	r.families = append(r.families, f)
+	r.mu.Unlock()
+}
+
+// Write schreibt alle Metriken in der Reihenfolge ihrer Registrierung.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	fs := slices.Clone(r.families)
+	r.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	for _, f := range fs {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.Write(w)
+}
+
+// key: eine Kombination von Label-Werten als Map-Schlüssel.
+func key(values []string) string { return strings.Join(values, "\xff") }
+
+func header(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, strings.ReplaceAll(help, "\n", " "), name, typ)
+}
+
+// labels formatiert {a="x",b="y"}; extra hängt z.B. le="…" an.
+func labels(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(n + `="` + escape(values[i]) + `"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extra[i] + `="` + escape(extra[i+1]) + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string { return escaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Counter zählt je Label-Kombination; nil-sicher.
+type Counter struct {
+	reg        *Registry
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64
+	order  map[string][]string
+}
+
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{reg: r, name: name, help: help, labels: labels, values: map[string]float64{}, order: map[string][]string{}}
+	r.add(c)
+	return c
+}
+
+func (c *Counter) Inc(values ...string) { c.Add(1, values...) }
+
+func (c *Counter) Add(n float64, values ...string) {
+	if c == nil || n < 0 || len(values) != len(c.labels) {
+		return
+	}
+	k := key(values)
+	c.mu.Lock()
+	if _, ok := c.order[k]; !ok {
+		c.order[k] = slices.Clone(values)
+	}
+	c.values[k] += n
+	c.mu.Unlock()
+	if sink := c.reg.current(); sink != nil {
+		sink.Count(c.name, tags(c.labels, values), n)
+	}
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	header(w, c.name, c.help, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labels(c.labels, c.order[k]), formatFloat(c.values[k]))
+	}
+}
+
+// Histogram verteilt Beobachtungen auf feste, aufsteigende Buckets.
+type Histogram struct {
+	reg        *Registry
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histSeries
+}
+
+type histSeries struct {
+	values []string
+	counts []uint64 // je Bucket, nicht kumuliert
+	sum    float64
+	count  uint64
+}
+
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{reg: r, name: name, help: help, labels: labels, buckets: slices.Sorted(slices.Values(buckets)), series: map[string]*histSeries{}}
+	r.add(h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, values ...string) {
+	if h == nil || len(values) != len(h.labels) {
+		return
+	}
+	k := key(values)
+	if sink := h.reg.current(); sink != nil {
+		sink.Observe(h.name, tags(h.labels, values), v)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[k]
+	if !ok {
+		s = &histSeries{values: slices.Clone(values), counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+	}
+	if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	header(w, h.name, h.help, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, k := range sortedKeys(h.series) {
+		s := h.series[k]
+		var cum uint64
+		for i, le := range h.buckets {
+			cum += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels(h.labels, s.values, "le", formatFloat(le)), cum)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels(h.labels, s.values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels(h.labels, s.values), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels(h.labels, s.values), s.count)
+	}
+}
+
+// Sample: ein Wert, den eine Func-Metrik beim Scrape liefert.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+type funcFamily struct {
+	name, help, typ string
+	labels          []string
+	fn              func() []Sample
+}
+
+/*
+GaugeFunc und CounterFunc werden erst beim Scrape berechnet, etwa aus dem Store
+oder aus Zählern, die es ohnehin schon gibt.
+*/
+func (r *Registry) GaugeFunc(name, help string, fn func() []Sample, labels ...string) {
+	r.add(&funcFamily{name: name, help: help, typ: "gauge", labels: labels, fn: fn})
+}
+
+func (r *Registry) CounterFunc(name, help string, fn func() []Sample, labels ...string) {
+	r.add(&funcFamily{name: name, help: help, typ: "counter", labels: labels, fn: fn})
+}
+
+func (f *funcFamily) write(w *bufio.Writer) {
+	header(w, f.name, f.help, f.typ)
+	samples := f.fn()
+	slices.SortFunc(samples, func(a, b Sample) int { return strings.Compare(key(a.Labels), key(b.Labels)) })
+	for _, s := range samples {
+		if len(s.Labels) == len(f.labels) {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, labels(f.labels, s.Labels), formatFloat(s.Value))
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Tag: ein Label mit Wert, so wie ein Sink es bekommt.
+type Tag struct{ Name, Value string }
+
+func tags(names, values []string) []Tag {
+	if len(names) == 0 {
+		return nil
+	}
+	out := make([]Tag, len(names))
+	for i := range names {
+		out[i] = Tag{names[i], values[i]}
+	}
+	return out
+}
+
+/*
+Sink bekommt Counter und Histogramme bei jeder Änderung; Count erhält den
+Zuwachs, nicht den Stand. Die Func-Metriken liefert Emit periodisch nach.
+*/
+type Sink interface {
+	Count(name string, tags []Tag, n float64)
+	Observe(name string, tags []Tag, v float64)
+	Gauge(name string, tags []Tag, v float64)
+}
+
+type sinkRef struct{ Sink }
+
+func (r *Registry) current() Sink {
+	if ref := r.sink.Load(); ref != nil {
+		return ref.Sink
+	}
+	return nil
+}
+
+/*
+Emit schickt ab jetzt alle Änderungen auch an sink und alle every die
+Func-Metriken: Gauges mit ihrem Wert, CounterFuncs mit dem Zuwachs seit dem
+letzten Mal. Läuft, bis ctx endet.
+*/
+func (r *Registry) Emit(ctx context.Context, sink Sink, every time.Duration) {
+	r.sink.Store(&sinkRef{sink})
+	last := map[string]float64{}
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		r.mu.Lock()
+		fs := slices.Clone(r.families)
+		r.mu.Unlock()
+		for _, f := range fs {
+			ff, ok := f.(*funcFamily)
+			if !ok {
+				continue
+			}
+			for _, s := range ff.fn() {
+				if len(s.Labels) != len(ff.labels) {
+					continue
+				}
+				if ff.typ == "gauge" {
+					sink.Gauge(ff.name, tags(ff.labels, s.Labels), s.Value)
+					continue
+				}
+				k := ff.name + "\xff" + key(s.Labels)
+				prev := last[k]
+				last[k] = s.Value
+				if s.Value > prev {
+					sink.Count(ff.name, tags(ff.labels, s.Labels), s.Value-prev)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			r.sink.Store(nil)
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -7,10 +7,52 @@ type Version struct {
 	Lang   string
 	Author string
 	At     time.Time
+
+	// AuthorID: verifizierte Identität (Login "iss|sub"); leer = frei eingegebener Name
+	AuthorID string
+	// Profilbild und -link des Autors, nur bei Logins wie GitHub
+	AuthorAvatar string
+	AuthorURL    string
+
+	// Size: entpackte Länge in Bytes; setzt der Store aus dem gzip-Trailer, wird nicht gespeichert
+	Size int64 `json:"-"`
+
+	// Lint: erster Syntaxfehler (JSON, YAML, TOML), beim Speichern der Version ermittelt
+	Lint *Problem `json:",omitempty"`
+	// Formatted: vor dem Speichern automatisch formatiert (gofmt, JSON, YAML)
+	Formatted bool `json:",omitempty"`
+	// KeyName: mit diesem Mitbearbeiter-Key gespeichert; leer = Haupt-Key, Login oder erste Version
+	KeyName string `json:",omitempty"`
+	// Message: optionale Änderungsnotiz des Bearbeiters ("Einrückung repariert")
+	Message string `json:",omitempty"`
+	// Files: Multi-File-Paste (ausgepacktes Archiv) – die Dateien mit der Zeile ihrer Kopfzeile
+	Files []File `json:",omitempty"`
+}
+
+// File: eine Datei einer Multi-File-Paste; Line ist 1-basiert.
+type File struct {
+	Name string `json:"name"`
+	Line int    `json:"line"`
+}
+
+// Problem: Syntaxfehler mit Position (1-basiert; 0 = unbekannt).
+type Problem struct {
+	Line int    `json:"line"`
+	Col  int    `json:"col,omitempty"`
+	Msg  string `json:"msg"`
+}
+
+// EditKey: benannter Edit-Key eines Mitbearbeiters (z.B. "alice", "ci-bot").
+type EditKey struct {
+	Name      string
+	Key       string
+	CreatedAt time.Time
 }
 
 type Paste struct {
 	ID        string
+	Title     string
+	Tags      []string
 	Lang      string
 	Code      string
 	Theme     string
@@ -19,6 +61,40 @@ type Paste struct {
 	Editable bool
 	EditKey  string
 	Author   string
+	// EditKeys: weitere, einzeln widerrufbare Keys für Mitbearbeiter; EditKey bleibt der des Besitzers
+	EditKeys []EditKey
+
+	// Public: im Archiv (/archive, /api/pastes) gelistet; sonst nur per Link erreichbar.
+	Public bool
+	// Indexable: öffentliche Paste, die Suchmaschinen indexieren dürfen (nur mit -allow-indexing)
+	Indexable bool
+
+	// Private: nur für angemeldete Benutzer sichtbar. Owner = AuthorID des Erstellers.
+	Private bool
+	Owner   string
+	// GrantGen: Generation der Share-Links; hochzählen widerruft alle
+	GrantGen int
+
+	// Redacted: Namen der Secret-Regeln, deren Treffer beim Anlegen geschwärzt wurden.
+	Redacted []string
+
+	// Flagged: von der externen Moderation markiert; dann nie gelistet.
+	Flagged    bool
+	FlagReason string
+
+	// Quarantined: Virenscanner hat angeschlagen; nur Admins dürfen den Inhalt sehen.
+	Quarantined      bool
+	QuarantineReason string
+
+	// DeletedAt: per Lösch-Link gelöscht. ExpiresAt steht dann auf diesem Zeitpunkt,
+	// DeletedExpiry hält den alten Ablauf fürs Wiederherstellen.
+	DeletedAt     time.Time
+	DeletedExpiry time.Time
+
+	// ReplyTo: ID der Paste, auf die diese antwortet (z.B. die reparierte Config); leer = keine
+	ReplyTo string
+	// CloneOf: ID der Paste, von der diese eine bearbeitbare Kopie ist; leer = keine
+	CloneOf string
 
 	Versions  []Version
 	CreatedAt time.Time
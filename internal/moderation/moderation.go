@@ -0,0 +1,122 @@
+/*
+Package moderation fragt vor dem Speichern einen externen Scanner (DLP o.ä.), ob
+eine Paste durchgehen, markiert oder abgelehnt werden soll.
+
+Der Scanner bekommt einen POST mit Request als JSON und antwortet mit Verdict:
+
+	{"action": "block", "reason": "contains customer data"}
+
+action ist "allow", "flag" oder "block"; unbekannte Werte gelten als "allow".
+*/
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"unglued/internal/webhook"
+)
+
+const (
+	Allow = "allow"
+	Flag  = "flag"
+	Block = "block"
+)
+
+type Config struct {
+	URL     string
+	Secret  string        // signiert wie bei Webhooks (X-Unglued-Signature)
+	Timeout time.Duration // danach greift FailClosed
+	// FailClosed: bei Timeout/Fehler ablehnen statt durchlassen
+	FailClosed bool
+}
+
+// Request ist der JSON-Body an den Scanner.
+type Request struct {
+	Event   string    `json:"event"` // webhook.EventCreated / EventEdited
+	ID      string    `json:"id"`
+	Title   string    `json:"title,omitempty"`
+	Lang    string    `json:"lang"`
+	Author  string    `json:"author,omitempty"`
+	Version int       `json:"version"`
+	Code    string    `json:"code"`
+	At      time.Time `json:"at"`
+}
+
+type Verdict struct {
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Moderator ist nil-sicher: ein nil-Moderator lässt alles durch.
+type Moderator struct {
+	cfg    Config
+	client *http.Client
+}
+
+func New(cfg Config) *Moderator {
+	if cfg.URL == "" {
+		return nil
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 3 * time.Second
+	}
+	return &Moderator{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Check liefert immer ein Verdict; Fehler des Scanners entscheidet die Fail-Policy.
+func (m *Moderator) Check(ctx context.Context, req Request) Verdict {
+	if m == nil {
+		return Verdict{Action: Allow}
+	}
+	v, err := m.ask(ctx, req)
+	if err != nil {
+		log.Printf("moderation: %s for %s: %v", req.Event, req.ID, err)
+		if m.cfg.FailClosed {
+			return Verdict{Action: Block, Reason: "moderation unavailable"}
+		}
+		return Verdict{Action: Allow}
+	}
+	switch v.Action {
+	case Flag, Block:
+	default:
+		v.Action = Allow
+	}
+	return v
+}
+
+func (m *Moderator) ask(ctx context.Context, req Request) (Verdict, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	hr, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, err
+	}
+	hr.Header.Set("Content-Type", "application/json")
+	hr.Header.Set("User-Agent", "unglued-moderation")
+	hr.Header.Set("X-Unglued-Event", req.Event)
+	if m.cfg.Secret != "" {
+		hr.Header.Set("X-Unglued-Signature", "sha256="+webhook.Sign(m.cfg.Secret, body))
+	}
+	res, err := m.client.Do(hr)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return Verdict{}, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	var v Verdict
+	if err := json.NewDecoder(io.LimitReader(res.Body, 64<<10)).Decode(&v); err != nil {
+		return Verdict{}, fmt.Errorf("bad verdict: %w", err)
+	}
+	return v, nil
+}
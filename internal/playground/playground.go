@@ -0,0 +1,82 @@
+/*
+Package playground teilt Go-Code über die Share-API des Go Playground
+(POST /share, Antwort ist die Snippet-ID) und merkt sich die Links, damit
+wiederholte Klicks nicht jedes Mal einen Request auslösen.
+*/
+package playground
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCached: so viele Links merkt sich der Client, danach fängt er von vorn an.
+const maxCached = 1000
+
+// Client ist nil, wenn keine Playground-URL gesetzt ist (etwa auf Air-gapped-Instanzen).
+type Client struct {
+	base   string
+	client *http.Client
+
+	mu    sync.Mutex
+	links map[[sha256.Size]byte]string
+}
+
+// New: base ist die Playground-URL, z.B. https://play.golang.org; leer = aus.
+func New(base string) *Client {
+	if base == "" {
+		return nil
+	}
+	return &Client{
+		base:   strings.TrimRight(base, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+		links:  map[[sha256.Size]byte]string{},
+	}
+}
+
+// Share lädt code hoch und liefert den Link zum Snippet.
+func (c *Client) Share(ctx context.Context, code string) (string, error) {
+	key := sha256.Sum256([]byte(code))
+	c.mu.Lock()
+	link, ok := c.links[key]
+	c.mu.Unlock()
+	if ok {
+		return link, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+"/share", strings.NewReader(code))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	id := string(bytes.TrimSpace(body))
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("playground: %s: %s", res.Status, id)
+	}
+	if id == "" || strings.ContainsAny(id, "/?#<> \n") {
+		return "", fmt.Errorf("playground: unexpected snippet id %q", id)
+	}
+	link = c.base + "/p/" + id
+	c.mu.Lock()
+	if len(c.links) >= maxCached {
+		clear(c.links)
+	}
+	c.links[key] = link
+	c.mu.Unlock()
+	return link, nil
+}
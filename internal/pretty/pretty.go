@@ -0,0 +1,87 @@
+// Package pretty formatiert Code kanonisch: gofmt für Go, eingerücktes JSON und YAML.
+package pretty
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/format"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxBytes: größere Eingaben bleiben, wie sie sind.
+const MaxBytes = 1 << 20
+
+var (
+	ErrUnsupported = errors.New("pretty: language not supported")
+	ErrTooLarge    = errors.New("pretty: input too large")
+)
+
+var formatters = map[string]func(code string) (string, error){
+	"go":   formatGo,
+	"json": formatJSON,
+	"yaml": formatYAML,
+}
+
+// Supports: lässt sich lang formatieren?
+func Supports(lang string) bool {
+	_, ok := formatters[lang]
+	return ok
+}
+
+// Format liefert code kanonisch formatiert; bei Syntaxfehlern den Fehler des Parsers.
+func Format(code, lang string) (string, error) {
+	fn, ok := formatters[lang]
+	if !ok {
+		return "", ErrUnsupported
+	}
+	if len(code) > MaxBytes {
+		return "", ErrTooLarge
+	}
+	return fn(code)
+}
+
+func formatGo(code string) (string, error) {
+	out, err := format.Source([]byte(code))
+	return string(out), err
+}
+
+// formatJSON rückt mit zwei Leerzeichen ein; die Reihenfolge der Schlüssel bleibt.
+func formatJSON(code string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(code), "", "  "); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+/*
+formatYAML geht über yaml.Node statt über map: Reihenfolge, Kommentare, Anker und
+mehrere Dokumente (---) bleiben erhalten, nur Einrückung und Quoting werden einheitlich.
+*/
+func formatYAML(code string) (string, error) {
+	dec := yaml.NewDecoder(strings.NewReader(code))
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for {
+		var n yaml.Node
+		err := dec.Decode(&n)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := enc.Encode(&n); err != nil {
+			return "", err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,293 @@
+/*
+Package redis ist ein kleiner RESP2-Client: Befehle gehen als Array von
+Bulk-Strings raus, Antworten kommen als Simple String, Fehler, Integer, Bulk
+oder Array zurück. Reicht für GET/SET, SCAN und Pub/Sub – kein Cluster, kein
+Sentinel, kein RESP3.
+*/
+package redis
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNil: der Schlüssel existiert nicht (Null-Bulk bzw. Null-Array).
+var ErrNil = errors.New("redis: nil")
+
+// Error ist eine Fehlerantwort des Servers, z.B. "ERR unknown command".
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// so viele freie Verbindungen hält der Client für Do vor
+const maxIdle = 8
+
+type Client struct {
+	network, addr string
+	tls           *tls.Config
+	user, pass    string
+	db            int
+	timeout       time.Duration
+
+	idle chan *Conn
+}
+
+/*
+New: rawURL ist redis://[user:passwort@]host[:port][/db], rediss://… für TLS
+oder unix:///pfad/zum/socket[?db=N]. Verbunden wird erst bei Bedarf.
+*/
+func New(rawURL string, timeout time.Duration) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	c := &Client{network: "tcp", timeout: timeout, idle: make(chan *Conn, maxIdle)}
+	if c.timeout <= 0 {
+		c.timeout = 5 * time.Second
+	}
+	db := strings.TrimPrefix(u.Path, "/")
+	switch u.Scheme {
+	case "redis", "rediss":
+		c.addr = u.Host
+		if u.Port() == "" {
+			c.addr = net.JoinHostPort(u.Hostname(), "6379")
+		}
+		if u.Scheme == "rediss" {
+			c.tls = &tls.Config{ServerName: u.Hostname()}
+		}
+	case "unix":
+		c.network, c.addr, db = "unix", u.Path, u.Query().Get("db")
+	default:
+		return nil, fmt.Errorf("redis: unsupported scheme %q (want redis, rediss or unix)", u.Scheme)
+	}
+	if c.addr == "" || c.addr == ":6379" {
+		return nil, errors.New("redis: missing host")
+	}
+	if db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("redis: bad database %q", db)
+		}
+	}
+	if u.User != nil {
+		c.user = u.User.Username()
+		c.pass, _ = u.User.Password()
+		// redis://:passwort@host – nur requirepass, kein ACL-Benutzer
+		if _, ok := u.User.Password(); !ok {
+			c.user, c.pass = "", c.user
+		}
+	}
+	return c, nil
+}
+
+func (c *Client) String() string { return c.network + ":" + c.addr }
+
+// Dial öffnet eine eigene Verbindung (z.B. für SUBSCRIBE), angemeldet und mit gewählter Datenbank.
+func (c *Client) Dial() (*Conn, error) {
+	d := net.Dialer{Timeout: c.timeout}
+	nc, err := d.Dial(c.network, c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	if c.tls != nil {
+		nc = tls.Client(nc, c.tls)
+	}
+	cn := &Conn{nc: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc), timeout: c.timeout}
+	if c.pass != "" {
+		args := []string{"AUTH", c.pass}
+		if c.user != "" {
+			args = []string{"AUTH", c.user, c.pass}
+		}
+		if _, err := cn.Do(args...); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.Do("SELECT", strconv.Itoa(c.db)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+/*
+Do schickt einen Befehl über eine freie Verbindung. Nach Netzwerkfehlern wird
+die Verbindung verworfen; Fehlerantworten des Servers (Error) lassen sie offen.
+War es eine Verbindung aus dem Vorrat, wird einmal mit einer neuen wiederholt –
+nach einem Neustart von Redis sind die alle tot.
+*/
+func (c *Client) Do(args ...string) (any, error) {
+	var cn *Conn
+	select {
+	case cn = <-c.idle:
+	default:
+	}
+	pooled := cn != nil
+	for {
+		if cn == nil {
+			var err error
+			if cn, err = c.Dial(); err != nil {
+				return nil, err
+			}
+		}
+		v, err := cn.Do(args...)
+		var rerr Error
+		if err == nil || errors.As(err, &rerr) || errors.Is(err, ErrNil) {
+			c.put(cn)
+			return v, err
+		}
+		cn.Close()
+		if !pooled {
+			return nil, err
+		}
+		cn, pooled = nil, false
+	}
+}
+
+func (c *Client) put(cn *Conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+// Close schließt die freien Verbindungen; laufende gehen danach ebenfalls zu.
+func (c *Client) Close() {
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// Conn ist eine einzelne Verbindung; nicht für gleichzeitige Nutzung.
+type Conn struct {
+	nc      net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	timeout time.Duration
+}
+
+/*
+Do schickt args und liest die Antwort: string (Simple String), int64, []byte
+(Bulk), []any (Array) oder Error. Null-Antworten kommen als ErrNil.
+*/
+func (cn *Conn) Do(args ...string) (any, error) {
+	_ = cn.nc.SetDeadline(time.Now().Add(cn.timeout))
+	if err := cn.send(args); err != nil {
+		return nil, err
+	}
+	return cn.read()
+}
+
+// Receive wartet ohne Frist auf die nächste Nachricht, etwa nach SUBSCRIBE.
+func (cn *Conn) Receive() (any, error) {
+	_ = cn.nc.SetDeadline(time.Time{})
+	return cn.read()
+}
+
+func (cn *Conn) Close() error { return cn.nc.Close() }
+
+func (cn *Conn) send(args []string) error {
+	fmt.Fprintf(cn.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(cn.w, "$%d\r\n", len(a))
+		cn.w.WriteString(a)
+		cn.w.WriteString("\r\n")
+	}
+	if err := cn.w.Flush(); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	return nil
+}
+
+func (cn *Conn) read() (any, error) {
+	line, err := cn.r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, Error(body)
+	case ':':
+		n, err := strconv.ParseInt(body, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed integer %q", body)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.r, b); err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", body)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		out := make([]any, n)
+		for i := range out {
+			// Null-Elemente (etwa bei MGET) bleiben nil statt den ganzen Befehl scheitern zu lassen
+			if out[i], err = cn.read(); err != nil && !errors.Is(err, ErrNil) {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
+
+// Bytes macht aus einer Bulk- oder Simple-String-Antwort Bytes.
+func Bytes(v any, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %T", v)
+}
+
+// Values macht aus einer Array-Antwort eine Liste.
+func Values(v any, err error) ([]any, error) {
+	if err != nil {
+		return nil, err
+	}
+	if vs, ok := v.([]any); ok {
+		return vs, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %T", v)
+}
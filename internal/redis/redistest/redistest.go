@@ -0,0 +1,278 @@
+/*
+Package redistest ist ein Redis-Ersatz im Speicher für Tests: genug RESP2 für
+das, was redisstore braucht (GET/SET/MGET/DEL/SCAN/PEXPIRE und Pub/Sub). Kein
+echtes Redis nötig, also laufen die Replica-Tests auch in CI.
+*/
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type entry struct {
+	val     string
+	expires time.Time // Null = läuft nicht ab
+}
+
+type Server struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	data  map[string]entry
+	subs  map[string][]*client
+	conns map[net.Conn]bool
+}
+
+type client struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+// Start lauscht auf einem freien Port und räumt mit dem Test auf.
+func Start(tb testing.TB) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	s := &Server{ln: ln, data: map[string]entry{}, subs: map[string][]*client{}, conns: map[net.Conn]bool{}}
+	go s.accept()
+	tb.Cleanup(func() {
+		ln.Close()
+		s.Drop()
+	})
+	return s
+}
+
+// URL für redis.New.
+func (s *Server) URL() string { return "redis://" + s.ln.Addr().String() }
+
+// Drop trennt alle Verbindungen, wie ein Neustart von Redis ohne Datenverlust.
+func (s *Server) Drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.subs = map[string][]*client{}
+}
+
+// Keys liefert alle Schlüssel, sortiert.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for k := range s.data {
+		if s.liveLocked(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) accept() {
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[nc] = true
+		s.mu.Unlock()
+		go s.serve(nc)
+	}
+}
+
+func (s *Server) serve(nc net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, nc)
+		s.mu.Unlock()
+		nc.Close()
+	}()
+	r := bufio.NewReader(nc)
+	c := &client{w: bufio.NewWriter(nc)}
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		s.exec(c, args)
+		err = c.w.Flush()
+		c.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || line[0] != '*' {
+		return nil, fmt.Errorf("bad command %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+	return args, nil
+}
+
+func (s *Server) liveLocked(key string) bool {
+	e, ok := s.data[key]
+	if ok && !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(s.data, key)
+		return false
+	}
+	return ok
+}
+
+// exec führt einen Befehl aus; c.mu ist gehalten.
+func (s *Server) exec(c *client, args []string) {
+	w := c.w
+	if len(args) == 0 {
+		fmt.Fprint(w, "-ERR empty command\r\n")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch cmd := strings.ToUpper(args[0]); cmd {
+	case "PING":
+		fmt.Fprint(w, "+PONG\r\n")
+	case "AUTH", "SELECT":
+		fmt.Fprint(w, "+OK\r\n")
+	case "GET":
+		if !s.liveLocked(args[1]) {
+			fmt.Fprint(w, "$-1\r\n")
+			return
+		}
+		bulk(w, s.data[args[1]].val)
+	case "MGET":
+		fmt.Fprintf(w, "*%d\r\n", len(args)-1)
+		for _, k := range args[1:] {
+			if s.liveLocked(k) {
+				bulk(w, s.data[k].val)
+			} else {
+				fmt.Fprint(w, "$-1\r\n")
+			}
+		}
+	case "SET":
+		e, exists := entry{val: args[2]}, s.liveLocked(args[1])
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				if exists {
+					fmt.Fprint(w, "$-1\r\n")
+					return
+				}
+			case "XX":
+				if !exists {
+					fmt.Fprint(w, "$-1\r\n")
+					return
+				}
+			case "PX":
+				i++
+				ms, _ := strconv.Atoi(args[i])
+				e.expires = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+		}
+		s.data[args[1]] = e
+		fmt.Fprint(w, "+OK\r\n")
+	case "DEL":
+		n := 0
+		for _, k := range args[1:] {
+			if s.liveLocked(k) {
+				delete(s.data, k)
+				n++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", n)
+	case "PEXPIRE":
+		if !s.liveLocked(args[1]) {
+			fmt.Fprint(w, ":0\r\n")
+			return
+		}
+		ms, _ := strconv.Atoi(args[2])
+		e := s.data[args[1]]
+		e.expires = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		s.data[args[1]] = e
+		fmt.Fprint(w, ":1\r\n")
+	case "SCAN":
+		// alles in einem Rutsch; Cursor 0 heißt fertig
+		pattern := "*"
+		for i := 2; i+1 < len(args); i += 2 {
+			if strings.ToUpper(args[i]) == "MATCH" {
+				pattern = args[i+1]
+			}
+		}
+		var keys []string
+		for k := range s.data {
+			if ok, _ := path.Match(pattern, k); ok && s.liveLocked(k) {
+				keys = append(keys, k)
+			}
+		}
+		fmt.Fprint(w, "*2\r\n")
+		bulk(w, "0")
+		fmt.Fprintf(w, "*%d\r\n", len(keys))
+		for _, k := range keys {
+			bulk(w, k)
+		}
+	case "PUBLISH":
+		subs := s.subs[args[1]]
+		// gleich zustellen, damit die Reihenfolge stimmt; Abonnenten senden nach
+		// SUBSCRIBE nichts mehr, halten sub.mu also nie lange
+		for _, sub := range subs {
+			if sub == c {
+				continue
+			}
+			sub.mu.Lock()
+			fmt.Fprint(sub.w, "*3\r\n")
+			bulk(sub.w, "message")
+			bulk(sub.w, args[1])
+			bulk(sub.w, args[2])
+			_ = sub.w.Flush()
+			sub.mu.Unlock()
+		}
+		fmt.Fprintf(w, ":%d\r\n", len(subs))
+	case "SUBSCRIBE":
+		for i, ch := range args[1:] {
+			s.subs[ch] = append(s.subs[ch], c)
+			fmt.Fprint(w, "*3\r\n")
+			bulk(w, "subscribe")
+			bulk(w, ch)
+			fmt.Fprintf(w, ":%d\r\n", i+1)
+		}
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", cmd)
+	}
+}
+
+func bulk(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
@@ -0,0 +1,512 @@
+/*
+Package redisstore legt den Store in Redis, damit mehrere Instanzen hinter einem
+Load-Balancer dieselben Pastes sehen. Jede Paste liegt als JSON (mit
+-encryption-key versiegelt) unter <prefix>paste:<id>; jede Änderung geht außerdem
+als "<instanz> s|d <id>" an den Kanal <prefix>changes. Die anderen Instanzen laden
+die Paste daraufhin neu oder vergessen sie – der Store meldet das über OnChange,
+so verwerfen auch ihre Render-Caches die alte Fassung.
+
+Lokal bleibt jede Instanz ein vollständiger Store im Speicher; Redis ist die
+gemeinsame Wahrheit, aus der beim Start und nach jedem Verbindungsabbruch neu
+geladen wird.
+*/
+package redisstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"unglued/internal/model"
+	"unglued/internal/redis"
+	"unglued/internal/util"
+)
+
+// Sealer verschlüsselt die Werte in Redis (siehe atrest.Keyring).
+type Sealer interface {
+	Seal(plain []byte) []byte
+	Open(sealed []byte) (plain []byte, stale bool, err error)
+}
+
+// Target bekommt die Änderungen der anderen Instanzen (store.Store).
+type Target interface {
+	Load(p model.Paste)
+	Forget(id string) bool
+	Find(match func(p *model.Paste) bool) []model.Paste
+}
+
+// so lange wartet Saved höchstens auf Redis, danach läuft der Request ohne Bestätigung weiter
+const saveWait = 5 * time.Second
+
+// so lange gehört der Janitor einer Instanz, ohne dass sie verlängert
+const leaseTTL = 2 * time.Minute
+
+type op struct {
+	id   string
+	save *model.Paste // nil = löschen
+	done chan struct{}
+}
+
+type Store struct {
+	c        *redis.Client
+	prefix   string
+	instance string
+
+	// optional: versiegelt die Werte in Redis; vor Start setzen
+	Sealer Sealer
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+	sub  atomic.Pointer[redis.Conn]
+
+	mu      sync.Mutex
+	pending []op
+	closed  bool
+	// IDs, deren DEL noch aussteht; Fetch darf sie nicht aus Redis zurückholen
+	deleting map[string]int
+}
+
+/*
+Open verbindet sich mit rawURL (siehe redis.New) und prüft die Verbindung.
+prefix steht vor allen Schlüsseln, damit sich mehrere Installationen eine
+Redis-Datenbank teilen können.
+*/
+func Open(rawURL, prefix string) (*Store, error) {
+	c, err := redis.New(rawURL, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Do("PING"); err != nil {
+		return nil, err
+	}
+	r := &Store{
+		c: c, prefix: prefix, instance: util.NewID(12),
+		wake: make(chan struct{}, 1), stop: make(chan struct{}), done: make(chan struct{}),
+		deleting: map[string]int{},
+	}
+	go r.run()
+	return r, nil
+}
+
+func (r *Store) String() string { return r.c.String() }
+
+func (r *Store) key(id string) string { return r.prefix + "paste:" + id }
+func (r *Store) channel() string      { return r.prefix + "changes" }
+
+/*
+Saved schreibt p und wartet darauf (höchstens saveWait): wer danach bei einer
+anderen Instanz nachfragt, soll die Paste dort schon finden.
+*/
+func (r *Store) Saved(p model.Paste) {
+	done := make(chan struct{})
+	if !r.enqueue(op{id: p.ID, save: &p, done: done}) {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(saveWait):
+		log.Printf("redisstore: saving %s takes longer than %s", p.ID, saveWait)
+	}
+}
+
+// Removed wird unter dem Store-Lock gerufen und wartet deshalb nicht.
+func (r *Store) Removed(id string) { r.enqueue(op{id: id}) }
+
+func (r *Store) enqueue(o op) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return false
+	}
+	r.pending = append(r.pending, o)
+	if o.save == nil && o.id != "" {
+		r.deleting[o.id]++
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+/*
+run schreibt die Änderungen der Reihe nach – so überholt kein Löschen ein
+vorheriges Speichern. Ist Redis weg, bleiben sie in der Schlange und werden mit
+wachsendem Abstand erneut versucht.
+*/
+func (r *Store) run() {
+	defer close(r.done)
+	var wait time.Duration
+	for {
+		var retry <-chan time.Time
+		if wait > 0 {
+			retry = time.After(wait)
+		}
+		select {
+		case <-r.wake:
+		case <-retry:
+		case <-r.stop:
+			if !r.drain() {
+				r.mu.Lock()
+				log.Printf("redisstore: %d changes not written", len(r.pending))
+				r.mu.Unlock()
+			}
+			return
+		}
+		if r.drain() {
+			wait = 0
+		} else {
+			wait = min(max(2*wait, 100*time.Millisecond), 10*time.Second)
+		}
+	}
+}
+
+// drain schreibt, was ansteht; false, wenn Redis nicht erreichbar war – der Rest steht dann wieder vorne.
+func (r *Store) drain() bool {
+	r.mu.Lock()
+	ops := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+	for i, o := range ops {
+		err := r.write(o)
+		var rerr redis.Error
+		if err != nil && !errors.As(err, &rerr) {
+			r.mu.Lock()
+			r.pending = append(ops[i:len(ops):len(ops)], r.pending...)
+			r.mu.Unlock()
+			log.Printf("redisstore: %v (%d changes waiting)", err, len(ops)-i)
+			return false
+		}
+		if err != nil {
+			log.Printf("redisstore: %s: %v", o.id, err)
+		}
+		if o.save == nil && o.id != "" {
+			r.mu.Lock()
+			if r.deleting[o.id]--; r.deleting[o.id] == 0 {
+				delete(r.deleting, o.id)
+			}
+			r.mu.Unlock()
+		}
+		if o.done != nil {
+			close(o.done)
+		}
+	}
+	return true
+}
+
+func (r *Store) write(o op) error {
+	if o.id == "" {
+		// nur eine Marke für Flush
+		return nil
+	}
+	kind := "d"
+	if o.save != nil {
+		b, err := r.encode(*o.save)
+		if err != nil {
+			return err
+		}
+		if _, err := r.c.Do("SET", r.key(o.id), string(b)); err != nil {
+			return err
+		}
+		kind = "s"
+	} else if _, err := r.c.Do("DEL", r.key(o.id)); err != nil {
+		return err
+	}
+	_, err := r.c.Do("PUBLISH", r.channel(), r.instance+" "+kind+" "+o.id)
+	return err
+}
+
+// Flush wartet, bis alle bisher gemeldeten Änderungen in Redis stehen.
+func (r *Store) Flush() {
+	done := make(chan struct{})
+	if r.enqueue(op{done: done}) {
+		<-done
+	}
+}
+
+// Close schreibt, was noch aussteht, und trennt die Verbindungen.
+func (r *Store) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	r.mu.Unlock()
+	close(r.stop)
+	<-r.done
+	if sub := r.sub.Load(); sub != nil {
+		sub.Close()
+	}
+	r.c.Close()
+}
+
+func (r *Store) encode(p model.Paste) ([]byte, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	if r.Sealer != nil {
+		b = r.Sealer.Seal(b)
+	}
+	return b, nil
+}
+
+func (r *Store) decode(b []byte) (model.Paste, error) {
+	var p model.Paste
+	if r.Sealer != nil {
+		plain, _, err := r.Sealer.Open(b)
+		if err != nil {
+			return p, err
+		}
+		b = plain
+	}
+	err := json.Unmarshal(b, &p)
+	return p, err
+}
+
+// Fetch liest eine Paste direkt aus Redis (store.Fetcher).
+func (r *Store) Fetch(id string) (model.Paste, bool, error) {
+	p, err := r.fetch(id)
+	switch {
+	case errors.Is(err, redis.ErrNil):
+		return p, false, nil
+	case err != nil:
+		log.Printf("redisstore: %s: %v", id, err)
+		return p, false, err
+	}
+	return p, true, nil
+}
+
+// fetch: redis.ErrNil, wenn es id in Redis nicht gibt oder das Löschen noch aussteht.
+func (r *Store) fetch(id string) (model.Paste, error) {
+	r.mu.Lock()
+	deleting := r.deleting[id] > 0
+	r.mu.Unlock()
+	if deleting {
+		return model.Paste{}, redis.ErrNil
+	}
+	b, err := redis.Bytes(r.c.Do("GET", r.key(id)))
+	if err != nil {
+		return model.Paste{}, err
+	}
+	return r.decode(b)
+}
+
+// Load ruft fn für jede Paste in Redis auf.
+func (r *Store) Load(fn func(model.Paste) error) error {
+	cursor := "0"
+	for {
+		reply, err := redis.Values(r.c.Do("SCAN", cursor, "MATCH", r.key("*"), "COUNT", "500"))
+		if err != nil {
+			return err
+		}
+		if len(reply) != 2 {
+			return fmt.Errorf("redisstore: unexpected SCAN reply")
+		}
+		next, _ := redis.Bytes(reply[0], nil)
+		keys, _ := redis.Values(reply[1], nil)
+		if len(keys) > 0 {
+			args := []string{"MGET"}
+			for _, k := range keys {
+				b, _ := redis.Bytes(k, nil)
+				args = append(args, string(b))
+			}
+			vals, err := redis.Values(r.c.Do(args...))
+			if err != nil {
+				return err
+			}
+			for i, v := range vals {
+				b, err := redis.Bytes(v, nil)
+				if err != nil {
+					// zwischen SCAN und MGET gelöscht
+					continue
+				}
+				p, err := r.decode(b)
+				if err != nil {
+					return fmt.Errorf("%s: %w", args[i+1], err)
+				}
+				if err := fn(p); err != nil {
+					return err
+				}
+			}
+		}
+		if cursor = string(next); cursor == "0" {
+			return nil
+		}
+	}
+}
+
+/*
+Start abonniert die Änderungen, lädt danach alles aus Redis in t und liefert die
+Anzahl. Im Hintergrund werden dann die Änderungen anderer Instanzen angewandt;
+reißt die Verbindung ab, wird neu abonniert und neu abgeglichen, denn verpasste
+Nachrichten schickt Redis nicht nach.
+*/
+func (r *Store) Start(t Target) (int, error) {
+	sub, err := r.subscribe()
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.sync(t)
+	if err != nil {
+		sub.Close()
+		return 0, err
+	}
+	go r.listen(t, sub)
+	return n, nil
+}
+
+func (r *Store) subscribe() (*redis.Conn, error) {
+	sub, err := r.c.Dial()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sub.Do("SUBSCRIBE", r.channel()); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+/*
+sync lädt alles aus Redis und vergisst, was es dort nicht mehr gibt. Eigene
+Änderungen, die noch nicht geschrieben sind, überschreibt es nicht: vorher wird
+gewartet, bis die Warteschlange leer ist, und vor dem Vergessen noch einmal
+nachgesehen – die Paste kann inzwischen angelegt worden sein.
+*/
+func (r *Store) sync(t Target) (int, error) {
+	r.Flush()
+	seen := map[string]bool{}
+	if err := r.Load(func(p model.Paste) error {
+		seen[p.ID] = true
+		t.Load(p)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	var gone []string
+	t.Find(func(p *model.Paste) bool {
+		if !seen[p.ID] {
+			gone = append(gone, p.ID)
+		}
+		return false
+	})
+	for _, id := range gone {
+		if _, err := r.fetch(id); errors.Is(err, redis.ErrNil) {
+			t.Forget(id)
+		}
+	}
+	return len(seen), nil
+}
+
+func (r *Store) listen(t Target, sub *redis.Conn) {
+	for {
+		// Close schließt die aktuelle Verbindung; kam es der neuen zuvor, hier
+		r.sub.Store(sub)
+		if r.isClosed() {
+			sub.Close()
+			return
+		}
+		msg, err := redis.Values(sub.Receive())
+		if err != nil {
+			if r.isClosed() {
+				return
+			}
+			log.Printf("redisstore: subscription lost: %v", err)
+			sub.Close()
+			if sub = r.resubscribe(t); sub == nil {
+				return
+			}
+			continue
+		}
+		if len(msg) == 3 {
+			if kind, _ := redis.Bytes(msg[0], nil); string(kind) == "message" {
+				payload, _ := redis.Bytes(msg[2], nil)
+				r.apply(t, string(payload))
+			}
+		}
+	}
+}
+
+// resubscribe versucht es mit wachsendem Abstand, bis es klappt oder Close gerufen wurde.
+func (r *Store) resubscribe(t Target) *redis.Conn {
+	wait := 100 * time.Millisecond
+	for {
+		select {
+		case <-r.stop:
+			return nil
+		case <-time.After(wait):
+		}
+		sub, err := r.subscribe()
+		if err == nil {
+			var n int
+			if n, err = r.sync(t); err == nil {
+				log.Printf("redisstore: resubscribed, %d pastes in sync", n)
+				return sub
+			}
+			sub.Close()
+		}
+		log.Printf("redisstore: reconnect: %v", err)
+		wait = min(2*wait, 10*time.Second)
+	}
+}
+
+// apply wendet eine Nachricht "<instanz> s|d <id>" an; eigene Nachrichten sind schon erledigt.
+func (r *Store) apply(t Target, msg string) {
+	from, rest, _ := strings.Cut(msg, " ")
+	kind, id, _ := strings.Cut(rest, " ")
+	if from == r.instance || id == "" {
+		return
+	}
+	if kind == "s" {
+		// den aktuellen Stand holen statt den der Nachricht: so landen alle bei der letzten Fassung
+		p, err := r.fetch(id)
+		switch {
+		case err == nil:
+			t.Load(p)
+			return
+		case !errors.Is(err, redis.ErrNil):
+			// Redis nicht erreichbar: lieber die alte Fassung behalten als die Paste verlieren
+			log.Printf("redisstore: %s: %v", id, err)
+			return
+		}
+	}
+	t.Forget(id)
+}
+
+func (r *Store) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+/*
+Lease: gehört der Janitor gerade dieser Instanz? Sonst räumen alle Instanzen
+dieselben abgelaufenen Pastes ab und jeder Webhook käme N-mal. Wer ihn hat,
+verlängert bei jedem Durchlauf; fällt die Instanz aus, übernimmt nach leaseTTL
+eine andere.
+*/
+func (r *Store) Lease() bool {
+	key, ttl := r.prefix+"janitor", strconv.FormatInt(leaseTTL.Milliseconds(), 10)
+	if _, err := r.c.Do("SET", key, r.instance, "NX", "PX", ttl); err == nil {
+		return true
+	} else if !errors.Is(err, redis.ErrNil) {
+		log.Printf("redisstore: janitor lease: %v", err)
+		return false
+	}
+	owner, err := redis.Bytes(r.c.Do("GET", key))
+	if err != nil || string(owner) != r.instance {
+		return false
+	}
+	// zwischen GET und PEXPIRE kann die Lease ablaufen; dann räumen für einen Durchlauf zwei ab
+	_, err = r.c.Do("PEXPIRE", key, ttl)
+	return err == nil
+}
@@ -0,0 +1,185 @@
+package redisstore_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"unglued/internal/model"
+	"unglued/internal/redis/redistest"
+	"unglued/internal/redisstore"
+	"unglued/internal/render"
+	"unglued/internal/store"
+	"unglued/internal/util"
+)
+
+type replica struct {
+	st    *store.Store
+	rs    *redisstore.Store
+	cache *render.Cache
+}
+
+// replicas startet n Instanzen auf demselben Redis, jede mit eigenem Store und Render-Cache.
+func replicas(t *testing.T, srv *redistest.Server, n int) []replica {
+	out := make([]replica, n)
+	for i := range out {
+		rs, err := redisstore.Open(srv.URL(), "test:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		st := store.New(time.Hour)
+		st.Backend = rs
+		cache := render.NewCache([]string{"dark"}, 1<<20)
+		st.OnChange(cache.Invalidate)
+		if _, err := rs.Start(st); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			rs.Close()
+			st.Close()
+		})
+		out[i] = replica{st, rs, cache}
+	}
+	return out
+}
+
+func paste(id, code string) model.Paste {
+	now := time.Now()
+	return model.Paste{
+		ID: id, Code: code, Lang: "go", Editable: true, EditKey: "k",
+		CreatedAt: now, UpdatedAt: now, ExpiresAt: now.Add(time.Hour),
+		Versions: []model.Version{{ZCode: util.GzipEncode(code), Lang: "go", At: now}},
+	}
+}
+
+// eventually wartet, bis ok stimmt; Pub/Sub kommt asynchron an.
+func eventually(t *testing.T, what string, ok func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if ok() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestReplicasShareState(t *testing.T) {
+	srv := redistest.Start(t)
+	rs := replicas(t, srv, 3)
+	a, b, c := rs[0], rs[1], rs[2]
+
+	a.st.Put(paste("p1", "one"))
+	// gleich danach, ohne auf die Nachricht zu warten: b liest aus Redis nach
+	if p, ok := b.st.Get("p1"); !ok || p.Code != "one" {
+		t.Fatalf("b right after create: %q %v", p.Code, ok)
+	}
+	eventually(t, "c to learn about p1", func() bool { return c.st.Has("p1") })
+
+	// b hat vorgerendert; eine Änderung auf c muss das verwerfen
+	b.cache.Put("p1", 0, "dark", "<pre>one</pre>", false)
+	if _, err := c.st.Update("p1", func(p *model.Paste) error {
+		p.Code = "two"
+		p.Versions = append(p.Versions, model.Version{ZCode: util.GzipEncode("two"), Lang: "go", At: time.Now()})
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range rs {
+		eventually(t, fmt.Sprintf("replica %d to see the edit", i), func() bool {
+			p, _ := r.st.Get("p1")
+			return p.Code == "two" && len(p.Versions) == 2
+		})
+	}
+	if _, _, ok := b.cache.Get("p1", 0, "dark"); ok {
+		t.Error("b still serves the rendered copy from before the edit")
+	}
+
+	b.st.Delete("p1")
+	for i, r := range rs {
+		eventually(t, fmt.Sprintf("replica %d to forget p1", i), func() bool { return !r.st.Has("p1") })
+	}
+	if keys := srv.Keys(); len(keys) != 0 {
+		t.Errorf("left in redis: %v", keys)
+	}
+}
+
+func TestReplicaResyncsAfterReconnect(t *testing.T) {
+	srv := redistest.Start(t)
+	rs := replicas(t, srv, 2)
+	a, b := rs[0], rs[1]
+	a.st.Put(paste("old", "x"))
+	eventually(t, "b to learn about old", func() bool { return b.st.Has("old") })
+
+	// Redis trennt alle; was in der Zwischenzeit passiert, kommt bei b nie als Nachricht an
+	srv.Drop()
+	a.st.Delete("old")
+	a.st.Put(paste("new", "y"))
+	a.rs.Flush()
+
+	// Find sieht nur, was b selbst hat; Get würde in Redis nachlesen
+	local := func(id string) bool {
+		return len(b.st.Find(func(p *model.Paste) bool { return p.ID == id })) > 0
+	}
+	eventually(t, "b to resync", func() bool { return local("new") && !local("old") })
+}
+
+func TestJanitorLeaseHasOneHolder(t *testing.T) {
+	srv := redistest.Start(t)
+	rs := replicas(t, srv, 3)
+	holders := 0
+	for range 2 {
+		holders = 0
+		for _, r := range rs {
+			if r.rs.Lease() {
+				holders++
+			}
+		}
+		if holders != 1 {
+			t.Fatalf("%d replicas hold the janitor lease, want 1", holders)
+		}
+	}
+}
+
+func TestUpdateStartsFromSharedState(t *testing.T) {
+	srv := redistest.Start(t)
+	rs := replicas(t, srv, 2)
+	a, b := rs[0], rs[1]
+	a.st.Put(paste("p1", "one"))
+	eventually(t, "b to learn about p1", func() bool { return b.st.Has("p1") })
+
+	if _, err := b.st.Update("p1", func(p *model.Paste) error { p.EditKey = "rotated"; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	// gleich danach auf a, dessen Kopie womöglich noch den alten Key hat
+	var seen string
+	if _, err := a.st.Update("p1", func(p *model.Paste) error { seen = p.EditKey; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if seen != "rotated" {
+		t.Errorf("a edited against key %q, want the rotated one", seen)
+	}
+}
+
+func TestConcurrentUpdatesOnOneReplica(t *testing.T) {
+	srv := redistest.Start(t)
+	rs := replicas(t, srv, 2)
+	a, b := rs[0], rs[1]
+	a.st.Put(paste("p1", "one"))
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := a.st.Update("p1", func(p *model.Paste) error { p.GrantGen++; return nil }); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	eventually(t, "b to see all 20 updates", func() bool {
+		p, _ := b.st.Get("p1")
+		return p.GrantGen == 20
+	})
+}
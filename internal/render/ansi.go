@@ -0,0 +1,33 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+)
+
+/*
+ANSI rendert code mit ANSI-Escapes (256 Farben) fürs Terminal, etwa für
+curl | less -R. Es gelten dieselben Limits wie bei Highlight; greifen sie,
+kommt der Text ungefärbt zurück (plain = true).
+*/
+func ANSI(code, lang, theme string, lim Limits) (out string, plain bool, err error) {
+	if lim.tooBig(code) {
+		return code, true, nil
+	}
+	it, err := lexerFor(code, lang).Tokenise(nil, code)
+	if err != nil {
+		return "", false, err
+	}
+	tokens, ok := lim.collect(it)
+	if !ok {
+		return code, true, nil
+	}
+	var b strings.Builder
+	b.Grow(2 * len(code))
+	if err := formatters.TTY256.Format(&b, styleFor(theme), chroma.Literator(tokens...)); err != nil {
+		return "", false, err
+	}
+	return b.String(), false, nil
+}
@@ -0,0 +1,213 @@
+package render
+
+import (
+	"container/list"
+	"html/template"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+Cache hält fertig gerendertes HTML je Paste, Version und Theme, damit /p/{id}
+nicht bei jedem Aufruf neu tokenisiert. Gefüllt wird beim Anlegen einer Version
+für die konfigurierten Themes; ist maxBytes erreicht, fliegt der am längsten
+nicht gelesene Eintrag. Nil-sicher: ein nil-Cache findet nie etwas.
+*/
+type Cache struct {
+	themes   []string
+	maxBytes int64
+
+	mu    sync.Mutex
+	bytes int64
+	lru   *list.List // *cacheEntry, vorn = zuletzt benutzt
+	items map[string]*list.Element
+	byID  map[string]map[*list.Element]struct{} // für Invalidate
+
+	hits, misses atomic.Int64
+}
+
+// CacheStats für Metriken und die Admin-API.
+type CacheStats struct {
+	Entries int   `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+type cacheEntry struct {
+	id    string
+	key   string
+	html  template.HTML
+	plain bool // ohne Highlighting, weil Limits gegriffen haben
+}
+
+// NewCache: nil, wenn themes leer ist (Vorrendern aus).
+func NewCache(themes []string, maxBytes int64) *Cache {
+	if len(themes) == 0 {
+		return nil
+	}
+	return &Cache{themes: themes, maxBytes: maxBytes, lru: list.New(), items: map[string]*list.Element{},
+		byID: map[string]map[*list.Element]struct{}{}}
+}
+
+// Themes: für diese Themes wird beim Schreiben vorgerendert.
+func (c *Cache) Themes() []string {
+	if c == nil {
+		return nil
+	}
+	return c.themes
+}
+
+// Wants: wird theme vorgerendert (und lohnt sich damit das Ablegen)?
+func (c *Cache) Wants(theme string) bool {
+	return c != nil && slices.Contains(c.themes, theme)
+}
+
+func cacheKey(id string, version int, theme string) string {
+	return id + "\x00" + strconv.Itoa(version) + "\x00" + theme
+}
+
+func (c *Cache) Get(id string, version int, theme string) (html template.HTML, plain, ok bool) {
+	if c == nil {
+		return "", false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[cacheKey(id, version, theme)]
+	if !ok {
+		c.misses.Add(1)
+		return "", false, false
+	}
+	c.hits.Add(1)
+	c.lru.MoveToFront(el)
+	e := el.Value.(*cacheEntry)
+	return e.html, e.plain, true
+}
+
+// Put legt html ab; Einträge größer als der halbe Cache lohnen nicht und bleiben draußen.
+func (c *Cache) Put(id string, version int, theme string, html template.HTML, plain bool) {
+	if c == nil || int64(len(html)) > c.maxBytes/2 {
+		return
+	}
+	key := cacheKey(id, version, theme)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	el := c.lru.PushFront(&cacheEntry{id: id, key: key, html: html, plain: plain})
+	c.items[key] = el
+	if c.byID[id] == nil {
+		c.byID[id] = map[*list.Element]struct{}{}
+	}
+	c.byID[id][el] = struct{}{}
+	c.bytes += int64(len(html))
+	for c.bytes > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove: c.mu muss gehalten werden.
+func (c *Cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*cacheEntry)
+	delete(c.items, e.key)
+	if set := c.byID[e.id]; set != nil {
+		delete(set, el)
+		if len(set) == 0 {
+			delete(c.byID, e.id)
+		}
+	}
+	c.bytes -= int64(len(e.html))
+}
+
+/*
+Invalidate wirft alles zu id weg, alle Versionen und Themes. Der Store ruft das
+bei jeder Änderung (neue Version, Löschen, Ablauf, Wiederherstellen), damit nach
+einem Edit nie altes HTML ausgeliefert wird – auch wenn eine ID neu belegt wird.
+*/
+func (c *Cache) Invalidate(id string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := range c.byID[id] {
+		c.remove(el)
+	}
+}
+
+// Flush leert den Cache; liefert, wie viele Einträge es waren.
+func (c *Cache) Flush() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.items)
+	c.lru.Init()
+	c.items = map[string]*list.Element{}
+	c.byID = map[string]map[*list.Element]struct{}{}
+	c.bytes = 0
+	return n
+}
+
+func (c *Cache) Stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Entries: len(c.items), Bytes: c.bytes, Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+/*
+MarkLines setzt die Klasse "hl" an die Zeilen aus hl, so wie CodeHTML es mit hl
+täte. So bleibt ?hl= auch mit vorgerendertem HTML billig: ein Durchlauf, kein
+neues Tokenisieren.
+*/
+func MarkLines(html template.HTML, hl map[int]bool) template.HTML {
+	return markLines(html, hl, "hl")
+}
+
+// MarkLine setzt class an Zeile n, etwa "lint" für den Syntaxfehler einer Paste.
+func MarkLine(html template.HTML, n int, class string) template.HTML {
+	return markLines(html, map[int]bool{n: true}, class)
+}
+
+// MarkLinesAs setzt class an mehreren Zeilen, etwa "file" für die Kopfzeilen einer Multi-File-Paste.
+func MarkLinesAs(html template.HTML, lines map[int]bool, class string) template.HTML {
+	return markLines(html, lines, class)
+}
+
+func markLines(html template.HTML, hl map[int]bool, class string) template.HTML {
+	if len(hl) == 0 {
+		return html
+	}
+	lines := make([]int, 0, len(hl))
+	for n, on := range hl {
+		if on {
+			lines = append(lines, n)
+		}
+	}
+	slices.Sort(lines)
+	src := string(html)
+	var b strings.Builder
+	b.Grow(len(src) + (len(class)+1)*len(lines))
+	pos := 0
+	for _, n := range lines {
+		marker := `<div id="L` + strconv.Itoa(n) + `" class="line`
+		i := strings.Index(src[pos:], marker)
+		if i < 0 {
+			continue
+		}
+		end := pos + i + len(marker)
+		b.WriteString(src[pos:end])
+		b.WriteString(" " + class)
+		pos = end
+	}
+	b.WriteString(src[pos:])
+	return template.HTML(b.String())
+}
@@ -0,0 +1,50 @@
+package render
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+/*
+Limits begrenzen, was Highlight tokenisiert; 0 heißt jeweils unbegrenzt.
+Chroma bricht einzelne Regex-Matches selbst nach 250 ms ab, aber viele
+langsame Matches hintereinander summieren sich – dafür ist Timeout da.
+*/
+type Limits struct {
+	MaxBytes int
+	MaxLines int
+	Timeout  time.Duration
+}
+
+func (l Limits) tooBig(code string) bool {
+	if l.MaxBytes > 0 && len(code) > l.MaxBytes {
+		return true
+	}
+	if l.MaxLines <= 0 {
+		return false
+	}
+	lines := strings.Count(code, "\n")
+	if !strings.HasSuffix(code, "\n") {
+		lines++
+	}
+	return lines > l.MaxLines
+}
+
+// collect liest alle Tokens; false, wenn das Zeitbudget vorher aufgebraucht ist.
+func (l Limits) collect(it chroma.Iterator) ([]chroma.Token, bool) {
+	var deadline time.Time
+	if l.Timeout > 0 {
+		deadline = time.Now().Add(l.Timeout)
+	}
+	var tokens []chroma.Token
+	for t := it(); t != chroma.EOF; t = it() {
+		tokens = append(tokens, t)
+		// time.Now nicht bei jedem Token: das kostet mehr als das meiste Lexen
+		if !deadline.IsZero() && len(tokens)%64 == 0 && time.Now().After(deadline) {
+			return nil, false
+		}
+	}
+	return tokens, true
+}
@@ -2,8 +2,8 @@ package render
 
 import (
 	"bytes"
-	"fmt"
 	"html/template"
+	"strconv"
 	"strings"
 
 	"github.com/alecthomas/chroma/v2"
@@ -12,16 +12,10 @@ import (
 	"github.com/alecthomas/chroma/v2/styles"
 )
 
-func CodeHTML(code, lang, theme string, hl map[int]bool) (template.HTML, error) {
-	lexer := lexers.Get(lang)
-	if lexer == nil {
-		lexer = lexers.Analyse(code)
-	}
-	if lexer == nil {
-		lexer = lexers.Fallback
-	}
-	lexer = chroma.Coalesce(lexer)
+// CSS-Klassen statt Inline-Styles, damit die CSP ohne 'unsafe-inline' auskommt.
+const classPrefix = "ch-"
 
+func styleFor(theme string) *chroma.Style {
 	styleName := "dracula"
 	if theme == "light" {
 		styleName = "github"
@@ -30,52 +24,134 @@ func CodeHTML(code, lang, theme string, hl map[int]bool) (template.HTML, error)
 	if style == nil {
 		style = styles.Fallback
 	}
+	return style
+}
 
-	formatter := chromahtml.New(
+func newFormatter() *chromahtml.Formatter {
+	return chromahtml.New(
 		chromahtml.WithLineNumbers(false),
-		chromahtml.WithClasses(false),
+		chromahtml.WithClasses(true),
+		chromahtml.ClassPrefix(classPrefix),
 		chromahtml.TabWidth(2),
 	)
+}
+
+// ThemeCSS liefert das Stylesheet für die Highlight-Klassen eines Themes ("dark"/"light").
+func ThemeCSS(theme string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := newFormatter().WriteCSS(&buf, styleFor(theme)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// lexerFor: Lexer zu lang, sonst geraten, sonst Fallback.
+func lexerFor(code, lang string) chroma.Lexer {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	return chroma.Coalesce(lexer)
+}
+
+func CodeHTML(code, lang, theme string, hl map[int]bool) (template.HTML, error) {
+	html, _, err := Highlight(code, lang, theme, hl, Limits{})
+	return html, err
+}
+
+/*
+Highlight rendert wie CodeHTML, hält sich aber an lim: zu große Pastes und
+solche, deren Tokenisierung das Zeitbudget sprengt, kommen als reiner Text
+(plain = true), statt einen Kern sekundenlang zu blockieren.
+*/
+func Highlight(code, lang, theme string, hl map[int]bool, lim Limits) (html template.HTML, plain bool, err error) {
+	if lim.tooBig(code) {
+		return plainHTML(code, hl), true, nil
+	}
+	lexer := lexerFor(code, lang)
+	style := styleFor(theme)
+	formatter := newFormatter()
 	it, err := lexer.Tokenise(nil, code)
 	if err != nil {
-		return "", err
+		return "", false, err
+	}
+	tokens, ok := lim.collect(it)
+	if !ok {
+		return plainHTML(code, hl), true, nil
 	}
 	var buf bytes.Buffer
-	if err := formatter.Format(&buf, style, it); err != nil {
-		return "", err
+	buf.Grow(4 * len(code))
+	if err := formatter.Format(&buf, style, chroma.Literator(tokens...)); err != nil {
+		return "", false, err
 	}
-	full := buf.String()
-	start := strings.Index(full, "<code")
+	return linesHTML(codeInner(buf.Bytes()), "", hl), false, nil
+}
+
+// codeInner: der Inhalt von <code>…</code> aus der Formatter-Ausgabe, ohne Kopie.
+func codeInner(full []byte) []byte {
+	start := bytes.Index(full, []byte("<code"))
 	if start == -1 {
 		start = 0
-	} else if gt := strings.Index(full[start:], ">"); gt != -1 {
+	} else if gt := bytes.IndexByte(full[start:], '>'); gt != -1 {
 		start = start + gt + 1
 	}
-	end := strings.LastIndex(full, "</code>")
-	if end == -1 {
+	end := bytes.LastIndex(full, []byte("</code>"))
+	if end < start {
 		end = len(full)
 	}
-	inner := full[start:end]
+	return full[start:end]
+}
 
-	lines := strings.Split(inner, "\n")
-	var out bytes.Buffer
-	out.WriteString(`<div class="codeframe"><div class="codeblock">`)
-	for i, ln := range lines {
-		if i == len(lines)-1 && ln == "" {
-			break
+// plainHTML: derselbe Rahmen wie beim Highlighting, nur escapter Text.
+func plainHTML(code string, hl map[int]bool) template.HTML {
+	var buf bytes.Buffer
+	buf.Grow(len(code) + len(code)/8)
+	template.HTMLEscape(&buf, []byte(code))
+	return linesHTML(buf.Bytes(), " plain", hl)
+}
+
+// Rahmen je Zeile ohne Inhalt und Nummern, zum Vorab-Reservieren.
+const lineOverhead = len(`<div id="L" class="line hl"><a class="ln" href="#L"></a><span class="code"></span></div>`)
+
+/*
+linesHTML baut aus fertigem (escaptem) HTML die nummerierten Zeilen – in einem
+Durchlauf und einen vorab passend reservierten Builder, ohne Split und ohne
+Zwischenstrings je Zeile; bei großen Pastes war das der teuerste Teil der Seite.
+*/
+func linesHTML(inner []byte, extraClass string, hl map[int]bool) template.HTML {
+	lines := bytes.Count(inner, []byte("\n")) + 1
+	var out strings.Builder
+	out.Grow(len(inner) + lines*(lineOverhead+3*len(strconv.Itoa(lines))) + 128)
+	out.WriteString(`<div class="codeframe"><div class="codeblock ` + classPrefix + `chroma`)
+	out.WriteString(extraClass)
+	out.WriteString(`">`)
+	var num [20]byte
+	for n := 1; len(inner) > 0; n++ {
+		ln := inner
+		if i := bytes.IndexByte(inner, '\n'); i >= 0 {
+			ln, inner = inner[:i], inner[i+1:]
+		} else {
+			inner = nil
 		}
-		n := i + 1
-		id := fmt.Sprintf("L%d", n)
-		cls := "line"
+		id := strconv.AppendInt(num[:0], int64(n), 10)
+		out.WriteString(`<div id="L`)
+		out.Write(id)
 		if hl[n] {
-			cls += " hl"
+			out.WriteString(`" class="line hl">`)
+		} else {
+			out.WriteString(`" class="line">`)
 		}
-		out.WriteString(`<div id="` + id + `" class="` + cls + `">`)
-		out.WriteString(`<a class="ln" href="#` + id + `">` + fmt.Sprint(n) + `</a>`)
-		out.WriteString(`<span class="code">` + ln + `</span>`)
-		out.WriteString(`</div>`)
+		out.WriteString(`<a class="ln" href="#L`)
+		out.Write(id)
+		out.WriteString(`">`)
+		out.Write(id)
+		out.WriteString(`</a><span class="code">`)
+		out.Write(ln)
+		out.WriteString(`</span></div>`)
 	}
 	out.WriteString(`</div></div>`)
-	return template.HTML(out.String()), nil
+	return template.HTML(out.String())
 }
-
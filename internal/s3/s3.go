@@ -0,0 +1,156 @@
+/*
+Package s3 ist ein kleiner S3-Client (Path-Style, signiert mit awsv4) für die wenigen
+Aufrufe, die unglued braucht: Put, Get, Delete und ListObjectsV2. Läuft gegen
+AWS wie gegen kompatible Dienste (MinIO, Ceph, R2, …).
+*/
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"unglued/internal/awsv4"
+)
+
+type Config struct {
+	Endpoint string // z.B. https://s3.eu-central-1.amazonaws.com
+	Region   string // leer = us-east-1
+	Bucket   string
+	awsv4.Credentials
+}
+
+// Client ist nil, wenn kein Bucket gesetzt ist.
+type Client struct {
+	cfg    Config
+	base   *url.URL
+	client *http.Client
+}
+
+var ErrNotFound = errors.New("s3: no such key")
+
+func New(cfg Config) (*Client, error) {
+	if cfg.Bucket == "" {
+		return nil, nil
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("s3: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required")
+	}
+	u, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", cfg.Endpoint)
+	}
+	return &Client{cfg: cfg, base: u, client: &http.Client{Timeout: time.Minute}}, nil
+}
+
+func (c *Client) Bucket() string { return c.cfg.Bucket }
+
+// Put legt body unter key ab.
+func (c *Client) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	res, err := c.do(ctx, http.MethodPut, key, nil, body, map[string]string{"Content-Type": contentType})
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Get liefert den Inhalt von key oder ErrNotFound.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := c.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+func (c *Client) Delete(ctx context.Context, key string) error {
+	res, err := c.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+type Object struct {
+	Key          string    `xml:"Key" json:"key"`
+	Size         int64     `xml:"Size" json:"size"`
+	LastModified time.Time `xml:"LastModified" json:"last_modified"`
+}
+
+// List liefert alle Objekte unter prefix (folgt den Continuation-Tokens).
+func (c *Client) List(ctx context.Context, prefix string) ([]Object, error) {
+	var out []Object
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		res, err := c.do(ctx, http.MethodGet, "", q, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents  []Object `xml:"Contents"`
+			Truncated bool     `xml:"IsTruncated"`
+			Next      string   `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page.Contents...)
+		if !page.Truncated || page.Next == "" {
+			return out, nil
+		}
+		token = page.Next
+	}
+}
+
+// do schickt einen signierten Request; Status ≥ 300 wird zum Fehler (404 → ErrNotFound).
+func (c *Client) do(ctx context.Context, method, key string, q url.Values, body []byte, h map[string]string) (*http.Response, error) {
+	u := *c.base
+	u.Path = u.Path + "/" + c.cfg.Bucket + "/" + key
+	u.RawPath = awsv4.Escape(u.Path, true)
+	u.RawQuery = awsv4.CanonicalQuery(q)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h {
+		req.Header.Set(k, v)
+	}
+	awsv4.Sign(req, body, c.cfg.Credentials, c.cfg.Region, "s3", time.Now())
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound && key != "" {
+		return nil, ErrNotFound
+	}
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&e)
+	return nil, fmt.Errorf("s3: %s %s: %s %s %s", method, u.Path, res.Status, e.Code, e.Message)
+}
@@ -0,0 +1,183 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"unglued/internal/model"
+	"unglued/internal/util"
+)
+
+/*
+Index ist ein kleiner invertierter In-Memory-Index über Inhalt, Titel, Tags,
+Sprache und Autor. Er hält nur IDs; gelöschte/abgelaufene Pastes werden beim
+Suchen vom Aufrufer gemeldet (Remove) statt über Store-Events.
+*/
+type Index struct {
+	mu    sync.RWMutex
+	terms map[string]map[string]int // token -> id -> Häufigkeit
+	docs  map[string][]string       // id -> tokens (für Remove/Reindex)
+}
+
+func New() *Index {
+	return &Index{
+		terms: make(map[string]map[string]int),
+		docs:  make(map[string][]string),
+	}
+}
+
+// Len: Anzahl indexierter Pastes und verschiedener Tokens (für Debug-Statistiken).
+func (ix *Index) Len() (docs, terms int) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs), len(ix.terms)
+}
+
+// maximal indexierte Zeichen je Paste, damit riesige Logs den Index nicht sprengen
+const maxIndexedBytes = 256 << 10
+
+// Add (re)indexiert die aktuelle Version von p.
+func (ix *Index) Add(p model.Paste) {
+	code := p.Code
+	if len(code) > maxIndexedBytes {
+		code = code[:maxIndexedBytes]
+	}
+	freq := map[string]int{}
+	for _, t := range Tokenize(code) {
+		freq[t]++
+	}
+	// Metadaten zählen stärker als Treffer im Inhalt
+	for _, t := range Tokenize(p.Title) {
+		freq[t] += 5
+	}
+	for _, tag := range p.Tags {
+		freq["tag:"+strings.ToLower(tag)] += 5
+		for _, t := range Tokenize(tag) {
+			freq[t] += 3
+		}
+	}
+	freq["lang:"+p.Lang] += 3
+	freq[strings.ToLower(p.Lang)] += 3
+	for _, t := range Tokenize(p.Author) {
+		freq[t]++
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.removeLocked(p.ID)
+	toks := make([]string, 0, len(freq))
+	for t, n := range freq {
+		m := ix.terms[t]
+		if m == nil {
+			m = make(map[string]int)
+			ix.terms[t] = m
+		}
+		m[p.ID] = n
+		toks = append(toks, t)
+	}
+	ix.docs[p.ID] = toks
+}
+
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	ix.removeLocked(id)
+	ix.mu.Unlock()
+}
+
+func (ix *Index) removeLocked(id string) {
+	for _, t := range ix.docs[id] {
+		if m := ix.terms[t]; m != nil {
+			delete(m, id)
+			if len(m) == 0 {
+				delete(ix.terms, t)
+			}
+		}
+	}
+	delete(ix.docs, id)
+}
+
+/*
+Search liefert IDs sortiert nach Relevanz. Alle Begriffe müssen treffen (AND);
+ein Begriff trifft auch als Präfix ("conf" → "config"). Filter: lang:go, tag:k8s.
+*/
+func (ix *Index) Search(q string) []string {
+	var terms []string
+	for _, f := range strings.Fields(strings.ToLower(q)) {
+		if l, ok := strings.CutPrefix(f, "lang:"); ok {
+			terms = append(terms, "lang:"+util.CanonicalLang(l))
+			continue
+		}
+		if strings.HasPrefix(f, "tag:") {
+			terms = append(terms, f)
+			continue
+		}
+		terms = append(terms, Tokenize(f)...)
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var score map[string]int
+	for _, t := range terms {
+		hits := map[string]int{}
+		if strings.Contains(t, ":") {
+			for id, n := range ix.terms[t] {
+				hits[id] += n
+			}
+		} else {
+			for tok, m := range ix.terms {
+				if !strings.HasPrefix(tok, t) {
+					continue
+				}
+				bonus := 1
+				if tok == t {
+					bonus = 2
+				}
+				for id, n := range m {
+					hits[id] += n * bonus
+				}
+			}
+		}
+		if score == nil {
+			score = hits
+			continue
+		}
+		for id := range score {
+			if n, ok := hits[id]; ok {
+				score[id] += n
+			} else {
+				delete(score, id)
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(score))
+	for id := range score {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if score[ids[i]] != score[ids[j]] {
+			return score[ids[i]] > score[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// Tokenize zerlegt Text in kleingeschriebene Wörter (Buchstaben, Ziffern, _), min. 2 Zeichen.
+func Tokenize(s string) []string {
+	f := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	out := f[:0]
+	for _, t := range f {
+		if len(t) >= 2 && len(t) <= 64 {
+			out = append(out, t)
+		}
+	}
+	return out
+}
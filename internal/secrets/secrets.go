@@ -128,3 +128,75 @@ func Brief(fs []Finding, max int) string {
 	return b.String()
 }
 
+
+// Platzhalter für geschwärzte Werte.
+const RedactedMark = "•••REDACTED•••"
+
+var pemBlock = regexp.MustCompile(`(?s)(-----BEGIN [A-Z ]*PRIVATE KEY-----).*?(-----END [A-Z ]*PRIVATE KEY-----)`)
+
+/*
+Redact ersetzt gefundene Secret-Werte durch RedactedMark und liefert die Namen
+der Regeln, die gegriffen haben (dedupliziert, in Fundreihenfolge).
+Bei key=value / key: value bleibt der Schlüssel stehen, bei URLs der Benutzername.
+*/
+func Redact(text string) (string, []string) {
+	var fired []string
+	seen := map[string]bool{}
+	hit := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			fired = append(fired, name)
+		}
+	}
+
+	// PEM-Blöcke komplett schwärzen, nicht nur die BEGIN-Zeile
+	text = pemBlock.ReplaceAllStringFunc(text, func(m string) string {
+		hit("PEM private key")
+		sub := pemBlock.FindStringSubmatch(m)
+		return sub[1] + "\n" + RedactedMark + "\n" + sub[2]
+	})
+
+	lines := strings.Split(text, "\n")
+	for li, line := range lines {
+		for _, rl := range rules {
+			if !rl.re.MatchString(line) || (rl.name == "PEM private key" && seen[rl.name]) {
+				continue
+			}
+			hit(rl.name)
+			line = rl.re.ReplaceAllStringFunc(line, redactMatch)
+		}
+		lines[li] = line
+	}
+
+	// Entropie-Heuristik wie in Scan nur, wenn keine Regel gegriffen hat
+	if len(fired) == 0 {
+		for li, line := range lines {
+			for _, g := range entCandidate.FindAllStringSubmatch(line, -1) {
+				if entropy(g[2]) >= 3.5 {
+					hit("High-entropy secret-like value")
+					line = strings.Replace(line, g[2], RedactedMark, 1)
+				}
+			}
+			lines[li] = line
+		}
+	}
+	return strings.Join(lines, "\n"), fired
+}
+
+func redactMatch(m string) string {
+	// scheme://user:pass@ → Passwort schwärzen
+	if at := strings.LastIndex(m, "@"); at > 0 && strings.Contains(m, "://") {
+		if c := strings.LastIndex(m[:at], ":"); c > strings.Index(m, "://")+2 {
+			return m[:c+1] + RedactedMark + m[at:]
+		}
+	}
+	// KEY=value / key: value → Schlüssel behalten
+	if i := strings.IndexAny(m, "=:"); i > 0 && i < len(m)-1 {
+		j := i + 1
+		for j < len(m) && (m[j] == ' ' || m[j] == '\t') {
+			j++
+		}
+		return m[:j] + RedactedMark
+	}
+	return RedactedMark
+}
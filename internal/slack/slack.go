@@ -0,0 +1,151 @@
+/*
+Package slack prüft signierte Requests von Slack (Slash-Commands, Events API) und
+ruft chat.unfurl auf, um Links mit einer Vorschau zu versehen.
+*/
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Requests, deren Zeitstempel weiter abweicht, gelten als Replay.
+const maxSkew = 5 * time.Minute
+
+type Config struct {
+	SigningSecret string // aus den App-Einstellungen ("Signing Secret")
+	BotToken      string // xoxb-…, nur fürs Unfurling (Scope links:write); leer = keine Vorschau
+}
+
+// Client ist nil, wenn kein Signing Secret gesetzt ist.
+type Client struct {
+	cfg    Config
+	client *http.Client
+	api    string
+}
+
+func New(cfg Config) *Client {
+	if cfg.SigningSecret == "" {
+		return nil
+	}
+	return &Client{cfg: cfg, client: &http.Client{Timeout: 5 * time.Second}, api: "https://slack.com/api"}
+}
+
+var (
+	ErrStale     = errors.New("slack: request timestamp too old")
+	ErrSignature = errors.New("slack: invalid signature")
+)
+
+// Verify prüft X-Slack-Signature (v0) über Zeitstempel und Body.
+func (c *Client) Verify(h http.Header, body []byte, now time.Time) error {
+	ts := h.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrSignature
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > maxSkew || d < -maxSkew {
+		return ErrStale
+	}
+	m := hmac.New(sha256.New, []byte(c.cfg.SigningSecret))
+	fmt.Fprintf(m, "v0:%s:", ts)
+	m.Write(body)
+	want := "v0=" + hex.EncodeToString(m.Sum(nil))
+	if !hmac.Equal([]byte(h.Get("X-Slack-Signature")), []byte(want)) {
+		return ErrSignature
+	}
+	return nil
+}
+
+// Envelope: äußere Hülle der Events API (url_verification oder event_callback).
+type Envelope struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge,omitempty"`
+	Event     Event  `json:"event"`
+}
+
+// Event: nur die Felder von link_shared, die fürs Unfurling gebraucht werden.
+type Event struct {
+	Type      string `json:"type"`
+	Channel   string `json:"channel"`
+	MessageTS string `json:"message_ts"`
+	UnfurlID  string `json:"unfurl_id,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Links     []struct {
+		Domain string `json:"domain"`
+		URL    string `json:"url"`
+	} `json:"links"`
+}
+
+// Attachment: Vorschau eines Links (Slack-"secondary attachment").
+type Attachment struct {
+	Title     string   `json:"title"`
+	TitleLink string   `json:"title_link"`
+	Text      string   `json:"text"`
+	Footer    string   `json:"footer,omitempty"`
+	MrkdwnIn  []string `json:"mrkdwn_in,omitempty"`
+}
+
+func (c *Client) CanUnfurl() bool { return c != nil && c.cfg.BotToken != "" }
+
+// Unfurl hängt die Vorschauen an die Nachricht aus ev (chat.unfurl).
+func (c *Client) Unfurl(ctx context.Context, ev Event, unfurls map[string]Attachment) error {
+	req := map[string]any{"unfurls": unfurls}
+	if ev.UnfurlID != "" {
+		req["unfurl_id"], req["source"] = ev.UnfurlID, ev.Source
+	} else {
+		req["channel"], req["ts"] = ev.Channel, ev.MessageTS
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	hr, err := http.NewRequestWithContext(ctx, http.MethodPost, c.api+"/chat.unfurl", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	hr.Header.Set("Content-Type", "application/json; charset=utf-8")
+	hr.Header.Set("Authorization", "Bearer "+c.cfg.BotToken)
+	res, err := c.client.Do(hr)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	var out struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return fmt.Errorf("chat.unfurl: status %d", res.StatusCode)
+	}
+	if !out.OK {
+		return fmt.Errorf("chat.unfurl: %s", out.Error)
+	}
+	return nil
+}
+
+// Escape maskiert &, < und >, wie Slack es in Nachrichtentext verlangt.
+func Escape(s string) string {
+	var b bytes.Buffer
+	for _, r := range s {
+		switch r {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,314 @@
+/*
+Package smtpd ist ein minimaler SMTP-Empfänger (RFC 5321 ohne STARTTLS und AUTH)
+für das Mail-Gateway: Er nimmt Mails für eine Domain an und reicht sie an einen
+Handler weiter. Gedacht für interne Netze oder hinter einem richtigen MTA.
+*/
+package smtpd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/netip"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Addr     string // z.B. :2525
+	Domain   string // nur Empfänger @Domain; leer = alle
+	Allow    string // kommagetrennt: IPs/CIDRs, Absender oder @domain; Pflicht
+	MaxBytes int64  // Obergrenze pro Mail; 0 = 10 MiB
+}
+
+// maxLine: längste Befehlszeile; RFC 5321 verlangt 512, etwas Luft für Erweiterungen.
+const maxLine = 1000
+
+// Envelope: wer die Mail von wo eingeliefert hat.
+type Envelope struct {
+	IP   netip.Addr
+	From string
+	To   []string
+
+	senders []string
+}
+
+/*
+SenderAllowed: addr steht als Absender (Adresse oder @domain) in der Allowlist.
+Nur an solche Adressen dürfen Antworten gehen, sonst wird das Gateway zur
+Backscatter-Schleuder für gefälschte Absender.
+*/
+func (e Envelope) SenderAllowed(addr string) bool { return matchSender(e.senders, addr) }
+
+// Handler verarbeitet eine angenommene Mail; ein Fehler wird dem Client als 554 gemeldet.
+type Handler func(env Envelope, data []byte) error
+
+type Server struct {
+	cfg     Config
+	handler Handler
+	nets    []netip.Prefix
+	senders []string
+	ln      net.Listener
+}
+
+// New liefert nil, wenn keine Adresse gesetzt ist; ohne Allowlist startet das Gateway nicht.
+func New(cfg Config, h Handler) (*Server, error) {
+	if cfg.Addr == "" {
+		return nil, nil
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 10 << 20
+	}
+	s := &Server{cfg: cfg, handler: h}
+	for _, a := range strings.Split(cfg.Allow, ",") {
+		a = strings.ToLower(strings.TrimSpace(a))
+		switch {
+		case a == "":
+		case strings.Contains(a, "@"):
+			s.senders = append(s.senders, a)
+		default:
+			p, err := netip.ParsePrefix(a)
+			if err != nil {
+				ip, ipErr := netip.ParseAddr(a)
+				if ipErr != nil {
+					return nil, fmt.Errorf("allow %q: not an address, network or sender", a)
+				}
+				p = netip.PrefixFrom(ip, ip.BitLen())
+			}
+			s.nets = append(s.nets, p.Masked())
+		}
+	}
+	if len(s.nets) == 0 && len(s.senders) == 0 {
+		return nil, errors.New("required: the gateway would accept mail from anyone")
+	}
+	return s, nil
+}
+
+// ListenAndServe nimmt Verbindungen an, bis Close aufgerufen wird.
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	s.ln = ln
+	for {
+		c, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go s.serve(c)
+	}
+}
+
+func (s *Server) Close() error {
+	if s == nil || s.ln == nil {
+		return nil
+	}
+	return s.ln.Close()
+}
+
+func (s *Server) trustedIP(ip netip.Addr) bool {
+	for _, p := range s.nets {
+		if p.Contains(ip.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSender(senders []string, from string) bool {
+	from = strings.ToLower(strings.TrimSpace(from))
+	if from == "" {
+		return false
+	}
+	for _, a := range senders {
+		if from == a || (strings.HasPrefix(a, "@") && strings.HasSuffix(from, a)) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+headerAllowed: kommt die Mail nicht aus einem erlaubten Netz, muss neben dem
+Umschlag-Absender auch der From-Header in der Allowlist stehen. Beides lässt sich
+fälschen; Absender-Einträge taugen nur hinter einem MTA, der SPF/DKIM prüft.
+*/
+func (s *Server) headerAllowed(ip netip.Addr, data []byte) bool {
+	if s.trustedIP(ip) {
+		return true
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	a, err := mail.ParseAddress(msg.Header.Get("From"))
+	return err == nil && matchSender(s.senders, a.Address)
+}
+
+func (s *Server) serve(c net.Conn) {
+	defer c.Close()
+	tp := textproto.NewConn(c)
+	var ip netip.Addr
+	if ap, err := netip.ParseAddrPort(c.RemoteAddr().String()); err == nil {
+		ip = ap.Addr()
+	}
+	reply := func(code int, msg string) { _ = tp.PrintfLine("%d %s", code, msg) }
+	host := s.cfg.Domain
+	if host == "" {
+		host = "unglued"
+	}
+	reply(220, host+" ESMTP unglued")
+	var from string
+	var to []string
+	for {
+		_ = c.SetDeadline(time.Now().Add(5 * time.Minute))
+		line, err := readLine(tp.R)
+		if errors.Is(err, errLineTooLong) {
+			reply(500, "line too long")
+			return
+		}
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			reply(250, host)
+		case "EHLO":
+			_ = tp.PrintfLine("250-%s", host)
+			_ = tp.PrintfLine("250-SIZE %d", s.cfg.MaxBytes)
+			_ = tp.PrintfLine("250-8BITMIME")
+			reply(250, "SMTPUTF8")
+		case "MAIL":
+			addr, ok := path(arg, "FROM:")
+			if !ok {
+				reply(501, "syntax: MAIL FROM:<address>")
+				continue
+			}
+			if !s.trustedIP(ip) && !matchSender(s.senders, addr) {
+				reply(550, "sender not allowed")
+				continue
+			}
+			from, to = addr, nil
+			reply(250, "OK")
+		case "RCPT":
+			addr, ok := path(arg, "TO:")
+			switch {
+			case from == "":
+				reply(503, "MAIL first")
+			case !ok || addr == "":
+				reply(501, "syntax: RCPT TO:<address>")
+			case s.cfg.Domain != "" && !strings.HasSuffix(strings.ToLower(addr), "@"+strings.ToLower(s.cfg.Domain)):
+				reply(550, "relay not permitted")
+			default:
+				to = append(to, addr)
+				reply(250, "OK")
+			}
+		case "DATA":
+			if len(to) == 0 {
+				reply(503, "RCPT first")
+				continue
+			}
+			reply(354, "end data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(io.LimitReader(tp.DotReader(), s.cfg.MaxBytes+1))
+			if err != nil {
+				return
+			}
+			if int64(len(data)) > s.cfg.MaxBytes {
+				// Rest verwerfen, damit die Verbindung weiter benutzbar bleibt
+				_, _ = io.Copy(io.Discard, tp.DotReader())
+				reply(552, "message too large")
+			} else if !s.headerAllowed(ip, data) {
+				reply(550, "sender not allowed")
+			} else if err := s.handler(Envelope{IP: ip, From: from, To: to, senders: s.senders}, data); err != nil {
+				log.Printf("smtp: %s: %v", from, err)
+				reply(554, strings.ReplaceAll(err.Error(), "\n", " "))
+			} else {
+				reply(250, "OK: queued as paste")
+			}
+			from, to = "", nil
+		case "RSET":
+			from, to = "", nil
+			reply(250, "OK")
+		case "NOOP":
+			reply(250, "OK")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			reply(502, "command not implemented")
+		}
+	}
+}
+
+var errLineTooLong = errors.New("line too long")
+
+// readLine liest eine Befehlszeile ohne CRLF; länger als maxLine ist ein Fehler.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		b, err := r.ReadSlice('\n')
+		if len(line)+len(b) > maxLine+2 {
+			return "", errLineTooLong
+		}
+		line = append(line, b...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(line), "\r\n"), nil
+	}
+}
+
+// path zieht die Adresse aus "FROM:<a@b> SIZE=123"; "<>" ist ein gültiger leerer Absender.
+func path(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", false
+	}
+	addr, _, ok := strings.Cut(rest[1:], ">")
+	return addr, ok
+}
+
+// Relay verschickt Antwort-Mails über einen vorhandenen MTA.
+type Relay struct {
+	Addr     string // host:port; leer = keine Antworten
+	From     string
+	User     string // optional, PLAIN-Auth
+	Password string
+}
+
+func (r Relay) Enabled() bool { return r.Addr != "" && r.From != "" }
+
+// Send schickt eine einfache Text-Mail an to.
+func (r Relay) Send(to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return errors.New("invalid header value")
+	}
+	var a smtp.Auth
+	if r.User != "" {
+		host, _, _ := net.SplitHostPort(r.Addr)
+		a = smtp.PlainAuth("", r.User, r.Password, host)
+	}
+	msg := "From: " + r.From + "\r\nTo: " + to + "\r\nSubject: " + mime.QEncoding.Encode("utf-8", subject) +
+		"\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nAuto-Submitted: auto-replied\r\n\r\n" +
+		strings.ReplaceAll(body, "\n", "\r\n")
+	return smtp.SendMail(r.Addr, a, r.From, []string{to}, []byte(msg))
+}
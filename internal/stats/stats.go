@@ -0,0 +1,106 @@
+/*
+Package stats zählt Ereignisse (Anlegen, Aufrufe, Edits, Abläufe, Löschungen) in
+einem Ringpuffer fester Buckets. Alles liegt im Speicher und beginnt nach einem
+Neustart von vorn – wie der Store selbst.
+*/
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// Ereignisse, die der Server meldet.
+const (
+	Created = "created"
+	Viewed  = "viewed"
+	Edited  = "edited"
+	Expired = "expired"
+	Deleted = "deleted"
+)
+
+var Events = []string{Created, Viewed, Edited, Expired, Deleted}
+
+type bucket struct {
+	start  time.Time
+	counts map[string]int
+}
+
+type Recorder struct {
+	mu      sync.Mutex
+	step    time.Duration
+	ring    []bucket
+	totals  map[string]uint64
+	started time.Time
+}
+
+// New hält n Buckets der Länge step, z.B. 168 × 1h = eine Woche.
+func New(step time.Duration, n int) *Recorder {
+	return &Recorder{step: step, ring: make([]bucket, n), totals: map[string]uint64{}, started: time.Now()}
+}
+
+// Add zählt n Ereignisse der Art event jetzt. Nil-sicher.
+func (r *Recorder) Add(event string, n int) {
+	if r == nil || n <= 0 {
+		return
+	}
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.bucketLocked(now)
+	b.counts[event] += n
+	r.totals[event] += uint64(n)
+}
+
+func (r *Recorder) bucketLocked(t time.Time) *bucket {
+	start := t.Truncate(r.step)
+	i := int(start.UnixNano()/int64(r.step)) % len(r.ring)
+	b := &r.ring[i]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start, counts: map[string]int{}}
+	}
+	return b
+}
+
+// Point: ein Bucket der Zeitreihe; fehlende Ereignisse zählen 0.
+type Point struct {
+	Start  time.Time      `json:"start"`
+	Counts map[string]int `json:"counts"`
+}
+
+// Series liefert die letzten n Buckets bis einschließlich des aktuellen, älteste zuerst.
+func (r *Recorder) Series(n int) []Point {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n = min(max(n, 1), len(r.ring))
+	now := time.Now().Truncate(r.step)
+	out := make([]Point, n)
+	for k := range n {
+		start := now.Add(-time.Duration(n-1-k) * r.step)
+		p := Point{Start: start, Counts: make(map[string]int, len(Events))}
+		for _, e := range Events {
+			p.Counts[e] = 0
+		}
+		i := int(start.UnixNano()/int64(r.step)) % len(r.ring)
+		if b := r.ring[i]; b.start.Equal(start) {
+			for e, c := range b.counts {
+				p.Counts[e] = c
+			}
+		}
+		out[k] = p
+	}
+	return out
+}
+
+// Totals: Summen seit dem Start des Prozesses.
+func (r *Recorder) Totals() (since time.Time, totals map[string]uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	totals = make(map[string]uint64, len(Events))
+	for _, e := range Events {
+		totals[e] = r.totals[e]
+	}
+	return r.started, totals
+}
+
+func (r *Recorder) Step() time.Duration { return r.step }
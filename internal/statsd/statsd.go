@@ -0,0 +1,162 @@
+/*
+Package statsd schickt Metriken per UDP an einen StatsD-Server oder an den
+Datadog-Agent (DogStatsD). Zeilen werden gesammelt und spätestens jede Sekunde
+als ein Paket verschickt; verlorene Pakete nimmt man bei UDP in Kauf.
+*/
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"unglued/internal/metrics"
+)
+
+// maxPacket: passt ohne Fragmentierung in ein Ethernet-Paket.
+const maxPacket = 1432
+
+type Config struct {
+	Addr   string // host:port, z.B. 127.0.0.1:8125
+	Prefix string // vor jeden Namen, z.B. "paste."
+	// DogStatsD: Labels als Tags (|#lang:go); sonst werden sie an den Namen gehängt
+	Dog bool
+	// feste Tags für jede Metrik (nur DogStatsD), z.B. env:prod
+	Tags []string
+}
+
+// Client erfüllt metrics.Sink; nil, wenn Addr leer ist.
+type Client struct {
+	cfg  Config
+	conn net.Conn
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func New(cfg Config) (*Client, error) {
+	if cfg.Addr == "" {
+		return nil, nil
+	}
+	if len(cfg.Tags) > 0 && !cfg.Dog {
+		return nil, fmt.Errorf("statsd: tags need the DogStatsD format")
+	}
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsd: %w", err)
+	}
+	return &Client{cfg: cfg, conn: conn}, nil
+}
+
+func (c *Client) Count(name string, tags []metrics.Tag, n float64) {
+	c.send(name, tags, n, "c")
+}
+
+// Observe: DogStatsD kennt Histogramme (h), klassisches StatsD nur Timer (ms).
+func (c *Client) Observe(name string, tags []metrics.Tag, v float64) {
+	typ := "ms"
+	if c.cfg.Dog {
+		typ = "h"
+	}
+	c.send(name, tags, v, typ)
+}
+
+func (c *Client) Gauge(name string, tags []metrics.Tag, v float64) {
+	c.send(name, tags, v, "g")
+}
+
+// Run verschickt jede Sekunde, was sich angesammelt hat, bis ctx endet.
+func (c *Client) Run(ctx context.Context) {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			c.mu.Lock()
+			c.flush()
+			c.mu.Unlock()
+			return
+		case <-t.C:
+			c.mu.Lock()
+			c.flush()
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (c *Client) send(name string, tags []metrics.Tag, v float64, typ string) {
+	line := c.line(name, tags, v, typ)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.buf.Len() > 0 && c.buf.Len()+1+len(line) > maxPacket {
+		c.flush()
+	}
+	if c.buf.Len() > 0 {
+		c.buf.WriteByte('\n')
+	}
+	c.buf.WriteString(line)
+}
+
+// flush: c.mu muss gehalten werden.
+func (c *Client) flush() {
+	if c.buf.Len() == 0 {
+		return
+	}
+	_, _ = c.conn.Write(c.buf.Bytes())
+	c.buf.Reset()
+}
+
+/*
+line baut eine Zeile. Aus den Prometheus-Namen fällt das _total der Counter weg;
+ohne DogStatsD werden die Label-Werte Teil des Namens
+(unglued_pastes_created.go.public).
+*/
+func (c *Client) line(name string, tags []metrics.Tag, v float64, typ string) string {
+	var b strings.Builder
+	b.WriteString(c.cfg.Prefix)
+	b.WriteString(strings.TrimSuffix(name, "_total"))
+	if !c.cfg.Dog {
+		for _, t := range tags {
+			b.WriteByte('.')
+			b.WriteString(clean(t.Value, true))
+		}
+	}
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	b.WriteByte('|')
+	b.WriteString(typ)
+	if c.cfg.Dog && len(tags)+len(c.cfg.Tags) > 0 {
+		b.WriteString("|#")
+		for i, t := range c.cfg.Tags {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(t)
+		}
+		for i, t := range tags {
+			if i > 0 || len(c.cfg.Tags) > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(t.Name + ":" + clean(t.Value, false))
+		}
+	}
+	return b.String()
+}
+
+// clean ersetzt, was das Zeilenformat stört, durch _; im Namen auch Punkte.
+func clean(s string, inName bool) string {
+	if s == "" {
+		return "none"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || strings.ContainsRune(":|,#@", r) || inName && r == '.' {
+			return '_'
+		}
+		return r
+	}, s)
+}
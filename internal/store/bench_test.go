@@ -0,0 +1,100 @@
+package store_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"unglued/internal/model"
+	"unglued/internal/store/storetest"
+	"unglued/internal/util"
+)
+
+// so viele Pastes liegen für View und Edit bereit; Versionen je Paste höchstens
+const (
+	benchPastes   = 100
+	benchVersions = 10
+)
+
+func benchPaste(i int) model.Paste {
+	now := time.Now()
+	code := storetest.Code(i)
+	return model.Paste{
+		ID: fmt.Sprintf("bench%06d", i), Title: "bench", Lang: "go", Code: code,
+		Editable: true, EditKey: "k", CreatedAt: now, UpdatedAt: now, ExpiresAt: now.Add(time.Hour),
+		Versions: []model.Version{{ZCode: util.GzipEncode(code), Lang: "go", At: now}},
+	}
+}
+
+// seed legt die Pastes an und wartet, bis das Backend sie geschrieben hat.
+func seed(st storetest.Env) []string {
+	ids := make([]string, benchPastes)
+	for i := range ids {
+		p := benchPaste(i)
+		st.Put(p)
+		ids[i] = p.ID
+	}
+	st.Flush()
+	return ids
+}
+
+func BenchmarkCreate(b *testing.B) {
+	for _, be := range storetest.Backends() {
+		b.Run(be.Name, func(b *testing.B) {
+			st := be.Open(b)
+			pastes := make([]model.Paste, benchPastes)
+			for i := range pastes {
+				pastes[i] = benchPaste(i)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p := pastes[i%len(pastes)]
+				p.ID = fmt.Sprintf("new%09d", i)
+				st.Put(p)
+			}
+			st.Flush()
+		})
+	}
+}
+
+func BenchmarkView(b *testing.B) {
+	for _, be := range storetest.Backends() {
+		b.Run(be.Name, func(b *testing.B) {
+			st := be.Open(b)
+			ids := seed(st)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, ok := st.Get(ids[i%len(ids)]); !ok {
+					b.Fatal("paste missing")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEdit(b *testing.B) {
+	for _, be := range storetest.Backends() {
+		b.Run(be.Name, func(b *testing.B) {
+			st := be.Open(b)
+			ids := seed(st)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p, ok := st.Get(ids[i%len(ids)])
+				if !ok {
+					b.Fatal("paste missing")
+				}
+				p.Code = storetest.Code(i)
+				p.UpdatedAt = time.Now()
+				p.Versions = append(p.Versions, model.Version{ZCode: util.GzipEncode(p.Code), Lang: "go", At: p.UpdatedAt})
+				if len(p.Versions) > benchVersions {
+					p.Versions = p.Versions[len(p.Versions)-benchVersions:]
+				}
+				st.Put(p)
+			}
+			st.Flush()
+		})
+	}
+}
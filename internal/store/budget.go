@@ -0,0 +1,122 @@
+package store
+
+import (
+	"log"
+	"math"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+// Unter diesen Anteil des Budgets räumt evict, damit nicht jeder Put erneut sortiert.
+const (
+	lowWatermark  = 0.9
+	warnWatermark = 0.8
+)
+
+/*
+SetBudget begrenzt die Bytes, die der Store für Inhalte hält (Code + Versionen, wie
+in Stats.Bytes); 0 = unbegrenzt. Wird es überschritten, fliegen die Pastes, die am
+ehesten ablaufen, bis der Store wieder unter 90 % liegt.
+*/
+func (s *Store) SetBudget(n int64) {
+	s.budget.Store(max(n, 0))
+	s.warned.Store(false)
+	s.evict("")
+}
+
+func (s *Store) Budget() int64 { return s.budget.Load() }
+
+/*
+BudgetFromMemLimit leitet ein Budget aus GOMEMLIMIT ab: share davon für Inhalte, der
+Rest bleibt für Suchindex, Rendering und Laufzeit. 0, wenn kein Limit gesetzt ist.
+*/
+func BudgetFromMemLimit(share float64) int64 {
+	limit := debug.SetMemoryLimit(-1)
+	if limit <= 0 || limit == math.MaxInt64 {
+		return 0
+	}
+	return int64(float64(limit) * share)
+}
+
+func recordSize(rec *record) int64 {
+	n := int64(len(rec.Code) + len(rec.sealed))
+	for _, v := range rec.Versions {
+		n += int64(len(v.ZCode))
+	}
+	return n
+}
+
+/*
+evict hält den Store im Budget. keep (die gerade gespeicherte Paste) bleibt
+verschont, damit ein angenommener Upload nicht sofort wieder verschwindet.
+Verdrängte Pastes gehen denselben Weg wie abgelaufene: replaceLocked (Render-Cache,
+Backend), danach Archiver und OnExpirePaste.
+*/
+func (s *Store) evict(keep string) {
+	budget := s.budget.Load()
+	if budget <= 0 {
+		return
+	}
+	s.mu.Lock()
+	used := s.bytes
+	if float64(used) < float64(budget)*warnWatermark {
+		s.mu.Unlock()
+		s.warned.Store(false)
+		return
+	}
+	if used <= budget {
+		s.mu.Unlock()
+		if !s.warned.Swap(true) {
+			log.Printf("store: warning: %d of %d budget bytes in use", used, budget)
+		}
+		return
+	}
+	victims := make([]*record, 0, len(s.items))
+	for _, rec := range s.items {
+		if rec.ID != keep {
+			victims = append(victims, rec)
+		}
+	}
+	// zuerst, was ohnehin bald abläuft; bei Gleichstand die älteste
+	sort.Slice(victims, func(i, j int) bool {
+		a, b := victims[i], victims[j]
+		if !a.ExpiresAt.Equal(b.ExpiresAt) {
+			return a.ExpiresAt.Before(b.ExpiresAt)
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	target := int64(float64(budget) * lowWatermark)
+	n := 0
+	now := time.Now()
+	var gone []*record
+	expired := 0
+	for _, rec := range victims {
+		if s.bytes <= target {
+			break
+		}
+		s.replaceLocked(rec.ID, nil)
+		gone = append(gone, rec)
+		if now.After(rec.ExpiresAt) {
+			expired++
+		} else {
+			n++
+		}
+	}
+	left := s.bytes
+	s.mu.Unlock()
+	s.evicted.Add(int64(n))
+	if s.Archiver != nil {
+		// schon aus dem Store; scheitert das Archiv, ist die Paste verloren wie ohne Archiver
+		for _, rec := range gone {
+			if rec.DeletedAt.IsZero() {
+				s.archive(rec)
+			}
+		}
+	}
+	s.expired(gone)
+	if fn := s.onExpire.Load(); fn != nil && expired > 0 {
+		(*fn)(expired)
+	}
+	log.Printf("store: warning: memory budget exceeded (%d of %d bytes), evicted %d pastes early, now %d bytes", used, budget, n, left)
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"crypto/sha256"
+	"slices"
+	"time"
+
+	"unglued/internal/model"
+)
+
+/*
+Index über den SHA-256 der aktuellen Fassung (Code), damit automatische
+Uploader vorab fragen können, ob es den Inhalt schon gibt – ohne ihn zu
+schicken. Gepflegt unter s.mu in replaceLocked bzw. beim Verdrängen.
+*/
+
+func digest(code string) [sha256.Size]byte { return sha256.Sum256([]byte(code)) }
+
+func (s *Store) indexLocked(rec *record) {
+	if s.bySum == nil {
+		s.bySum = map[[sha256.Size]byte][]string{}
+	}
+	s.bySum[rec.sum] = append(s.bySum[rec.sum], rec.ID)
+	s.indexReplyLocked(rec)
+}
+
+func (s *Store) unindexLocked(rec *record) {
+	s.unindexReplyLocked(rec)
+	ids := slices.DeleteFunc(s.bySum[rec.sum], func(id string) bool { return id == rec.ID })
+	if len(ids) == 0 {
+		delete(s.bySum, rec.sum)
+		return
+	}
+	s.bySum[rec.sum] = ids
+}
+
+// FindSHA256 liefert die nicht abgelaufenen Pastes mit genau diesem Inhalt, ohne Code (wie GetMeta).
+func (s *Store) FindSHA256(sum [sha256.Size]byte) []model.Paste {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now()
+	var out []model.Paste
+	for _, id := range s.bySum[sum] {
+		if rec, ok := s.items[id]; ok && now.Before(rec.ExpiresAt) {
+			out = append(out, meta(rec))
+		}
+	}
+	return out
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"log"
+	"runtime"
+	"sort"
+	"time"
+
+	"unglued/internal/model"
+)
+
+// SetGrace: so lange nach Ablauf hält der Janitor eine Paste noch zurück; 0 = sofort weg.
+func (s *Store) SetGrace(d time.Duration) { s.grace.Store(int64(max(d, 0))) }
+
+func (s *Store) Grace() time.Duration { return time.Duration(s.grace.Load()) }
+
+// SetDeleteGrace: so lange bleibt eine gelöschte Paste wiederherstellbar; 0 = sofort weg.
+func (s *Store) SetDeleteGrace(d time.Duration) { s.deleteGrace.Store(int64(max(d, 0))) }
+
+func (s *Store) DeleteGrace() time.Duration { return time.Duration(s.deleteGrace.Load()) }
+
+// GraceFor: die Schonfrist, die für p gilt – nach dem Löschen DeleteGrace, nach Ablauf Grace.
+func (s *Store) GraceFor(p *model.Paste) time.Duration {
+	if !p.DeletedAt.IsZero() {
+		return s.DeleteGrace()
+	}
+	return s.Grace()
+}
+
+/*
+Expired liefert eine abgelaufene oder gelöschte Paste, solange sie noch in der
+Schonfrist liegt – für 410 Gone und das Wiederherstellen durch Key-Inhaber.
+*/
+func (s *Store) Expired(id string) (model.Paste, bool) {
+	rec, ok := s.lookup(id)
+	now := time.Now()
+	if !ok || now.Before(rec.ExpiresAt) || now.After(rec.ExpiresAt.Add(s.GraceFor(&rec.Paste))) {
+		return model.Paste{}, false
+	}
+	p, _, err := s.open(rec)
+	if err != nil {
+		log.Printf("store: cannot decrypt %s: %v", id, err)
+		return model.Paste{}, false
+	}
+	return p, true
+}
+
+// PurgeExpired entfernt abgelaufene und gelöschte Pastes sofort, ohne die Schonfrist abzuwarten.
+func (s *Store) PurgeExpired() int {
+	return s.expireBefore(time.Now(), false)
+}
+
+/*
+Der Janitor löscht in Häppchen: höchstens expireBatch Pastes bzw. expireSlice
+lang unter dem Schreib-Lock, dann kommen wartende Leser und Schreiber dran.
+Bei Hunderttausenden abgelaufenen Pastes steht der Store so nicht sekundenlang.
+*/
+const (
+	expireBatch = 1000
+	expireSlice = 5 * time.Millisecond
+)
+
+/*
+expireBefore entfernt alles, was vor t abgelaufen ist – mit grace erst nach
+der jeweiligen Schonfrist – und meldet die Anzahl an OnExpire.
+*/
+func (s *Store) expireBefore(t time.Time, grace bool) int {
+	if s.Archiver != nil {
+		return s.archiveBefore(t, grace)
+	}
+	due := s.dueBefore(t, grace)
+	n := 0
+	for len(due) > 0 {
+		var gone []*record
+		s.mu.Lock()
+		start := time.Now()
+		for len(due) > 0 && len(gone) < expireBatch && time.Since(start) < expireSlice {
+			rec := due[0]
+			due = due[1:]
+			// inzwischen wiederhergestellt oder ersetzt? Dann bleibt die neue Fassung
+			if s.items[rec.ID] == rec {
+				s.replaceLocked(rec.ID, nil)
+				gone = append(gone, rec)
+			}
+		}
+		s.mu.Unlock()
+		s.expired(gone)
+		n += len(gone)
+		runtime.Gosched()
+	}
+	if fn := s.onExpire.Load(); fn != nil && n > 0 {
+		(*fn)(n)
+	}
+	return n
+}
+
+// dueBefore sammelt unter dem Lese-Lock, was vor t fällig ist; Leser laufen dabei weiter.
+func (s *Store) dueBefore(t time.Time, grace bool) []*record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var due []*record
+	for _, rec := range s.items {
+		end := rec.ExpiresAt
+		if grace {
+			end = end.Add(s.GraceFor(&rec.Paste))
+		}
+		if t.After(end) {
+			due = append(due, rec)
+		}
+	}
+	return due
+}
+
+/*
+archiveBefore: wie expireBefore, aber jede Paste geht vorher an den Archiver –
+außerhalb des Locks, das kann dauern. Beim ersten Fehler ist für diesen Durchlauf
+Schluss (der Bucket ist dann vermutlich nicht erreichbar). Gelöschte Pastes
+kommen nicht ins Archiv: wer löscht, will sie weg haben.
+*/
+func (s *Store) archiveBefore(t time.Time, grace bool) int {
+	due := s.dueBefore(t, grace)
+	n := 0
+	for _, rec := range due {
+		if rec.DeletedAt.IsZero() && !s.archive(rec) {
+			break
+		}
+		s.mu.Lock()
+		// inzwischen wiederhergestellt oder ersetzt? Dann bleibt die neue Fassung
+		removed := s.items[rec.ID] == rec
+		if removed {
+			s.replaceLocked(rec.ID, nil)
+			n++
+		}
+		s.mu.Unlock()
+		if removed {
+			s.expired([]*record{rec})
+		}
+	}
+	if fn := s.onExpire.Load(); fn != nil && n > 0 {
+		(*fn)(n)
+	}
+	return n
+}
+
+// archive gibt rec an den Archiver; false, wenn der nicht erreichbar war.
+func (s *Store) archive(rec *record) bool {
+	p, _, err := s.open(rec)
+	if err != nil {
+		// nicht zu entschlüsseln: nicht archivierbar, wird gelöscht wie ohne Archiver
+		log.Printf("store: cannot decrypt %s: %v", rec.ID, err)
+		return true
+	}
+	if err := s.Archiver.Archive(p); err != nil {
+		log.Printf("store: archive %s: %v (retrying next run)", rec.ID, err)
+		return false
+	}
+	return true
+}
+
+/*
+Trash liefert alle Pastes in der Schonfrist – gelöscht oder abgelaufen –, zuletzt
+weggefallene zuerst. Für den Admin-Papierkorb; entschlüsselt wird wie bei Find.
+*/
+func (s *Store) Trash() []model.Paste {
+	now := time.Now()
+	s.mu.RLock()
+	var hits []*record
+	for _, rec := range s.items {
+		if !now.Before(rec.ExpiresAt) && !now.After(rec.ExpiresAt.Add(s.GraceFor(&rec.Paste))) {
+			hits = append(hits, rec)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(hits, func(i, j int) bool { return hits[i].ExpiresAt.After(hits[j].ExpiresAt) })
+	out := make([]model.Paste, 0, len(hits))
+	for _, rec := range hits {
+		p, _, err := s.open(rec)
+		if err != nil {
+			log.Printf("store: cannot decrypt %s: %v", rec.ID, err)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+/*
+Purge entfernt eine Paste aus dem Papierkorb sofort, wie PurgeExpired für eine
+einzelne; aktive Pastes bleiben unberührt (false).
+*/
+func (s *Store) Purge(id string) bool {
+	s.mu.Lock()
+	rec, ok := s.items[id]
+	if !ok || time.Now().Before(rec.ExpiresAt) {
+		s.mu.Unlock()
+		return false
+	}
+	s.replaceLocked(id, nil)
+	s.mu.Unlock()
+	s.expired([]*record{rec})
+	if fn := s.onExpire.Load(); fn != nil {
+		(*fn)(1)
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"unglued/internal/model"
+)
+
+// Rückwärts-Index für ReplyTo: welche Pastes antworten auf id. Wie bySum in replaceLocked gepflegt.
+
+func (s *Store) indexReplyLocked(rec *record) {
+	if rec.ReplyTo == "" {
+		return
+	}
+	if s.byParent == nil {
+		s.byParent = map[string][]string{}
+	}
+	s.byParent[rec.ReplyTo] = append(s.byParent[rec.ReplyTo], rec.ID)
+}
+
+func (s *Store) unindexReplyLocked(rec *record) {
+	if rec.ReplyTo == "" {
+		return
+	}
+	ids := slices.DeleteFunc(s.byParent[rec.ReplyTo], func(id string) bool { return id == rec.ID })
+	if len(ids) == 0 {
+		delete(s.byParent, rec.ReplyTo)
+		return
+	}
+	s.byParent[rec.ReplyTo] = ids
+}
+
+// Replies liefert die nicht abgelaufenen Antworten auf id, neueste zuerst, ohne Code (wie GetMeta).
+func (s *Store) Replies(id string) []model.Paste {
+	s.mu.RLock()
+	now := time.Now()
+	var out []model.Paste
+	for _, rid := range s.byParent[id] {
+		if rec, ok := s.items[rid]; ok && now.Before(rec.ExpiresAt) {
+			out = append(out, meta(rec))
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
@@ -0,0 +1,89 @@
+package store
+
+import "unglued/internal/model"
+
+/*
+Fetcher ist ein Backend, das mehrere Instanzen teilen (siehe redisstore): es
+kennt auch Pastes, die eine andere Instanz gerade angelegt oder geändert hat und
+deren Änderungsmeldung hier noch nicht angekommen ist. err heißt: gerade nicht
+erreichbar, found=false: gibt es dort nicht.
+*/
+type Fetcher interface {
+	Fetch(id string) (p model.Paste, found bool, err error)
+}
+
+/*
+lookup liefert den record zu id. Fehlt er und ist das Backend ein Fetcher, wird
+dort nachgesehen und das Ergebnis abgelegt – außer, es ist inzwischen eine
+eigene Fassung da. Abgelaufene records kommen mit; prüfen muss der Aufrufer.
+*/
+func (s *Store) lookup(id string) (*record, bool) {
+	s.mu.RLock()
+	rec, ok := s.items[id]
+	s.mu.RUnlock()
+	if ok {
+		return rec, true
+	}
+	f, _ := s.Backend.(Fetcher)
+	if f == nil {
+		return nil, false
+	}
+	p, found, err := f.Fetch(id)
+	if err != nil || !found {
+		return nil, false
+	}
+	fresh := s.seal(p)
+	s.mu.Lock()
+	if rec, ok = s.items[id]; !ok {
+		s.replaceLocked(id, fresh)
+		rec = fresh
+	}
+	s.mu.Unlock()
+	s.evict(id)
+	return rec, true
+}
+
+/*
+current ist lookup für Schreibzugriffe: mit einem Fetcher zählt, was dort steht,
+nicht die eigene, womöglich ältere Kopie – sonst gälte hier etwa ein Edit-Key noch,
+den eine andere Instanz gerade rotiert hat. Ist das Backend nicht erreichbar,
+bleibt es bei der eigenen Kopie.
+*/
+func (s *Store) current(id string) (*record, bool) {
+	f, _ := s.Backend.(Fetcher)
+	if f == nil {
+		return s.lookup(id)
+	}
+	p, found, err := f.Fetch(id)
+	switch {
+	case err != nil:
+		return s.lookup(id)
+	case !found:
+		return nil, false
+	}
+	fresh := s.seal(p)
+	s.mu.Lock()
+	s.replaceLocked(id, fresh)
+	s.mu.Unlock()
+	s.evict(id)
+	return fresh, true
+}
+
+/*
+Forget entfernt id wie Delete, meldet es aber nicht ans Backend – für Löschungen,
+die von dort kommen. Caches erfahren es trotzdem über OnChange.
+*/
+func (s *Store) Forget(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[id]; !ok {
+		return false
+	}
+	s.setLocked(id, nil)
+	return true
+}
+
+// janitorDue: darf diese Instanz jetzt abräumen? Ohne JanitorLease immer.
+func (s *Store) janitorDue() bool {
+	return s.JanitorLease == nil || s.JanitorLease()
+}
@@ -1,21 +1,121 @@
 package store
 
 import (
+	"crypto/sha256"
+	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"unglued/internal/model"
+	"unglued/internal/util"
 )
 
 type Store struct {
 	mu     sync.RWMutex
-	items  map[string]*model.Paste
+	items  map[string]*record
 	quitCh chan struct{}
+
+	// optional: verschlüsselt Code und Versionen im Speicher; vor dem ersten Put setzen
+	Sealer Sealer
+
+	// optional: bekommt jede Änderung mit und macht sie dauerhaft (siehe gitstore); vor dem ersten Put setzen
+	Backend Backend
+
+	// optional: abgelaufene Pastes gehen hierhin, statt gelöscht zu werden (siehe coldstore)
+	Archiver Archiver
+
+	// optional: der Janitor räumt nur ab, solange das true liefert – bei mehreren Instanzen genau eine (siehe shared.go)
+	JanitorLease func() bool
+	// mit einem Fetcher-Backend läuft ein Update nach dem anderen (siehe Update)
+	updateMu sync.Mutex
+
+	onExpire atomic.Pointer[func(n int)]
+	onExpirePaste atomic.Pointer[func(p model.Paste)]
+	onChange atomic.Pointer[func(id string)]
+
+	// Speicherbudget (siehe budget.go); bytes wird unter mu mitgeführt
+	bytes   int64
+	budget  atomic.Int64
+	evicted atomic.Int64
+	warned  atomic.Bool
+
+	// abgelaufene bzw. gelöschte Pastes bleiben so lange als Tombstone liegen (siehe grace.go)
+	grace       atomic.Int64
+	deleteGrace atomic.Int64
+
+	// SHA-256 des Inhalts → IDs (siehe digest.go); unter mu
+	bySum map[[sha256.Size]byte][]string
+	// Paste → IDs der Antworten darauf (siehe replies.go); unter mu
+	byParent map[string][]string
+}
+
+// OnExpire meldet, wie viele Pastes der Janitor pro Durchlauf abgeräumt hat (Statistik).
+func (s *Store) OnExpire(fn func(n int)) { s.onExpire.Store(&fn) }
+
+// OnExpirePaste meldet jede abgelaufene oder vom Budget verdrängte Paste einzeln, nachdem sie entfernt wurde (Webhooks).
+func (s *Store) OnExpirePaste(fn func(p model.Paste)) { s.onExpirePaste.Store(&fn) }
+
+/*
+OnChange meldet jede ID, die ersetzt wird oder verschwindet (neue Version,
+Metadaten, Löschen, Ablauf) – für Caches abgeleiteter Daten wie render.Cache.
+Wird unter dem Lock gerufen und darf nicht blockieren.
+*/
+func (s *Store) OnChange(fn func(id string)) { s.onChange.Store(&fn) }
+
+// expired ruft OnExpirePaste für recs auf; nicht unter dem Lock aufrufen.
+func (s *Store) expired(recs []*record) {
+	fn := s.onExpirePaste.Load()
+	if fn == nil {
+		return
+	}
+	for _, rec := range recs {
+		if p, _, err := s.open(rec); err == nil {
+			(*fn)(p)
+		}
+	}
+}
+
+// Sealer verschlüsselt Inhalte at rest (siehe atrest.Keyring).
+type Sealer interface {
+	Seal(plain []byte) []byte
+	// stale = mit altem Key oder gar nicht versiegelt, bitte neu versiegeln
+	Open(sealed []byte) (plain []byte, stale bool, err error)
+}
+
+/*
+Backend spiegelt den Store nach außen. Saved bekommt den Klartext, Removed jede ID,
+die aus dem Store verschwindet (Löschen, Ablauf); beide werden teils unter dem Lock
+gerufen und dürfen nicht blockieren.
+*/
+type Backend interface {
+	Saved(p model.Paste)
+	Removed(id string)
+}
+
+/*
+Archiver übernimmt abgelaufene Pastes, bevor der Janitor sie entfernt. Schlägt
+Archive fehl, bleibt die Paste liegen und der nächste Durchlauf versucht es erneut.
+*/
+type Archiver interface {
+	Archive(p model.Paste) error
+}
+
+/*
+record ist die gespeicherte Form einer Paste. Mit Sealer liegen Code (in sealed)
+und alle Versionen nur verschlüsselt vor; Code ist dann leer.
+*/
+type record struct {
+	model.Paste
+	sealed []byte
+	size   int64
+	sum    [sha256.Size]byte // des Klartexts von Code
 }
 
 func New(janitorInterval time.Duration) *Store {
 	s := &Store{
-		items:  make(map[string]*model.Paste),
+		items:  make(map[string]*record),
 		quitCh: make(chan struct{}),
 	}
 	go s.janitor(janitorInterval)
@@ -25,19 +125,163 @@ func New(janitorInterval time.Duration) *Store {
 func (s *Store) Close() { close(s.quitCh) }
 
 func (s *Store) Put(p model.Paste) {
+	s.Load(p)
+	if s.Backend != nil {
+		s.Backend.Saved(p)
+	}
+}
+
+// Load legt p ab, ohne das Backend zu benachrichtigen – zum Einlesen beim Start.
+func (s *Store) Load(p model.Paste) {
+	rec := s.seal(p)
 	s.mu.Lock()
-	s.items[p.ID] = &p
+	s.replaceLocked(p.ID, rec)
 	s.mu.Unlock()
+	s.evict(p.ID)
+}
+
+// replaceLocked setzt id auf rec (nil = löschen) und meldet Löschungen ans Backend.
+func (s *Store) replaceLocked(id string, rec *record) {
+	s.setLocked(id, rec)
+	if rec == nil && s.Backend != nil {
+		s.Backend.Removed(id)
+	}
+}
+
+// setLocked setzt id auf rec (nil = löschen) und führt Byte-Summe und Indizes nach.
+func (s *Store) setLocked(id string, rec *record) {
+	if old, ok := s.items[id]; ok {
+		s.bytes -= old.size
+		s.unindexLocked(old)
+	}
+	if fn := s.onChange.Load(); fn != nil {
+		(*fn)(id)
+	}
+	if rec == nil {
+		delete(s.items, id)
+		return
+	}
+	s.items[id] = rec
+	s.bytes += rec.size
+	s.indexLocked(rec)
 }
 
 func (s *Store) Get(id string) (model.Paste, bool) {
+	rec, ok := s.lookup(id)
+	if !ok || time.Now().After(rec.ExpiresAt) {
+		return model.Paste{}, false
+	}
+	p, stale, err := s.open(rec)
+	if err != nil {
+		log.Printf("store: cannot decrypt %s: %v", id, err)
+		return model.Paste{}, false
+	}
+	if stale {
+		// Key-Rotation: beim Lesen mit dem aktuellen Key neu versiegeln
+		fresh := s.seal(p)
+		s.mu.Lock()
+		if s.items[id] == rec {
+			s.replaceLocked(id, fresh)
+		}
+		s.mu.Unlock()
+	}
+	return p, true
+}
+
+// Delete entfernt id sofort; false, wenn es sie nicht (mehr) gab.
+func (s *Store) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[id]; !ok {
+		return false
+	}
+	s.replaceLocked(id, nil)
+	return true
+}
+
+/*
+Find liefert alle nicht abgelaufenen Pastes, für die match true ist, neueste zuerst.
+match sieht nur Metadaten (bei Verschlüsselung ist Code dort leer), entschlüsselt
+werden nur die Treffer.
+*/
+func (s *Store) Find(match func(p *model.Paste) bool) []model.Paste {
+	now := time.Now()
 	s.mu.RLock()
-	ptr, ok := s.items[id]
+	var hits []*record
+	for _, rec := range s.items {
+		if now.Before(rec.ExpiresAt) && match(&rec.Paste) {
+			hits = append(hits, rec)
+		}
+	}
 	s.mu.RUnlock()
-	if !ok || time.Now().After(ptr.ExpiresAt) {
-		return model.Paste{}, false
+	sort.Slice(hits, func(i, j int) bool { return hits[i].CreatedAt.After(hits[j].CreatedAt) })
+	out := make([]model.Paste, 0, len(hits))
+	for _, rec := range hits {
+		p, _, err := s.open(rec)
+		if err != nil {
+			log.Printf("store: cannot decrypt %s: %v", rec.ID, err)
+			continue
+		}
+		out = append(out, p)
 	}
-	return *ptr, true
+	return out
+}
+
+/*
+Snapshot liefert alle nicht abgelaufenen Pastes zum selben Zeitpunkt (ein Lock für
+die Auswahl) – für Backups, auch während der Server weiterläuft.
+*/
+func (s *Store) Snapshot() []model.Paste {
+	return s.Find(func(*model.Paste) bool { return true })
+}
+
+/*
+seal baut den record; nebenbei bekommt jede Version ihre entpackte Größe (aus dem
+Klartext, vor dem Verschlüsseln), damit GetMeta sie ohne Entpacken liefern kann.
+*/
+func (s *Store) seal(p model.Paste) *record {
+	rec := &record{Paste: p, sum: digest(p.Code)}
+	rec.Versions = make([]model.Version, len(p.Versions))
+	for i, v := range p.Versions {
+		v.Size = util.GzipSize(v.ZCode)
+		if s.Sealer != nil {
+			v.ZCode = s.Sealer.Seal(v.ZCode)
+		}
+		rec.Versions[i] = v
+	}
+	if s.Sealer != nil {
+		rec.sealed = s.Sealer.Seal([]byte(p.Code))
+		rec.Code = ""
+	}
+	rec.size = recordSize(rec)
+	return rec
+}
+
+// open liefert die Klartext-Paste; ohne Sealer (oder unversiegelt) einfach eine Kopie.
+func (s *Store) open(rec *record) (model.Paste, bool, error) {
+	p := rec.Paste
+	if s.Sealer == nil {
+		return p, false, nil
+	}
+	if rec.sealed == nil {
+		return p, true, nil // aus der Zeit vor der Verschlüsselung
+	}
+	code, stale, err := s.Sealer.Open(rec.sealed)
+	if err != nil {
+		return model.Paste{}, false, err
+	}
+	p.Code = string(code)
+	p.Versions = make([]model.Version, len(rec.Versions))
+	for i, v := range rec.Versions {
+		z, st, err := s.Sealer.Open(v.ZCode)
+		if err != nil {
+			return model.Paste{}, false, err
+		}
+		v.ZCode = z
+		p.Versions[i] = v
+		stale = stale || st
+	}
+	return p, stale, nil
 }
 
 func (s *Store) CountActive() int {
@@ -53,20 +297,105 @@ func (s *Store) CountActive() int {
 	return n
 }
 
+// ActiveByLang zählt die nicht abgelaufenen Pastes je Sprache (für /metrics).
+func (s *Store) ActiveByLang() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now()
+	out := map[string]int{}
+	for _, rec := range s.items {
+		if now.Before(rec.ExpiresAt) {
+			out[rec.Lang]++
+		}
+	}
+	return out
+}
+
+// Stats: grobe Größenangaben des Stores für Debug-Endpunkte.
+type Stats struct {
+	Items    int   `json:"items"`    // inkl. abgelaufener, die der Janitor noch nicht geholt hat
+	Active   int   `json:"active"`
+	Versions int   `json:"versions"`
+	Bytes    int64 `json:"bytes"` // Code + komprimierte Versionen (bzw. verschlüsselt)
+	Budget   int64 `json:"budget,omitempty"`
+	Evicted  int64 `json:"evicted,omitempty"` // wegen des Budgets vorzeitig entfernt
+}
+
+func (s *Store) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now()
+	st := Stats{Items: len(s.items), Bytes: s.bytes, Budget: s.budget.Load(), Evicted: s.evicted.Load()}
+	for _, rec := range s.items {
+		if now.Before(rec.ExpiresAt) {
+			st.Active++
+		}
+		st.Versions += len(rec.Versions)
+	}
+	return st
+}
+
+// Sortierungen für ListPublic.
+const (
+	SortCreated = "created" // neueste zuerst
+	SortExpires = "expires" // läuft zuerst ab
+)
+
+/*
+ListPublic liefert eine Seite öffentlicher, nicht abgelaufener Pastes plus die Gesamtzahl.
+Unlisted Pastes tauchen hier nie auf.
+*/
+func (s *Store) ListPublic(sortBy string, offset, limit int) ([]model.Paste, int) {
+	now := time.Now()
+	s.mu.RLock()
+	all := make([]*record, 0, 32)
+	for _, p := range s.items {
+		if p.Public && now.Before(p.ExpiresAt) {
+			all = append(all, p)
+		}
+	}
+	s.mu.RUnlock()
+
+	switch sortBy {
+	case SortExpires:
+		sort.Slice(all, func(i, j int) bool { return all[i].ExpiresAt.Before(all[j].ExpiresAt) })
+	default:
+		sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt.After(all[j].CreatedAt) })
+	}
+
+	total := len(all)
+	if offset >= total {
+		return nil, total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	// nur die angefragte Seite entschlüsseln
+	out := make([]model.Paste, 0, end-offset)
+	for _, rec := range all[offset:end] {
+		p, _, err := s.open(rec)
+		if err != nil {
+			log.Printf("store: cannot decrypt %s: %v", rec.ID, err)
+			total--
+			continue
+		}
+		out = append(out, p)
+	}
+	return out, total
+}
+
 func (s *Store) janitor(interval time.Duration) {
 	t := time.NewTicker(interval)
 	defer t.Stop()
 	for {
 		select {
 		case <-t.C:
-			now := time.Now()
-			s.mu.Lock()
-			for id, p := range s.items {
-				if now.After(p.ExpiresAt) {
-					delete(s.items, id)
-				}
+			if !s.janitorDue() {
+				continue
 			}
-			s.mu.Unlock()
+			s.expireBefore(time.Now(), true)
+			s.evict("")
 		case <-s.quitCh:
 			return
 		}
@@ -0,0 +1,79 @@
+/*
+Package storetest baut Stores mit den Backends, wie main sie zusammensteckt:
+nur Speicher, versiegelt (-encryption-key) und mit Git (-git-store). Das Archiv
+fehlt: Anlegen, Ansehen und Bearbeiten berühren es nicht. Für Benchmarks in
+store und httpx.
+*/
+package storetest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"unglued/internal/atrest"
+	"unglued/internal/gitstore"
+	"unglued/internal/store"
+)
+
+type Backend struct {
+	Name string
+	Open func(tb testing.TB) Env
+}
+
+// Env: ein Store und wie man wartet, bis sein Backend alles geschrieben hat.
+type Env struct {
+	*store.Store
+	flush func()
+}
+
+// Flush wartet auf das Backend (gitstore committet im Hintergrund), damit Benchmarks die Commits mitmessen.
+func (e Env) Flush() {
+	if e.flush != nil {
+		e.flush()
+	}
+}
+
+// Backends in fester Reihenfolge; gitstore wird übersprungen, wenn git fehlt.
+func Backends() []Backend {
+	return []Backend{
+		{"memory", func(tb testing.TB) Env { return Env{Store: open(tb)} }},
+		{"sealed", func(tb testing.TB) Env {
+			kr, err := atrest.NewKeyring(bytes.Repeat([]byte{7}, 32))
+			if err != nil {
+				tb.Fatal(err)
+			}
+			st := open(tb)
+			st.Sealer = kr
+			return Env{Store: st}
+		}},
+		{"gitstore", func(tb testing.TB) Env {
+			repo, err := gitstore.Open(tb.TempDir()+"/pastes.git", "")
+			if err != nil {
+				tb.Skip(err)
+			}
+			st := open(tb)
+			st.Backend = repo
+			// vor TempDir aufräumen: Cleanups laufen rückwärts
+			tb.Cleanup(repo.Close)
+			return Env{Store: st, flush: repo.Flush}
+		}},
+	}
+}
+
+// Code erzeugt rund 4 KB Go-Quelltext, für jedes i anders.
+func Code(i int) string {
+	var b strings.Builder
+	for l := 0; l < 100; l++ {
+		fmt.Fprintf(&b, "func f%d_%d(x int) int { return x * %d }\n", i, l, l)
+	}
+	return b.String()
+}
+
+func open(tb testing.TB) *store.Store {
+	st := store.New(time.Hour)
+	tb.Cleanup(st.Close)
+	return st
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"unglued/internal/model"
+)
+
+var ErrNotFound = errors.New("paste not found")
+
+/*
+Update ändert eine Paste atomar: fn bekommt den aktuellen Stand, gespeichert wird
+nur, wenn seitdem niemand anderes gespeichert hat – sonst läuft fn mit dem neuen
+Stand noch einmal. fn darf also mehrfach laufen und prüft Zugriffsrechte am
+übergebenen Stand, nicht an einem vorher gelesenen. Ein Fehler aus fn bricht ohne
+Speichern ab und kommt unverändert zurück; ErrNotFound, wenn es id nicht (mehr) gibt.
+Mit einem Fetcher-Backend (mehrere Instanzen) geht fn vom Stand dort aus; damit
+der nicht hinter einem eigenen, noch nicht geschriebenen Update zurückliegt,
+laufen Updates dann nacheinander, jedes bis sein Backend.Saved fertig ist.
+*/
+func (s *Store) Update(id string, fn func(p *model.Paste) error) (model.Paste, error) {
+	if _, ok := s.Backend.(Fetcher); ok {
+		s.updateMu.Lock()
+		defer s.updateMu.Unlock()
+	}
+	for {
+		rec, ok := s.current(id)
+		if !ok || time.Now().After(rec.ExpiresAt) {
+			return model.Paste{}, ErrNotFound
+		}
+		p, _, err := s.open(rec)
+		if err != nil {
+			return model.Paste{}, err
+		}
+		// fn darf Slices ändern, ohne den gespeicherten record anzufassen
+		p.Versions, p.EditKeys, p.Tags = slices.Clone(p.Versions), slices.Clone(p.EditKeys), slices.Clone(p.Tags)
+		if err := fn(&p); err != nil {
+			return p, err
+		}
+		fresh := s.seal(p)
+		s.mu.Lock()
+		swapped := s.items[id] == rec
+		if swapped {
+			s.replaceLocked(id, fresh)
+		}
+		s.mu.Unlock()
+		if !swapped {
+			continue
+		}
+		s.evict(id)
+		if s.Backend != nil {
+			s.Backend.Saved(p)
+		}
+		return p, nil
+	}
+}
@@ -0,0 +1,55 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"unglued/internal/model"
+	"unglued/internal/store"
+)
+
+func TestUpdateRetriesOnConcurrentChange(t *testing.T) {
+	st := store.New(time.Hour)
+	defer st.Close()
+	st.Put(model.Paste{ID: "a", Code: "v1", EditKey: "old", ExpiresAt: time.Now().Add(time.Hour)})
+
+	calls := 0
+	p, err := st.Update("a", func(p *model.Paste) error {
+		calls++
+		if calls == 1 {
+			// Rotation, während die Bearbeitung noch den alten Stand hält
+			if _, err := st.Update("a", func(p *model.Paste) error { p.EditKey = "new"; return nil }); err != nil {
+				t.Fatal(err)
+			}
+		}
+		p.Code = "v2"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("fn ran %d times, want 2", calls)
+	}
+	got, _ := st.Get("a")
+	if got.EditKey != "new" || got.Code != "v2" || p.EditKey != "new" {
+		t.Errorf("got key %q code %q, want the rotated key and the edit", got.EditKey, got.Code)
+	}
+}
+
+func TestUpdateAbortsOnError(t *testing.T) {
+	st := store.New(time.Hour)
+	defer st.Close()
+	st.Put(model.Paste{ID: "a", Code: "v1", ExpiresAt: time.Now().Add(time.Hour)})
+	stop := errors.New("stop")
+	if _, err := st.Update("a", func(p *model.Paste) error { p.Code = "v2"; return stop }); err != stop {
+		t.Fatalf("err = %v, want %v", err, stop)
+	}
+	if got, _ := st.Get("a"); got.Code != "v1" {
+		t.Errorf("code = %q, want it unchanged", got.Code)
+	}
+	if _, err := st.Update("missing", func(*model.Paste) error { return nil }); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"log"
+	"time"
+
+	"unglued/internal/model"
+)
+
+/*
+Leichte Sichten für Handler, die nicht die ganze Paste brauchen: Get kopiert
+alle Versionen (und entschlüsselt sie mit Sealer), auch wenn nur eine davon
+gerendert wird. Was GetMeta und GetVersion liefern, ist nur zum Lesen da –
+nie per Put zurückschreiben, sonst fehlt der Inhalt.
+*/
+
+func (s *Store) live(id string) (*record, bool) {
+	rec, ok := s.lookup(id)
+	if !ok || time.Now().After(rec.ExpiresAt) {
+		return nil, false
+	}
+	return rec, true
+}
+
+// Has: gibt es id und ist sie nicht abgelaufen?
+func (s *Store) Has(id string) bool {
+	_, ok := s.live(id)
+	return ok
+}
+
+// GetMeta liefert die Paste ohne Inhalt: Code ist leer, die Versionen haben kein ZCode.
+func (s *Store) GetMeta(id string) (model.Paste, bool) {
+	rec, ok := s.live(id)
+	if !ok {
+		return model.Paste{}, false
+	}
+	return meta(rec), true
+}
+
+/*
+GetVersion liefert die Metadaten wie GetMeta und dazu Version n (ab 0) mit
+ihrem gzip-Inhalt; nur diese eine wird entschlüsselt.
+*/
+func (s *Store) GetVersion(id string, n int) (model.Paste, model.Version, bool) {
+	rec, ok := s.live(id)
+	if !ok || n < 0 || n >= len(rec.Versions) {
+		return model.Paste{}, model.Version{}, false
+	}
+	v := rec.Versions[n]
+	if s.Sealer != nil && rec.sealed != nil {
+		z, _, err := s.Sealer.Open(v.ZCode)
+		if err != nil {
+			log.Printf("store: cannot decrypt %s: %v", id, err)
+			return model.Paste{}, model.Version{}, false
+		}
+		v.ZCode = z
+	}
+	return meta(rec), v, true
+}
+
+// ZSize: was die Versionen von id gepackt belegen, ohne sie zu kopieren.
+func (s *Store) ZSize(id string) (int64, bool) {
+	rec, ok := s.live(id)
+	if !ok {
+		return 0, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var n int64
+	for _, v := range rec.Versions {
+		n += int64(len(v.ZCode))
+	}
+	return n, true
+}
+
+func meta(rec *record) model.Paste {
+	p := rec.Paste
+	p.Code = ""
+	p.Versions = make([]model.Version, len(rec.Versions))
+	for i, v := range rec.Versions {
+		v.ZCode = nil
+		p.Versions[i] = v
+	}
+	return p
+}
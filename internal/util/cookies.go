@@ -6,13 +6,44 @@ import (
 )
 
 func WriteCookie(w http.ResponseWriter, name, value string, life time.Duration) {
-	http.SetCookie(w, &http.Cookie{
+	WriteCookieWith(w, CookieFlags{SameSite: http.SameSiteLaxMode}, name, value, life)
+}
+
+// CookieFlags: Sicherheits-Attribute, die der Server pro Request festlegt.
+type CookieFlags struct {
+	HttpOnly bool
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// WriteCookieWith setzt ein Cookie für "/"; life < 0 löscht es.
+func WriteCookieWith(w http.ResponseWriter, f CookieFlags, name, value string, life time.Duration) {
+	c := &http.Cookie{
 		Name:     name,
 		Value:    value,
 		Path:     "/",
-		Expires:  time.Now().Add(life),
-		MaxAge:   int(life / time.Second),
-		SameSite: http.SameSiteLaxMode,
-	})
+		HttpOnly: f.HttpOnly,
+		Secure:   f.Secure,
+		SameSite: f.SameSite,
+	}
+	if life < 0 {
+		c.MaxAge = -1
+	} else {
+		c.Expires = time.Now().Add(life)
+		c.MaxAge = int(life / time.Second)
+	}
+	http.SetCookie(w, c)
 }
 
+// ParseSameSite: "lax", "strict", "none"; false bei unbekanntem Wert.
+func ParseSameSite(s string) (http.SameSite, bool) {
+	switch s {
+	case "lax", "":
+		return http.SameSiteLaxMode, true
+	case "strict":
+		return http.SameSiteStrictMode, true
+	case "none":
+		return http.SameSiteNoneMode, true
+	}
+	return 0, false
+}
@@ -0,0 +1,59 @@
+package util
+
+import "strings"
+
+// bis zu dieser Größe (Zeilen × Zeilen) wird echt verglichen, darüber nur Anfang/Ende
+const diffCells = 1 << 20
+
+/*
+LineDiff vergleicht a und b zeilenweise und liefert die geänderten Zeilen mit "- " bzw.
+"+ " davor; gleiche Zeilen fallen weg. Gedacht für kurze Benachrichtigungen, nicht als
+vollständiges Unified-Diff.
+*/
+func LineDiff(a, b string) []string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	for len(x) > 0 && len(y) > 0 && x[0] == y[0] {
+		x, y = x[1:], y[1:]
+	}
+	for len(x) > 0 && len(y) > 0 && x[len(x)-1] == y[len(y)-1] {
+		x, y = x[:len(x)-1], y[:len(y)-1]
+	}
+	var out []string
+	if len(x)*len(y) > diffCells {
+		for _, l := range x {
+			out = append(out, "- "+l)
+		}
+		for _, l := range y {
+			out = append(out, "+ "+l)
+		}
+		return out
+	}
+	// LCS-Tabelle von hinten, dann vorne beginnend ablaufen
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+x[i])
+			i++
+		default:
+			out = append(out, "+ "+y[j])
+			j++
+		}
+	}
+	return out
+}
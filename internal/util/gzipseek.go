@@ -0,0 +1,97 @@
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+/*
+GzipSeeker liest gzip-Daten entpackt, ohne sie ganz in den Speicher zu holen,
+und lässt sich trotzdem positionieren (für http.ServeContent und Range).
+Die Größe steht im gzip-Trailer (ISIZE); das passt, solange die Daten aus
+einem einzigen Member unter 4 GiB bestehen, wie bei GzipEncode. Rückwärts
+springen heißt von vorn entpacken; vorwärts wird übersprungen.
+*/
+type GzipSeeker struct {
+	src  []byte
+	size int64
+	zr   *gzip.Reader
+	pos  int64 // Position von zr
+	want int64 // Position nach Seek; wird beim nächsten Read eingeholt
+}
+
+func NewGzipSeeker(b []byte) (*GzipSeeker, error) {
+	if len(b) < 18 {
+		return nil, errors.New("gzip: data too short")
+	}
+	g := &GzipSeeker{src: b, size: GzipSize(b)}
+	if err := g.rewind(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// GzipSize: entpackte Länge laut Trailer (ISIZE), ohne zu entpacken; -1 bei zu kurzen Daten.
+func GzipSize(b []byte) int64 {
+	if len(b) < 18 {
+		return -1
+	}
+	return int64(binary.LittleEndian.Uint32(b[len(b)-4:]))
+}
+
+// Size: Länge der entpackten Daten.
+func (g *GzipSeeker) Size() int64 { return g.size }
+
+func (g *GzipSeeker) rewind() error {
+	var err error
+	if g.zr == nil {
+		g.zr, err = gzip.NewReader(bytes.NewReader(g.src))
+	} else {
+		err = g.zr.Reset(bytes.NewReader(g.src))
+	}
+	if err != nil {
+		return err
+	}
+	g.zr.Multistream(false)
+	g.pos = 0
+	return nil
+}
+
+func (g *GzipSeeker) Read(p []byte) (int, error) {
+	if g.want < g.pos {
+		if err := g.rewind(); err != nil {
+			return 0, err
+		}
+	}
+	if g.want > g.pos {
+		n, err := io.CopyN(io.Discard, g.zr, g.want-g.pos)
+		g.pos += n
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := g.zr.Read(p)
+	g.pos += int64(n)
+	g.want = g.pos
+	return n, err
+}
+
+func (g *GzipSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += g.want
+	case io.SeekEnd:
+		offset += g.size
+	default:
+		return 0, errors.New("gzip: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("gzip: negative position")
+	}
+	g.want = offset
+	return offset, nil
+}
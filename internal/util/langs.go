@@ -0,0 +1,74 @@
+package util
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// LangExts: Dateiendung → Sprache, für Uploads per Client und Mail-Anhänge.
+var LangExts = map[string]string{
+	".go": "go", ".js": "javascript", ".mjs": "javascript", ".ts": "typescript",
+	".json": "json", ".yaml": "yaml", ".yml": "yaml", ".toml": "toml", ".py": "python",
+	".sh": "bash", ".bash": "bash", ".html": "html", ".htm": "html", ".css": "css",
+	".sql": "sql", ".md": "markdown", ".txt": "plaintext",
+}
+
+/*
+LangAliases: gängige Kurz- und Alternativnamen → Name des Chroma-Lexers, wie ihn
+die Sprachauswahl führt. Sonst landet "js" oder "yml" still als plaintext.
+*/
+var LangAliases = map[string]string{
+	"js": "javascript", "mjs": "javascript", "cjs": "javascript", "node": "javascript",
+	"ts": "typescript", "py": "python", "py3": "python", "python3": "python",
+	"sh": "bash", "shell": "bash", "zsh": "bash", "yml": "yaml", "golang": "go",
+	"md": "markdown", "htm": "html", "txt": "plaintext", "text": "plaintext", "plain": "plaintext",
+}
+
+// CanonicalLang: lang klein geschrieben und mit aufgelöstem Alias ("JS" → "javascript").
+func CanonicalLang(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if c, ok := LangAliases[lang]; ok {
+		return c
+	}
+	return lang
+}
+
+// langExt: übliche Endung je Sprache (umgekehrt zu LangExts, dort gibt es mehrere).
+var langExt = map[string]string{
+	"go": ".go", "javascript": ".js", "typescript": ".ts", "json": ".json", "yaml": ".yaml",
+	"toml": ".toml", "python": ".py", "bash": ".sh", "html": ".html", "css": ".css",
+	"sql": ".sql", "markdown": ".md",
+}
+
+// ExtForLang: Dateiendung für Downloads; .txt, wenn es keine eigene gibt.
+func ExtForLang(lang string) string {
+	if e, ok := langExt[lang]; ok {
+		return e
+	}
+	return ".txt"
+}
+
+// LangForFile: Sprache zur Endung von name; leer, wenn unbekannt.
+func LangForFile(name string) string {
+	return LangExts[strings.ToLower(filepath.Ext(name))]
+}
+
+/*
+LangMIME: Sprache → Content-Type für /raw?ct=auto. html und javascript fehlen
+absichtlich: die Antwort käme von unserer Origin und würde vom Browser
+ausgeführt (script-src 'self').
+*/
+var LangMIME = map[string]string{
+	"go": "text/x-go", "typescript": "text/x-typescript", "json": "application/json",
+	"yaml": "text/x-yaml", "toml": "application/toml", "python": "text/x-python",
+	"bash": "text/x-shellscript", "css": "text/css", "sql": "application/sql",
+	"markdown": "text/markdown",
+}
+
+// MIMEForLang: Content-Type samt charset; text/plain, wenn es keinen passenderen gibt.
+func MIMEForLang(lang string) string {
+	if t, ok := LangMIME[lang]; ok {
+		return t + "; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
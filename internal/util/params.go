@@ -1,19 +1,51 @@
 package util
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+)
+
+// ParseTTL: wie time.ParseDuration, dazu d (Tage) und w (Wochen) vorneweg, z.B. "3d", "2w", "1w2d12h".
 func ParseTTL(s string) (time.Duration, error) {
 	switch s {
 	case "1h": return time.Hour, nil
 	case "24h": return 24 * time.Hour, nil
 	case "168h", "7d": return 168 * time.Hour, nil
 	case "": return 24 * time.Hour, nil
-	default: return time.ParseDuration(s)
 	}
+	in := strings.ToLower(strings.TrimSpace(s))
+	var d time.Duration
+	for _, u := range []struct{ sfx string; unit time.Duration }{{"w", Week}, {"d", Day}} {
+		i := strings.Index(in, u.sfx)
+		if i < 0 { continue }
+		n, err := strconv.ParseFloat(in[:i], 64)
+		if err != nil || n < 0 { return 0, errors.New("invalid ttl " + strconv.Quote(s)) }
+		d += time.Duration(n * float64(u.unit))
+		in = in[i+1:]
+	}
+	if in == "" { return d, nil }
+	rest, err := time.ParseDuration(in)
+	if err != nil { return 0, err }
+	return d + rest, nil
+}
+
+// FormatTTL: kürzeste Schreibweise, die ParseTTL wieder versteht ("2w", "3d", "36h", "30m").
+func FormatTTL(d time.Duration) string {
+	switch {
+	case d >= Week && d%Week == 0: return strconv.FormatInt(int64(d/Week), 10) + "w"
+	case d >= Day && d%Day == 0: return strconv.FormatInt(int64(d/Day), 10) + "d"
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") { s = s[:len(s)-2] }
+	if strings.HasSuffix(s, "h0m") { s = s[:len(s)-2] }
+	return s
 }
 
 func ParseHL(s string) map[int]bool {
@@ -42,3 +74,21 @@ func IsTruthy(s string) bool {
 	return s=="1" || s=="true" || s=="on" || s=="yes"
 }
 
+
+// ParseTags: "k8s, Terraform,,k8s" -> [k8s terraform]; max. 10 Tags à 32 Zeichen.
+func ParseTags(s string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] || len(t) > 32 {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+		if len(out) == 10 {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+/*
+Package version hält die Build-Infos. Gesetzt per
+
+	go build -ldflags "-X unglued/internal/version.Version=v1.4.0 \
+		-X unglued/internal/version.Commit=$(git rev-parse HEAD) \
+		-X unglued/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+
+Fehlt etwas, springen die VCS-Angaben ein, die go build selbst einbettet.
+*/
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = "" // Build-Zeitpunkt; ohne ldflags der Zeitpunkt des Commits
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // Build aus einem Arbeitsbaum mit Änderungen
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+func Get() Info {
+	in := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return in
+	}
+	if in.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		in.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if in.Commit == "" {
+				in.Commit = s.Value
+			}
+		case "vcs.time":
+			if in.Date == "" {
+				in.Date = s.Value
+			}
+		case "vcs.modified":
+			in.Modified = s.Value == "true"
+		}
+	}
+	return in
+}
+
+// String für -version und das Startup-Log.
+func (in Info) String() string {
+	s := "unglued " + in.Version
+	if in.Commit != "" {
+		c := in.Commit
+		if len(c) > 12 {
+			c = c[:12]
+		}
+		s += " (" + c
+		if in.Modified {
+			s += "+dirty"
+		}
+		s += ")"
+	}
+	if in.Date != "" {
+		s += " " + in.Date
+	}
+	return s + " " + in.GoVersion + " " + in.Platform
+}
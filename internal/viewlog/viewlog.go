@@ -0,0 +1,163 @@
+/*
+Package viewlog merkt sich pro Paste, wann sie aufgerufen wurde – für den
+Besitzer, nicht für Statistiken. Wie genau, legt die Stufe fest: vom bloßen
+Zeitpunkt (auf die Stunde gerundet) bis zu Client-Familie und Land. IPs und
+vollständige User-Agents werden nie gespeichert. Alles liegt im Speicher.
+*/
+package viewlog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level: wie viel über einen Aufruf festgehalten wird.
+type Level int
+
+const (
+	Off     Level = iota
+	Time          // nur der Zeitpunkt, auf die Stunde gerundet
+	Agent         // minutengenau, dazu die grobe Client-Familie ("Firefox/Linux", "curl")
+	Country       // wie Agent, dazu das Land aus dem Header des Proxys bzw. CDNs
+)
+
+var levelNames = []string{"off", "time", "agent", "country"}
+
+func (l Level) String() string { return levelNames[l] }
+
+// ParseLevel: "off", "time", "agent" oder "country"; leer = off.
+func ParseLevel(s string) (Level, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return Off, nil
+	}
+	for i, n := range levelNames {
+		if s == n {
+			return Level(i), nil
+		}
+	}
+	return Off, fmt.Errorf("unknown level %q (want %s)", s, strings.Join(levelNames, ", "))
+}
+
+type Event struct {
+	At      time.Time `json:"at"`
+	Agent   string    `json:"agent,omitempty"`
+	Country string    `json:"country,omitempty"`
+}
+
+type Log struct {
+	level Level
+	keep  int
+
+	mu    sync.Mutex
+	byID  map[string][]Event // älteste zuerst, höchstens keep
+	total map[string]int     // auch die, die schon herausgefallen sind
+}
+
+// New behält je Paste die letzten keep Aufrufe; nil bei Off, damit Aufrufer nur auf nil prüfen.
+func New(level Level, keep int) *Log {
+	if level == Off {
+		return nil
+	}
+	if keep <= 0 {
+		keep = 100
+	}
+	return &Log{level: level, keep: keep, byID: map[string][]Event{}, total: map[string]int{}}
+}
+
+func (l *Log) Level() Level { return l.level }
+
+// Add hält einen Aufruf fest; ua ist der rohe User-Agent, country der Ländercode (oder leer).
+func (l *Log) Add(id string, at time.Time, ua, country string) {
+	e := Event{At: at.UTC().Truncate(time.Minute)}
+	switch l.level {
+	case Time:
+		e.At = at.UTC().Truncate(time.Hour)
+	case Country:
+		e.Country = coarseCountry(country)
+		fallthrough
+	case Agent:
+		e.Agent = CoarseAgent(ua)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	evs := append(l.byID[id], e)
+	if len(evs) > l.keep {
+		evs = evs[len(evs)-l.keep:]
+	}
+	l.byID[id] = evs
+	l.total[id]++
+}
+
+// Events: die gemerkten Aufrufe von id, neueste zuerst, und wie viele es insgesamt waren.
+func (l *Log) Events(id string) ([]Event, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	evs := l.byID[id]
+	out := make([]Event, len(evs))
+	for i, e := range evs {
+		out[len(evs)-1-i] = e
+	}
+	return out, l.total[id]
+}
+
+// Forget wirft alles zu id weg (Paste endgültig entfernt).
+func (l *Log) Forget(id string) {
+	l.mu.Lock()
+	delete(l.byID, id)
+	delete(l.total, id)
+	l.mu.Unlock()
+}
+
+// coarseCountry: nur zweibuchstabige Codes; "XX", "T1" (Tor) u. Ä. der CDNs bleiben leer.
+func coarseCountry(c string) string {
+	c = strings.ToUpper(strings.TrimSpace(c))
+	if len(c) != 2 || c[0] < 'A' || c[0] > 'Z' || c[1] < 'A' || c[1] > 'Z' || c == "XX" {
+		return ""
+	}
+	return c
+}
+
+// Reihenfolge zählt: Edge und Opera nennen auch Chrome, Chrome nennt auch Safari.
+var browsers = []struct {
+	token, name string
+	bare        bool // Kommandozeile und Bots: das System sagt nichts
+}{
+	{"curl/", "curl", true}, {"Wget/", "wget", true}, {"HTTPie/", "httpie", true}, {"python-requests", "python", true},
+	{"Go-http-client", "go", true}, {"bot", "bot", true}, {"Bot", "bot", true}, {"spider", "bot", true},
+	{"Edg/", "Edge", false}, {"OPR/", "Opera", false}, {"Firefox/", "Firefox", false}, {"Chrome/", "Chrome", false}, {"Safari/", "Safari", false},
+}
+
+var systems = []struct{ token, name string }{
+	{"Android", "Android"}, {"iPhone", "iOS"}, {"iPad", "iOS"}, {"Windows", "Windows"},
+	{"Mac OS X", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+}
+
+/*
+CoarseAgent reduziert einen User-Agent auf Familie und System ohne Versionen,
+z.B. "Firefox/Linux" oder "curl" – genug, um "das war ich" von "das war ein
+Chat-Vorschau-Bot" zu unterscheiden, zu wenig, um jemanden wiederzuerkennen.
+*/
+func CoarseAgent(ua string) string {
+	if strings.TrimSpace(ua) == "" {
+		return "unknown"
+	}
+	name := "other"
+	for _, b := range browsers {
+		if strings.Contains(ua, b.token) {
+			if b.bare {
+				return b.name
+			}
+			name = b.name
+			break
+		}
+	}
+	for _, o := range systems {
+		if strings.Contains(ua, o.token) {
+			return name + "/" + o.name
+		}
+	}
+	return name
+}
@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+/*
+Target ist ein Empfänger von Events. Ohne Template geht das Event als JSON raus
+(wie bei -webhook-url); mit Template bestimmt das Template den Body, etwa für
+Zapier, n8n oder einen Chat-Webhook. Das Template sieht das Event (.Type, .ID,
+.URL, .Title, .Lang, .Author, .Version, .Size, .Code, .Flagged, .At).
+*/
+type Target struct {
+	Name           string            `json:"name"`
+	URL            string            `json:"url"`
+	Events         []string          `json:"events,omitempty"` // leer = alle
+	Method         string            `json:"method,omitempty"` // POST oder PUT; leer = POST
+	ContentType    string            `json:"content_type,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Template       string            `json:"template,omitempty"`
+	Secret         string            `json:"secret,omitempty"`
+	IncludeContent bool              `json:"include_content,omitempty"`
+
+	tmpl *template.Template
+}
+
+// Hilfsfunktionen in Templates; json ist für Werte innerhalb eines JSON-Bodys gedacht.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"truncate": func(n int, s string) string {
+		if len(s) <= n {
+			return s
+		}
+		s = s[:n]
+		for !utf8.ValidString(s) {
+			s = s[:len(s)-1]
+		}
+		return s + "…"
+	},
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+var knownEvents = []string{EventCreated, EventEdited, EventExpired}
+
+// prepare prüft das Ziel, setzt Vorgaben und übersetzt das Template.
+func (t *Target) prepare() error {
+	if t.Name == "" {
+		t.Name = t.URL
+	}
+	if !strings.HasPrefix(t.URL, "https://") && !strings.HasPrefix(t.URL, "http://") {
+		return fmt.Errorf("webhook %s: url must be http(s)", t.Name)
+	}
+	switch t.Method = strings.ToUpper(t.Method); t.Method {
+	case "":
+		t.Method = http.MethodPost
+	case http.MethodPost, http.MethodPut:
+	default:
+		return fmt.Errorf("webhook %s: method must be POST or PUT", t.Name)
+	}
+	for _, e := range t.Events {
+		if !slices.Contains(knownEvents, e) {
+			return fmt.Errorf("webhook %s: unknown event %q (known: %s)", t.Name, e, strings.Join(knownEvents, ", "))
+		}
+	}
+	if t.ContentType == "" {
+		t.ContentType = "application/json"
+	}
+	if t.Template == "" {
+		return nil
+	}
+	tmpl, err := template.New(t.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(t.Template)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", t.Name, err)
+	}
+	t.tmpl = tmpl
+	// einmal mit einem Beispiel ausführen, damit Tippfehler beim Start auffallen statt beim ersten Event
+	if _, err := t.render(Event{Type: EventCreated, ID: "example", Version: 1}); err != nil {
+		return fmt.Errorf("webhook %s: %w", t.Name, err)
+	}
+	return nil
+}
+
+func (t *Target) wants(typ string) bool {
+	return len(t.Events) == 0 || slices.Contains(t.Events, typ)
+}
+
+func (t *Target) render(ev Event) ([]byte, error) {
+	if t.tmpl == nil {
+		return json.Marshal(ev)
+	}
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, ev); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+/*
+Reload liest TargetsFile neu (JSON-Liste von Targets) und ersetzt die Ziele erst,
+wenn alle gültig sind. Das Ziel aus URL/Secret bleibt immer dabei.
+*/
+func (d *Dispatcher) Reload() error {
+	if d == nil {
+		return nil
+	}
+	var targets []*Target
+	if d.cfg.URL != "" {
+		targets = append(targets, &Target{Name: "default", URL: d.cfg.URL, Secret: d.cfg.Secret, IncludeContent: d.cfg.IncludeContent})
+	}
+	if d.cfg.TargetsFile != "" {
+		b, err := os.ReadFile(d.cfg.TargetsFile)
+		if err != nil {
+			return err
+		}
+		var list []*Target
+		if err := json.Unmarshal(b, &list); err != nil {
+			return fmt.Errorf("%s: %w", d.cfg.TargetsFile, err)
+		}
+		targets = append(targets, list...)
+	}
+	for _, t := range targets {
+		if err := t.prepare(); err != nil {
+			return err
+		}
+	}
+	d.targets.Store(&targets)
+	return nil
+}
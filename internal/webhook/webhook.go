@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"unglued/internal/util"
+)
+
+// Event-Typen, die an den Webhook gehen.
+const (
+	EventCreated = "paste.created"
+	EventEdited  = "paste.edited"
+	EventExpired = "paste.expired"
+)
+
+// Event ist der JSON-Body einer Zustellung.
+type Event struct {
+	Type    string    `json:"type"`
+	ID      string    `json:"id"`
+	URL     string    `json:"url"`
+	Title   string    `json:"title,omitempty"`
+	Lang    string    `json:"lang"`
+	Author  string    `json:"author,omitempty"`
+	Version int       `json:"version"`
+	Size    int       `json:"size"`
+	Code    string    `json:"code,omitempty"`
+	Flagged bool      `json:"flagged,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+type Config struct {
+	URL            string
+	Secret         string
+	IncludeContent bool // Code mitschicken (z.B. für externe Secret-Scanner)
+	// weitere Ziele mit eigenen Payload-Templates, siehe Target; leer = nur URL
+	TargetsFile string
+}
+
+/*
+Dispatcher verschickt Events asynchron, damit Handler nie auf den Empfänger warten.
+Ein nil-Dispatcher ist gültig und tut nichts.
+*/
+type Dispatcher struct {
+	cfg     Config
+	client  *http.Client
+	queue   chan Event
+	wg      sync.WaitGroup
+	targets atomic.Pointer[[]*Target]
+}
+
+// New liefert nil, wenn weder URL noch TargetsFile gesetzt sind.
+func New(cfg Config) (*Dispatcher, error) {
+	if cfg.URL == "" && cfg.TargetsFile == "" {
+		return nil, nil
+	}
+	d := &Dispatcher{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan Event, 256),
+	}
+	if err := d.Reload(); err != nil {
+		return nil, err
+	}
+	d.wg.Add(1)
+	go d.worker()
+	return d, nil
+}
+
+// Send stellt ein Event in die Queue; ist sie voll, wird verworfen statt zu blockieren.
+func (d *Dispatcher) Send(ev Event) {
+	if d == nil {
+		return
+	}
+	select {
+	case d.queue <- ev:
+	default:
+		log.Printf("webhook: queue full, dropping %s for %s", ev.Type, ev.ID)
+	}
+}
+
+// Close leert die Queue und wartet auf laufende Zustellungen.
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+	close(d.queue)
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for ev := range d.queue {
+		for _, t := range *d.targets.Load() {
+			if !t.wants(ev.Type) {
+				continue
+			}
+			e := ev
+			if !t.IncludeContent {
+				e.Code = ""
+			}
+			body, err := t.render(e)
+			if err != nil {
+				log.Printf("webhook %s: %s for %s: %v", t.Name, ev.Type, ev.ID, err)
+				continue
+			}
+			delivery := util.NewID(9)
+			for attempt := 0; attempt < 3; attempt++ {
+				if attempt > 0 {
+					time.Sleep(time.Duration(attempt) * 2 * time.Second)
+				}
+				if err = d.post(t, ev.Type, delivery, body); err == nil {
+					break
+				}
+			}
+			if err != nil {
+				log.Printf("webhook %s: %s for %s failed: %v", t.Name, ev.Type, ev.ID, err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) post(t *Target, typ, delivery string, body []byte) error {
+	req, err := http.NewRequest(t.Method, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", t.ContentType)
+	req.Header.Set("User-Agent", "unglued-webhook")
+	for k, v := range t.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("X-Unglued-Event", typ)
+	req.Header.Set("X-Unglued-Delivery", delivery)
+	if t.Secret != "" {
+		req.Header.Set("X-Unglued-Signature", "sha256="+Sign(t.Secret, body))
+	}
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Sign liefert die hex-kodierte HMAC-SHA256 über body (wie bei GitHub-Webhooks).
+func Sign(secret string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
@@ -0,0 +1,457 @@
+/*
+loadgen erzeugt Last gegen eine laufende unglued-Instanz: eine Mischung aus
+Anlegen, Ansehen, Raw und Bearbeiten über die öffentliche API, parallel für
+eine feste Dauer oder Anzahl Requests. Am Ende stehen Durchsatz und
+Latenz-Perzentile je Operation – als Tabelle oder JSON zum Vergleichen
+zwischen Releases und Backends (-label).
+
+	go run ./tools/loadgen -url http://localhost:8080 -c 16 -d 30s -mix create=1,view=8,raw=2,edit=1
+
+Mit -fail-p99 endet loadgen mit Status 1, wenn eine Operation langsamer war;
+so lässt es sich in CI als Regressionstest einsetzen.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+type config struct {
+	base     string
+	token    string
+	workers  int
+	duration time.Duration
+	total    int64
+	mix      []weighted
+	size     int
+	lang     string
+	seed     int
+	label    string
+	asJSON   bool
+	failP99  time.Duration
+}
+
+type weighted struct {
+	op     string
+	weight int
+}
+
+var ops = []string{"create", "view", "raw", "edit"}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("loadgen: ")
+	var cfg config
+	var mix string
+	flag.StringVar(&cfg.base, "url", "http://localhost:8080", "base URL of the unglued instance")
+	flag.StringVar(&cfg.token, "token", os.Getenv("UNGLUED_TOKEN"), "sent as Authorization: Bearer (API token, auth proxy)")
+	flag.IntVar(&cfg.workers, "c", 8, "concurrent workers")
+	flag.DurationVar(&cfg.duration, "d", 30*time.Second, "how long to run (ignored with -n)")
+	flag.Int64Var(&cfg.total, "n", 0, "stop after this many requests instead of -d")
+	flag.StringVar(&mix, "mix", "create=1,view=8,raw=2,edit=1", "relative weights of the operations create, view, raw and edit")
+	flag.IntVar(&cfg.size, "size", 4096, "approximate size of generated pastes in bytes")
+	flag.StringVar(&cfg.lang, "lang", "go", "language of generated pastes (drives the highlighter)")
+	flag.IntVar(&cfg.seed, "seed", 20, "pastes created before the measurement, so views and edits have targets")
+	flag.StringVar(&cfg.label, "label", "", "free-form name of this run, e.g. the backend under test; included in the report")
+	flag.BoolVar(&cfg.asJSON, "json", false, "print the report as JSON")
+	flag.DurationVar(&cfg.failP99, "fail-p99", 0, "exit with status 1 if any operation's p99 latency exceeds this (0 = never)")
+	flag.Parse()
+
+	var err error
+	if cfg.mix, err = parseMix(mix); err != nil {
+		log.Fatalf("-mix: %v", err)
+	}
+	if cfg.workers < 1 || cfg.size < 1 {
+		log.Fatal("-c and -size must be positive")
+	}
+	cfg.base = strings.TrimRight(cfg.base, "/")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	rep, err := run(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(rep)
+	} else {
+		rep.print(os.Stdout)
+	}
+	if cfg.failP99 > 0 {
+		for _, o := range rep.Ops {
+			if o.P99 > cfg.failP99 {
+				log.Printf("%s: p99 %v exceeds -fail-p99 %v", o.Op, o.P99, cfg.failP99)
+				os.Exit(1)
+			}
+		}
+	}
+}
+
+// parseMix: "create=1,view=8" → Gewichte; unbekannte Operationen sind ein Fehler.
+func parseMix(s string) ([]weighted, error) {
+	var mix []weighted
+	for _, part := range strings.Split(s, ",") {
+		name, w, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: want op=weight", part)
+		}
+		if !slices.Contains(ops, name) {
+			return nil, fmt.Errorf("unknown operation %q (want %s)", name, strings.Join(ops, ", "))
+		}
+		n, err := strconv.Atoi(w)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q: weight must be a non-negative integer", part)
+		}
+		if n > 0 {
+			mix = append(mix, weighted{name, n})
+		}
+	}
+	if len(mix) == 0 {
+		return nil, errors.New("no operation has a positive weight")
+	}
+	return mix, nil
+}
+
+func (c config) pick(rng *rand.Rand) string {
+	sum := 0
+	for _, w := range c.mix {
+		sum += w.weight
+	}
+	n := rng.IntN(sum)
+	for _, w := range c.mix {
+		if n -= w.weight; n < 0 {
+			return w.op
+		}
+	}
+	return c.mix[0].op
+}
+
+// target: eine angelegte Paste; key ist der Edit-Token aus edit_url.
+type target struct {
+	id, key string
+}
+
+// pool der Pastes, auf die view/raw/edit zielen; create füllt ihn weiter auf.
+type pool struct {
+	mu sync.RWMutex
+	ts []target
+}
+
+func (p *pool) add(t target) {
+	p.mu.Lock()
+	p.ts = append(p.ts, t)
+	p.mu.Unlock()
+}
+
+func (p *pool) any(rng *rand.Rand) (target, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.ts) == 0 {
+		return target{}, false
+	}
+	return p.ts[rng.IntN(len(p.ts))], true
+}
+
+type client struct {
+	cfg  config
+	http *http.Client
+}
+
+func (c *client) do(ctx context.Context, method, path string, body []byte) (int, []byte, error) {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.base+path, rd)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json") // sonst antwortet /api/paste mit Text
+	}
+	if c.cfg.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, b, err
+}
+
+func (c *client) create(ctx context.Context, rng *rand.Rand) (target, error) {
+	body, _ := json.Marshal(map[string]any{
+		"code": genCode(rng, c.cfg.size), "lang": c.cfg.lang, "ttl": "1h", "editable": true,
+		"title": "loadgen",
+	})
+	status, b, err := c.do(ctx, http.MethodPost, "/api/v1/paste", body)
+	if err != nil {
+		return target{}, err
+	}
+	if status/100 != 2 {
+		return target{}, fmt.Errorf("create: %d %s", status, bytes.TrimSpace(b))
+	}
+	var resp struct {
+		ID      string `json:"id"`
+		EditURL string `json:"edit_url"`
+	}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return target{}, fmt.Errorf("create: %w", err)
+	}
+	t := target{id: resp.ID}
+	if u, err := url.Parse(resp.EditURL); err == nil {
+		t.key = u.Query().Get("key")
+	}
+	return t, nil
+}
+
+// op führt eine Operation aus; gemessen wird im Aufrufer.
+func (c *client) op(ctx context.Context, name string, rng *rand.Rand, p *pool) error {
+	if name == "create" {
+		t, err := c.create(ctx, rng)
+		if err == nil {
+			p.add(t)
+		}
+		return err
+	}
+	t, ok := p.any(rng)
+	if !ok {
+		return errors.New("no pastes to work on")
+	}
+	var status int
+	var b []byte
+	var err error
+	switch name {
+	case "view":
+		status, b, err = c.do(ctx, http.MethodGet, "/p/"+t.id, nil)
+	case "raw":
+		status, b, err = c.do(ctx, http.MethodGet, "/raw/"+t.id, nil)
+	case "edit":
+		if t.key == "" {
+			return errors.New("edit: instance returned no edit key")
+		}
+		body, _ := json.Marshal(map[string]any{"code": genCode(rng, c.cfg.size)})
+		status, b, err = c.do(ctx, http.MethodPost, "/api/v1/paste/"+t.id+"/edit?key="+url.QueryEscape(t.key), body)
+	}
+	if err != nil {
+		return err
+	}
+	if status/100 != 2 {
+		return fmt.Errorf("%s: %d %s", name, status, firstLine(b))
+	}
+	return nil
+}
+
+func firstLine(b []byte) []byte {
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		b = b[:i]
+	}
+	if len(b) > 200 {
+		b = b[:200]
+	}
+	return b
+}
+
+// samples sammelt Latenzen und Fehler einer Operation.
+type samples struct {
+	mu     sync.Mutex
+	lat    []time.Duration
+	errors int
+	last   string // letzter Fehler, für den Bericht
+}
+
+func (s *samples) add(d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.errors++
+		s.last = err.Error()
+		return
+	}
+	s.lat = append(s.lat, d)
+}
+
+func run(ctx context.Context, cfg config) (*report, error) {
+	c := &client{cfg: cfg, http: &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: cfg.workers, ForceAttemptHTTP2: true},
+	}}
+	rng := rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0))
+	p := &pool{}
+	for i := 0; i < cfg.seed; i++ {
+		t, err := c.create(ctx, rng)
+		if err != nil {
+			return nil, fmt.Errorf("seeding: %w", err)
+		}
+		p.add(t)
+	}
+
+	if cfg.total == 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.duration)
+		defer cancel()
+	}
+	stats := map[string]*samples{}
+	for _, w := range cfg.mix {
+		stats[w.op] = &samples{}
+	}
+	var issued atomic.Int64
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+		go func(seed uint64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(seed, uint64(time.Now().UnixNano())))
+			for ctx.Err() == nil {
+				if cfg.total > 0 && issued.Add(1) > cfg.total {
+					return
+				}
+				name := cfg.pick(rng)
+				t0 := time.Now()
+				err := c.op(ctx, name, rng, p)
+				if ctx.Err() != nil {
+					return // abgebrochen, nicht gescheitert
+				}
+				stats[name].add(time.Since(t0), err)
+			}
+		}(uint64(i))
+	}
+	wg.Wait()
+	return newReport(cfg, stats, time.Since(start)), nil
+}
+
+type opReport struct {
+	Op        string        `json:"op"`
+	Requests  int           `json:"requests"`
+	Errors    int           `json:"errors"`
+	LastError string        `json:"last_error,omitempty"`
+	RPS       float64       `json:"rps"`
+	P50       time.Duration `json:"p50_ns"`
+	P90       time.Duration `json:"p90_ns"`
+	P99       time.Duration `json:"p99_ns"`
+	Max       time.Duration `json:"max_ns"`
+}
+
+type report struct {
+	Label    string        `json:"label,omitempty"`
+	URL      string        `json:"url"`
+	Workers  int           `json:"workers"`
+	Size     int           `json:"size"`
+	Elapsed  time.Duration `json:"elapsed_ns"`
+	Requests int           `json:"requests"`
+	RPS      float64       `json:"rps"`
+	Ops      []opReport    `json:"ops"`
+}
+
+func newReport(cfg config, stats map[string]*samples, elapsed time.Duration) *report {
+	rep := &report{Label: cfg.label, URL: cfg.base, Workers: cfg.workers, Size: cfg.size, Elapsed: elapsed}
+	secs := elapsed.Seconds()
+	for _, name := range ops {
+		s, ok := stats[name]
+		if !ok {
+			continue
+		}
+		slices.Sort(s.lat)
+		o := opReport{Op: name, Requests: len(s.lat) + s.errors, Errors: s.errors, LastError: s.last}
+		if secs > 0 {
+			o.RPS = float64(o.Requests) / secs
+		}
+		if n := len(s.lat); n > 0 {
+			o.P50, o.P90, o.P99, o.Max = percentile(s.lat, 50), percentile(s.lat, 90), percentile(s.lat, 99), s.lat[n-1]
+		}
+		rep.Requests += o.Requests
+		rep.Ops = append(rep.Ops, o)
+	}
+	if secs > 0 {
+		rep.RPS = float64(rep.Requests) / secs
+	}
+	return rep
+}
+
+// percentile nach Nearest-Rank; sorted muss aufsteigend sortiert sein.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p + 99) / 100
+	if i < 1 {
+		i = 1
+	}
+	return sorted[i-1]
+}
+
+func (r *report) print(w io.Writer) {
+	if r.Label != "" {
+		fmt.Fprintf(w, "%s: ", r.Label)
+	}
+	fmt.Fprintf(w, "%d requests in %v against %s (%d workers, %d-byte pastes), %.1f req/s\n\n",
+		r.Requests, r.Elapsed.Round(time.Millisecond), r.URL, r.Workers, r.Size, r.RPS)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\trequests\terrors\treq/s\tp50\tp90\tp99\tmax\t")
+	for _, o := range r.Ops {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%v\t%v\t%v\t%v\t\n", o.Op, o.Requests, o.Errors, o.RPS,
+			round(o.P50), round(o.P90), round(o.P99), round(o.Max))
+	}
+	_ = tw.Flush()
+	for _, o := range r.Ops {
+		if o.LastError != "" {
+			fmt.Fprintf(w, "\n%s: last error: %s", o.Op, o.LastError)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+func round(d time.Duration) time.Duration {
+	if d > time.Millisecond {
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
+/*
+genCode baut Go-artigen Quelltext von etwa size Bytes mit zufälligen Namen, damit
+jede Paste anders aussieht (kein Dedup) und der Highlighter echte Arbeit hat.
+*/
+func genCode(rng *rand.Rand, size int) string {
+	var b strings.Builder
+	b.WriteString("package loadgen\n\n")
+	for b.Len() < size {
+		name := ident(rng)
+		fmt.Fprintf(&b, "// %s sums the values above a threshold.\n", name)
+		fmt.Fprintf(&b, "func %s(xs []int, limit int) (sum int) {\n", name)
+		fmt.Fprintf(&b, "\tfor i, x := range xs {\n\t\tif x > limit && i%%%d != 0 {\n", rng.IntN(7)+2)
+		fmt.Fprintf(&b, "\t\t\tsum += x * %d // %q\n\t\t}\n\t}\n\treturn sum\n}\n\n", rng.IntN(1000), ident(rng))
+	}
+	return b.String()
+}
+
+func ident(rng *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, 8)
+	for i := range b {
+		b[i] = letters[rng.IntN(len(letters))]
+	}
+	return string(b)
+}